@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log"
 	"log/slog"
 	"os"
@@ -16,12 +18,25 @@ import (
 func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
-	if err := run(ctx); err != nil {
+	if err := run(ctx, os.Args[1:]); err != nil {
 		log.Fatal(err)
 	}
 }
 
-func run(ctx context.Context) error {
+// run starts the bridge, or runs one of the session subcommands, which only
+// need the session settings of the configuration.
+func run(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "serve":
+		case "mcp":
+			return runMCP(ctx, os.Stdin, os.Stdout)
+		case "login", "logout", "status":
+			return runSessionCommand(ctx, args[0], config.FromEnv(), os.Stdin, os.Stdout)
+		default:
+			return fmt.Errorf("unknown command %q (want serve, mcp, login, logout or status)", args[0])
+		}
+	}
 	cfg, err := config.Load()
 	if err != nil {
 		return err
@@ -36,6 +51,18 @@ func run(ctx context.Context) error {
 	return application.Run(ctx)
 }
 
+// runMCP serves MCP over stdio. Stdout carries the protocol, so logs go to
+// stderr, and only the provider settings of the configuration are needed.
+func runMCP(ctx context.Context, in io.Reader, out io.Writer) error {
+	cfg := config.FromEnv()
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level(cfg.LogLevel)}))
+	prov, err := app.BuildProvider(cfg)
+	if err != nil {
+		return err
+	}
+	return app.New(cfg, prov, nil, logger).ServeMCP(ctx, in, out)
+}
+
 func level(v string) slog.Level {
 	switch v {
 	case "debug":
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"log/slog"
+	"strings"
 	"testing"
 	"time"
 )
@@ -23,7 +25,7 @@ func TestRunValidationError(t *testing.T) {
 	t.Setenv("PCB_BEARER_TOKEN", "")
 	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
 	defer cancel()
-	if err := run(ctx); err == nil {
+	if err := run(ctx, nil); err == nil {
 		t.Fatal("expected config validation error")
 	}
 }
@@ -40,8 +42,25 @@ func TestRunSuccessCancel(t *testing.T) {
 		time.Sleep(40 * time.Millisecond)
 		cancel()
 	}()
-	err := run(ctx)
+	err := run(ctx, nil)
 	if err != nil && !errors.Is(err, context.Canceled) {
 		t.Fatalf("unexpected run error: %v", err)
 	}
 }
+
+func TestRunMCP(t *testing.T) {
+	t.Setenv("PCB_PROVIDER", "ics")
+	t.Setenv("PCB_ICS_URL", "https://example.test/a.ics")
+	in := strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}` + "\n")
+	var out bytes.Buffer
+	if err := runMCP(context.Background(), in, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), `"result":{}`) {
+		t.Fatalf("unexpected reply %q", out.String())
+	}
+	t.Setenv("PCB_PROVIDER", "bogus")
+	if err := runMCP(context.Background(), in, &out); err == nil {
+		t.Fatal("expected a provider error")
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/auth"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/config"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/protonapi"
+	"golang.org/x/term"
+)
+
+// account is the part of the Proton client the session commands use.
+type account interface {
+	Login(ctx context.Context, username, password string) (protonapi.Auth, error)
+	Submit2FA(ctx context.Context, totpCode string) error
+	SaltKeyPassword(ctx context.Context, mailboxPassword []byte) ([]byte, error)
+	Logout(ctx context.Context) error
+	GetUser(ctx context.Context) (protonapi.User, error)
+	Status() protonapi.ConnectionStatus
+}
+
+type protonAccount struct {
+	*protonapi.Authenticator
+	*protonapi.Client
+}
+
+func newProtonAccount(session auth.Session, onAuth func(protonapi.Auth)) account {
+	client := protonapi.NewClient(protonapi.ClientOptions{
+		UID:          session.UID,
+		AccessToken:  session.AccessToken,
+		RefreshToken: session.RefreshToken,
+		OnAuth:       onAuth,
+	})
+	return protonAccount{Authenticator: protonapi.NewAuthenticator(client), Client: client}
+}
+
+type sessionCommand struct {
+	cfg        config.Config
+	store      auth.Store
+	prompt     *prompter
+	out        io.Writer
+	newAccount func(auth.Session, func(protonapi.Auth)) account
+	now        func() time.Time
+}
+
+func runSessionCommand(ctx context.Context, name string, cfg config.Config, in *os.File, out io.Writer) error {
+	cmd := &sessionCommand{
+		cfg:        cfg,
+		store:      auth.Store{Path: cfg.SessionPath},
+		prompt:     newPrompter(in, out),
+		out:        out,
+		newAccount: newProtonAccount,
+		now:        time.Now,
+	}
+	return cmd.run(ctx, name)
+}
+
+func (c *sessionCommand) run(ctx context.Context, name string) error {
+	if c.store.Path == "" {
+		return errors.New("PCB_SESSION_PATH is not set")
+	}
+	switch name {
+	case "login":
+		return c.login(ctx)
+	case "logout":
+		return c.logout(ctx)
+	case "status":
+		return c.status(ctx)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
+}
+
+func (c *sessionCommand) login(ctx context.Context) error {
+	username, err := c.prompt.line("Username")
+	if err != nil {
+		return err
+	}
+	password, err := c.prompt.secret("Password")
+	if err != nil {
+		return err
+	}
+	acct := c.newAccount(auth.Session{}, nil)
+	session, err := acct.Login(ctx, username, password)
+	if err != nil {
+		return fmt.Errorf("login: %w", err)
+	}
+	if session.TwoFA.Enabled&protonapi.TwoFATOTP != 0 {
+		code, err := c.prompt.line("TOTP code")
+		if err != nil {
+			return err
+		}
+		if err := acct.Submit2FA(ctx, code); err != nil {
+			return fmt.Errorf("submit 2FA: %w", err)
+		}
+	}
+	mailboxPassword := password
+	if session.PasswordMode == protonapi.TwoPasswordMode {
+		if mailboxPassword, err = c.prompt.secret("Mailbox password"); err != nil {
+			return err
+		}
+	}
+	keyPassword, err := acct.SaltKeyPassword(ctx, []byte(mailboxPassword))
+	if err != nil {
+		return err
+	}
+	sessionPassword, err := c.sessionPassword()
+	if err != nil {
+		return err
+	}
+	saved := auth.Session{
+		UID:          session.UID,
+		AccessToken:  session.AccessToken,
+		RefreshToken: session.RefreshToken,
+		Username:     username,
+		KeyPassword:  string(keyPassword),
+		CreatedAt:    c.now().UTC(),
+	}
+	if err := c.store.Save(saved, sessionPassword); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "Logged in as %s; session saved to %s\n", username, c.store.Path)
+	return nil
+}
+
+// logout revokes the session upstream when it can be read and always removes
+// the local copy.
+func (c *sessionCommand) logout(ctx context.Context) error {
+	session, err := c.load()
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintln(c.out, "Not logged in")
+		return nil
+	}
+	if err != nil {
+		fmt.Fprintf(c.out, "Could not read the saved session (%v); removing it without revoking\n", err)
+	} else if err := c.newAccount(session, nil).Logout(ctx); err != nil {
+		fmt.Fprintf(c.out, "Could not revoke the session upstream: %v\n", err)
+	}
+	if err := c.store.Delete(); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out, "Logged out")
+	return nil
+}
+
+func (c *sessionCommand) status(ctx context.Context) error {
+	session, err := c.load()
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintln(c.out, "Not logged in")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var password string
+	if password, err = c.sessionPassword(); err != nil {
+		return err
+	}
+	acct := c.newAccount(session, func(a protonapi.Auth) {
+		session.UID, session.AccessToken, session.RefreshToken = a.UID, a.AccessToken, a.RefreshToken
+		if err := c.store.Save(session, password); err != nil {
+			fmt.Fprintf(c.out, "Could not save refreshed session: %v\n", err)
+		}
+	})
+	connection := "connected"
+	if _, err := acct.GetUser(ctx); err != nil {
+		connection = fmt.Sprintf("%s (%v)", acct.Status(), err)
+	}
+	age := "unknown"
+	if !session.CreatedAt.IsZero() {
+		age = c.now().Sub(session.CreatedAt).Round(time.Second).String()
+	}
+	fmt.Fprintf(c.out, "Account:     %s\nSession age: %s\nConnection:  %s\n", session.Username, age, connection)
+	return nil
+}
+
+func (c *sessionCommand) load() (auth.Session, error) {
+	if _, err := os.Stat(c.store.Path); err != nil {
+		return auth.Session{}, err
+	}
+	password, err := c.sessionPassword()
+	if err != nil {
+		return auth.Session{}, err
+	}
+	return c.store.Load(password)
+}
+
+// sessionPassword comes from PCB_SESSION_PASSWORD and is asked for once
+// otherwise.
+func (c *sessionCommand) sessionPassword() (string, error) {
+	if c.cfg.SessionPassword != "" {
+		return c.cfg.SessionPassword, nil
+	}
+	password, err := c.prompt.secret("Session password")
+	if err != nil {
+		return "", err
+	}
+	if password == "" {
+		return "", errors.New("session password is required")
+	}
+	c.cfg.SessionPassword = password
+	return password, nil
+}
+
+// prompter reads answers line by line, without echo for secrets when the
+// input is a terminal.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+	fd  int
+}
+
+func newPrompter(in *os.File, out io.Writer) *prompter {
+	p := &prompter{in: bufio.NewReader(in), out: out, fd: -1}
+	if fd := int(in.Fd()); term.IsTerminal(fd) {
+		p.fd = fd
+	}
+	return p
+}
+
+func (p *prompter) line(label string) (string, error) {
+	fmt.Fprintf(p.out, "%s: ", label)
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("read %s: %w", strings.ToLower(label), err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (p *prompter) secret(label string) (string, error) {
+	if p.fd < 0 {
+		return p.line(label)
+	}
+	fmt.Fprintf(p.out, "%s: ", label)
+	raw, err := term.ReadPassword(p.fd)
+	fmt.Fprintln(p.out)
+	if err != nil {
+		return "", fmt.Errorf("read %s: %w", strings.ToLower(label), err)
+	}
+	return string(raw), nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	proton "github.com/ProtonMail/go-proton-api"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/auth"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/config"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/protonapi"
+)
+
+type fakeAccount struct {
+	auth        protonapi.Auth
+	loginErr    error
+	totp        string
+	mailbox     []byte
+	userErr     error
+	logoutErr   error
+	loggedOut   bool
+	session     auth.Session
+	onAuth      func(protonapi.Auth)
+	refreshWith *protonapi.Auth
+}
+
+func (f *fakeAccount) Login(_ context.Context, username, password string) (protonapi.Auth, error) {
+	if username != "user@example.com" || password != "secret" {
+		return protonapi.Auth{}, errors.New("bad credentials")
+	}
+	return f.auth, f.loginErr
+}
+func (f *fakeAccount) Submit2FA(_ context.Context, code string) error {
+	f.totp = code
+	return nil
+}
+func (f *fakeAccount) SaltKeyPassword(_ context.Context, mailbox []byte) ([]byte, error) {
+	f.mailbox = mailbox
+	return []byte("salted"), nil
+}
+func (f *fakeAccount) Logout(context.Context) error {
+	f.loggedOut = true
+	return f.logoutErr
+}
+func (f *fakeAccount) GetUser(context.Context) (protonapi.User, error) {
+	if f.refreshWith != nil && f.onAuth != nil {
+		f.onAuth(*f.refreshWith)
+	}
+	return protonapi.User{}, f.userErr
+}
+func (f *fakeAccount) Status() protonapi.ConnectionStatus {
+	if f.userErr != nil {
+		return protonapi.StatusDisconnected
+	}
+	return protonapi.StatusConnected
+}
+
+func testSessionCommand(t *testing.T, input string, cfg config.Config, acct *fakeAccount) (*sessionCommand, *bytes.Buffer) {
+	t.Helper()
+	out := &bytes.Buffer{}
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+	return &sessionCommand{
+		cfg:    cfg,
+		store:  auth.Store{Path: cfg.SessionPath},
+		prompt: &prompter{in: bufio.NewReader(strings.NewReader(input)), out: out, fd: -1},
+		out:    out,
+		newAccount: func(session auth.Session, onAuth func(protonapi.Auth)) account {
+			acct.session, acct.onAuth = session, onAuth
+			return acct
+		},
+		now: func() time.Time { return now },
+	}, out
+}
+
+func TestLoginStatusLogout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.enc")
+	cfg := config.Config{SessionPath: path}
+	acct := &fakeAccount{auth: protonapi.Auth{
+		UID: "uid", AccessToken: "a1", RefreshToken: "r1",
+		TwoFA:        proton.TwoFAInfo{Enabled: protonapi.TwoFATOTP},
+		PasswordMode: protonapi.TwoPasswordMode,
+	}}
+
+	cmd, out := testSessionCommand(t, "user@example.com\nsecret\n123456\nmailbox\nsession-pw\n", cfg, acct)
+	if err := cmd.run(context.Background(), "login"); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if acct.totp != "123456" || string(acct.mailbox) != "mailbox" || !strings.Contains(out.String(), "Logged in as user@example.com") {
+		t.Fatalf("unexpected login flow totp=%q mailbox=%q out=%q", acct.totp, acct.mailbox, out)
+	}
+	saved, err := auth.Store{Path: path}.Load("session-pw")
+	if err != nil {
+		t.Fatalf("load saved session: %v", err)
+	}
+	if saved.UID != "uid" || saved.RefreshToken != "r1" || saved.Username != "user@example.com" || saved.KeyPassword != "salted" || saved.CreatedAt.IsZero() {
+		t.Fatalf("unexpected saved session: %+v", saved)
+	}
+
+	// Status reports the account and persists tokens rotated on the way.
+	cfg.SessionPassword = "session-pw"
+	acct.refreshWith = &protonapi.Auth{UID: "uid", AccessToken: "a2", RefreshToken: "r2"}
+	cmd, out = testSessionCommand(t, "", cfg, acct)
+	cmd.now = func() time.Time { return saved.CreatedAt.Add(90 * time.Minute) }
+	if err := cmd.run(context.Background(), "status"); err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	for _, want := range []string{"Account:     user@example.com", "Session age: 1h30m0s", "Connection:  connected"} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("status output missing %q:\n%s", want, out)
+		}
+	}
+	if acct.session.AccessToken != "a1" {
+		t.Fatalf("status used wrong session: %+v", acct.session)
+	}
+	if saved, _ = (auth.Store{Path: path}).Load("session-pw"); saved.RefreshToken != "r2" || saved.KeyPassword != "salted" {
+		t.Fatalf("rotated tokens not persisted: %+v", saved)
+	}
+
+	acct.refreshWith = nil
+	acct.userErr = protonapi.ErrSessionExpired
+	cmd, out = testSessionCommand(t, "", cfg, acct)
+	if err := cmd.run(context.Background(), "status"); err != nil || !strings.Contains(out.String(), "Connection:  disconnected (proton session expired") {
+		t.Fatalf("unexpected disconnected status %v:\n%s", err, out)
+	}
+
+	acct.logoutErr = errors.New("offline")
+	cmd, out = testSessionCommand(t, "", cfg, acct)
+	if err := cmd.run(context.Background(), "logout"); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
+	if !acct.loggedOut || !strings.Contains(out.String(), "Could not revoke") || !strings.Contains(out.String(), "Logged out") {
+		t.Fatalf("unexpected logout output:\n%s", out)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected session file removed, got %v", err)
+	}
+
+	for _, name := range []string{"status", "logout"} {
+		cmd, out = testSessionCommand(t, "", cfg, acct)
+		if err := cmd.run(context.Background(), name); err != nil || !strings.Contains(out.String(), "Not logged in") {
+			t.Fatalf("%s without session: %v %q", name, err, out)
+		}
+	}
+}
+
+func TestSessionCommandErrors(t *testing.T) {
+	acct := &fakeAccount{auth: protonapi.Auth{UID: "uid"}}
+	cmd, _ := testSessionCommand(t, "", config.Config{}, acct)
+	if err := cmd.run(context.Background(), "status"); err == nil {
+		t.Fatal("expected missing session path error")
+	}
+
+	path := filepath.Join(t.TempDir(), "session.enc")
+	cfg := config.Config{SessionPath: path, SessionPassword: "pw"}
+	cmd, _ = testSessionCommand(t, "user@example.com\nwrong\n", cfg, acct)
+	if err := cmd.run(context.Background(), "login"); err == nil || !strings.Contains(err.Error(), "bad credentials") {
+		t.Fatalf("expected login error, got %v", err)
+	}
+	cmd, _ = testSessionCommand(t, "", cfg, acct)
+	if err := cmd.run(context.Background(), "login"); err == nil {
+		t.Fatal("expected prompt error on empty input")
+	}
+	cmd, _ = testSessionCommand(t, "", cfg, acct)
+	if err := cmd.run(context.Background(), "bogus"); err == nil {
+		t.Fatal("expected unknown command error")
+	}
+
+	// Single-password accounts reuse the login password for the keys.
+	cmd, _ = testSessionCommand(t, "user@example.com\nsecret\n", cfg, acct)
+	if err := cmd.run(context.Background(), "login"); err != nil || string(acct.mailbox) != "secret" {
+		t.Fatalf("login: %v mailbox=%q", err, acct.mailbox)
+	}
+
+	// An unreadable session is removed without revoking it.
+	cfg.SessionPassword = ""
+	cmd, out := testSessionCommand(t, "\n", cfg, acct)
+	if err := cmd.run(context.Background(), "status"); err == nil {
+		t.Fatal("expected empty session password error")
+	}
+	acct.loggedOut = false
+	cmd, out = testSessionCommand(t, "wrong-pw\n", cfg, acct)
+	if err := cmd.run(context.Background(), "logout"); err != nil || acct.loggedOut || !strings.Contains(out.String(), "without revoking") {
+		t.Fatalf("unexpected logout %v revoked=%v:\n%s", err, acct.loggedOut, out)
+	}
+}
+
+func TestRunDispatchesCommands(t *testing.T) {
+	t.Setenv("PCB_SESSION_PATH", filepath.Join(t.TempDir(), "session.enc"))
+	if err := run(context.Background(), []string{"bogus"}); err == nil {
+		t.Fatal("expected unknown command error")
+	}
+	if err := run(context.Background(), []string{"status"}); err != nil {
+		t.Fatalf("status without session: %v", err)
+	}
+	if got := newProtonAccount(auth.Session{}, nil).Status(); got != protonapi.StatusUnknown {
+		t.Fatalf("unexpected new account status %s", got)
+	}
+}
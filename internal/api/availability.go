@@ -0,0 +1,46 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/freebusy"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/security"
+)
+
+type slotsResponse struct {
+	TimeZone  string        `json:"timezone"`
+	Calendars []string      `json:"calendars"`
+	Slots     []domain.Slot `json:"slots"`
+}
+
+// handleSlots finds meeting slots that are free in every selected calendar,
+// within working hours and keeping the requested buffers around busy time.
+func (s *Server) handleSlots(w http.ResponseWriter, r *http.Request) {
+	var in freebusy.SlotRequest
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeInvalid(w, fieldError{Field: "body", Issue: "invalid json: " + err.Error()})
+		return
+	}
+	q, invalid := in.Query()
+	if len(invalid) > 0 {
+		details := make([]fieldError, 0, len(invalid))
+		for _, e := range invalid {
+			details = append(details, fieldError{Field: e.Field, Issue: e.Reason})
+		}
+		writeInvalid(w, details...)
+		return
+	}
+	ids, err := security.FromContext(r.Context()).CheckCalendars(in.CalendarIDs)
+	if err != nil {
+		writeProviderErr(w, err)
+		return
+	}
+	ids, slots, err := freebusy.FindSlots(r.Context(), s.provider, ids, q)
+	if err != nil {
+		writeProviderErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, slotsResponse{TimeZone: q.Location.String(), Calendars: ids, Slots: slots})
+}
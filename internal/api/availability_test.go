@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSlotsEndpoint(t *testing.T) {
+	s := New(Options{Provider: busyProvider{}})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+	post := func(body string) *http.Response {
+		t.Helper()
+		res, err := http.Post(ts.URL+"/v1/availability/slots", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	window := `"from":"2026-03-02T00:00:00Z","to":"2026-03-03T00:00:00Z"`
+
+	// Default working hours are 09:00-17:00 and the step defaults to the
+	// duration; 09:00-11:00 is busy and 14:00-15:00 tentative.
+	res := post(`{` + window + `,"duration_minutes":60}`)
+	var out slotsResponse
+	_ = json.NewDecoder(res.Body).Decode(&out)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || out.TimeZone != "UTC" || len(out.Calendars) != 2 {
+		t.Fatalf("unexpected response %d %+v", res.StatusCode, out)
+	}
+	want := []int{11, 12, 13, 15, 16}
+	if len(out.Slots) != len(want) {
+		t.Fatalf("got slots %+v", out.Slots)
+	}
+	for i, h := range want {
+		if out.Slots[i].Start.Hour() != h {
+			t.Fatalf("slot %d: got %+v want %02d:00", i, out.Slots[i], h)
+		}
+	}
+
+	res = post(`{` + window + `,"calendar_ids":["c1"],"duration_minutes":30,"buffer_before_minutes":30,` +
+		`"timezone":"Europe/Berlin","working_hours":{"Monday":[{"start":"10:00","end":"12:00"}]},"max_results":1}`)
+	out = slotsResponse{}
+	_ = json.NewDecoder(res.Body).Decode(&out)
+	res.Body.Close()
+	// c1 is busy 10:00-11:00 Berlin, so the buffer leaves 11:30 local.
+	if len(out.Slots) != 1 || out.TimeZone != "Europe/Berlin" || out.Slots[0].Start.UTC().Hour() != 10 || out.Slots[0].Start.Minute() != 30 {
+		t.Fatalf("unexpected zoned slots %+v", out)
+	}
+
+	res = post(`{"to":"2026-03-03T00:00:00Z","duration_minutes":0,"timezone":"Mars/Olympus",` +
+		`"working_hours":{"someday":[],"friday":[{"start":"9:00","end":"17:00"}]},"max_results":1000}`)
+	if e := decodeEnvelope(t, res); res.StatusCode != http.StatusBadRequest || len(e.Details) != 6 {
+		t.Fatalf("expected six field errors, got %d %+v", res.StatusCode, e)
+	}
+	res = post(`{"from":"2026-03-02T00:00:00Z","to":"2026-09-02T00:00:00Z","duration_minutes":30}`)
+	if e := decodeEnvelope(t, res); res.StatusCode != http.StatusBadRequest || e.Details[0].Field != "to" {
+		t.Fatalf("expected window too long, got %d %+v", res.StatusCode, e)
+	}
+	res = post(`{`)
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", res.StatusCode)
+	}
+	res = post(`{` + window + `,"calendar_ids":["missing"],"duration_minutes":30}`)
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d", res.StatusCode)
+	}
+	res, _ = http.Get(ts.URL + "/v1/availability/slots")
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 got %d", res.StatusCode)
+	}
+}
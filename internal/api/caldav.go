@@ -0,0 +1,872 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/ical"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/security"
+)
+
+// CalDAV (RFC 4791) front-end over the provider. The layout is fixed: one
+// principal whose calendar home lists every provider calendar, and one
+// calendar object resource per event series, named after the series ID or,
+// for objects created over CalDAV, the name the client chose.
+const (
+	davPrincipalPath = "/dav/principal/"
+	davHomePath      = "/dav/calendars/"
+
+	davNS       = "DAV:"
+	calDAVNS    = "urn:ietf:params:xml:ns:caldav"
+	calServerNS = "http://calendarserver.org/ns/"
+	appleICalNS = "http://apple.com/ns/ical/"
+
+	davCalendarType = "text/calendar; charset=utf-8; component=VEVENT"
+	maxDAVBody      = 1 << 20
+)
+
+func (s *Server) registerCalDAV(mux *http.ServeMux) {
+	mux.HandleFunc("/.well-known/caldav", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, davPrincipalPath, http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/dav/{$}", s.handleDAVRoot)
+	mux.HandleFunc(davPrincipalPath+"{$}", s.handleDAVPrincipal)
+	mux.HandleFunc(davHomePath+"{$}", s.handleDAVHome)
+	mux.HandleFunc(davHomePath+"{id}/{$}", s.handleDAVCalendar)
+	mux.HandleFunc(davHomePath+"{id}/{object}", s.handleDAVObject)
+}
+
+// isDAVPath reports whether path is served by the CalDAV front-end, whose
+// clients need a Basic challenge to prompt for credentials.
+func isDAVPath(path string) bool {
+	return strings.HasPrefix(path, "/dav/") || path == "/.well-known/caldav"
+}
+
+// davOptions answers OPTIONS and reports whether the request was handled.
+func davOptions(w http.ResponseWriter, r *http.Request, allow string) bool {
+	w.Header().Set("DAV", "1, 3, calendar-access")
+	if r.Method != http.MethodOptions {
+		return false
+	}
+	w.Header().Set("Allow", allow)
+	w.WriteHeader(http.StatusOK)
+	return true
+}
+
+func (s *Server) handleDAVRoot(w http.ResponseWriter, r *http.Request) {
+	if davOptions(w, r, "OPTIONS, PROPFIND") {
+		return
+	}
+	if r.Method != "PROPFIND" {
+		methodNotAllowed(w, r)
+		return
+	}
+	req, ok := readPropfind(w, r)
+	if !ok {
+		return
+	}
+	writeMultistatus(w, []davResponse{req.respond("/dav/", davCollectionProps("Proton Calendar Bridge"))})
+}
+
+func (s *Server) handleDAVPrincipal(w http.ResponseWriter, r *http.Request) {
+	if davOptions(w, r, "OPTIONS, PROPFIND") {
+		return
+	}
+	if r.Method != "PROPFIND" {
+		methodNotAllowed(w, r)
+		return
+	}
+	req, ok := readPropfind(w, r)
+	if !ok {
+		return
+	}
+	props := davProps{
+		{Space: davNS, Local: "resourcetype"}:             `<principal xmlns="DAV:"/><collection xmlns="DAV:"/>`,
+		{Space: davNS, Local: "displayname"}:              escapeXML(s.provider.Name()),
+		{Space: davNS, Local: "current-user-principal"}:   davHref(davPrincipalPath),
+		{Space: davNS, Local: "principal-URL"}:            davHref(davPrincipalPath),
+		{Space: calDAVNS, Local: "calendar-home-set"}:     davHref(davHomePath),
+		{Space: davNS, Local: "principal-collection-set"}: davHref(davPrincipalPath),
+	}
+	writeMultistatus(w, []davResponse{req.respond(davPrincipalPath, props)})
+}
+
+func (s *Server) handleDAVHome(w http.ResponseWriter, r *http.Request) {
+	if davOptions(w, r, "OPTIONS, PROPFIND") {
+		return
+	}
+	if r.Method != "PROPFIND" {
+		methodNotAllowed(w, r)
+		return
+	}
+	req, ok := readPropfind(w, r)
+	if !ok {
+		return
+	}
+	responses := []davResponse{req.respond(davHomePath, davCollectionProps("Calendars"))}
+	if req.depth != "0" {
+		cals, err := s.provider.ListCalendars(r.Context())
+		if err != nil {
+			writeProviderErr(w, err)
+			return
+		}
+		writable := s.writable(r.Context())
+		for _, cal := range visibleCalendars(r, cals) {
+			props, err := s.davCalendarProps(r.Context(), cal, writable)
+			if err != nil {
+				writeProviderErr(w, err)
+				return
+			}
+			responses = append(responses, req.respond(davCalendarHref(cal.ID), props))
+		}
+	}
+	writeMultistatus(w, responses)
+}
+
+func (s *Server) handleDAVCalendar(w http.ResponseWriter, r *http.Request) {
+	if davOptions(w, r, "OPTIONS, PROPFIND, REPORT") {
+		return
+	}
+	cal, err := s.findCalendar(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeProviderErr(w, err)
+		return
+	}
+	switch r.Method {
+	case "PROPFIND":
+		s.davPropfindCalendar(w, r, cal)
+	case "REPORT":
+		s.davReport(w, r, cal)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+func (s *Server) davPropfindCalendar(w http.ResponseWriter, r *http.Request, cal domain.Calendar) {
+	req, ok := readPropfind(w, r)
+	if !ok {
+		return
+	}
+	writable := s.writable(r.Context())
+	if req.depth == "0" {
+		props, err := s.davCalendarProps(r.Context(), cal, writable)
+		if err != nil {
+			writeProviderErr(w, err)
+			return
+		}
+		writeMultistatus(w, []davResponse{req.respond(davCalendarHref(cal.ID), props)})
+		return
+	}
+	objects, err := s.davObjects(r.Context(), cal.ID, time.Time{}, time.Time{})
+	if err != nil {
+		writeProviderErr(w, err)
+		return
+	}
+	ctag, ok := s.syncedCTag(cal.ID)
+	if !ok {
+		ctag = objectsCTag(objects)
+	}
+	responses := []davResponse{req.respond(davCalendarHref(cal.ID), calendarProps(cal, writable, ctag))}
+	for _, o := range objects {
+		responses = append(responses, req.respond(davObjectHref(cal.ID, o.name), o.props(cal, false)))
+	}
+	writeMultistatus(w, responses)
+}
+
+// davReport answers calendar-query, filtered by the first time-range of the
+// request, and calendar-multiget.
+func (s *Server) davReport(w http.ResponseWriter, r *http.Request, cal domain.Calendar) {
+	var report davReportRequest
+	if err := xml.NewDecoder(io.LimitReader(r.Body, maxDAVBody)).Decode(&report); err != nil {
+		writeInvalid(w, fieldError{Field: "body", Issue: "invalid xml: " + err.Error()})
+		return
+	}
+	req := davPropRequest{names: report.Prop.names()}
+	switch report.XMLName {
+	case xml.Name{Space: calDAVNS, Local: "calendar-query"}:
+		var from, to time.Time
+		if tr := report.Filter.timeRange(); tr != nil {
+			var details []fieldError
+			from, to, details = tr.bounds()
+			if len(details) > 0 {
+				writeInvalid(w, details...)
+				return
+			}
+		}
+		objects, err := s.davObjects(r.Context(), cal.ID, from, to)
+		if err != nil {
+			writeProviderErr(w, err)
+			return
+		}
+		responses := make([]davResponse, 0, len(objects))
+		for _, o := range objects {
+			responses = append(responses, req.respond(davObjectHref(cal.ID, o.name), o.props(cal, true)))
+		}
+		writeMultistatus(w, responses)
+	case xml.Name{Space: calDAVNS, Local: "calendar-multiget"}:
+		objects, err := s.davObjects(r.Context(), cal.ID, time.Time{}, time.Time{})
+		if err != nil {
+			writeProviderErr(w, err)
+			return
+		}
+		byName := make(map[string]davObject, len(objects))
+		for _, o := range objects {
+			byName[o.name] = o
+		}
+		responses := make([]davResponse, 0, len(report.Hrefs))
+		for _, href := range report.Hrefs {
+			o, ok := byName[davObjectName(href)]
+			if !ok {
+				responses = append(responses, davResponse{Href: href, Status: davStatus(http.StatusNotFound)})
+				continue
+			}
+			responses = append(responses, req.respond(href, o.props(cal, true)))
+		}
+		writeMultistatus(w, responses)
+	default:
+		writeDAVPrecondition(w, `<supported-report xmlns="DAV:"/>`)
+	}
+}
+
+// writeDAVPrecondition answers 403 naming the failed precondition element.
+func writeDAVPrecondition(w http.ResponseWriter, condition string) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusForbidden)
+	_, _ = io.WriteString(w, xml.Header+`<error xmlns="DAV:">`+condition+`</error>`)
+}
+
+func (s *Server) handleDAVObject(w http.ResponseWriter, r *http.Request) {
+	if davOptions(w, r, "OPTIONS, GET, HEAD, PUT, DELETE") {
+		return
+	}
+	calendarID, name := r.PathValue("id"), strings.TrimSuffix(r.PathValue("object"), ".ics")
+	cal, err := s.findCalendar(r.Context(), calendarID)
+	if err != nil {
+		writeProviderErr(w, err)
+		return
+	}
+	current, err := s.davObject(r.Context(), calendarID, name)
+	if err != nil {
+		writeProviderErr(w, err)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		if current == nil {
+			writeErr(w, http.StatusNotFound, codeNotFound, provider.ErrEventNotFound.Error())
+			return
+		}
+		w.Header().Set("Content-Type", davCalendarType)
+		w.Header().Set("ETag", current.etag)
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		_, _ = io.WriteString(w, current.data(cal))
+	case http.MethodPut:
+		s.davPut(w, r, calendarID, name, current)
+	case http.MethodDelete:
+		if current == nil {
+			writeErr(w, http.StatusNotFound, codeNotFound, provider.ErrEventNotFound.Error())
+			return
+		}
+		if !davPreconditions(w, r, current) {
+			return
+		}
+		if err := s.provider.DeleteEvent(r.Context(), current.id); err != nil {
+			writeDAVProviderErr(w, err)
+			return
+		}
+		if err := s.davNames.forget(calendarID, current.id); err != nil {
+			s.log.Warn("failed to save CalDAV object names", "error", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// davPut creates or replaces the series at name from an iCalendar body.
+// Only the series master is written, keeping the client's UID: providers
+// cannot store EXDATE, RDATE, RECURRENCE-ID overrides, attendees, STATUS or
+// TRANSP from a mutation, so bodies that change them are refused rather than
+// silently cut down. Unchanged ones, as sent back by clients that edit the
+// master, are kept by the provider.
+func (s *Server) davPut(w http.ResponseWriter, r *http.Request, calendarID, name string, current *davObject) {
+	if !davPreconditions(w, r, current) {
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxDAVBody))
+	if err != nil {
+		writeInvalid(w, fieldError{Field: "body", Issue: err.Error()})
+		return
+	}
+	e, overrides, err := decodeDAVEvent(string(body))
+	if err != nil {
+		writeInvalid(w, fieldError{Field: "body", Issue: err.Error()})
+		return
+	}
+	var existing []domain.Event
+	if current != nil {
+		existing = current.events
+	}
+	if !davWritable(e, overrides, existing) {
+		writeDAVPrecondition(w, `<supported-calendar-data xmlns="urn:ietf:params:xml:ns:caldav"/>`)
+		return
+	}
+	in := provider.MutationFromEvent(e)
+	in.CalendarID = calendarID
+	if current != nil {
+		if _, err := s.provider.UpdateEvent(r.Context(), current.id, in); err != nil {
+			writeDAVProviderErr(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	created, err := s.provider.CreateEvent(r.Context(), in)
+	if err != nil {
+		writeDAVProviderErr(w, err)
+		return
+	}
+	// The provider picks the event ID; the object keeps the client's name so
+	// the URL it wrote stays valid. No ETag is sent because the provider may
+	// normalise the event, which makes clients fetch it back.
+	if err := s.davNames.remember(calendarID, name, created.ID); err != nil {
+		s.log.Warn("failed to save CalDAV object names; the object moves to its event ID on restart", "event_id", created.ID, "error", err)
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// decodeDAVEvent returns the series master of a calendar object and its
+// RECURRENCE-ID overrides. Objects holding only overrides use the first as
+// the master.
+func decodeDAVEvent(body string) (domain.Event, []domain.Event, error) {
+	roots, err := ical.ParseString(body)
+	if err != nil {
+		return domain.Event{}, nil, err
+	}
+	vevents := ical.Find(roots, "VEVENT")
+	if len(vevents) == 0 {
+		return domain.Event{}, nil, errors.New("no VEVENT in calendar object")
+	}
+	master := vevents[0]
+	for _, v := range vevents {
+		if _, ok := v.Prop("RECURRENCE-ID"); !ok {
+			master = v
+			break
+		}
+	}
+	tz := ical.NewTimezones(roots)
+	tz.Floating = time.UTC
+	var overrides []domain.Event
+	for _, v := range vevents {
+		if v == master {
+			continue
+		}
+		o, err := ical.DecodeEvent(v, tz)
+		if err != nil {
+			return domain.Event{}, nil, err
+		}
+		overrides = append(overrides, o)
+	}
+	e, err := ical.DecodeEvent(master, tz)
+	return e, overrides, err
+}
+
+// davWritable reports whether writing master as a mutation keeps the
+// object as the body has it: its UID, EXDATE, RDATE, attendees, STATUS,
+// TRANSP and overrides must match those of the existing events, or be
+// absent or at their defaults for a new object.
+func davWritable(master domain.Event, overrides, existing []domain.Event) bool {
+	var current domain.Event
+	var currentOverrides []domain.Event
+	for _, e := range existing {
+		if e.RecurrenceID == nil {
+			current = e
+		} else {
+			currentOverrides = append(currentOverrides, e)
+		}
+	}
+	if current.UID != "" && master.UID != current.UID {
+		return false
+	}
+	if !sameScheduling(master, current) || !sameTimes(master.ExceptionDates, current.ExceptionDates, master.AllDay) ||
+		!sameTimes(master.RecurrenceDates, current.RecurrenceDates, master.AllDay) || len(overrides) != len(currentOverrides) {
+		return false
+	}
+	for _, o := range overrides {
+		if !slices.ContainsFunc(currentOverrides, func(c domain.Event) bool { return sameOverride(o, c) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameScheduling compares the properties no mutation writes: attendees and
+// STATUS, where an unset one means CONFIRMED.
+func sameScheduling(a, b domain.Event) bool {
+	status := func(s string) string {
+		if s == "" {
+			return "CONFIRMED"
+		}
+		return strings.ToUpper(s)
+	}
+	return status(a.Status) == status(b.Status) && provider.SameAttendees(a.Attendees, b.Attendees)
+}
+
+func sameOverride(a, b domain.Event) bool {
+	return sameScheduling(a, b) && a.Transparent == b.Transparent && a.Title == b.Title && a.Description == b.Description && a.Location == b.Location && a.AllDay == b.AllDay &&
+		timeKey(*a.RecurrenceID, a.AllDay) == timeKey(*b.RecurrenceID, a.AllDay) &&
+		timeKey(a.Start, a.AllDay) == timeKey(b.Start, a.AllDay) && timeKey(a.End, a.AllDay) == timeKey(b.End, a.AllDay)
+}
+
+// sameTimes compares two date lists as sets.
+func sameTimes(a, b []time.Time, allDay bool) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	keys := make(map[string]int, len(a))
+	for _, t := range a {
+		keys[timeKey(t, allDay)]++
+	}
+	for _, t := range b {
+		keys[timeKey(t, allDay)]--
+	}
+	for _, n := range keys {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// timeKey identifies an instant, or a day for all-day values, whose zone is
+// whatever they happened to be decoded in.
+func timeKey(t time.Time, allDay bool) string {
+	if allDay {
+		return t.Format("20060102")
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// davPreconditions applies If-Match and If-None-Match against the current
+// resource, answering 412 when they fail.
+func davPreconditions(w http.ResponseWriter, r *http.Request, current *davObject) bool {
+	ok := true
+	if match := r.Header.Get("If-Match"); match != "" {
+		ok = current != nil && (match == "*" || etagListed(match, current.etag))
+	}
+	if none := r.Header.Get("If-None-Match"); none != "" && current != nil {
+		ok = ok && none != "*" && !etagListed(none, current.etag)
+	}
+	if !ok {
+		writeErr(w, http.StatusPreconditionFailed, codeInvalidRequest, "precondition failed")
+	}
+	return ok
+}
+
+func etagListed(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeDAVProviderErr reports unsupported writes as 403, which CalDAV clients
+// take to mean the calendar is read-only.
+func writeDAVProviderErr(w http.ResponseWriter, err error) {
+	if errors.Is(err, provider.ErrNotSupported) {
+		writeErr(w, http.StatusForbidden, codeNotSupported, err.Error())
+		return
+	}
+	writeProviderErr(w, err)
+}
+
+// writable reports whether the provider currently accepts writes from the
+// request's token.
+func (s *Server) writable(ctx context.Context) bool {
+	if !security.FromContext(ctx).Allows(security.ScopeEventsWrite) {
+		return false
+	}
+	cp, ok := s.provider.(provider.CapabilityProvider)
+	if !ok {
+		return false
+	}
+	caps, err := cp.Capabilities(ctx)
+	return err == nil && caps.WriteSupported
+}
+
+// davCalendarProps returns the properties of a calendar collection, listing
+// its objects only when the provider keeps no sync cursor for it.
+func (s *Server) davCalendarProps(ctx context.Context, cal domain.Calendar, writable bool) (davProps, error) {
+	ctag, ok := s.syncedCTag(cal.ID)
+	if !ok {
+		objects, err := s.davObjects(ctx, cal.ID, time.Time{}, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		ctag = objectsCTag(objects)
+	}
+	return calendarProps(cal, writable, ctag), nil
+}
+
+// syncedCTag derives a calendar's collection tag from its sync cursor, which
+// moves with every change to the events the provider serves, and from the
+// names of objects created over CalDAV, which the cursor does not cover.
+func (s *Server) syncedCTag(calendarID string) (string, bool) {
+	syncer, ok := s.provider.(provider.Syncer)
+	if !ok {
+		return "", false
+	}
+	status := syncer.SyncStatus()
+	if !status.Enabled {
+		return "", false
+	}
+	for _, c := range status.Calendars {
+		if c.CalendarID == calendarID && c.Cursor != "" {
+			return davTag("cursor", c.Cursor, s.davNames.fingerprint(calendarID)), true
+		}
+	}
+	return "", false
+}
+
+// objectsCTag derives a collection tag from the names and ETags of a
+// calendar's objects.
+func objectsCTag(objects []davObject) string {
+	parts := make([]string, 0, 2*len(objects))
+	for _, o := range objects {
+		parts = append(parts, o.name, o.etag)
+	}
+	return davTag(parts...)
+}
+
+// davTag hashes parts into a quoted tag.
+func davTag(parts ...string) string {
+	sum := sha256.New()
+	for _, part := range parts {
+		io.WriteString(sum, part)
+		sum.Write([]byte{0})
+	}
+	return `"` + hex.EncodeToString(sum.Sum(nil))[:32] + `"`
+}
+
+func calendarProps(cal domain.Calendar, writable bool, ctag string) davProps {
+	privileges := "<privilege xmlns=\"DAV:\"><read/></privilege>"
+	if writable && !cal.ReadOnly {
+		privileges += "<privilege xmlns=\"DAV:\"><write/></privilege><privilege xmlns=\"DAV:\"><write-content/></privilege>" +
+			"<privilege xmlns=\"DAV:\"><bind/></privilege><privilege xmlns=\"DAV:\"><unbind/></privilege>"
+	}
+	props := davProps{
+		{Space: davNS, Local: "resourcetype"}:                        `<collection xmlns="DAV:"/><calendar xmlns="urn:ietf:params:xml:ns:caldav"/>`,
+		{Space: davNS, Local: "displayname"}:                         escapeXML(cal.Name),
+		{Space: davNS, Local: "current-user-principal"}:              davHref(davPrincipalPath),
+		{Space: davNS, Local: "current-user-privilege-set"}:          privileges,
+		{Space: calDAVNS, Local: "supported-calendar-component-set"}: `<comp xmlns="urn:ietf:params:xml:ns:caldav" name="VEVENT"/>`,
+		{Space: calServerNS, Local: "getctag"}:                       escapeXML(ctag),
+		{Space: davNS, Local: "supported-report-set"}:                `<supported-report xmlns="DAV:"><report><calendar-query xmlns="urn:ietf:params:xml:ns:caldav"/></report></supported-report><supported-report xmlns="DAV:"><report><calendar-multiget xmlns="urn:ietf:params:xml:ns:caldav"/></report></supported-report>`,
+	}
+	if cal.Description != "" {
+		props[xml.Name{Space: calDAVNS, Local: "calendar-description"}] = escapeXML(cal.Description)
+	}
+	if cal.Color != "" {
+		props[xml.Name{Space: appleICalNS, Local: "calendar-color"}] = escapeXML(cal.Color)
+	}
+	return props
+}
+
+func davCollectionProps(name string) davProps {
+	return davProps{
+		{Space: davNS, Local: "resourcetype"}:           `<collection xmlns="DAV:"/>`,
+		{Space: davNS, Local: "displayname"}:            escapeXML(name),
+		{Space: davNS, Local: "current-user-principal"}: davHref(davPrincipalPath),
+	}
+}
+
+// davObject is one calendar object resource: a series master with its
+// overrides, or a single event. id is the provider's ID for the series and
+// name the object's URL segment.
+type davObject struct {
+	id     string
+	name   string
+	events []domain.Event
+	etag   string
+}
+
+// davNames maps the IDs the provider chose for objects created over CalDAV
+// to the names their clients gave them, which providers cannot store. The
+// mapping is saved to path, when set, so the names survive restarts.
+type davNames struct {
+	mu     sync.Mutex
+	path   string
+	byID   map[davNameKey]string
+	byName map[davNameKey]string
+}
+
+type davNameKey struct{ calendarID, value string }
+
+// davNamesFile is the saved mapping: calendar ID to event ID to name.
+type davNamesFile map[string]map[string]string
+
+// load reads the mapping saved at path, which need not exist yet, and saves
+// later changes there.
+func (n *davNames) load(path string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.path = path
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var saved davNamesFile
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return err
+	}
+	for calendarID, names := range saved {
+		for id, name := range names {
+			n.set(calendarID, name, id)
+		}
+	}
+	return nil
+}
+
+// remember records the name of a created object and saves the mapping.
+func (n *davNames) remember(calendarID, name, id string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.set(calendarID, name, id)
+	return n.save()
+}
+
+// forget drops the name of a deleted object and saves the mapping.
+func (n *davNames) forget(calendarID, id string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	key := davNameKey{calendarID, id}
+	name, ok := n.byID[key]
+	if !ok {
+		return nil
+	}
+	delete(n.byName, davNameKey{calendarID, name})
+	delete(n.byID, key)
+	return n.save()
+}
+
+func (n *davNames) set(calendarID, name, id string) {
+	if n.byID == nil {
+		n.byID, n.byName = map[davNameKey]string{}, map[davNameKey]string{}
+	}
+	n.byID[davNameKey{calendarID, id}] = name
+	n.byName[davNameKey{calendarID, name}] = id
+}
+
+// save writes the mapping through a temporary file, so a crash leaves the
+// previous version in place. n.mu must be held.
+func (n *davNames) save() error {
+	if n.path == "" {
+		return nil
+	}
+	out := davNamesFile{}
+	for key, name := range n.byID {
+		if out[key.calendarID] == nil {
+			out[key.calendarID] = map[string]string{}
+		}
+		out[key.calendarID][key.value] = name
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(n.path), 0o700); err != nil {
+		return err
+	}
+	tmp := n.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, n.path)
+}
+
+// id returns the series ID an object name stands for. It fails for the IDs
+// of renamed objects, which are no longer their names.
+func (n *davNames) id(calendarID, name string) (string, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if id, ok := n.byName[davNameKey{calendarID, name}]; ok {
+		return id, true
+	}
+	_, renamed := n.byID[davNameKey{calendarID, name}]
+	return name, !renamed
+}
+
+// fingerprint lists the renamed objects of a calendar in a stable order.
+func (n *davNames) fingerprint(calendarID string) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var pairs []string
+	for key, name := range n.byID {
+		if key.calendarID == calendarID {
+			pairs = append(pairs, key.value+"="+name)
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "\n")
+}
+
+// name returns the object name of the series id.
+func (n *davNames) name(calendarID, id string) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if name, ok := n.byID[davNameKey{calendarID, id}]; ok {
+		return name
+	}
+	return id
+}
+
+func (o davObject) data(cal domain.Calendar) string {
+	return ical.EncodeString(ical.Export(cal, o.events, time.Now()))
+}
+
+func (o davObject) props(cal domain.Calendar, withData bool) davProps {
+	props := davProps{
+		{Space: davNS, Local: "getetag"}:        escapeXML(o.etag),
+		{Space: davNS, Local: "getcontenttype"}: davCalendarType,
+		{Space: davNS, Local: "resourcetype"}:   "",
+	}
+	if withData {
+		props[xml.Name{Space: calDAVNS, Local: "calendar-data"}] = escapeXML(o.data(cal))
+	}
+	return props
+}
+
+// davObjects groups the events of a calendar into resources by UID. Series
+// are listed unexpanded when the provider can, so overrides travel with their
+// master.
+func (s *Server) davObjects(ctx context.Context, calendarID string, from, to time.Time) ([]davObject, error) {
+	var events []domain.Event
+	var err error
+	if lister, ok := s.provider.(provider.SeriesLister); ok {
+		events, err = lister.ListSeries(ctx, calendarID, from, to)
+	} else {
+		events, err = s.provider.ListEvents(ctx, calendarID, from, to)
+		standalone(events)
+	}
+	if err != nil {
+		return nil, err
+	}
+	index := map[string]int{}
+	var objects []davObject
+	for _, e := range events {
+		key := e.UID
+		if key == "" {
+			key = e.ID
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(objects)
+			index[key] = i
+			objects = append(objects, davObject{})
+		}
+		o := &objects[i]
+		if e.RecurrenceID == nil || o.id == "" {
+			o.id = e.ID
+			if e.RecurrenceID != nil && e.SeriesID != "" {
+				o.id = e.SeriesID
+			}
+		}
+		o.events = append(o.events, e)
+	}
+	for i := range objects {
+		objects[i] = s.newDAVObject(calendarID, objects[i].id, objects[i].events)
+	}
+	return objects, nil
+}
+
+// newDAVObject names the series id and tags its events, master first.
+func (s *Server) newDAVObject(calendarID, id string, events []domain.Event) davObject {
+	sort.SliceStable(events, func(a, b int) bool { return events[a].RecurrenceID == nil && events[b].RecurrenceID != nil })
+	data, _ := json.Marshal(events)
+	sum := sha256.Sum256(data)
+	return davObject{id: id, name: s.davNames.name(calendarID, id), events: events, etag: `"` + hex.EncodeToString(sum[:16]) + `"`}
+}
+
+// davObject returns the object called name, or nil when there is none. A
+// single event is looked up directly when the provider can; a series is
+// listed from its start so its overrides come along.
+func (s *Server) davObject(ctx context.Context, calendarID, name string) (*davObject, error) {
+	id, ok := s.davNames.id(calendarID, name)
+	if !ok {
+		return nil, nil
+	}
+	var from time.Time
+	getter, canGet := s.provider.(provider.EventGetter)
+	if _, series := s.provider.(provider.SeriesLister); canGet && series {
+		e, err := getter.GetEvent(ctx, calendarID, id)
+		switch {
+		case errors.Is(err, provider.ErrEventNotFound):
+			return nil, nil
+		case err != nil:
+			return nil, err
+		case e.ID == id && e.RecurrenceID == nil && e.Recurrence == "":
+			o := s.newDAVObject(calendarID, id, []domain.Event{e})
+			return &o, nil
+		case e.ID == id && e.RecurrenceID == nil:
+			from = e.Start
+		}
+	}
+	objects, err := s.davObjects(ctx, calendarID, from, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range objects {
+		if objects[i].name == name {
+			return &objects[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func davCalendarHref(calendarID string) string {
+	return davHomePath + url.PathEscape(calendarID) + "/"
+}
+
+func davObjectHref(calendarID, name string) string {
+	return davCalendarHref(calendarID) + url.PathEscape(name) + ".ics"
+}
+
+// davObjectName turns an object href or path segment back into the object
+// name.
+func davObjectName(href string) string {
+	if i := strings.LastIndex(href, "/"); i >= 0 {
+		href = href[i+1:]
+	}
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	return strings.TrimSuffix(href, ".ics")
+}
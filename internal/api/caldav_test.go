@@ -0,0 +1,393 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/security"
+)
+
+// davProvider serves one calendar holding a recurring series with an
+// override, a single event and the event it created, and records listings
+// and writes. A non-empty cursor reports the calendar as synced.
+type davProvider struct {
+	fakeProvider
+	writable bool
+	cursor   string
+	lists    int
+	from     time.Time
+	created  domain.EventMutation
+	stored   *domain.Event
+	updated  string
+	deleted  string
+}
+
+func (p *davProvider) Capabilities(context.Context) (provider.CapabilitySet, error) {
+	return provider.CapabilitySet{WriteSupported: p.writable}, nil
+}
+func (p *davProvider) ListCalendars(context.Context) ([]domain.Calendar, error) {
+	return []domain.Calendar{{ID: "c1", Name: "Work & Play", Description: "Team", Color: "#ff0000"}}, nil
+}
+func (p *davProvider) ListSeries(_ context.Context, calendarID string, from, _ time.Time) ([]domain.Event, error) {
+	p.lists++
+	p.from = from
+	return p.events(calendarID), nil
+}
+func (p *davProvider) GetEvent(_ context.Context, calendarID, eventID string) (domain.Event, error) {
+	for _, e := range p.events(calendarID) {
+		if e.ID == eventID {
+			return e, nil
+		}
+	}
+	return domain.Event{}, fmt.Errorf("%w: %s", provider.ErrEventNotFound, eventID)
+}
+func (p *davProvider) RunSync(context.Context) {}
+func (p *davProvider) SyncStatus() provider.SyncStatus {
+	return provider.SyncStatus{Enabled: p.cursor != "", Calendars: []provider.CalendarSyncStatus{{CalendarID: "c1", Cursor: p.cursor}}}
+}
+func (p *davProvider) events(calendarID string) []domain.Event {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	rid := start.AddDate(0, 0, 7)
+	events := []domain.Event{
+		{ID: "o1", UID: "u1", SeriesID: "s1", RecurrenceID: &rid, CalendarID: calendarID, Title: "Moved", Start: rid.Add(time.Hour), End: rid.Add(2 * time.Hour)},
+		{ID: "s1", UID: "u1", CalendarID: calendarID, Title: "Standup", Start: start, End: start.Add(time.Hour), Recurrence: "FREQ=WEEKLY"},
+		{ID: "e2", UID: "u2", CalendarID: calendarID, Title: "Lunch", Start: start, End: start.Add(time.Hour)},
+	}
+	if p.stored != nil {
+		events = append(events, *p.stored)
+	}
+	return events
+}
+func (p *davProvider) CreateEvent(_ context.Context, in domain.EventMutation) (domain.Event, error) {
+	if !p.writable {
+		return domain.Event{}, provider.NotSupportedError{Operation: "create_event"}
+	}
+	p.created = in
+	p.stored = &domain.Event{ID: "new-id", UID: in.UID, CalendarID: in.CalendarID, Title: in.Title, Start: in.Start, End: in.End, Transparent: in.Transparent}
+	return *p.stored, nil
+}
+func (p *davProvider) UpdateEvent(_ context.Context, eventID string, in domain.EventMutation) (domain.Event, error) {
+	p.updated = eventID + ":" + in.Title
+	return domain.Event{ID: eventID}, nil
+}
+func (p *davProvider) DeleteEvent(_ context.Context, eventID string) error {
+	p.deleted = eventID
+	if p.stored != nil && p.stored.ID == eventID {
+		p.stored = nil
+	}
+	return nil
+}
+
+const davEventBody = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:client-uid\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20260401T100000\r\nDURATION:PT30M\r\nSUMMARY:Review\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+
+func TestCalDAVDiscoveryAndReports(t *testing.T) {
+	p := &davProvider{writable: true}
+	s := New(Options{Provider: p, Auth: security.BearerAuth{Enabled: true, Token: "t"}})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+	do := func(method, path, depth, body string) (*http.Response, string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.SetBasicAuth("user", "t")
+		if depth != "" {
+			req.Header.Set("Depth", depth)
+		}
+		res, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		data, _ := io.ReadAll(res.Body)
+		return res, string(data)
+	}
+	expect := func(res *http.Response, body string, status int, want ...string) {
+		t.Helper()
+		if res.StatusCode != status {
+			t.Fatalf("%s %s: expected %d got %d: %s", res.Request.Method, res.Request.URL.Path, status, res.StatusCode, body)
+		}
+		for _, w := range want {
+			if !strings.Contains(body, w) {
+				t.Fatalf("%s %s: missing %q in:\n%s", res.Request.Method, res.Request.URL.Path, w, body)
+			}
+		}
+	}
+
+	req, _ := http.NewRequest("PROPFIND", ts.URL+davPrincipalPath, nil)
+	res, _ := http.DefaultClient.Do(req)
+	if res.StatusCode != http.StatusUnauthorized || !strings.HasPrefix(res.Header.Get("WWW-Authenticate"), "Basic") {
+		t.Fatalf("expected basic challenge, got %d %q", res.StatusCode, res.Header.Get("WWW-Authenticate"))
+	}
+
+	res, body := do(http.MethodGet, "/.well-known/caldav", "", "")
+	if res.StatusCode != http.StatusMovedPermanently || res.Header.Get("Location") != davPrincipalPath {
+		t.Fatalf("unexpected well-known redirect %d %q", res.StatusCode, res.Header.Get("Location"))
+	}
+	res, body = do(http.MethodOptions, "/dav/calendars/c1/", "", "")
+	if res.StatusCode != http.StatusOK || !strings.Contains(res.Header.Get("DAV"), "calendar-access") {
+		t.Fatalf("unexpected OPTIONS %d %q", res.StatusCode, res.Header.Get("DAV"))
+	}
+
+	res, body = do("PROPFIND", "/dav/", "0", "")
+	expect(res, body, http.StatusMultiStatus, `<current-user-principal xmlns="DAV:"><href xmlns="DAV:">/dav/principal/</href>`)
+
+	res, body = do("PROPFIND", davPrincipalPath, "0", `<?xml version="1.0"?><d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`+
+		`<d:prop><c:calendar-home-set/><d:getlastmodified/></d:prop></d:propfind>`)
+	expect(res, body, http.StatusMultiStatus, `<calendar-home-set xmlns="urn:ietf:params:xml:ns:caldav"><href xmlns="DAV:">/dav/calendars/</href>`,
+		"HTTP/1.1 404 Not Found", `<getlastmodified xmlns="DAV:"></getlastmodified>`)
+
+	res, body = do("PROPFIND", davHomePath, "1", "")
+	expect(res, body, http.StatusMultiStatus, "<href>/dav/calendars/c1/</href>", `<calendar xmlns="urn:ietf:params:xml:ns:caldav"/>`,
+		"Work &amp; Play", "getctag", "<write/>", "#ff0000")
+
+	res, body = do("PROPFIND", "/dav/calendars/c1/", "1", "")
+	expect(res, body, http.StatusMultiStatus, "<href>/dav/calendars/c1/s1.ics</href>", "<href>/dav/calendars/c1/e2.ics</href>", "getetag")
+	if strings.Contains(body, "o1.ics") || strings.Contains(body, "calendar-data") {
+		t.Fatalf("overrides must stay inside their series and allprop must skip data:\n%s", body)
+	}
+
+	res, body = do("REPORT", "/dav/calendars/c1/", "1", `<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`+
+		`<d:prop><d:getetag/><c:calendar-data/></d:prop><c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VEVENT">`+
+		`<c:time-range start="20260301T000000Z" end="20260401T000000Z"/></c:comp-filter></c:comp-filter></c:filter></c:calendar-query>`)
+	expect(res, body, http.StatusMultiStatus, "BEGIN:VCALENDAR", "RECURRENCE-ID:20260309T090000Z", "RRULE:FREQ=WEEKLY")
+	if !p.from.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("time-range not passed on: %v", p.from)
+	}
+	res, body = do("REPORT", "/dav/calendars/c1/", "1", `<c:calendar-query xmlns:c="urn:ietf:params:xml:ns:caldav"><c:filter>`+
+		`<c:comp-filter name="VCALENDAR"><c:time-range start="tomorrow"/></c:comp-filter></c:filter></c:calendar-query>`)
+	expect(res, body, http.StatusBadRequest, "time-range.start")
+
+	res, body = do("REPORT", "/dav/calendars/c1/", "1", `<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`+
+		`<d:prop><c:calendar-data/></d:prop><d:href>/dav/calendars/c1/e2.ics</d:href><d:href>/dav/calendars/c1/nope.ics</d:href></c:calendar-multiget>`)
+	expect(res, body, http.StatusMultiStatus, "SUMMARY:Lunch", "<href>/dav/calendars/c1/nope.ics</href><status>HTTP/1.1 404 Not Found</status>")
+
+	res, body = do("REPORT", "/dav/calendars/c1/", "1", `<d:sync-collection xmlns:d="DAV:"/>`)
+	expect(res, body, http.StatusForbidden, "supported-report")
+	res, body = do("PROPFIND", "/dav/calendars/missing/", "0", "")
+	expect(res, body, http.StatusNotFound)
+	res, body = do(http.MethodGet, "/dav/", "", "")
+	expect(res, body, http.StatusMethodNotAllowed)
+}
+
+func TestCalDAVObjects(t *testing.T) {
+	p := &davProvider{writable: true}
+	s := New(Options{Provider: p})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+	do := func(method, path, body string, headers ...string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		for i := 0; i+1 < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res := do(http.MethodGet, "/dav/calendars/c1/s1.ics", "")
+	data, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	etag := res.Header.Get("ETag")
+	if res.StatusCode != http.StatusOK || etag == "" || !strings.Contains(string(data), "UID:u1\r\n") || strings.Count(string(data), "BEGIN:VEVENT") != 2 {
+		t.Fatalf("unexpected GET %d %q:\n%s", res.StatusCode, etag, data)
+	}
+	if res := do(http.MethodHead, "/dav/calendars/c1/s1.ics", ""); res.StatusCode != http.StatusOK || res.Header.Get("ETag") != etag {
+		t.Fatalf("unexpected HEAD %d", res.StatusCode)
+	}
+	if res := do(http.MethodGet, "/dav/calendars/c1/missing.ics", ""); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d", res.StatusCode)
+	}
+
+	if res := do(http.MethodPut, "/dav/calendars/c1/s1.ics", davEventBody, "If-Match", `"stale"`); res.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 got %d", res.StatusCode)
+	}
+	if res := do(http.MethodPut, "/dav/calendars/c1/s1.ics", davEventBody, "If-None-Match", "*"); res.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("expected 412 got %d", res.StatusCode)
+	}
+	// Series keep their overrides, which a mutation cannot change or drop.
+	unsupported := func(path, body string) {
+		t.Helper()
+		res := do(http.MethodPut, path, body)
+		data, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != http.StatusForbidden || !strings.Contains(string(data), "supported-calendar-data") {
+			t.Fatalf("expected 403 supported-calendar-data, got %d %s", res.StatusCode, data)
+		}
+	}
+	unsupported("/dav/calendars/c1/s1.ics", davEventBody)
+	unsupported("/dav/calendars/c1/s1.ics", strings.Replace(string(data), "SUMMARY:Moved", "SUMMARY:Moved again", 1))
+	unsupported("/dav/calendars/c1/s1.ics", strings.Replace(string(data), "RRULE:FREQ=WEEKLY\r\n", "RRULE:FREQ=WEEKLY\r\nEXDATE:20260316T090000Z\r\n", 1))
+	unsupported("/dav/calendars/c1/fresh.ics", strings.Replace(davEventBody, "SUMMARY:Review", "SUMMARY:Review\r\nRDATE:20260402T080000Z", 1))
+	if p.updated != "" || p.created.Title != "" {
+		t.Fatalf("unexpected writes %q %+v", p.updated, p.created)
+	}
+	edited := strings.Replace(string(data), "SUMMARY:Standup", "SUMMARY:Review", 1)
+	if res := do(http.MethodPut, "/dav/calendars/c1/s1.ics", edited, "If-Match", etag); res.StatusCode != http.StatusNoContent || p.updated != "s1:Review" {
+		t.Fatalf("unexpected update %d %q", res.StatusCode, p.updated)
+	}
+
+	// Apple Calendar marks all-day events TRANSP:TRANSPARENT.
+	transparent := strings.Replace(davEventBody, "SUMMARY:Review", "SUMMARY:Review\r\nTRANSP:TRANSPARENT", 1)
+	if res := do(http.MethodPut, "/dav/calendars/c1/client-uid.ics", transparent, "If-None-Match", "*"); res.StatusCode != http.StatusCreated {
+		t.Fatalf("unexpected create %d", res.StatusCode)
+	}
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	if want := time.Date(2026, 4, 1, 10, 0, 0, 0, berlin); p.created.UID != "client-uid" || !p.created.Transparent || p.created.CalendarID != "c1" || !p.created.Start.Equal(want) || p.created.End.Sub(p.created.Start) != 30*time.Minute {
+		t.Fatalf("unexpected mutation %+v", p.created)
+	}
+	// Attendees and STATUS cannot be written, nor can a UID change.
+	unsupported("/dav/calendars/c1/fresh.ics", strings.Replace(davEventBody, "SUMMARY:Review", "SUMMARY:Review\r\nATTENDEE:mailto:a@example.com", 1))
+	unsupported("/dav/calendars/c1/fresh.ics", strings.Replace(davEventBody, "SUMMARY:Review", "SUMMARY:Review\r\nSTATUS:TENTATIVE", 1))
+	unsupported("/dav/calendars/c1/client-uid.ics", strings.Replace(davEventBody, "UID:client-uid", "UID:other-uid", 1))
+	// The created object stays at the client's URL, not the provider's ID.
+	res = do(http.MethodGet, "/dav/calendars/c1/client-uid.ics", "")
+	created, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	createdTag := res.Header.Get("ETag")
+	if res.StatusCode != http.StatusOK || !strings.Contains(string(created), "SUMMARY:Review") {
+		t.Fatalf("unexpected GET of created object %d:\n%s", res.StatusCode, created)
+	}
+	if res := do(http.MethodGet, "/dav/calendars/c1/new-id.ics", ""); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for the provider ID got %d", res.StatusCode)
+	}
+	if res := do(http.MethodPut, "/dav/calendars/c1/client-uid.ics", davEventBody, "If-Match", createdTag); res.StatusCode != http.StatusNoContent || p.updated != "new-id:Review" {
+		t.Fatalf("unexpected update of created object %d %q", res.StatusCode, p.updated)
+	}
+	if res := do(http.MethodDelete, "/dav/calendars/c1/client-uid.ics", ""); res.StatusCode != http.StatusNoContent || p.deleted != "new-id" {
+		t.Fatalf("unexpected delete of created object %d %q", res.StatusCode, p.deleted)
+	}
+	if res := do(http.MethodPut, "/dav/calendars/c1/bad.ics", "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"); res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", res.StatusCode)
+	}
+
+	if res := do(http.MethodDelete, "/dav/calendars/c1/e2.ics", ""); res.StatusCode != http.StatusNoContent || p.deleted != "e2" {
+		t.Fatalf("unexpected delete %d %q", res.StatusCode, p.deleted)
+	}
+	if res := do(http.MethodDelete, "/dav/calendars/c1/missing.ics", ""); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d", res.StatusCode)
+	}
+	if res := do(http.MethodPost, "/dav/calendars/c1/e2.ics", ""); res.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 got %d", res.StatusCode)
+	}
+
+	p.writable = false
+	if res := do(http.MethodPut, "/dav/calendars/c1/other.ics", davEventBody); res.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for read-only provider got %d", res.StatusCode)
+	}
+}
+
+func TestCalDAVLookups(t *testing.T) {
+	p := &davProvider{writable: true}
+	s := New(Options{Provider: p})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+	do := func(method, path, depth, body string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.Header.Set("Depth", depth)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		data, _ := io.ReadAll(res.Body)
+		return res.StatusCode, string(data)
+	}
+	ctag := func() string {
+		t.Helper()
+		status, body := do("PROPFIND", "/dav/calendars/c1/", "0", "")
+		_, rest, ok := strings.Cut(body, "<getctag")
+		if status != http.StatusMultiStatus || !ok {
+			t.Fatalf("unexpected PROPFIND %d:\n%s", status, body)
+		}
+		tag, _, _ := strings.Cut(rest, "</getctag>")
+		return tag
+	}
+
+	// Single events are looked up, series listed from their start.
+	if status, body := do(http.MethodGet, "/dav/calendars/c1/e2.ics", "", ""); status != http.StatusOK || !strings.Contains(body, "SUMMARY:Lunch") || p.lists != 0 {
+		t.Fatalf("unexpected GET %d after %d listings:\n%s", status, p.lists, body)
+	}
+	if status, _ := do(http.MethodGet, "/dav/calendars/c1/missing.ics", "", ""); status != http.StatusNotFound || p.lists != 0 {
+		t.Fatalf("unexpected GET %d after %d listings", status, p.lists)
+	}
+	if status, body := do(http.MethodGet, "/dav/calendars/c1/s1.ics", "", ""); status != http.StatusOK || !strings.Contains(body, "SUMMARY:Moved") ||
+		p.lists != 1 || !p.from.Equal(time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected series GET %d after %d listings from %v:\n%s", status, p.lists, p.from, body)
+	}
+
+	// Without a cursor the collection tag hashes a listing.
+	p.lists = 0
+	unsynced := ctag()
+	if p.lists != 1 {
+		t.Fatalf("expected one listing, got %d", p.lists)
+	}
+	p.cursor = "cursor-1"
+	p.lists = 0
+	synced := ctag()
+	if p.lists != 0 || synced == unsynced || ctag() != synced {
+		t.Fatalf("expected a stable tag from the cursor without listing, got %q after %d listings", synced, p.lists)
+	}
+	p.cursor = "cursor-2"
+	if ctag() == synced {
+		t.Fatal("expected the tag to follow the cursor")
+	}
+	synced = ctag()
+	if status, _ := do(http.MethodPut, "/dav/calendars/c1/client.ics", "", davEventBody); status != http.StatusCreated {
+		t.Fatalf("unexpected create %d", status)
+	}
+	if ctag() == synced {
+		t.Fatal("expected the tag to change with the object names")
+	}
+}
+
+func TestCalDAVNamesSurviveRestart(t *testing.T) {
+	p := &davProvider{writable: true}
+	path := filepath.Join(t.TempDir(), "state", "caldav-names.json")
+	do := func(s *Server, method, path, body string) int {
+		t.Helper()
+		ts := httptest.NewServer(s.httpSrv.Handler)
+		defer ts.Close()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	if status := do(New(Options{Provider: p, CalDAVNamesPath: path}), http.MethodPut, "/dav/calendars/c1/client.ics", davEventBody); status != http.StatusCreated {
+		t.Fatalf("unexpected create %d", status)
+	}
+	restarted := New(Options{Provider: p, CalDAVNamesPath: path})
+	if status := do(restarted, http.MethodGet, "/dav/calendars/c1/client.ics", ""); status != http.StatusOK {
+		t.Fatalf("expected the created object at its name after a restart, got %d", status)
+	}
+	if status := do(restarted, http.MethodDelete, "/dav/calendars/c1/client.ics", ""); status != http.StatusNoContent || p.deleted != "new-id" {
+		t.Fatalf("unexpected delete %d %q", status, p.deleted)
+	}
+	if data, err := os.ReadFile(path); err != nil || strings.Contains(string(data), "client") {
+		t.Fatalf("expected the deleted name to be dropped, got %s %v", data, err)
+	}
+
+	if err := os.WriteFile(path, []byte("not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if status := do(New(Options{Provider: p, CalDAVNamesPath: path}), http.MethodGet, "/dav/calendars/c1/e2.ics", ""); status != http.StatusOK {
+		t.Fatalf("expected a broken names file not to stop the server, got %d", status)
+	}
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// davProps maps property names to their raw XML content. Values must be
+// escaped already.
+type davProps map[xml.Name]string
+
+type davPropNames struct {
+	Props []struct {
+		XMLName xml.Name
+	} `xml:",any"`
+}
+
+func (p davPropNames) names() []xml.Name {
+	out := make([]xml.Name, 0, len(p.Props))
+	for _, prop := range p.Props {
+		out = append(out, prop.XMLName)
+	}
+	return out
+}
+
+type davPropfindRequest struct {
+	XMLName xml.Name      `xml:"DAV: propfind"`
+	AllProp *struct{}     `xml:"DAV: allprop"`
+	Prop    *davPropNames `xml:"DAV: prop"`
+}
+
+type davReportRequest struct {
+	XMLName xml.Name
+	Prop    davPropNames   `xml:"DAV: prop"`
+	Hrefs   []string       `xml:"DAV: href"`
+	Filter  *davCompFilter `xml:"urn:ietf:params:xml:ns:caldav filter>comp-filter"`
+}
+
+type davCompFilter struct {
+	Name      string          `xml:"name,attr"`
+	TimeRange *davTimeRange   `xml:"urn:ietf:params:xml:ns:caldav time-range"`
+	Comps     []davCompFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+// timeRange returns the first time-range found in the filter tree.
+func (f *davCompFilter) timeRange() *davTimeRange {
+	if f == nil {
+		return nil
+	}
+	if f.TimeRange != nil {
+		return f.TimeRange
+	}
+	for i := range f.Comps {
+		if tr := f.Comps[i].timeRange(); tr != nil {
+			return tr
+		}
+	}
+	return nil
+}
+
+type davTimeRange struct {
+	Start string `xml:"start,attr"`
+	End   string `xml:"end,attr"`
+}
+
+// bounds parses the UTC date-time attributes of a time-range; either may be
+// missing to leave that side open.
+func (tr davTimeRange) bounds() (from, to time.Time, details []fieldError) {
+	for _, attr := range []struct {
+		name  string
+		value string
+		dst   *time.Time
+	}{{"start", tr.Start, &from}, {"end", tr.End, &to}} {
+		if attr.value == "" {
+			continue
+		}
+		t, err := time.Parse("20060102T150405Z", attr.value)
+		if err != nil {
+			details = append(details, fieldError{Field: "time-range." + attr.name, Issue: "must be a UTC date-time"})
+			continue
+		}
+		*attr.dst = t
+	}
+	return from, to, details
+}
+
+// davPropRequest is what a PROPFIND or REPORT asked for. A nil names list
+// means allprop.
+type davPropRequest struct {
+	depth string
+	names []xml.Name
+}
+
+// readPropfind decodes a PROPFIND body. An empty body is an allprop request.
+func readPropfind(w http.ResponseWriter, r *http.Request) (davPropRequest, bool) {
+	req := davPropRequest{depth: r.Header.Get("Depth")}
+	var body davPropfindRequest
+	err := xml.NewDecoder(io.LimitReader(r.Body, maxDAVBody)).Decode(&body)
+	switch {
+	case errors.Is(err, io.EOF):
+	case err != nil:
+		writeInvalid(w, fieldError{Field: "body", Issue: "invalid xml: " + err.Error()})
+		return req, false
+	case body.AllProp == nil && body.Prop != nil:
+		req.names = body.Prop.names()
+	}
+	return req, true
+}
+
+// respond builds the multistatus entry for href, splitting the requested
+// properties into found and missing.
+func (req davPropRequest) respond(href string, props davProps) davResponse {
+	var found, missing []davProp
+	if req.names == nil {
+		for name, value := range props {
+			if name.Local == "calendar-data" {
+				continue
+			}
+			found = append(found, davProp{XMLName: name, Inner: value})
+		}
+		sort.Slice(found, func(i, j int) bool {
+			return found[i].XMLName.Space+found[i].XMLName.Local < found[j].XMLName.Space+found[j].XMLName.Local
+		})
+	}
+	for _, name := range req.names {
+		if value, ok := props[name]; ok {
+			found = append(found, davProp{XMLName: name, Inner: value})
+		} else {
+			missing = append(missing, davProp{XMLName: name})
+		}
+	}
+	out := davResponse{Href: href}
+	if len(found) > 0 || len(missing) == 0 {
+		out.Propstats = append(out.Propstats, davPropstat{Props: found, Status: davStatus(http.StatusOK)})
+	}
+	if len(missing) > 0 {
+		out.Propstats = append(out.Propstats, davPropstat{Props: missing, Status: davStatus(http.StatusNotFound)})
+	}
+	return out
+}
+
+type davMultistatus struct {
+	XMLName   xml.Name      `xml:"DAV: multistatus"`
+	Responses []davResponse `xml:"response"`
+}
+
+type davResponse struct {
+	Href      string        `xml:"href"`
+	Propstats []davPropstat `xml:"propstat"`
+	Status    string        `xml:"status,omitempty"`
+}
+
+type davPropstat struct {
+	Props  []davProp `xml:"prop>any"`
+	Status string    `xml:"status"`
+}
+
+type davProp struct {
+	XMLName xml.Name
+	Inner   string `xml:",innerxml"`
+}
+
+func writeMultistatus(w http.ResponseWriter, responses []davResponse) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	_, _ = io.WriteString(w, xml.Header)
+	_ = xml.NewEncoder(w).Encode(davMultistatus{Responses: responses})
+}
+
+func davStatus(code int) string {
+	return fmt.Sprintf("HTTP/1.1 %d %s", code, http.StatusText(code))
+}
+
+func davHref(path string) string {
+	return `<href xmlns="DAV:">` + escapeXML(path) + `</href>`
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
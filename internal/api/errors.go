@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/security"
+)
+
+// Error codes reported in the "code" field of error responses.
+const (
+	codeInvalidRequest      = "invalid_request"
+	codeUnauthorized        = "unauthorized"
+	codeForbidden           = "forbidden"
+	codeNotFound            = "not_found"
+	codeMethodNotAllowed    = "method_not_allowed"
+	codeNotSupported        = "not_supported"
+	codeSessionExpired      = "session_expired"
+	codeUnavailable         = "unavailable"
+	codeRateLimited         = "rate_limited"
+	codeUpstreamUnavailable = "upstream_unavailable"
+	codeUpstreamTimeout     = "upstream_timeout"
+	codeDecryptFailed       = "decrypt_failed"
+	codeUpstreamError       = "upstream_error"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+type errorEnvelope struct {
+	Error errorBody `json:"error"`
+}
+
+type errorBody struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Details   []fieldError `json:"details,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// fieldError explains why a single request field was rejected.
+type fieldError struct {
+	Field string `json:"field"`
+	Issue string `json:"issue"`
+}
+
+func writeErr(w http.ResponseWriter, status int, code, msg string) {
+	writeErrDetails(w, status, code, msg, nil)
+}
+
+func writeErrDetails(w http.ResponseWriter, status int, code, msg string, details []fieldError) {
+	writeJSON(w, status, errorEnvelope{Error: errorBody{
+		Code:      code,
+		Message:   msg,
+		Details:   details,
+		RequestID: w.Header().Get(requestIDHeader),
+	}})
+}
+
+func writeInvalid(w http.ResponseWriter, details ...fieldError) {
+	writeErrDetails(w, http.StatusBadRequest, codeInvalidRequest, "invalid request", details)
+}
+
+func methodNotAllowed(w http.ResponseWriter, _ *http.Request) {
+	writeErr(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "method not allowed")
+}
+
+// retryAfter is implemented by upstream errors that carry a Retry-After
+// delay.
+type retryAfter interface {
+	RetryAfterDelay() time.Duration
+}
+
+// writeProviderErr maps provider errors to a status code and error code.
+func writeProviderErr(w http.ResponseWriter, err error) {
+	var invalid provider.InvalidInputError
+	var delay retryAfter
+	switch {
+	case errors.As(err, &invalid):
+		writeErrDetails(w, http.StatusBadRequest, codeInvalidRequest, err.Error(), []fieldError{{Field: invalid.Field, Issue: invalid.Reason}})
+	case errors.Is(err, provider.ErrInvalidInput):
+		writeErr(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+	case errors.Is(err, security.ErrForbidden):
+		writeErr(w, http.StatusForbidden, codeForbidden, err.Error())
+	case errors.Is(err, provider.ErrNotSupported):
+		writeErr(w, http.StatusNotImplemented, codeNotSupported, err.Error())
+	case errors.Is(err, provider.ErrCalendarNotFound), errors.Is(err, provider.ErrEventNotFound):
+		writeErr(w, http.StatusNotFound, codeNotFound, err.Error())
+	case errors.Is(err, provider.ErrSessionExpired):
+		writeErr(w, http.StatusServiceUnavailable, codeSessionExpired, err.Error())
+	case errors.Is(err, provider.ErrRateLimited):
+		if errors.As(err, &delay) && delay.RetryAfterDelay() > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(delay.RetryAfterDelay().Seconds())))
+		}
+		writeErr(w, http.StatusTooManyRequests, codeRateLimited, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		writeErr(w, http.StatusGatewayTimeout, codeUpstreamTimeout, err.Error())
+	case errors.Is(err, provider.ErrUpstreamUnavailable):
+		writeErr(w, http.StatusServiceUnavailable, codeUpstreamUnavailable, err.Error())
+	case errors.Is(err, provider.ErrDecryptFailed):
+		writeErr(w, http.StatusInternalServerError, codeDecryptFailed, err.Error())
+	default:
+		writeErr(w, http.StatusBadGateway, codeUpstreamError, err.Error())
+	}
+}
+
+// withRequestID tags every response with a request ID, keeping a well-formed
+// one sent by the client so logs can be correlated across hops.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	buf := make([]byte, 8)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
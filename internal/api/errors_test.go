@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/protonapi"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/security"
+)
+
+func decodeEnvelope(t *testing.T, res *http.Response) errorBody {
+	t.Helper()
+	defer res.Body.Close()
+	var env errorEnvelope
+	if err := json.NewDecoder(res.Body).Decode(&env); err != nil {
+		t.Fatalf("decode error envelope: %v", err)
+	}
+	return env.Error
+}
+
+func TestErrorEnvelopeAndRequestID(t *testing.T) {
+	s := New(Options{Provider: fakeProvider{}, Auth: security.BearerAuth{Enabled: true, Token: "t"}})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+
+	res, _ := http.Get(ts.URL + "/v1/calendars")
+	body := decodeEnvelope(t, res)
+	if res.StatusCode != http.StatusUnauthorized || body.Code != codeUnauthorized {
+		t.Fatalf("expected unauthorized, got %d %+v", res.StatusCode, body)
+	}
+	if body.RequestID == "" || body.RequestID != res.Header.Get(requestIDHeader) {
+		t.Fatalf("request id mismatch: body %q header %q", body.RequestID, res.Header.Get(requestIDHeader))
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/healthz", nil)
+	req.Header.Set(requestIDHeader, "trace-42")
+	res, _ = http.DefaultClient.Do(req)
+	if got := res.Header.Get(requestIDHeader); got != "trace-42" {
+		t.Fatalf("expected incoming request id to be kept, got %q", got)
+	}
+
+	req.Header.Set(requestIDHeader, "bad id\twith spaces")
+	res, _ = http.DefaultClient.Do(req)
+	if got := res.Header.Get(requestIDHeader); got == "" || strings.ContainsAny(got, " \t") {
+		t.Fatalf("expected generated request id, got %q", got)
+	}
+	if validRequestID(strings.Repeat("a", 65)) {
+		t.Fatal("expected overlong request id to be rejected")
+	}
+}
+
+func TestListEventsQueryValidation(t *testing.T) {
+	s := New(Options{Provider: fakeProvider{}})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+
+	res, _ := http.Get(ts.URL + "/v1/events?from=yesterday&to=2026-01-01&expand=maybe")
+	body := decodeEnvelope(t, res)
+	if res.StatusCode != http.StatusBadRequest || body.Code != codeInvalidRequest {
+		t.Fatalf("expected invalid_request, got %d %+v", res.StatusCode, body)
+	}
+	fields := map[string]bool{}
+	for _, d := range body.Details {
+		fields[d.Field] = true
+	}
+	if len(body.Details) != 3 || !fields["from"] || !fields["to"] || !fields["expand"] {
+		t.Fatalf("unexpected details: %+v", body.Details)
+	}
+
+	res, _ = http.Get(ts.URL + "/v1/calendars/c1/events?from=2026-02-01T00:00:00Z&to=2026-01-01T00:00:00Z")
+	body = decodeEnvelope(t, res)
+	if res.StatusCode != http.StatusBadRequest || len(body.Details) != 1 || body.Details[0].Field != "to" {
+		t.Fatalf("expected inverted range to be rejected, got %d %+v", res.StatusCode, body)
+	}
+
+	res, _ = http.Get(ts.URL + "/v1/events?from=2026-01-01T00:00:00Z&to=2026-02-01T00:00:00Z")
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 got %d", res.StatusCode)
+	}
+
+	res, _ = http.Post(ts.URL+"/v1/events/create", "application/json", strings.NewReader("{"))
+	body = decodeEnvelope(t, res)
+	if res.StatusCode != http.StatusBadRequest || len(body.Details) != 1 || body.Details[0].Field != "body" {
+		t.Fatalf("expected invalid body, got %d %+v", res.StatusCode, body)
+	}
+}
+
+func TestWriteProviderErrMapping(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{provider.InvalidInputError{Field: "start", Reason: "required"}, http.StatusBadRequest, codeInvalidRequest},
+		{fmt.Errorf("x: %w", provider.ErrInvalidInput), http.StatusBadRequest, codeInvalidRequest},
+		{provider.NotSupportedError{Operation: "create"}, http.StatusNotImplemented, codeNotSupported},
+		{provider.ErrCalendarNotFound, http.StatusNotFound, codeNotFound},
+		{provider.ErrEventNotFound, http.StatusNotFound, codeNotFound},
+		{provider.ErrSessionExpired, http.StatusServiceUnavailable, codeSessionExpired},
+		{&protonapi.APIError{Status: 429, RetryAfter: 30 * time.Second}, http.StatusTooManyRequests, codeRateLimited},
+		{fmt.Errorf("list: %w", context.DeadlineExceeded), http.StatusGatewayTimeout, codeUpstreamTimeout},
+		{&protonapi.APIError{Status: 503}, http.StatusServiceUnavailable, codeUpstreamUnavailable},
+		{fmt.Errorf("event: %w", provider.ErrDecryptFailed), http.StatusInternalServerError, codeDecryptFailed},
+		{errors.New("boom"), http.StatusBadGateway, codeUpstreamError},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		writeProviderErr(w, tc.err)
+		var env errorEnvelope
+		_ = json.Unmarshal(w.Body.Bytes(), &env)
+		if w.Code != tc.status || env.Error.Code != tc.code {
+			t.Fatalf("%v: got %d %q, want %d %q", tc.err, w.Code, env.Error.Code, tc.status, tc.code)
+		}
+		if tc.code == codeRateLimited && w.Header().Get("Retry-After") != "30" {
+			t.Fatalf("expected Retry-After 30, got %q", w.Header().Get("Retry-After"))
+		}
+		if tc.status == http.StatusBadRequest && tc.err.Error() == "invalid start: required" && len(env.Error.Details) != 1 {
+			t.Fatalf("expected field details, got %+v", env.Error.Details)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/ical"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
+)
+
+// handleExport serves a calendar as an RFC 5545 file so the bridge can be
+// used as a local, decrypted ICS subscription. Recurring series are exported
+// with their rules and overrides when the provider can list them unexpanded.
+func (s *Server) handleExport(w http.ResponseWriter, r *http.Request) {
+	calendarID := r.PathValue("id")
+	from, to, details := parseTimeRange(r)
+	if len(details) > 0 {
+		writeInvalid(w, details...)
+		return
+	}
+	cal, err := s.findCalendar(r.Context(), calendarID)
+	if err != nil {
+		writeProviderErr(w, err)
+		return
+	}
+	var events []domain.Event
+	if lister, ok := s.provider.(provider.SeriesLister); ok {
+		events, err = lister.ListSeries(r.Context(), calendarID, from, to)
+	} else {
+		events, err = s.provider.ListEvents(r.Context(), calendarID, from, to)
+		standalone(events)
+	}
+	if err != nil {
+		writeProviderErr(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="calendar.ics"`)
+	_ = ical.Encode(w, ical.Export(cal, events, time.Now()))
+}
+
+func (s *Server) findCalendar(ctx context.Context, calendarID string) (domain.Calendar, error) {
+	cals, err := s.provider.ListCalendars(ctx)
+	if err != nil {
+		return domain.Calendar{}, err
+	}
+	for _, c := range cals {
+		if c.ID == calendarID {
+			return c, nil
+		}
+	}
+	return domain.Calendar{}, provider.ErrCalendarNotFound
+}
+
+// standalone detaches expanded instances from their series. Without the
+// master a reader cannot apply a RECURRENCE-ID, so each instance is written
+// as an event of its own.
+func standalone(events []domain.Event) {
+	for i := range events {
+		e := &events[i]
+		if e.RecurrenceID == nil {
+			continue
+		}
+		e.UID = e.ID
+		e.SeriesID = ""
+		e.RecurrenceID = nil
+		e.Recurrence = ""
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+)
+
+// instanceProvider only lists expanded instances.
+type instanceProvider struct{ fakeProvider }
+
+func (instanceProvider) ListEvents(context.Context, string, time.Time, time.Time) ([]domain.Event, error) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	return []domain.Event{{
+		ID: "s1_20260302T090000Z", UID: "s1@x", SeriesID: "s1", RecurrenceID: &start,
+		Title: "Daily", Start: start, End: start.Add(time.Hour), Recurrence: "FREQ=DAILY",
+	}}, nil
+}
+
+func TestExportCalendar(t *testing.T) {
+	s := New(Options{Provider: seriesProvider{}})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+
+	res, _ := http.Get(ts.URL + "/v1/calendars/1/export.ics?from=2026-01-01T00:00:00Z")
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || !strings.HasPrefix(res.Header.Get("Content-Type"), "text/calendar") {
+		t.Fatalf("unexpected response %d %q", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+	for _, want := range []string{"BEGIN:VCALENDAR\r\n", "X-WR-CALNAME:x\r\n", "UID:s1\r\n", "RRULE:FREQ=DAILY\r\n"} {
+		if !strings.Contains(string(body), want) {
+			t.Fatalf("missing %q in:\n%s", want, body)
+		}
+	}
+
+	res, _ = http.Get(ts.URL + "/v1/calendars/missing/export.ics")
+	if body := decodeEnvelope(t, res); res.StatusCode != http.StatusNotFound || body.Code != codeNotFound {
+		t.Fatalf("expected 404 got %d %+v", res.StatusCode, body)
+	}
+	res, _ = http.Get(ts.URL + "/v1/calendars/1/export.ics?to=soon")
+	if body := decodeEnvelope(t, res); res.StatusCode != http.StatusBadRequest || body.Details[0].Field != "to" {
+		t.Fatalf("expected 400 got %d %+v", res.StatusCode, body)
+	}
+	res, _ = http.Post(ts.URL+"/v1/calendars/1/export.ics", "text/calendar", nil)
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 got %d", res.StatusCode)
+	}
+}
+
+func TestExportExpandedInstances(t *testing.T) {
+	s := New(Options{Provider: instanceProvider{}})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+
+	res, _ := http.Get(ts.URL + "/v1/calendars/1/export.ics")
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	out := string(body)
+	if res.StatusCode != http.StatusOK || !strings.Contains(out, "UID:s1_20260302T090000Z\r\n") {
+		t.Fatalf("unexpected export %d:\n%s", res.StatusCode, out)
+	}
+	if strings.Contains(out, "RECURRENCE-ID") || strings.Contains(out, "RRULE") {
+		t.Fatalf("instances must be standalone:\n%s", out)
+	}
+}
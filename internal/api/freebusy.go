@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/freebusy"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/ical"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/security"
+)
+
+type freeBusyResponse struct {
+	From      time.Time           `json:"from"`
+	To        time.Time           `json:"to"`
+	Calendars []string            `json:"calendars"`
+	Busy      []domain.BusyPeriod `json:"busy"`
+}
+
+// handleFreeBusy reports the merged busy time of the calendars named by
+// calendar_id, which may repeat, or of every calendar. Event content is never
+// returned. format=ics, or an Accept header asking for text/calendar, answers
+// with a VFREEBUSY instead of JSON.
+func (s *Server) handleFreeBusy(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, to, details := parseTimeRange(r)
+	for _, name := range []string{"from", "to"} {
+		if q.Get(name) == "" {
+			details = append(details, fieldError{Field: name, Issue: "required"})
+		}
+	}
+	format := q.Get("format")
+	switch format {
+	case "":
+		if strings.Contains(r.Header.Get("Accept"), "text/calendar") {
+			format = "ics"
+		}
+	case "json", "ics":
+	default:
+		details = append(details, fieldError{Field: "format", Issue: "must be json or ics"})
+	}
+	if len(details) > 0 {
+		writeInvalid(w, details...)
+		return
+	}
+
+	// Tokens limited to some calendars default to those calendars; named
+	// ones were checked by wrapAuth.
+	ids, _ := security.FromContext(r.Context()).CheckCalendars(q["calendar_id"])
+	ids, periods, err := freebusy.Collect(r.Context(), s.provider, ids, from, to)
+	if err != nil {
+		writeProviderErr(w, err)
+		return
+	}
+	if format == "ics" {
+		w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+		_ = ical.Encode(w, ical.FreeBusy(periods, from, to, time.Now()))
+		return
+	}
+	writeJSON(w, http.StatusOK, freeBusyResponse{From: from, To: to, Calendars: ids, Busy: periods})
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
+)
+
+// busyProvider has two calendars whose events overlap.
+type busyProvider struct{ fakeProvider }
+
+func (busyProvider) ListCalendars(context.Context) ([]domain.Calendar, error) {
+	return []domain.Calendar{{ID: "c1"}, {ID: "c2"}}, nil
+}
+func (busyProvider) ListEvents(_ context.Context, calendarID string, _, _ time.Time) ([]domain.Event, error) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	switch calendarID {
+	case "c1":
+		return []domain.Event{
+			{Title: "secret", Start: day.Add(9 * time.Hour), End: day.Add(10 * time.Hour)},
+			{Start: day.Add(12 * time.Hour), End: day.Add(13 * time.Hour), Transparent: true},
+		}, nil
+	case "c2":
+		return []domain.Event{
+			{Start: day.Add(9*time.Hour + 30*time.Minute), End: day.Add(11 * time.Hour)},
+			{Start: day.Add(14 * time.Hour), End: day.Add(15 * time.Hour), Status: "TENTATIVE"},
+		}, nil
+	}
+	return nil, provider.ErrCalendarNotFound
+}
+
+func TestFreeBusy(t *testing.T) {
+	s := New(Options{Provider: busyProvider{}})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+	window := "from=2026-03-02T00:00:00Z&to=2026-03-03T00:00:00Z"
+
+	res, _ := http.Get(ts.URL + "/v1/freebusy?" + window)
+	var all freeBusyResponse
+	_ = json.NewDecoder(res.Body).Decode(&all)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || len(all.Calendars) != 2 || len(all.Busy) != 2 {
+		t.Fatalf("unexpected response %d %+v", res.StatusCode, all)
+	}
+	if b := all.Busy[0]; b.Type != domain.BusyTypeBusy || b.Start.Hour() != 9 || b.End.Hour() != 11 {
+		t.Fatalf("unexpected merged block %+v", b)
+	}
+	if b := all.Busy[1]; b.Type != domain.BusyTypeTentative || b.Start.Hour() != 14 {
+		t.Fatalf("unexpected tentative block %+v", b)
+	}
+
+	res, _ = http.Get(ts.URL + "/v1/freebusy?calendar_id=c1&" + window)
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if strings.Contains(string(body), "secret") || !strings.Contains(string(body), `"calendars":["c1"]`) {
+		t.Fatalf("unexpected single calendar response:\n%s", body)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/freebusy?"+window, nil)
+	req.Header.Set("Accept", "text/calendar")
+	res, _ = http.DefaultClient.Do(req)
+	body, _ = io.ReadAll(res.Body)
+	res.Body.Close()
+	if !strings.HasPrefix(res.Header.Get("Content-Type"), "text/calendar") ||
+		!strings.Contains(string(body), "FREEBUSY;FBTYPE=BUSY:20260302T090000Z/20260302T110000Z") {
+		t.Fatalf("unexpected VFREEBUSY:\n%s", body)
+	}
+
+	res, _ = http.Get(ts.URL + "/v1/freebusy?from=2026-03-02T00:00:00Z&format=xml")
+	if e := decodeEnvelope(t, res); res.StatusCode != http.StatusBadRequest || len(e.Details) != 2 {
+		t.Fatalf("expected missing to and bad format, got %d %+v", res.StatusCode, e)
+	}
+	res, _ = http.Get(ts.URL + "/v1/freebusy?calendar_id=missing&" + window)
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d", res.StatusCode)
+	}
+	res, _ = http.Post(ts.URL+"/v1/freebusy", "application/json", nil)
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 got %d", res.StatusCode)
+	}
+}
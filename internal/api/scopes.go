@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/security"
+)
+
+// routeScope returns the scope a request needs and the calendars its path
+// and calendar_id parameters name. Routes not listed here, including unknown
+// paths, need the admin scope so a new route is closed until it is mapped.
+// The empty scope means the route checks the token itself.
+func routeScope(r *http.Request) (security.Scope, []string) {
+	path := r.URL.Path
+	calendars := r.URL.Query()["calendar_id"]
+	read := r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions
+	switch path {
+	case "/healthz", "/mcp":
+		return "", nil
+	case "/v1/capabilities", "/v1/calendars", "/v1/sync/status",
+		"/.well-known/caldav", "/dav/", davPrincipalPath, davHomePath:
+		return security.ScopeCalendarsRead, nil
+	case "/v1/events", "/v1/events/stream", "/v1/freebusy", "/v1/availability/slots":
+		return security.ScopeEventsRead, calendars
+	case "/v1/events/create", "/v1/events/update", "/v1/events/delete":
+		return security.ScopeEventsWrite, nil
+	}
+	if path == "/v1/webhooks" || strings.HasPrefix(path, "/v1/webhooks/") {
+		return security.ScopeAdmin, nil
+	}
+	for _, prefix := range []string{"/v1/calendars/", davHomePath} {
+		rest, ok := strings.CutPrefix(path, prefix)
+		if !ok {
+			continue
+		}
+		id, _, _ := strings.Cut(rest, "/")
+		// PROPFIND and REPORT read CalDAV collections.
+		if read || r.Method == "PROPFIND" || r.Method == "REPORT" {
+			return security.ScopeEventsRead, []string{id}
+		}
+		return security.ScopeEventsWrite, []string{id}
+	}
+	return security.ScopeAdmin, nil
+}
+
+// authorizeRoute checks the token against the scope and calendars of the
+// request's route.
+func authorizeRoute(t security.Token, r *http.Request) error {
+	scope, calendars := routeScope(r)
+	if scope != "" {
+		if err := t.Check(scope); err != nil {
+			return err
+		}
+	}
+	for _, id := range calendars {
+		if err := t.CheckCalendar(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// visibleCalendars drops the calendars the request's token may not see.
+func visibleCalendars(r *http.Request, cals []domain.Calendar) []domain.Calendar {
+	t := security.FromContext(r.Context())
+	if !t.Restricted() {
+		return cals
+	}
+	visible := make([]domain.Calendar, 0, len(cals))
+	for _, cal := range cals {
+		if t.AllowsCalendar(cal.ID) {
+			visible = append(visible, cal)
+		}
+	}
+	return visible
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/security"
+)
+
+const (
+	dashboardSecret = "dashboard-secret-0001"
+	writerSecret    = "writer-secret-000001"
+	limitedSecret   = "limited-writer-00001"
+)
+
+func TestScopedTokens(t *testing.T) {
+	auth := security.BearerAuth{Enabled: true, Token: "t", Tokens: []security.Token{
+		{Name: "dashboard", Secret: dashboardSecret, Scopes: []security.Scope{security.ScopeCalendarsRead, security.ScopeEventsRead}, Calendars: []string{"c1"}},
+		{Name: "writer", Secret: writerSecret, Scopes: []security.Scope{security.ScopeEventsWrite}},
+		{Name: "limited", Secret: limitedSecret, Scopes: []security.Scope{security.ScopeEventsWrite}, Calendars: []string{"c1"}},
+	}}
+	s := New(Options{Provider: busyProvider{}, Auth: auth})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+	do := func(secret, method, path, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+secret)
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+	forbidden := func(secret, method, path, body string) {
+		t.Helper()
+		res := do(secret, method, path, body)
+		if e := decodeEnvelope(t, res); res.StatusCode != http.StatusForbidden || e.Code != codeForbidden {
+			t.Fatalf("%s %s: expected 403 forbidden, got %d %+v", method, path, res.StatusCode, e)
+		}
+	}
+
+	res := do(dashboardSecret, http.MethodGet, "/v1/calendars", "")
+	var cals []domain.Calendar
+	_ = json.NewDecoder(res.Body).Decode(&cals)
+	if res.StatusCode != http.StatusOK || len(cals) != 1 || cals[0].ID != "c1" {
+		t.Fatalf("expected only c1, got %d %+v", res.StatusCode, cals)
+	}
+	if res := do(dashboardSecret, http.MethodGet, "/v1/events?calendar_id=c1", ""); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 got %d", res.StatusCode)
+	}
+	window := "from=2026-03-02T00:00:00Z&to=2026-03-03T00:00:00Z"
+	res = do(dashboardSecret, http.MethodGet, "/v1/freebusy?"+window, "")
+	var fb freeBusyResponse
+	_ = json.NewDecoder(res.Body).Decode(&fb)
+	if res.StatusCode != http.StatusOK || !slices.Equal(fb.Calendars, []string{"c1"}) {
+		t.Fatalf("expected free/busy of c1 only, got %d %+v", res.StatusCode, fb)
+	}
+	forbidden(dashboardSecret, http.MethodGet, "/v1/events", "")
+	forbidden(dashboardSecret, http.MethodGet, "/v1/calendars/c2/events", "")
+	forbidden(dashboardSecret, http.MethodGet, "/v1/freebusy?calendar_id=c2&"+window, "")
+	forbidden(dashboardSecret, http.MethodPost, "/v1/availability/slots", `{"calendar_ids":["c2"],"from":"2026-03-02T00:00:00Z","to":"2026-03-03T00:00:00Z","duration_minutes":30}`)
+	forbidden(dashboardSecret, http.MethodDelete, "/v1/calendars/c1/events/e1", "")
+	forbidden(dashboardSecret, http.MethodPost, "/v1/events/delete", `{"event_id":"e1"}`)
+	forbidden(dashboardSecret, http.MethodGet, "/v1/webhooks", "")
+	forbidden(dashboardSecret, http.MethodGet, "/v1/unknown", "")
+
+	// The writer reaches the provider, whose delete fails.
+	if res := do(writerSecret, http.MethodDelete, "/v1/calendars/c2/events/e1", ""); res.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected the provider error, got %d", res.StatusCode)
+	}
+	if res := do(writerSecret, http.MethodPost, "/v1/events/delete", `{"event_id":"e1"}`); res.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected the provider error, got %d", res.StatusCode)
+	}
+	forbidden(writerSecret, http.MethodGet, "/v1/calendars", "")
+
+	// Without event lookup the provider cannot confirm an event's calendar.
+	forbidden(limitedSecret, http.MethodDelete, "/v1/calendars/c1/events/e1", "")
+	forbidden(limitedSecret, http.MethodPatch, "/v1/calendars/c1/events/e1", `{}`)
+	forbidden(limitedSecret, http.MethodPost, "/v1/events/update", `{"event_id":"e1"}`)
+	forbidden(limitedSecret, http.MethodPost, "/v1/events/create", `{"mutation":{"calendar_id":"c2"}}`)
+	if res := do(limitedSecret, http.MethodPost, "/v1/events/create", `{"mutation":{"calendar_id":"c1"}}`); res.StatusCode != http.StatusNotImplemented {
+		t.Fatalf("expected the provider error, got %d", res.StatusCode)
+	}
+
+	if res := do("t", http.MethodGet, "/v1/webhooks", ""); res.StatusCode != http.StatusNotImplemented {
+		t.Fatalf("expected the full-access token to reach webhooks, got %d", res.StatusCode)
+	}
+	if res := do("unknown-secret-0001", http.MethodGet, "/v1/calendars", ""); res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 got %d", res.StatusCode)
+	}
+
+	// CalDAV clients send the secret as a Basic password and see only their
+	// calendars, read-only.
+	req, _ := http.NewRequest("PROPFIND", ts.URL+davHomePath, strings.NewReader(""))
+	req.SetBasicAuth("dashboard", dashboardSecret)
+	req.Header.Set("Depth", "1")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusMultiStatus || !strings.Contains(string(body), davCalendarHref("c1")) || strings.Contains(string(body), davCalendarHref("c2")) {
+		t.Fatalf("unexpected calendar home %d %s", res.StatusCode, body)
+	}
+}
+
+func TestRouteScope(t *testing.T) {
+	for _, tc := range []struct {
+		method, path string
+		scope        security.Scope
+		calendars    []string
+	}{
+		{http.MethodGet, "/healthz", "", nil},
+		{http.MethodPost, "/mcp", "", nil},
+		{http.MethodGet, "/v1/capabilities", security.ScopeCalendarsRead, nil},
+		{"PROPFIND", "/dav/principal/", security.ScopeCalendarsRead, nil},
+		{http.MethodGet, "/v1/events?calendar_id=a&calendar_id=b", security.ScopeEventsRead, []string{"a", "b"}},
+		{http.MethodPost, "/v1/events/create", security.ScopeEventsWrite, nil},
+		{http.MethodGet, "/v1/calendars/a/export.ics", security.ScopeEventsRead, []string{"a"}},
+		{http.MethodPatch, "/v1/calendars/a/events/e", security.ScopeEventsWrite, []string{"a"}},
+		{"REPORT", "/dav/calendars/a/", security.ScopeEventsRead, []string{"a"}},
+		{http.MethodPut, "/dav/calendars/a/e.ics", security.ScopeEventsWrite, []string{"a"}},
+		{http.MethodDelete, "/v1/webhooks/w", security.ScopeAdmin, nil},
+		{http.MethodGet, "/v2/anything", security.ScopeAdmin, nil},
+	} {
+		scope, calendars := routeScope(httptest.NewRequest(tc.method, tc.path, nil))
+		if scope != tc.scope || !slices.Equal(calendars, tc.calendars) {
+			t.Fatalf("%s %s: got %q %v want %q %v", tc.method, tc.path, scope, calendars, tc.scope, tc.calendars)
+		}
+	}
+}
@@ -8,24 +8,48 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/sevenofnine/proton-calendar-bridge/internal/changes"
 	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
 	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
 	"github.com/sevenofnine/proton-calendar-bridge/internal/security"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/webhook"
 )
 
 type Server struct {
 	provider provider.CalendarProvider
 	auth     security.BearerAuth
 	log      *slog.Logger
+	changes  *changes.Feed
+	webhooks *webhook.Dispatcher
+	mcp      http.Handler
 	httpSrv  *http.Server
+	// davNames keeps the names of objects created over CalDAV.
+	davNames davNames
+
+	// stopping is closed on shutdown to end long-lived streams, which
+	// Shutdown would otherwise wait for.
+	stopping chan struct{}
+	stopOnce sync.Once
 }
 
 type Options struct {
 	Provider provider.CalendarProvider
 	Auth     security.BearerAuth
 	Logger   *slog.Logger
+	// CalDAVNamesPath is the file the names of objects created over CalDAV
+	// are kept in; empty keeps them in memory only.
+	CalDAVNamesPath string
+	// Changes feeds GET /v1/events/stream; without it the stream is disabled.
+	Changes *changes.Feed
+	// Webhooks serves /v1/webhooks; without it those routes answer 501.
+	Webhooks *webhook.Dispatcher
+	// MCP serves the Model Context Protocol streamable HTTP transport at
+	// /mcp, behind the same authentication as the API.
+	MCP http.Handler
 }
 
 func New(opts Options) *Server {
@@ -33,7 +57,10 @@ func New(opts Options) *Server {
 	if logger == nil {
 		logger = slog.Default()
 	}
-	s := &Server{provider: opts.Provider, auth: opts.Auth, log: logger}
+	s := &Server{provider: opts.Provider, auth: opts.Auth, log: logger, changes: opts.Changes, webhooks: opts.Webhooks, mcp: opts.MCP, stopping: make(chan struct{})}
+	if err := s.davNames.load(opts.CalDAVNamesPath); err != nil {
+		logger.Warn("failed to load CalDAV object names; objects created over CalDAV appear under their event IDs", "path", opts.CalDAVNamesPath, "error", err)
+	}
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", s.handleHealth)
 	mux.HandleFunc("/v1/capabilities", s.handleCapabilities)
@@ -42,7 +69,29 @@ func New(opts Options) *Server {
 	mux.HandleFunc("/v1/events/create", s.handleCreateEvent)
 	mux.HandleFunc("/v1/events/update", s.handleUpdateEvent)
 	mux.HandleFunc("/v1/events/delete", s.handleDeleteEvent)
-	s.httpSrv = &http.Server{Handler: s.wrapAuth(mux), ReadHeaderTimeout: 5 * time.Second}
+	mux.HandleFunc("GET /v1/events/stream", s.handleEventStream)
+	mux.HandleFunc("/v1/events/stream", methodNotAllowed)
+	mux.HandleFunc("/v1/sync/status", s.handleSyncStatus)
+	mux.HandleFunc("GET /v1/calendars/{id}/events", s.handleCalendarEvents)
+	mux.HandleFunc("POST /v1/calendars/{id}/events", s.handleCreateCalendarEvent)
+	mux.HandleFunc("/v1/calendars/{id}/events", methodNotAllowed)
+	mux.HandleFunc("GET /v1/calendars/{id}/events/{eventID}", s.handleGetEvent)
+	mux.HandleFunc("PATCH /v1/calendars/{id}/events/{eventID}", s.handlePatchEvent)
+	mux.HandleFunc("DELETE /v1/calendars/{id}/events/{eventID}", s.handleRemoveEvent)
+	mux.HandleFunc("/v1/calendars/{id}/events/{eventID}", methodNotAllowed)
+	mux.HandleFunc("GET /v1/calendars/{id}/export.ics", s.handleExport)
+	mux.HandleFunc("/v1/calendars/{id}/export.ics", methodNotAllowed)
+	mux.HandleFunc("GET /v1/freebusy", s.handleFreeBusy)
+	mux.HandleFunc("/v1/freebusy", methodNotAllowed)
+	mux.HandleFunc("POST /v1/availability/slots", s.handleSlots)
+	mux.HandleFunc("/v1/availability/slots", methodNotAllowed)
+	s.registerWebhooks(mux)
+	if s.mcp != nil {
+		mux.Handle("/mcp", s.mcp)
+	}
+	s.registerCalDAV(mux)
+	s.httpSrv = &http.Server{Handler: withRequestID(s.wrapAuth(mux)), ReadHeaderTimeout: 5 * time.Second}
+	s.httpSrv.RegisterOnShutdown(func() { s.stopOnce.Do(func() { close(s.stopping) }) })
 	return s
 }
 
@@ -74,13 +123,28 @@ func (s *Server) ServeUnix(ctx context.Context, path string) error {
 	return s.httpSrv.Serve(ln)
 }
 
+// wrapAuth authenticates the request and checks the token's scopes and
+// calendars against the route. Handlers find the token in the request
+// context for the checks that need the body or the provider.
 func (s *Server) wrapAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Path != "/healthz" && !s.auth.Authorize(r) {
-			writeErr(w, http.StatusUnauthorized, "unauthorized")
+		if r.URL.Path == "/healthz" {
+			next.ServeHTTP(w, r)
 			return
 		}
-		next.ServeHTTP(w, r)
+		token, ok := s.auth.Authenticate(r)
+		if !ok {
+			if isDAVPath(r.URL.Path) {
+				w.Header().Set("WWW-Authenticate", `Basic realm="proton-calendar-bridge"`)
+			}
+			writeErr(w, http.StatusUnauthorized, codeUnauthorized, "unauthorized")
+			return
+		}
+		if err := authorizeRoute(token, r); err != nil {
+			writeProviderErr(w, err)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(security.NewContext(r.Context(), token)))
 	})
 }
 
@@ -92,19 +156,27 @@ func (s *Server) shutdownOnContext(ctx context.Context) {
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	writeJSON(w, http.StatusOK, map[string]string{"status": "ok", "provider": s.provider.Name()})
+	body := map[string]any{"status": "ok", "provider": s.provider.Name()}
+	if hr, ok := s.provider.(provider.HealthReporter); ok {
+		for k, v := range hr.Health() {
+			if _, reserved := body[k]; !reserved {
+				body[k] = v
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, body)
 }
 
 func (s *Server) handleCapabilities(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		methodNotAllowed(w, r)
 		return
 	}
 	caps := provider.CapabilitySet{ReadOnly: true, WriteSupported: false, Notes: []string{"provider does not expose capability metadata"}}
 	if cp, ok := s.provider.(provider.CapabilityProvider); ok {
 		c, err := cp.Capabilities(r.Context())
 		if err != nil {
-			writeErr(w, http.StatusBadGateway, err.Error())
+			writeProviderErr(w, err)
 			return
 		}
 		caps = c
@@ -114,47 +186,217 @@ func (s *Server) handleCapabilities(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) handleCalendars(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		methodNotAllowed(w, r)
 		return
 	}
 	items, err := s.provider.ListCalendars(r.Context())
 	if err != nil {
-		writeErr(w, http.StatusBadGateway, err.Error())
+		writeProviderErr(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, items)
+	writeJSON(w, http.StatusOK, visibleCalendars(r, items))
 }
 
 func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
-		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		methodNotAllowed(w, r)
+		return
+	}
+	s.listEvents(w, r, r.URL.Query().Get("calendar_id"))
+}
+
+func (s *Server) handleCalendarEvents(w http.ResponseWriter, r *http.Request) {
+	s.listEvents(w, r, r.PathValue("id"))
+}
+
+func (s *Server) listEvents(w http.ResponseWriter, r *http.Request, calendarID string) {
+	from, to, expand, details := parseRangeQuery(r)
+	if len(details) > 0 {
+		writeInvalid(w, details...)
 		return
 	}
-	calendarID := r.URL.Query().Get("calendar_id")
-	from, _ := time.Parse(time.RFC3339, r.URL.Query().Get("from"))
-	to, _ := time.Parse(time.RFC3339, r.URL.Query().Get("to"))
-	items, err := s.provider.ListEvents(r.Context(), calendarID, from, to)
+	if err := security.FromContext(r.Context()).CheckCalendar(calendarID); err != nil {
+		writeProviderErr(w, err)
+		return
+	}
+	list := s.provider.ListEvents
+	if lister, ok := s.provider.(provider.SeriesLister); ok && !expand {
+		list = lister.ListSeries
+	}
+	items, err := list(r.Context(), calendarID, from, to)
 	if err != nil {
-		writeErr(w, http.StatusBadGateway, err.Error())
+		writeProviderErr(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, items)
 }
 
+func (s *Server) handleCreateCalendarEvent(w http.ResponseWriter, r *http.Request) {
+	var in domain.EventMutation
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeInvalid(w, fieldError{Field: "body", Issue: "invalid json: " + err.Error()})
+		return
+	}
+	in.CalendarID = r.PathValue("id")
+	created, err := s.provider.CreateEvent(r.Context(), in)
+	if err != nil {
+		writeProviderErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (s *Server) handleGetEvent(w http.ResponseWriter, r *http.Request) {
+	getter, ok := s.provider.(provider.EventGetter)
+	if !ok {
+		writeProviderErr(w, provider.NotSupportedError{Operation: "get_event"})
+		return
+	}
+	e, err := getter.GetEvent(r.Context(), r.PathValue("id"), r.PathValue("eventID"))
+	if err != nil {
+		writeProviderErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+// parseRangeQuery reads the from, to and expand parameters, collecting a
+// field error for each one that is malformed. Missing values leave the range
+// open and expand defaults to true.
+func parseRangeQuery(r *http.Request) (from, to time.Time, expand bool, details []fieldError) {
+	from, to, details = parseTimeRange(r)
+	expand = true
+	if raw := r.URL.Query().Get("expand"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			details = append(details, fieldError{Field: "expand", Issue: "must be a boolean"})
+		} else {
+			expand = v
+		}
+	}
+	return from, to, expand, details
+}
+
+// parseTimeRange reads the optional RFC 3339 from and to parameters.
+func parseTimeRange(r *http.Request) (from, to time.Time, details []fieldError) {
+	q := r.URL.Query()
+	for _, name := range []string{"from", "to"} {
+		raw := q.Get(name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			details = append(details, fieldError{Field: name, Issue: "must be an RFC 3339 timestamp"})
+			continue
+		}
+		if name == "from" {
+			from = t
+		} else {
+			to = t
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		details = append(details, fieldError{Field: "to", Issue: "must not be before from"})
+	}
+	return from, to, details
+}
+
+// handlePatchEvent applies the fields present in the body on top of the
+// current event, so callers can change a single field. Providers that cannot
+// look up events get the body as the whole mutation.
+func (s *Server) handlePatchEvent(w http.ResponseWriter, r *http.Request) {
+	calendarID, eventID := r.PathValue("id"), r.PathValue("eventID")
+	var in domain.EventMutation
+	if getter, ok := s.provider.(provider.EventGetter); ok {
+		current, err := getter.GetEvent(r.Context(), calendarID, eventID)
+		if err != nil {
+			writeProviderErr(w, err)
+			return
+		}
+		if provider.IsInstance(current, eventID) {
+			writeProviderErr(w, provider.NotSupportedError{Operation: "update_instance"})
+			return
+		}
+		in = provider.MutationFromEvent(current)
+	} else if err := security.FromContext(r.Context()).CheckAllCalendars(); err != nil {
+		writeProviderErr(w, err)
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeInvalid(w, fieldError{Field: "body", Issue: "invalid json: " + err.Error()})
+		return
+	}
+	in.CalendarID = calendarID
+	updated, err := s.provider.UpdateEvent(r.Context(), eventID, in)
+	if err != nil {
+		writeProviderErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, updated)
+}
+
+func (s *Server) handleRemoveEvent(w http.ResponseWriter, r *http.Request) {
+	calendarID, eventID := r.PathValue("id"), r.PathValue("eventID")
+	if getter, ok := s.provider.(provider.EventGetter); ok {
+		current, err := getter.GetEvent(r.Context(), calendarID, eventID)
+		if err != nil {
+			writeProviderErr(w, err)
+			return
+		}
+		if provider.IsInstance(current, eventID) {
+			writeProviderErr(w, provider.NotSupportedError{Operation: "delete_instance"})
+			return
+		}
+	} else if err := security.FromContext(r.Context()).CheckAllCalendars(); err != nil {
+		writeProviderErr(w, err)
+		return
+	}
+	if err := s.provider.DeleteEvent(r.Context(), eventID); err != nil {
+		writeProviderErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleSyncStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	syncer, ok := s.provider.(provider.Syncer)
+	if !ok {
+		writeProviderErr(w, provider.NotSupportedError{Operation: "sync_status"})
+		return
+	}
+	writeJSON(w, http.StatusOK, syncer.SyncStatus())
+}
+
 func (s *Server) handleCreateEvent(w http.ResponseWriter, r *http.Request) {
 	s.handleMutation(w, r, func(ctx context.Context, payload mutationRequest) (any, error) {
+		if err := security.FromContext(ctx).CheckCalendar(payload.Mutation.CalendarID); err != nil {
+			return nil, err
+		}
 		return s.provider.CreateEvent(ctx, payload.Mutation)
 	})
 }
 
+// handleUpdateEvent and handleDeleteEvent address events by ID alone, so
+// tokens limited to some calendars must use the calendar-scoped routes.
 func (s *Server) handleUpdateEvent(w http.ResponseWriter, r *http.Request) {
 	s.handleMutation(w, r, func(ctx context.Context, payload mutationRequest) (any, error) {
+		if err := security.FromContext(ctx).CheckAllCalendars(); err != nil {
+			return nil, err
+		}
 		return s.provider.UpdateEvent(ctx, payload.EventID, payload.Mutation)
 	})
 }
 
 func (s *Server) handleDeleteEvent(w http.ResponseWriter, r *http.Request) {
 	s.handleMutation(w, r, func(ctx context.Context, payload mutationRequest) (any, error) {
+		if err := security.FromContext(ctx).CheckAllCalendars(); err != nil {
+			return nil, err
+		}
 		return map[string]string{"event_id": payload.EventID}, s.provider.DeleteEvent(ctx, payload.EventID)
 	})
 }
@@ -166,21 +408,17 @@ type mutationRequest struct {
 
 func (s *Server) handleMutation(w http.ResponseWriter, r *http.Request, run func(context.Context, mutationRequest) (any, error)) {
 	if r.Method != http.MethodPost {
-		writeErr(w, http.StatusMethodNotAllowed, "method not allowed")
+		methodNotAllowed(w, r)
 		return
 	}
 	var payload mutationRequest
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeErr(w, http.StatusBadRequest, "invalid json")
+		writeInvalid(w, fieldError{Field: "body", Issue: "invalid json: " + err.Error()})
 		return
 	}
 	out, err := run(r.Context(), payload)
 	if err != nil {
-		if errors.Is(err, provider.ErrNotSupported) {
-			writeErr(w, http.StatusNotImplemented, err.Error())
-			return
-		}
-		writeErr(w, http.StatusBadGateway, err.Error())
+		writeProviderErr(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, out)
@@ -191,7 +429,3 @@ func writeJSON(w http.ResponseWriter, code int, v any) {
 	w.WriteHeader(code)
 	_ = json.NewEncoder(w).Encode(v)
 }
-
-func writeErr(w http.ResponseWriter, code int, msg string) {
-	writeJSON(w, code, map[string]string{"error": msg})
-}
@@ -5,13 +5,16 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/mcp"
 	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
 	"github.com/sevenofnine/proton-calendar-bridge/internal/security"
 )
@@ -96,13 +99,13 @@ func TestServerMutationsAndMethods(t *testing.T) {
 
 func TestHelpersAndServeValidation(t *testing.T) {
 	r := httptest.NewRecorder()
-	writeErr(r, 400, "x")
+	writeErr(r, 400, codeInvalidRequest, "x")
 	if r.Code != 400 {
 		t.Fatal("wrong status")
 	}
-	var m map[string]string
+	var m errorEnvelope
 	_ = json.Unmarshal(r.Body.Bytes(), &m)
-	if m["error"] != "x" {
+	if m.Error.Message != "x" || m.Error.Code != codeInvalidRequest {
 		t.Fatal("wrong payload")
 	}
 
@@ -128,7 +131,10 @@ func (errProvider) Name() string { return "err" }
 func (errProvider) ListCalendars(context.Context) ([]domain.Calendar, error) {
 	return nil, errors.New("boom")
 }
-func (errProvider) ListEvents(context.Context, string, time.Time, time.Time) ([]domain.Event, error) {
+func (errProvider) ListEvents(_ context.Context, calendarID string, _, _ time.Time) ([]domain.Event, error) {
+	if calendarID == "missing" {
+		return nil, fmt.Errorf("%w: %s", provider.ErrCalendarNotFound, calendarID)
+	}
 	return nil, errors.New("boom")
 }
 func (errProvider) CreateEvent(context.Context, domain.EventMutation) (domain.Event, error) {
@@ -149,8 +155,12 @@ func TestServerErrorPaths(t *testing.T) {
 		t.Fatalf("expected 502 got %d", res.StatusCode)
 	}
 	res, _ = http.Get(ts.URL + "/v1/events?from=bad")
-	if res.StatusCode != http.StatusBadGateway {
-		t.Fatalf("expected 502 got %d", res.StatusCode)
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", res.StatusCode)
+	}
+	res, _ = http.Get(ts.URL + "/v1/events?calendar_id=missing")
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d", res.StatusCode)
 	}
 
 	res, _ = http.Post(ts.URL+"/v1/events/update", "application/json", bytes.NewBufferString(`{"event_id":"1","mutation":{}}`))
@@ -193,3 +203,272 @@ func TestCapabilitiesMethodNotAllowed(t *testing.T) {
 		t.Fatalf("expected 405 got %d", res.StatusCode)
 	}
 }
+
+type seriesProvider struct{ fakeProvider }
+
+func (seriesProvider) ListSeries(context.Context, string, time.Time, time.Time) ([]domain.Event, error) {
+	return []domain.Event{{ID: "s1", Recurrence: "FREQ=DAILY"}}, nil
+}
+
+func TestEventsExpandOption(t *testing.T) {
+	s := New(Options{Provider: seriesProvider{}, Auth: security.BearerAuth{Enabled: false}})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+
+	cases := map[string]string{"": "e1", "?expand=true": "e1", "?expand=false": "s1", "?expand=0": "s1"}
+	for query, want := range cases {
+		res, err := http.Get(ts.URL + "/v1/events" + query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var items []domain.Event
+		_ = json.NewDecoder(res.Body).Decode(&items)
+		res.Body.Close()
+		if len(items) != 1 || items[0].ID != want {
+			t.Fatalf("%q: unexpected items %+v", query, items)
+		}
+	}
+
+	res, _ := http.Get(ts.URL + "/v1/events?expand=maybe")
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", res.StatusCode)
+	}
+
+	// Providers without series support fall back to ListEvents.
+	s = New(Options{Provider: fakeProvider{}, Auth: security.BearerAuth{Enabled: false}})
+	ts2 := httptest.NewServer(s.httpSrv.Handler)
+	defer ts2.Close()
+	res, _ = http.Get(ts2.URL + "/v1/events?expand=false")
+	var items []domain.Event
+	_ = json.NewDecoder(res.Body).Decode(&items)
+	if len(items) != 1 || items[0].ID != "e1" {
+		t.Fatalf("unexpected fallback items %+v", items)
+	}
+}
+
+type healthProvider struct{ fakeProvider }
+
+func (healthProvider) Health() map[string]any {
+	return map[string]any{"cache": map[string]int{"hits": 3}, "status": "overridden"}
+}
+
+func TestHealthIncludesProviderDetails(t *testing.T) {
+	s := New(Options{Provider: healthProvider{}, Auth: security.BearerAuth{Enabled: true, Token: "t"}})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var body map[string]any
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body["status"] != "ok" || body["provider"] != "fake" || body["cache"] == nil {
+		t.Fatalf("unexpected health body: %+v", body)
+	}
+}
+
+type syncProvider struct{ fakeProvider }
+
+func (syncProvider) RunSync(context.Context) {}
+func (syncProvider) SyncStatus() provider.SyncStatus {
+	return provider.SyncStatus{Enabled: true, Calendars: []provider.CalendarSyncStatus{{CalendarID: "c1", Cursor: "cur", LagSeconds: 2}}}
+}
+
+func TestSyncStatus(t *testing.T) {
+	s := New(Options{Provider: syncProvider{}, Auth: security.BearerAuth{Enabled: false}})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/v1/sync/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var status provider.SyncStatus
+	_ = json.NewDecoder(res.Body).Decode(&status)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || !status.Enabled || len(status.Calendars) != 1 || status.Calendars[0].Cursor != "cur" {
+		t.Fatalf("unexpected status %d %+v", res.StatusCode, status)
+	}
+	res, _ = http.Post(ts.URL+"/v1/sync/status", "application/json", nil)
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 got %d", res.StatusCode)
+	}
+
+	s = New(Options{Provider: fakeProvider{}, Auth: security.BearerAuth{Enabled: false}})
+	ts2 := httptest.NewServer(s.httpSrv.Handler)
+	defer ts2.Close()
+	res, _ = http.Get(ts2.URL + "/v1/sync/status")
+	if res.StatusCode != http.StatusNotImplemented {
+		t.Fatalf("expected 501 got %d", res.StatusCode)
+	}
+}
+
+// resourceProvider records what the resource routes pass through.
+type resourceProvider struct {
+	fakeProvider
+	listed  string
+	created domain.EventMutation
+	updated domain.EventMutation
+	deleted string
+}
+
+func (p *resourceProvider) ListEvents(_ context.Context, calendarID string, _, _ time.Time) ([]domain.Event, error) {
+	p.listed = calendarID
+	if calendarID == "missing" {
+		return nil, provider.ErrCalendarNotFound
+	}
+	return []domain.Event{{ID: "e1", CalendarID: calendarID}}, nil
+}
+func (p *resourceProvider) CreateEvent(_ context.Context, in domain.EventMutation) (domain.Event, error) {
+	p.created = in
+	return domain.Event{ID: "new", CalendarID: in.CalendarID, Title: in.Title}, nil
+}
+func (p *resourceProvider) UpdateEvent(_ context.Context, eventID string, in domain.EventMutation) (domain.Event, error) {
+	p.updated = in
+	return domain.Event{ID: eventID, Title: in.Title, Location: in.Location}, nil
+}
+func (p *resourceProvider) DeleteEvent(_ context.Context, eventID string) error {
+	p.deleted = eventID
+	return nil
+}
+func (p *resourceProvider) GetEvent(_ context.Context, calendarID, eventID string) (domain.Event, error) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	series := domain.Event{ID: "e1", CalendarID: "c1", Title: "Standup", Location: "Room 1", Start: start, End: start.Add(time.Hour), TimeZone: "Europe/Berlin", Recurrence: "FREQ=WEEKLY"}
+	switch {
+	case calendarID != "c1":
+	case eventID == "e1":
+		return series, nil
+	case eventID == "e1_20260309T090000Z":
+		instance := series
+		instance.ID, instance.SeriesID, instance.Recurrence = eventID, "e1", ""
+		instance.Start, instance.End = start.AddDate(0, 0, 7), start.AddDate(0, 0, 7).Add(time.Hour)
+		instance.RecurrenceID = &instance.Start
+		return instance, nil
+	}
+	return domain.Event{}, fmt.Errorf("%w: %s", provider.ErrEventNotFound, eventID)
+}
+
+func TestResourceRoutes(t *testing.T) {
+	p := &resourceProvider{}
+	s := New(Options{Provider: p, Auth: security.BearerAuth{Enabled: false}})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+
+	do := func(method, path, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, bytes.NewBufferString(body))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+
+	res := do(http.MethodGet, "/v1/calendars/c1/events?expand=true", "")
+	if res.StatusCode != http.StatusOK || p.listed != "c1" {
+		t.Fatalf("list: %d, calendar %q", res.StatusCode, p.listed)
+	}
+	if res := do(http.MethodGet, "/v1/calendars/missing/events", ""); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown calendar, got %d", res.StatusCode)
+	}
+
+	res = do(http.MethodPost, "/v1/calendars/c1/events", `{"calendar_id":"other","title":"Lunch"}`)
+	if res.StatusCode != http.StatusCreated || p.created.CalendarID != "c1" || p.created.Title != "Lunch" {
+		t.Fatalf("create: %d %+v", res.StatusCode, p.created)
+	}
+	if res := do(http.MethodPost, "/v1/calendars/c1/events", `{`); res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", res.StatusCode)
+	}
+
+	res = do(http.MethodGet, "/v1/calendars/c1/events/e1", "")
+	var got domain.Event
+	_ = json.NewDecoder(res.Body).Decode(&got)
+	if res.StatusCode != http.StatusOK || got.Title != "Standup" {
+		t.Fatalf("get: %d %+v", res.StatusCode, got)
+	}
+	if res := do(http.MethodGet, "/v1/calendars/c1/events/nope", ""); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d", res.StatusCode)
+	}
+
+	// PATCH keeps the fields the body leaves out.
+	res = do(http.MethodPatch, "/v1/calendars/c1/events/e1", `{"title":"Daily standup"}`)
+	if res.StatusCode != http.StatusOK || p.updated.Title != "Daily standup" || p.updated.Location != "Room 1" || p.updated.Start.IsZero() || p.updated.CalendarID != "c1" ||
+		p.updated.TimeZone != "Europe/Berlin" || p.updated.Recurrence != "FREQ=WEEKLY" {
+		t.Fatalf("patch: %d %+v", res.StatusCode, p.updated)
+	}
+	// A single occurrence cannot be written on its own.
+	p.updated = domain.EventMutation{}
+	if res := do(http.MethodPatch, "/v1/calendars/c1/events/e1_20260309T090000Z", `{"title":"Moved"}`); res.StatusCode != http.StatusNotImplemented || p.updated.Title != "" {
+		t.Fatalf("expected 501 without update, got %d %+v", res.StatusCode, p.updated)
+	}
+	if res := do(http.MethodDelete, "/v1/calendars/c1/events/e1_20260309T090000Z", ""); res.StatusCode != http.StatusNotImplemented || p.deleted != "" {
+		t.Fatalf("expected 501 without delete, got %d (%q)", res.StatusCode, p.deleted)
+	}
+	if res := do(http.MethodPatch, "/v1/calendars/c1/events/nope", `{}`); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d", res.StatusCode)
+	}
+	if res := do(http.MethodPatch, "/v1/calendars/c1/events/e1", `[`); res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", res.StatusCode)
+	}
+
+	if res := do(http.MethodDelete, "/v1/calendars/c1/events/nope", ""); res.StatusCode != http.StatusNotFound || p.deleted != "" {
+		t.Fatalf("expected 404 without delete, got %d (%q)", res.StatusCode, p.deleted)
+	}
+	if res := do(http.MethodDelete, "/v1/calendars/c1/events/e1", ""); res.StatusCode != http.StatusNoContent || p.deleted != "e1" {
+		t.Fatalf("delete: %d %q", res.StatusCode, p.deleted)
+	}
+
+	if res := do(http.MethodPut, "/v1/calendars/c1/events/e1", ""); res.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 got %d", res.StatusCode)
+	}
+	if res := do(http.MethodDelete, "/v1/calendars/c1/events", ""); res.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 got %d", res.StatusCode)
+	}
+}
+
+func TestResourceRoutesWithoutLookup(t *testing.T) {
+	s := New(Options{Provider: fakeProvider{}, Auth: security.BearerAuth{Enabled: false}})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+
+	res, _ := http.Get(ts.URL + "/v1/calendars/c1/events/e1")
+	if res.StatusCode != http.StatusNotImplemented {
+		t.Fatalf("expected 501 got %d", res.StatusCode)
+	}
+	req, _ := http.NewRequest(http.MethodPatch, ts.URL+"/v1/calendars/c1/events/e1", bytes.NewBufferString(`{"title":"x"}`))
+	if res, _ := http.DefaultClient.Do(req); res.StatusCode != http.StatusOK {
+		t.Fatalf("expected patch without lookup to update, got %d", res.StatusCode)
+	}
+	req, _ = http.NewRequest(http.MethodDelete, ts.URL+"/v1/calendars/c1/events/e1", nil)
+	if res, _ := http.DefaultClient.Do(req); res.StatusCode != http.StatusBadGateway {
+		t.Fatalf("expected 502 got %d", res.StatusCode)
+	}
+	res, _ = http.Post(ts.URL+"/v1/calendars/c1/events", "application/json", bytes.NewBufferString(`{}`))
+	if res.StatusCode != http.StatusNotImplemented {
+		t.Fatalf("expected 501 got %d", res.StatusCode)
+	}
+}
+
+func TestMCPRoute(t *testing.T) {
+	s := New(Options{Provider: fakeProvider{}, Auth: security.BearerAuth{Enabled: true, Token: "t"}, MCP: mcp.New(context.Background(), fakeProvider{}, "dev")})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`
+	res, _ := http.Post(ts.URL+"/mcp", "application/json", strings.NewReader(body))
+	if res.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 got %d", res.StatusCode)
+	}
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/mcp", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer t")
+	res, _ = http.DefaultClient.Do(req)
+	out, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || !strings.Contains(string(out), `"list_events"`) {
+		t.Fatalf("unexpected MCP reply %d %s", res.StatusCode, out)
+	}
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/security"
+)
+
+// streamHeartbeat is how often an idle stream sends a comment, so proxies
+// and clients can tell a quiet stream from a dead one.
+const streamHeartbeat = 15 * time.Second
+
+// handleEventStream sends feed changes as Server-Sent Events, each with its
+// feed ID so a reconnecting client resumes with Last-Event-ID. The
+// last_event_id parameter does the same for clients that cannot set headers.
+// When the position cannot be resumed from, a reset event tells the client to
+// refetch before following the stream from the current head.
+func (s *Server) handleEventStream(w http.ResponseWriter, r *http.Request) {
+	if s.changes == nil {
+		writeErr(w, http.StatusNotImplemented, codeNotSupported, "change stream is disabled")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErr(w, http.StatusInternalServerError, codeUpstreamError, "streaming unsupported")
+		return
+	}
+	cursor := r.Header.Get("Last-Event-ID")
+	if cursor == "" {
+		cursor = r.URL.Query().Get("last_event_id")
+	}
+	if cursor == "" {
+		cursor = s.changes.Head()
+	}
+	calendars := make(map[string]bool)
+	ids, _ := security.FromContext(r.Context()).CheckCalendars(r.URL.Query()["calendar_id"])
+	for _, id := range ids {
+		calendars[id] = true
+	}
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		wake := s.changes.Wait()
+		items, ok := s.changes.Since(cursor)
+		if !ok {
+			cursor = s.changes.Head()
+			writeSSE(w, cursor, "reset", map[string]string{"id": cursor})
+		}
+		for _, c := range items {
+			cursor = c.ID
+			if len(calendars) == 0 || calendars[c.CalendarID] {
+				writeSSE(w, c.ID, c.Type, c)
+			}
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.stopping:
+			return
+		case <-wake:
+		case <-heartbeat.C:
+			_, _ = io.WriteString(w, ": ping\n\n")
+		}
+	}
+}
+
+func writeSSE(w io.Writer, id, event string, v any) {
+	data, _ := json.Marshal(v)
+	_, _ = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, event, data)
+}
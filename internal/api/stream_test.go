@@ -0,0 +1,127 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/changes"
+)
+
+type sseEvent struct{ id, event, data string }
+
+// readSSE returns the next event on the stream, skipping comments.
+func readSSE(t *testing.T, r *bufio.Reader) sseEvent {
+	t.Helper()
+	var ev sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("stream ended: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && ev.event != "":
+			return ev
+		case strings.HasPrefix(line, "id: "):
+			ev.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			ev.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			ev.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	feed := changes.NewFeed(2)
+	s := New(Options{Provider: fakeProvider{}, Changes: feed})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	open := func(query, lastID string) (*http.Response, *bufio.Reader) {
+		t.Helper()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/v1/events/stream"+query, nil)
+		if lastID != "" {
+			req.Header.Set("Last-Event-ID", lastID)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "text/event-stream" {
+			t.Fatalf("unexpected stream response %d %q", res.StatusCode, res.Header.Get("Content-Type"))
+		}
+		return res, bufio.NewReader(res.Body)
+	}
+
+	res, r := open("", "")
+	feed.Publish([]changes.Change{{Type: changes.EventCreated, CalendarID: "c1", EventID: "e1"}})
+	first := readSSE(t, r)
+	var c changes.Change
+	if err := json.Unmarshal([]byte(first.data), &c); err != nil || first.event != changes.EventCreated || c.ID != first.id || c.EventID != "e1" {
+		t.Fatalf("unexpected event %+v (%v)", first, err)
+	}
+	res.Body.Close()
+
+	feed.Publish([]changes.Change{{Type: changes.EventDeleted, CalendarID: "c1", EventID: "e1"}})
+	res, r = open("", first.id)
+	if got := readSSE(t, r); got.event != changes.EventDeleted {
+		t.Fatalf("expected resume after %s, got %+v", first.id, got)
+	}
+	res.Body.Close()
+
+	feed.Publish([]changes.Change{{Type: changes.CalendarChanged, CalendarID: "c2"}, {Type: changes.CalendarChanged, CalendarID: "c1"}})
+	res, r = open("?calendar_id=c1&last_event_id="+first.id, "")
+	if got := readSSE(t, r); got.event != "reset" || got.id != feed.Head() {
+		t.Fatalf("expected a reset for a position no longer retained, got %+v", got)
+	}
+	feed.Publish([]changes.Change{{Type: changes.EventCreated, CalendarID: "c2"}, {Type: changes.EventUpdated, CalendarID: "c1"}})
+	if got := readSSE(t, r); got.event != changes.EventUpdated {
+		t.Fatalf("expected only c1 changes, got %+v", got)
+	}
+	res.Body.Close()
+
+	res, _ = http.Post(ts.URL+"/v1/events/stream", "text/plain", nil)
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 got %d", res.StatusCode)
+	}
+}
+
+func TestEventStreamDisabled(t *testing.T) {
+	s := New(Options{Provider: fakeProvider{}})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+	res, _ := http.Get(ts.URL + "/v1/events/stream")
+	if e := decodeEnvelope(t, res); res.StatusCode != http.StatusNotImplemented || e.Code != codeNotSupported {
+		t.Fatalf("expected 501 not_supported, got %d %+v", res.StatusCode, e)
+	}
+}
+
+func TestEventStreamEndsOnShutdown(t *testing.T) {
+	s := New(Options{Provider: fakeProvider{}, Changes: changes.NewFeed(0)})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+	res, err := http.Get(ts.URL + "/v1/events/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	_ = s.httpSrv.Shutdown(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_, _ = bufio.NewReader(res.Body).ReadString('\x00')
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the stream to end on shutdown")
+	}
+}
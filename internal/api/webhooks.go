@@ -0,0 +1,109 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/webhook"
+)
+
+type webhookRequest struct {
+	URL         string   `json:"url"`
+	Secret      string   `json:"secret"`
+	Types       []string `json:"types"`
+	CalendarIDs []string `json:"calendar_ids"`
+}
+
+func (s *Server) registerWebhooks(mux *http.ServeMux) {
+	mux.HandleFunc("GET /v1/webhooks", s.withWebhooks(s.handleListWebhooks))
+	mux.HandleFunc("POST /v1/webhooks", s.withWebhooks(s.handleCreateWebhook))
+	mux.HandleFunc("/v1/webhooks", methodNotAllowed)
+	mux.HandleFunc("DELETE /v1/webhooks/{id}", s.withWebhooks(s.handleDeleteWebhook))
+	mux.HandleFunc("/v1/webhooks/{id}", methodNotAllowed)
+	mux.HandleFunc("GET /v1/webhooks/{id}/deliveries", s.withWebhooks(s.handleWebhookDeliveries))
+	mux.HandleFunc("/v1/webhooks/{id}/deliveries", methodNotAllowed)
+	mux.HandleFunc("GET /v1/webhooks/{id}/dead-letters", s.withWebhooks(s.handleWebhookDeadLetters))
+	mux.HandleFunc("/v1/webhooks/{id}/dead-letters", methodNotAllowed)
+	mux.HandleFunc("POST /v1/webhooks/{id}/dead-letters/{deliveryID}/retry", s.withWebhooks(s.handleRedeliver))
+	mux.HandleFunc("/v1/webhooks/{id}/dead-letters/{deliveryID}/retry", methodNotAllowed)
+}
+
+// withWebhooks answers 501 while webhooks are disabled, which they are
+// whenever the change feed is.
+func (s *Server) withWebhooks(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.webhooks == nil {
+			writeErr(w, http.StatusNotImplemented, codeNotSupported, "webhooks are disabled")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleListWebhooks(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.webhooks.List())
+}
+
+// handleCreateWebhook registers a subscription. The response is the only
+// place its secret is reported.
+func (s *Server) handleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var in webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		writeInvalid(w, fieldError{Field: "body", Issue: "invalid json: " + err.Error()})
+		return
+	}
+	sub, err := s.webhooks.Add(webhook.Subscription{URL: in.URL, Secret: in.Secret, Types: in.Types, CalendarIDs: in.CalendarIDs})
+	if err != nil {
+		writeProviderErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, sub)
+}
+
+func (s *Server) handleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := s.webhooks.Remove(r.PathValue("id")); err != nil {
+		writeWebhookErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) handleWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	items, err := s.webhooks.Deliveries(r.PathValue("id"))
+	if err != nil {
+		writeWebhookErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) handleWebhookDeadLetters(w http.ResponseWriter, r *http.Request) {
+	items, err := s.webhooks.DeadLetters(r.PathValue("id"))
+	if err != nil {
+		writeWebhookErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, items)
+}
+
+func (s *Server) handleRedeliver(w http.ResponseWriter, r *http.Request) {
+	d, err := s.webhooks.Redeliver(r.PathValue("id"), r.PathValue("deliveryID"))
+	if err != nil {
+		writeWebhookErr(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, d)
+}
+
+func writeWebhookErr(w http.ResponseWriter, err error) {
+	if errors.Is(err, webhook.ErrNotFound) {
+		writeErr(w, http.StatusNotFound, codeNotFound, err.Error())
+		return
+	}
+	if errors.Is(err, webhook.ErrStopped) {
+		writeErr(w, http.StatusServiceUnavailable, codeUnavailable, err.Error())
+		return
+	}
+	writeProviderErr(w, err)
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/changes"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/webhook"
+)
+
+func TestWebhookRoutes(t *testing.T) {
+	hooks := webhook.NewDispatcher(changes.NewFeed(0), nil, nil)
+	s := New(Options{Provider: fakeProvider{}, Webhooks: hooks})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+	do := func(method, path, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res := do(http.MethodPost, "/v1/webhooks", `{"url":"http://127.0.0.1:9/hook","types":["event.created"]}`)
+	var sub webhook.Subscription
+	_ = json.NewDecoder(res.Body).Decode(&sub)
+	res.Body.Close()
+	if res.StatusCode != http.StatusCreated || sub.ID == "" || len(sub.Secret) != 64 {
+		t.Fatalf("unexpected create %d %+v", res.StatusCode, sub)
+	}
+	res = do(http.MethodGet, "/v1/webhooks", "")
+	var list []webhook.Subscription
+	_ = json.NewDecoder(res.Body).Decode(&list)
+	res.Body.Close()
+	if len(list) != 1 || list[0].ID != sub.ID || list[0].Secret != "" {
+		t.Fatalf("unexpected list %+v", list)
+	}
+	for _, path := range []string{"/deliveries", "/dead-letters"} {
+		res = do(http.MethodGet, "/v1/webhooks/"+sub.ID+path, "")
+		var items []webhook.Delivery
+		_ = json.NewDecoder(res.Body).Decode(&items)
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK || len(items) != 0 {
+			t.Fatalf("%s: unexpected response %d %+v", path, res.StatusCode, items)
+		}
+	}
+
+	res = do(http.MethodPost, "/v1/webhooks", `{"url":"https://example.com/hook"}`)
+	if e := decodeEnvelope(t, res); res.StatusCode != http.StatusBadRequest || len(e.Details) != 1 || e.Details[0].Field != "url" {
+		t.Fatalf("expected a url error, got %d %+v", res.StatusCode, e)
+	}
+	res = do(http.MethodPost, "/v1/webhooks", `{`)
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 got %d", res.StatusCode)
+	}
+	res = do(http.MethodPost, "/v1/webhooks/"+sub.ID+"/dead-letters/nope/retry", "")
+	if e := decodeEnvelope(t, res); res.StatusCode != http.StatusNotFound || e.Code != codeNotFound {
+		t.Fatalf("expected 404, got %d %+v", res.StatusCode, e)
+	}
+	res = do(http.MethodDelete, "/v1/webhooks/"+sub.ID, "")
+	if res.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected 204 got %d", res.StatusCode)
+	}
+	for _, path := range []string{"/v1/webhooks/" + sub.ID + "/deliveries", "/v1/webhooks/" + sub.ID + "/dead-letters"} {
+		if res = do(http.MethodGet, path, ""); res.StatusCode != http.StatusNotFound {
+			t.Fatalf("%s: expected 404 got %d", path, res.StatusCode)
+		}
+	}
+	if res = do(http.MethodDelete, "/v1/webhooks/"+sub.ID, ""); res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 got %d", res.StatusCode)
+	}
+	if res = do(http.MethodPut, "/v1/webhooks", ""); res.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 got %d", res.StatusCode)
+	}
+}
+
+func TestWebhooksDisabled(t *testing.T) {
+	s := New(Options{Provider: fakeProvider{}})
+	ts := httptest.NewServer(s.httpSrv.Handler)
+	defer ts.Close()
+	res, _ := http.Get(ts.URL + "/v1/webhooks")
+	if e := decodeEnvelope(t, res); res.StatusCode != http.StatusNotImplemented || e.Code != codeNotSupported {
+		t.Fatalf("expected 501, got %d %+v", res.StatusCode, e)
+	}
+}
+
+func TestWebhookStoppedIsUnavailable(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeWebhookErr(rec, webhook.ErrStopped)
+	if e := decodeEnvelope(t, rec.Result()); rec.Code != http.StatusServiceUnavailable || e.Code != codeUnavailable {
+		t.Fatalf("expected 503 unavailable, got %d %+v", rec.Code, e)
+	}
+}
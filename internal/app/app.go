@@ -4,17 +4,23 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
 	"strings"
 	"sync"
 
 	"github.com/sevenofnine/proton-calendar-bridge/internal/api"
 	"github.com/sevenofnine/proton-calendar-bridge/internal/auth"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/changes"
 	"github.com/sevenofnine/proton-calendar-bridge/internal/config"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/mcp"
 	"github.com/sevenofnine/proton-calendar-bridge/internal/protonapi"
 	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
 	"github.com/sevenofnine/proton-calendar-bridge/internal/security"
 	"github.com/sevenofnine/proton-calendar-bridge/internal/tray"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/version"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/webhook"
 )
 
 type Application struct {
@@ -41,23 +47,96 @@ func BuildProvider(cfg config.Config) (provider.CalendarProvider, error) {
 	}
 	switch providerType {
 	case "ics":
-		return provider.NewICSProvider(cfg.ICSURL, nil), nil
+		feeds, err := provider.ParseICSFeeds(cfg.ICSURL)
+		if err != nil {
+			return nil, fmt.Errorf("PCB_ICS_URL: %w", err)
+		}
+		return provider.NewICSProviderWithFeeds(feeds, nil, cfg.ICSCacheTTL), nil
 	case "proton":
-		client := protonapi.NewClient(protonapi.ClientOptions{})
-		return provider.NewProtonProvider(client, auth.Store{}), nil
+		p, err := buildProtonProvider(cfg)
+		if err != nil {
+			return nil, err
+		}
+		p.SetWriteEnabled(cfg.ProtonWrite)
+		p.SetSyncInterval(cfg.ProtonSyncInterval)
+		return p, nil
 	default:
 		return nil, fmt.Errorf("unsupported provider type: %s", providerType)
 	}
 }
 
+// buildProtonProvider restores the saved Proton session, if there is one,
+// and writes rotated tokens back to the store whenever the client refreshes.
+func buildProtonProvider(cfg config.Config) (*provider.ProtonProvider, error) {
+	store := auth.Store{Path: cfg.SessionPath}
+	var session auth.Session
+	opts := protonapi.ClientOptions{}
+	switch {
+	case cfg.SessionPath == "":
+	case cfg.SessionPassword == "":
+		slog.Warn("PCB_SESSION_PASSWORD is not set; the saved Proton session is not loaded", "path", cfg.SessionPath)
+	default:
+		loaded, err := store.Load(cfg.SessionPassword)
+		if errors.Is(err, fs.ErrNotExist) {
+			slog.Warn("no saved Proton session; log in first", "path", cfg.SessionPath)
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("load proton session: %w", err)
+		}
+		session = loaded
+		opts.UID, opts.AccessToken, opts.RefreshToken = session.UID, session.AccessToken, session.RefreshToken
+		opts.OnAuth = persistSession(store, session, cfg.SessionPassword)
+	}
+	client := protonapi.NewClient(opts)
+	return provider.NewProtonProviderWithKeyPassword(client, store, []byte(session.KeyPassword)), nil
+}
+
+// persistSession returns an OnAuth hook that saves rotated tokens.
+func persistSession(store auth.Store, session auth.Session, password string) func(protonapi.Auth) {
+	var mu sync.Mutex
+	return func(a protonapi.Auth) {
+		mu.Lock()
+		defer mu.Unlock()
+		session.UID, session.AccessToken, session.RefreshToken = a.UID, a.AccessToken, a.RefreshToken
+		if err := store.Save(session, password); err != nil {
+			slog.Warn("failed to save refreshed Proton session", "error", err)
+		}
+	}
+}
+
 func (a *Application) Run(ctx context.Context) error {
+	var feed *changes.Feed
+	var hooks *webhook.Dispatcher
+	if a.cfg.ChangePollInterval > 0 {
+		feed = changes.NewFeed(changes.DefaultRetain)
+		hooks = webhook.NewDispatcher(feed, nil, a.logger)
+		subs, err := webhook.ParseSubscriptions(a.cfg.Webhooks)
+		if err != nil {
+			return fmt.Errorf("PCB_WEBHOOKS: %w", err)
+		}
+		for _, sub := range subs {
+			if _, err := hooks.Add(sub); err != nil {
+				return fmt.Errorf("PCB_WEBHOOKS: %w", err)
+			}
+		}
+	}
+	tokens, err := security.ParseTokens(a.cfg.APITokens)
+	if err != nil {
+		return fmt.Errorf("PCB_API_TOKENS: %w", err)
+	}
 	server := api.New(api.Options{
 		Provider: a.provider,
 		Auth: security.BearerAuth{
 			Enabled: a.cfg.RequireBearerToken,
 			Token:   a.cfg.BearerToken,
+			Tokens:  tokens,
 		},
-		Logger: a.logger,
+		Logger:          a.logger,
+		CalDAVNamesPath: a.cfg.CalDAVNamesPath,
+		Changes:         feed,
+		Webhooks:        hooks,
+		MCP:             mcp.New(ctx, a.provider, version.Version),
 	})
 
 	ctx, cancel := context.WithCancel(ctx)
@@ -85,6 +164,27 @@ func (a *Application) Run(ctx context.Context) error {
 		}()
 	}
 
+	if syncer, ok := a.provider.(provider.Syncer); ok {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			syncer.RunSync(ctx)
+		}()
+	}
+
+	if feed != nil {
+		watcher := changes.NewWatcher(a.provider, feed, a.cfg.ChangePollInterval, a.logger)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			watcher.Run(ctx)
+		}()
+		go func() {
+			defer wg.Done()
+			hooks.Run(ctx)
+		}()
+	}
+
 	if a.cfg.EnableTray {
 		wg.Add(1)
 		go func() {
@@ -105,3 +205,21 @@ func (a *Application) Run(ctx context.Context) error {
 		return nil
 	}
 }
+
+// ServeMCP serves the provider as MCP tools over stdin and stdout until in is
+// closed or ctx is done, keeping the provider synced meanwhile. No HTTP
+// listener is started.
+func (a *Application) ServeMCP(ctx context.Context, in io.Reader, out io.Writer) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if syncer, ok := a.provider.(provider.Syncer); ok {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			syncer.RunSync(ctx)
+		}()
+	}
+	return mcp.New(ctx, a.provider, version.Version).ServeStdio(ctx, in, out)
+}
@@ -1,13 +1,18 @@
 package app
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/sevenofnine/proton-calendar-bridge/internal/auth"
 	"github.com/sevenofnine/proton-calendar-bridge/internal/config"
 	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/protonapi"
 	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
 )
 
@@ -39,6 +44,34 @@ func TestApplicationRunCancel(t *testing.T) {
 	}
 }
 
+type syncingProvider struct {
+	fakeProvider
+	started chan struct{}
+}
+
+func (p syncingProvider) RunSync(ctx context.Context) {
+	close(p.started)
+	<-ctx.Done()
+}
+func (syncingProvider) SyncStatus() provider.SyncStatus { return provider.SyncStatus{} }
+
+func TestApplicationRunStartsSync(t *testing.T) {
+	p := syncingProvider{started: make(chan struct{})}
+	a := New(config.Config{RequireBearerToken: false}, p, nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- a.Run(ctx) }()
+	select {
+	case <-p.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sync was not started")
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+}
+
 type errTray struct{}
 
 func (errTray) Run(context.Context) error { return errors.New("tray failed") }
@@ -63,6 +96,40 @@ func TestApplicationRunTrayError(t *testing.T) {
 	}
 }
 
+func TestApplicationRunWebhooks(t *testing.T) {
+	cfg := config.Config{RequireBearerToken: false, ChangePollInterval: time.Second, Webhooks: "a|http://127.0.0.1:1/hook"}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := New(cfg, fakeProvider{}, nil, nil).Run(ctx); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	cfg.Webhooks = "a|https://example.com/hook"
+	if err := New(cfg, fakeProvider{}, nil, nil).Run(ctx); err == nil {
+		t.Fatal("expected a non-local webhook URL to be rejected")
+	}
+}
+
+func TestApplicationRunRejectsBadTokens(t *testing.T) {
+	cfg := config.Config{RequireBearerToken: true, APITokens: "dashboard|short|events:read"}
+	err := New(cfg, fakeProvider{}, nil, nil).Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "PCB_API_TOKENS") {
+		t.Fatalf("expected a PCB_API_TOKENS error, got %v", err)
+	}
+}
+
+func TestApplicationServeMCP(t *testing.T) {
+	p := syncingProvider{started: make(chan struct{})}
+	in := strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}` + "\n")
+	var out bytes.Buffer
+	if err := New(config.Config{}, p, nil, nil).ServeMCP(context.Background(), in, &out); err != nil {
+		t.Fatal(err)
+	}
+	<-p.started
+	if !strings.Contains(out.String(), `"list_calendars"`) || strings.Contains(out.String(), `"create_event"`) {
+		t.Fatalf("unexpected tools for a read-only provider: %s", out.String())
+	}
+}
+
 func TestBuildProvider(t *testing.T) {
 	ics, err := BuildProvider(config.Config{ProviderType: "ics", ICSURL: "https://example.test/a.ics"})
 	if err != nil {
@@ -72,15 +139,67 @@ func TestBuildProvider(t *testing.T) {
 		t.Fatalf("unexpected provider: %s", ics.Name())
 	}
 
-	proton, err := BuildProvider(config.Config{ProviderType: "proton"})
+	proton, err := BuildProvider(config.Config{ProviderType: "proton", ProtonWrite: true, ProtonSyncInterval: time.Minute})
 	if err != nil {
 		t.Fatalf("proton provider: %v", err)
 	}
+	caps, _ := proton.(provider.CapabilityProvider).Capabilities(context.Background())
+	if !caps.WriteSupported {
+		t.Fatalf("expected proton writes enabled: %+v", caps)
+	}
+	if status := proton.(provider.Syncer).SyncStatus(); !status.Enabled || status.IntervalSeconds != 60 {
+		t.Fatalf("expected proton sync enabled: %+v", status)
+	}
 	if proton.Name() != "proton" {
 		t.Fatalf("unexpected provider: %s", proton.Name())
 	}
 
+	if _, err := BuildProvider(config.Config{ProviderType: "ics", ICSURL: "a|https://x/a.ics,a|https://x/b.ics"}); err == nil {
+		t.Fatal("expected duplicate feed error")
+	}
+
 	if _, err := BuildProvider(config.Config{ProviderType: "unknown"}); err == nil {
 		t.Fatal("expected invalid provider error")
 	}
 }
+
+func TestBuildProviderLoadsProtonSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.enc")
+	store := auth.Store{Path: path}
+	saved := auth.Session{UID: "uid", AccessToken: "a1", RefreshToken: "r1", Username: "user", KeyPassword: "salted"}
+	if err := store.Save(saved, "pw"); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+
+	p, err := BuildProvider(config.Config{ProviderType: "proton", SessionPath: path, SessionPassword: "pw"})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	health := p.(provider.HealthReporter).Health()["proton_session"].(map[string]any)
+	if health["status"] != protonapi.StatusUnknown || health["error"] != nil {
+		t.Fatalf("unexpected session health: %+v", health)
+	}
+
+	if _, err := BuildProvider(config.Config{ProviderType: "proton", SessionPath: path, SessionPassword: "wrong"}); err == nil {
+		t.Fatal("expected wrong password error")
+	}
+	for _, cfg := range []config.Config{
+		{ProviderType: "proton", SessionPath: filepath.Join(t.TempDir(), "missing.enc"), SessionPassword: "pw"},
+		{ProviderType: "proton", SessionPath: path},
+	} {
+		if _, err := BuildProvider(cfg); err != nil {
+			t.Fatalf("expected unauthenticated provider for %+v, got %v", cfg, err)
+		}
+	}
+
+	// Rotated tokens are written back, keeping the other session fields.
+	persistSession(store, saved, "pw")(protonapi.Auth{UID: "uid", AccessToken: "a2", RefreshToken: "r2"})
+	got, err := store.Load("pw")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got.AccessToken != "a2" || got.RefreshToken != "r2" || got.Username != "user" || got.KeyPassword != "salted" {
+		t.Fatalf("unexpected persisted session: %+v", got)
+	}
+	persistSession(auth.Store{}, saved, "pw")(protonapi.Auth{})
+}
@@ -5,9 +5,13 @@ import (
 	"crypto/cipher"
 	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"time"
 
 	"golang.org/x/crypto/argon2"
 )
@@ -19,6 +23,10 @@ type Session struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 	Username     string `json:"username"`
+	// KeyPassword is the salted mailbox password that unlocks the address
+	// keys.
+	KeyPassword string    `json:"key_password,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
 type Store struct {
@@ -52,6 +60,9 @@ func (s Store) Save(session Session, bridgePassword string) error {
 	}
 	ciphertext := gcm.Seal(nil, nonce, plaintext, nil)
 	blob := append(append(salt, nonce...), ciphertext...)
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o700); err != nil {
+		return fmt.Errorf("create session dir: %w", err)
+	}
 	if err := os.WriteFile(s.Path, blob, 0o600); err != nil {
 		return fmt.Errorf("write session: %w", err)
 	}
@@ -95,6 +106,17 @@ func (s Store) Load(bridgePassword string) (Session, error) {
 	return session, nil
 }
 
+// Delete removes the saved session. A missing file is not an error.
+func (s Store) Delete() error {
+	if s.Path == "" {
+		return fmt.Errorf("store path is required")
+	}
+	if err := os.Remove(s.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("delete session: %w", err)
+	}
+	return nil
+}
+
 func deriveKey(password string, salt []byte) []byte {
 	return argon2.IDKey([]byte(password), salt, 3, 64*1024, 4, 32)
 }
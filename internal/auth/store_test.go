@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"errors"
+	"io/fs"
 	"path/filepath"
 	"testing"
 )
@@ -9,7 +11,7 @@ func TestStoreRoundTrip(t *testing.T) {
 	t.Parallel()
 	path := filepath.Join(t.TempDir(), "session.enc")
 	store := Store{Path: path}
-	in := Session{UID: "uid", AccessToken: "at", RefreshToken: "rt", Username: "user"}
+	in := Session{UID: "uid", AccessToken: "at", RefreshToken: "rt", Username: "user", KeyPassword: "salted"}
 	if err := store.Save(in, "bridge-password"); err != nil {
 		t.Fatalf("save: %v", err)
 	}
@@ -33,3 +35,24 @@ func TestStoreLoadWrongPassword(t *testing.T) {
 		t.Fatal("expected decrypt error with wrong password")
 	}
 }
+
+func TestStoreDelete(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "nested", "session.enc")
+	store := Store{Path: path}
+	if err := store.Save(Session{UID: "uid"}, "pw"); err != nil {
+		t.Fatalf("save into new dir: %v", err)
+	}
+	if err := store.Delete(); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := store.Load("pw"); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected missing session, got %v", err)
+	}
+	if err := store.Delete(); err != nil {
+		t.Fatalf("delete missing: %v", err)
+	}
+	if err := (Store{}).Delete(); err == nil {
+		t.Fatal("expected missing path error")
+	}
+}
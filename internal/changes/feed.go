@@ -0,0 +1,125 @@
+// Package changes turns provider refreshes, or successive snapshots, into a
+// numbered feed of calendar and event changes that subscribers can resume from.
+package changes
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+)
+
+// Change types.
+const (
+	EventCreated    = "event.created"
+	EventUpdated    = "event.updated"
+	EventDeleted    = "event.deleted"
+	CalendarChanged = "calendar.changed"
+)
+
+// DefaultRetain is how many changes a feed keeps for resuming subscribers.
+const DefaultRetain = 1000
+
+// Change is one entry of the feed. Event is the new state of a created or
+// updated event; Calendar is nil when a calendar was removed.
+type Change struct {
+	ID         string           `json:"id"`
+	Type       string           `json:"type"`
+	Time       time.Time        `json:"time"`
+	CalendarID string           `json:"calendar_id"`
+	EventID    string           `json:"event_id,omitempty"`
+	Event      *domain.Event    `json:"event,omitempty"`
+	Calendar   *domain.Calendar `json:"calendar,omitempty"`
+
+	seq uint64
+}
+
+// Feed numbers published changes and retains the most recent ones. IDs are
+// "<epoch>-<seq>", where the epoch identifies the process run, so an ID from
+// an earlier run is recognised instead of being mistaken for a position.
+type Feed struct {
+	epoch  string
+	retain int
+
+	mu     sync.Mutex
+	seq    uint64
+	log    []Change
+	notify chan struct{}
+}
+
+// NewFeed returns an empty feed keeping up to retain changes, or
+// DefaultRetain when retain is not positive.
+func NewFeed(retain int) *Feed {
+	if retain <= 0 {
+		retain = DefaultRetain
+	}
+	return &Feed{
+		epoch:  strconv.FormatInt(time.Now().UnixMilli(), 36),
+		retain: retain,
+		notify: make(chan struct{}),
+	}
+}
+
+// Publish numbers changes in order, appends them and wakes every waiter.
+func (f *Feed) Publish(changes []Change) {
+	if len(changes) == 0 {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range changes {
+		f.seq++
+		c.seq = f.seq
+		c.ID = f.id(f.seq)
+		f.log = append(f.log, c)
+	}
+	if over := len(f.log) - f.retain; over > 0 {
+		f.log = append([]Change(nil), f.log[over:]...)
+	}
+	close(f.notify)
+	f.notify = make(chan struct{})
+}
+
+// Head is the ID of the latest change, or of the start of the feed when
+// nothing has been published yet.
+func (f *Feed) Head() string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.id(f.seq)
+}
+
+// Since returns the retained changes published after id. ok is false when id
+// cannot be resumed from: it is malformed, belongs to another run, or the
+// changes after it are no longer retained.
+func (f *Feed) Since(id string) (changes []Change, ok bool) {
+	epoch, raw, found := strings.Cut(id, "-")
+	seq, err := strconv.ParseUint(raw, 10, 64)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !found || err != nil || epoch != f.epoch || seq > f.seq {
+		return nil, false
+	}
+	if len(f.log) > 0 && seq+1 < f.log[0].seq {
+		return nil, false
+	}
+	for _, c := range f.log {
+		if c.seq > seq {
+			changes = append(changes, c)
+		}
+	}
+	return changes, true
+}
+
+// Wait returns a channel that is closed by the next Publish. Callers take it
+// before reading with Since so no change slips between the two.
+func (f *Feed) Wait() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.notify
+}
+
+func (f *Feed) id(seq uint64) string {
+	return f.epoch + "-" + strconv.FormatUint(seq, 10)
+}
@@ -0,0 +1,46 @@
+package changes
+
+import (
+	"testing"
+)
+
+func TestFeedSinceAndRetention(t *testing.T) {
+	t.Parallel()
+
+	f := NewFeed(3)
+	start := f.Head()
+	if got, ok := f.Since(start); !ok || len(got) != 0 {
+		t.Fatalf("expected an empty feed, got %v %v", got, ok)
+	}
+	wake := f.Wait()
+	f.Publish([]Change{{Type: EventCreated, EventID: "a"}, {Type: EventUpdated, EventID: "a"}})
+	select {
+	case <-wake:
+	default:
+		t.Fatal("expected Publish to wake waiters")
+	}
+	got, ok := f.Since(start)
+	if !ok || len(got) != 2 || got[1].ID != f.Head() {
+		t.Fatalf("unexpected changes %+v", got)
+	}
+	if more, _ := f.Since(got[0].ID); len(more) != 1 || more[0].EventID != "a" || more[0].Type != EventUpdated {
+		t.Fatalf("expected resume after the first change, got %+v", more)
+	}
+
+	f.Publish([]Change{{EventID: "b"}, {EventID: "c"}})
+	if _, ok := f.Since(start); ok {
+		t.Fatal("expected a position older than the retained changes to be rejected")
+	}
+	if got, ok := f.Since(got[1].ID); !ok || len(got) != 2 {
+		t.Fatalf("expected the retained tail, got %+v %v", got, ok)
+	}
+	for _, id := range []string{"", "nonsense", "zz-1", start[:len(start)-1] + "99"} {
+		if _, ok := f.Since(id); ok {
+			t.Fatalf("expected %q to be rejected", id)
+		}
+	}
+	f.Publish(nil)
+	if got := NewFeed(0); got.retain != DefaultRetain {
+		t.Fatalf("expected default retention, got %d", got.retain)
+	}
+}
@@ -0,0 +1,321 @@
+package changes
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
+)
+
+// Default watcher settings. The window is relative to each poll.
+const (
+	DefaultInterval    = 30 * time.Second
+	DefaultWindowPast  = 30 * 24 * time.Hour
+	DefaultWindowAhead = 365 * 24 * time.Hour
+)
+
+// Snapshot is the state of every calendar at one poll, reduced to what is
+// needed to tell whether something changed.
+type Snapshot struct {
+	from, to  time.Time
+	calendars map[string]calendarState
+}
+
+type calendarState struct {
+	calendar domain.Calendar
+	hash     [sha256.Size]byte
+	events   map[string]eventState
+}
+
+type eventState struct {
+	event domain.Event
+	hash  [sha256.Size]byte
+}
+
+// Take lists every calendar and its events in [from, to]. Providers that can
+// list series are snapshotted unexpanded, so a change to a series is one
+// change rather than one per instance.
+func Take(ctx context.Context, p provider.CalendarProvider, from, to time.Time) (*Snapshot, error) {
+	cals, err := p.ListCalendars(ctx)
+	if err != nil {
+		return nil, err
+	}
+	list := p.ListEvents
+	if lister, ok := p.(provider.SeriesLister); ok {
+		list = lister.ListSeries
+	}
+	s := &Snapshot{from: from, to: to, calendars: make(map[string]calendarState, len(cals))}
+	for _, c := range cals {
+		items, err := list(ctx, c.ID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		s.calendars[c.ID] = calendarState{calendar: c, hash: hash(c), events: eventStates(items)}
+	}
+	return s, nil
+}
+
+// Diff reports what changed from prev to next. Because the window moves with
+// every poll, an event that only scrolled into next's window is not reported
+// as created, and one that only scrolled out of it is not reported as deleted.
+func Diff(prev, next *Snapshot, now time.Time) []Change {
+	var out []Change
+	for _, id := range calendarIDs(prev, next) {
+		before, hadBefore := prev.calendars[id]
+		after, hasAfter := next.calendars[id]
+		switch {
+		case !hadBefore:
+			cal := after.calendar
+			out = append(out, Change{Type: CalendarChanged, Time: now, CalendarID: id, Calendar: &cal})
+		case !hasAfter:
+			out = append(out, Change{Type: CalendarChanged, Time: now, CalendarID: id})
+		case before.hash != after.hash:
+			cal := after.calendar
+			out = append(out, Change{Type: CalendarChanged, Time: now, CalendarID: id, Calendar: &cal})
+		}
+
+		out = append(out, diffEvents(id, before.events, after.events, now,
+			func(e domain.Event) bool { return overlaps(e, prev.from, prev.to) },
+			func(e domain.Event) bool { return !hasAfter || overlaps(e, next.from, next.to) })...)
+	}
+	return out
+}
+
+// DiffRefresh reports the event changes of one provider refresh.
+func DiffRefresh(r provider.Refresh, now time.Time) []Change {
+	always := func(domain.Event) bool { return true }
+	return diffEvents(r.CalendarID, eventStates(r.Before), eventStates(r.After), now, always, always)
+}
+
+// diffEvents reports the event changes of one calendar. A created event is
+// only reported when created accepts it, and a deleted one when deleted does.
+func diffEvents(calendarID string, before, after map[string]eventState, now time.Time, created, deleted func(domain.Event) bool) []Change {
+	var out []Change
+	for _, eventID := range eventIDs(before, after) {
+		old, existed := before[eventID]
+		cur, exists := after[eventID]
+		switch {
+		case !existed:
+			if !created(cur.event) {
+				continue
+			}
+			e := cur.event
+			out = append(out, Change{Type: EventCreated, Time: now, CalendarID: calendarID, EventID: eventID, Event: &e})
+		case !exists:
+			if !deleted(old.event) {
+				continue
+			}
+			out = append(out, Change{Type: EventDeleted, Time: now, CalendarID: calendarID, EventID: eventID})
+		case old.hash != cur.hash:
+			e := cur.event
+			out = append(out, Change{Type: EventUpdated, Time: now, CalendarID: calendarID, EventID: eventID, Event: &e})
+		}
+	}
+	return out
+}
+
+func eventStates(events []domain.Event) map[string]eventState {
+	out := make(map[string]eventState, len(events))
+	for _, e := range events {
+		out[e.ID] = eventState{event: e, hash: hash(e)}
+	}
+	return out
+}
+
+// Watcher publishes a provider's changes to a feed. Providers that keep a
+// local copy current, such as ICS feeds refetched when their cache expires
+// and Proton calendars following the event loop, report each refresh and the
+// watcher diffs it as it happens. Other providers are polled and successive
+// snapshots compared.
+type Watcher struct {
+	provider provider.CalendarProvider
+	feed     *Feed
+	interval time.Duration
+	past     time.Duration
+	ahead    time.Duration
+	now      func() time.Time
+	log      *slog.Logger
+
+	last *Snapshot
+}
+
+// NewWatcher returns a watcher that polls providers which do not report their
+// refreshes every interval, or DefaultInterval when interval is not positive.
+func NewWatcher(p provider.CalendarProvider, feed *Feed, interval time.Duration, logger *slog.Logger) *Watcher {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Watcher{
+		provider: p,
+		feed:     feed,
+		interval: interval,
+		past:     DefaultWindowPast,
+		ahead:    DefaultWindowAhead,
+		now:      time.Now,
+		log:      logger,
+	}
+}
+
+// Run publishes changes until ctx is done. A provider that reports its
+// refreshes is followed; otherwise Run takes a baseline snapshot and polls
+// every interval. A failed poll is logged and the last good snapshot is kept,
+// so nothing is reported until the provider answers again.
+func (w *Watcher) Run(ctx context.Context) {
+	if reporter, ok := w.provider.(provider.ChangeReporter); ok && refreshing(w.provider) {
+		w.follow(ctx, reporter)
+		return
+	}
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if err := w.poll(ctx); err != nil && ctx.Err() == nil {
+			w.log.Warn("change watcher: snapshot failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Watcher) poll(ctx context.Context) error {
+	now := w.now()
+	next, err := Take(ctx, w.provider, now.Add(-w.past), now.Add(w.ahead))
+	if err != nil {
+		return err
+	}
+	if w.last != nil {
+		w.feed.Publish(Diff(w.last, next, now))
+	}
+	w.last = next
+	return nil
+}
+
+// refreshing reports whether p keeps its local copy current by itself. A
+// syncer with sync disabled only refreshes when asked, so it is polled.
+func refreshing(p provider.CalendarProvider) bool {
+	syncer, ok := p.(provider.Syncer)
+	return !ok || syncer.SyncStatus().Enabled
+}
+
+// follow publishes the changes of every refresh the provider reports. The
+// calendars are listed at the start and again whenever a refresh says they
+// may have changed.
+func (w *Watcher) follow(ctx context.Context, reporter provider.ChangeReporter) {
+	var (
+		mu      sync.Mutex
+		pending []provider.Refresh
+		wake    = make(chan struct{}, 1)
+	)
+	reporter.ReportChanges(func(r provider.Refresh) {
+		mu.Lock()
+		pending = append(pending, r)
+		mu.Unlock()
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	})
+	defer reporter.ReportChanges(nil)
+
+	if err := w.checkCalendars(ctx); err != nil && ctx.Err() == nil {
+		w.log.Warn("change watcher: list calendars failed", "error", err)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		}
+		mu.Lock()
+		refreshes := pending
+		pending = nil
+		mu.Unlock()
+		calendarsChanged := false
+		for _, r := range refreshes {
+			if r.CalendarID == "" {
+				calendarsChanged = true
+				continue
+			}
+			w.feed.Publish(DiffRefresh(r, w.now()))
+		}
+		if calendarsChanged {
+			if err := w.checkCalendars(ctx); err != nil && ctx.Err() == nil {
+				w.log.Warn("change watcher: list calendars failed", "error", err)
+			}
+		}
+	}
+}
+
+// checkCalendars lists the calendars and publishes the ones that were added,
+// removed or changed since the last listing.
+func (w *Watcher) checkCalendars(ctx context.Context) error {
+	cals, err := w.provider.ListCalendars(ctx)
+	if err != nil {
+		return err
+	}
+	next := &Snapshot{calendars: make(map[string]calendarState, len(cals))}
+	for _, c := range cals {
+		next.calendars[c.ID] = calendarState{calendar: c, hash: hash(c)}
+	}
+	if w.last != nil {
+		w.feed.Publish(Diff(w.last, next, w.now()))
+	}
+	w.last = next
+	return nil
+}
+
+// overlaps reports whether e falls inside [from, to]. Series are always
+// treated as inside, since their first instance says little about the rest.
+func overlaps(e domain.Event, from, to time.Time) bool {
+	if e.Recurrence != "" || len(e.RecurrenceDates) > 0 {
+		return true
+	}
+	return e.End.After(from) && e.Start.Before(to)
+}
+
+func hash(v any) [sha256.Size]byte {
+	b, _ := json.Marshal(v)
+	return sha256.Sum256(b)
+}
+
+func calendarIDs(a, b *Snapshot) []string {
+	seen := make(map[string]bool)
+	for id := range a.calendars {
+		seen[id] = true
+	}
+	for id := range b.calendars {
+		seen[id] = true
+	}
+	return sortedKeys(seen)
+}
+
+func eventIDs(a, b map[string]eventState) []string {
+	seen := make(map[string]bool)
+	for id := range a {
+		seen[id] = true
+	}
+	for id := range b {
+		seen[id] = true
+	}
+	return sortedKeys(seen)
+}
+
+func sortedKeys(m map[string]bool) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
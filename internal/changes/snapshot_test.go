@@ -0,0 +1,230 @@
+package changes
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
+)
+
+// memProvider serves whatever calendars and events the test puts in it.
+type memProvider struct {
+	mu        sync.Mutex
+	calendars []domain.Calendar
+	events    map[string][]domain.Event
+	err       error
+}
+
+func (p *memProvider) Name() string { return "mem" }
+func (p *memProvider) ListCalendars(context.Context) ([]domain.Calendar, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]domain.Calendar(nil), p.calendars...), p.err
+}
+func (p *memProvider) ListEvents(_ context.Context, calendarID string, _, _ time.Time) ([]domain.Event, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]domain.Event(nil), p.events[calendarID]...), nil
+}
+func (p *memProvider) CreateEvent(context.Context, domain.EventMutation) (domain.Event, error) {
+	return domain.Event{}, provider.NotSupportedError{}
+}
+func (p *memProvider) UpdateEvent(context.Context, string, domain.EventMutation) (domain.Event, error) {
+	return domain.Event{}, provider.NotSupportedError{}
+}
+func (p *memProvider) DeleteEvent(context.Context, string) error { return provider.NotSupportedError{} }
+
+func (p *memProvider) set(calendars []domain.Calendar, events map[string][]domain.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calendars, p.events = calendars, events
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	ev := func(id string, start time.Time) domain.Event {
+		return domain.Event{ID: id, CalendarID: "c1", Title: id, Start: start, End: start.Add(time.Hour)}
+	}
+	p := &memProvider{}
+	p.set([]domain.Calendar{{ID: "c1", Name: "Work"}, {ID: "c2"}}, map[string][]domain.Event{
+		"c1": {ev("keep", day), ev("edit", day), ev("gone", day), ev("old", day.AddDate(0, 0, -9))},
+		"c2": {ev("other", day)},
+	})
+	prev, err := Take(ctx, p, day.AddDate(0, 0, -10), day.AddDate(0, 0, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := ev("edit", day)
+	edited.Title = "edited"
+	series := ev("series", day.AddDate(0, 0, -30))
+	series.Recurrence = "FREQ=DAILY"
+	p.set([]domain.Calendar{{ID: "c1", Name: "Renamed"}, {ID: "c3"}}, map[string][]domain.Event{
+		"c1": {ev("keep", day), edited, ev("new", day), ev("later", day.AddDate(0, 0, 10)), series},
+	})
+	next, err := Take(ctx, p, day.AddDate(0, 0, -5), day.AddDate(0, 0, 15))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// "old" scrolled out of the window and "later" scrolled in; neither is a
+	// change. The removed calendar's events are deleted with it.
+	want := []struct{ typ, cal, event string }{
+		{CalendarChanged, "c1", ""},
+		{EventUpdated, "c1", "edit"},
+		{EventDeleted, "c1", "gone"},
+		{EventCreated, "c1", "new"},
+		{EventCreated, "c1", "series"},
+		{CalendarChanged, "c2", ""},
+		{EventDeleted, "c2", "other"},
+		{CalendarChanged, "c3", ""},
+	}
+	got := Diff(prev, next, day)
+	if len(got) != len(want) {
+		t.Fatalf("got %+v", got)
+	}
+	for i, w := range want {
+		if got[i].Type != w.typ || got[i].CalendarID != w.cal || got[i].EventID != w.event {
+			t.Fatalf("change %d: got %+v want %+v", i, got[i], w)
+		}
+	}
+	if got[0].Calendar == nil || got[0].Calendar.Name != "Renamed" || got[5].Calendar != nil {
+		t.Fatalf("unexpected calendar payloads %+v %+v", got[0], got[5])
+	}
+	if got[1].Event == nil || got[1].Event.Title != "edited" || got[2].Event != nil {
+		t.Fatalf("unexpected event payloads %+v %+v", got[1], got[2])
+	}
+	if again := Diff(next, next, day); len(again) != 0 {
+		t.Fatalf("expected no changes between equal snapshots, got %+v", again)
+	}
+}
+
+func TestWatcherPublishes(t *testing.T) {
+	t.Parallel()
+
+	p := &memProvider{}
+	p.set([]domain.Calendar{{ID: "c1"}}, nil)
+	feed := NewFeed(0)
+	w := NewWatcher(p, feed, 0, nil)
+	if w.interval != DefaultInterval {
+		t.Fatalf("expected default interval, got %v", w.interval)
+	}
+	ctx := context.Background()
+	if err := w.poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	start := feed.Head()
+	now := time.Now()
+	p.set([]domain.Calendar{{ID: "c1"}}, map[string][]domain.Event{"c1": {{ID: "e1", Start: now, End: now.Add(time.Hour)}}})
+	if err := w.poll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := feed.Since(start)
+	if !ok || len(got) != 1 || got[0].Type != EventCreated || got[0].EventID != "e1" {
+		t.Fatalf("unexpected changes %+v", got)
+	}
+
+	// A failed poll keeps the last snapshot, so nothing is reported.
+	p.mu.Lock()
+	p.err = errors.New("down")
+	p.mu.Unlock()
+	if err := w.poll(ctx); err == nil {
+		t.Fatal("expected the poll to fail")
+	}
+	if got, _ := feed.Since(feed.Head()); len(got) != 0 {
+		t.Fatalf("expected no changes, got %+v", got)
+	}
+
+	w.interval = time.Millisecond
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		w.Run(runCtx)
+		close(done)
+	}()
+	time.Sleep(5 * time.Millisecond)
+	cancel()
+	<-done
+}
+
+// reportingProvider hands the watcher's callback to the test.
+type reportingProvider struct {
+	memProvider
+	report chan func(provider.Refresh)
+}
+
+func (p *reportingProvider) ReportChanges(fn func(provider.Refresh)) {
+	if fn != nil {
+		p.report <- fn
+	}
+}
+
+func TestWatcherFollowsReports(t *testing.T) {
+	t.Parallel()
+
+	p := &reportingProvider{report: make(chan func(provider.Refresh), 1)}
+	p.set([]domain.Calendar{{ID: "c1"}}, nil)
+	feed := NewFeed(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewWatcher(p, feed, time.Hour, nil).Run(ctx)
+		close(done)
+	}()
+	report := <-p.report
+	start := feed.Head()
+
+	now := time.Now()
+	kept := domain.Event{ID: "kept", CalendarID: "c1", Start: now, End: now.Add(time.Hour)}
+	edited := kept
+	edited.Title = "edited"
+	report(provider.Refresh{
+		CalendarID: "c1",
+		Before:     []domain.Event{kept, {ID: "gone", CalendarID: "c1"}},
+		After:      []domain.Event{edited, {ID: "new", CalendarID: "c1"}},
+	})
+	waitFor := func(n int) []Change {
+		t.Helper()
+		for {
+			wait := feed.Wait()
+			if got, _ := feed.Since(start); len(got) >= n {
+				return got
+			}
+			select {
+			case <-wait:
+			case <-time.After(time.Second):
+				got, _ := feed.Since(start)
+				t.Fatalf("expected %d changes, got %+v", n, got)
+			}
+		}
+	}
+	// Events are only diffed once the calendars have been listed, so the
+	// rename below is compared against that listing.
+	waitFor(3)
+	p.set([]domain.Calendar{{ID: "c1", Name: "Renamed"}}, nil)
+	report(provider.Refresh{})
+
+	want := []struct{ typ, event string }{
+		{EventDeleted, "gone"},
+		{EventUpdated, "kept"},
+		{EventCreated, "new"},
+		{CalendarChanged, ""},
+	}
+	got := waitFor(len(want))
+	for i, w := range want {
+		if got[i].Type != w.typ || got[i].EventID != w.event {
+			t.Fatalf("change %d: got %+v want %+v", i, got[i], w)
+		}
+	}
+	if got[1].Event == nil || got[1].Event.Title != "edited" || got[3].Calendar == nil || got[3].Calendar.Name != "Renamed" {
+		t.Fatalf("unexpected payloads %+v", got)
+	}
+	cancel()
+	<-done
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -13,34 +14,57 @@ type Config struct {
 	ProviderType       string
 	Provider           string // Deprecated alias for ProviderType.
 	ICSURL             string
+	ICSCacheTTL        time.Duration
+	ProtonWrite        bool
+	ProtonSyncInterval time.Duration
+	ChangePollInterval time.Duration
+	Webhooks           string
+	SessionPath        string
+	SessionPassword    string
+	CalDAVNamesPath    string
 	BindAddress        string
 	UnixSocketPath     string
 	RequireBearerToken bool
 	BearerToken        string
+	APITokens          string
 	RequestTimeout     time.Duration
 	LogLevel           string
 	EnableTray         bool
 }
 
 func Load() (Config, error) {
+	cfg := FromEnv()
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+// FromEnv reads the configuration without validating it, for commands that
+// only need part of it.
+func FromEnv() Config {
 	providerType := getenvDefault("PCB_PROVIDER", "ics")
-	cfg := Config{
+	return Config{
 		ProviderType:       providerType,
 		Provider:           providerType,
 		ICSURL:             strings.TrimSpace(os.Getenv("PCB_ICS_URL")),
+		ICSCacheTTL:        getenvDuration("PCB_ICS_CACHE_TTL", 5*time.Minute),
+		ProtonWrite:        getenvBool("PCB_PROTON_WRITE", false),
+		ProtonSyncInterval: getenvDuration("PCB_PROTON_SYNC_INTERVAL", time.Minute),
+		ChangePollInterval: getenvDuration("PCB_CHANGE_POLL_INTERVAL", 30*time.Second),
+		Webhooks:           strings.TrimSpace(os.Getenv("PCB_WEBHOOKS")),
+		SessionPath:        getenvDefault("PCB_SESSION_PATH", defaultSessionPath()),
+		CalDAVNamesPath:    getenvDefault("PCB_CALDAV_NAMES_PATH", configFile("caldav-names.json")),
+		SessionPassword:    os.Getenv("PCB_SESSION_PASSWORD"),
 		BindAddress:        getenvDefault("PCB_BIND_ADDRESS", "127.0.0.1:9842"),
 		UnixSocketPath:     strings.TrimSpace(os.Getenv("PCB_UNIX_SOCKET")),
 		RequireBearerToken: getenvBool("PCB_REQUIRE_TOKEN", true),
 		BearerToken:        strings.TrimSpace(os.Getenv("PCB_BEARER_TOKEN")),
+		APITokens:          strings.TrimSpace(os.Getenv("PCB_API_TOKENS")),
 		RequestTimeout:     getenvDuration("PCB_REQUEST_TIMEOUT", 10*time.Second),
 		LogLevel:           getenvDefault("PCB_LOG_LEVEL", "info"),
 		EnableTray:         getenvBool("PCB_ENABLE_TRAY", false),
 	}
-
-	if err := cfg.Validate(); err != nil {
-		return Config{}, err
-	}
-	return cfg, nil
 }
 
 func (c Config) Validate() error {
@@ -64,8 +88,20 @@ func (c Config) Validate() error {
 	if c.BindAddress == "" && c.UnixSocketPath == "" {
 		return errors.New("either bind address or unix socket path must be configured")
 	}
-	if c.RequireBearerToken && c.BearerToken == "" {
-		return errors.New("PCB_BEARER_TOKEN is required when token auth is enabled")
+	if c.RequireBearerToken && c.BearerToken == "" && c.APITokens == "" {
+		return errors.New("PCB_BEARER_TOKEN or PCB_API_TOKENS is required when token auth is enabled")
+	}
+	if c.ICSCacheTTL < 0 {
+		return errors.New("ics cache ttl must be >= 0")
+	}
+	if c.ProtonSyncInterval < 0 {
+		return errors.New("proton sync interval must be >= 0")
+	}
+	if c.ChangePollInterval < 0 {
+		return errors.New("change poll interval must be >= 0")
+	}
+	if c.Webhooks != "" && c.ChangePollInterval == 0 {
+		return errors.New("PCB_WEBHOOKS requires the change feed, which PCB_CHANGE_POLL_INTERVAL=0 disables")
 	}
 	if c.RequestTimeout <= 0 {
 		return errors.New("request timeout must be > 0")
@@ -78,6 +114,22 @@ func (c Config) Validate() error {
 	return nil
 }
 
+// defaultSessionPath places the encrypted Proton session in the user's config
+// directory.
+func defaultSessionPath() string {
+	return configFile("session.enc")
+}
+
+// configFile places a state file in the user's config directory, or returns
+// "" when there is none.
+func configFile(name string) string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "proton-calendar-bridge", name)
+}
+
 func getenvDefault(key, fallback string) string {
 	if value, ok := os.LookupEnv(key); ok {
 		trimmed := strings.TrimSpace(value)
@@ -14,6 +14,13 @@ func TestLoadSuccess(t *testing.T) {
 	t.Setenv("PCB_BEARER_TOKEN", "secret")
 	t.Setenv("PCB_REQUEST_TIMEOUT", "5s")
 	t.Setenv("PCB_LOG_LEVEL", "debug")
+	t.Setenv("PCB_ICS_CACHE_TTL", "30s")
+	t.Setenv("PCB_PROTON_WRITE", "true")
+	t.Setenv("PCB_PROTON_SYNC_INTERVAL", "2m")
+	t.Setenv("PCB_CHANGE_POLL_INTERVAL", "10s")
+	t.Setenv("PCB_SESSION_PATH", "/tmp/pcb/session.enc")
+	t.Setenv("PCB_SESSION_PASSWORD", "pw")
+	t.Setenv("PCB_CALDAV_NAMES_PATH", "/tmp/pcb/names.json")
 
 	cfg, err := Load()
 	if err != nil {
@@ -22,11 +29,33 @@ func TestLoadSuccess(t *testing.T) {
 	if cfg.RequestTimeout != 5*time.Second {
 		t.Fatalf("unexpected timeout: %v", cfg.RequestTimeout)
 	}
+	if !cfg.ProtonWrite {
+		t.Fatal("expected proton writes enabled")
+	}
+	if cfg.SessionPath != "/tmp/pcb/session.enc" || cfg.SessionPassword != "pw" || cfg.CalDAVNamesPath != "/tmp/pcb/names.json" {
+		t.Fatalf("unexpected state paths: %q %q %q", cfg.SessionPath, cfg.SessionPassword, cfg.CalDAVNamesPath)
+	}
+	if cfg.ProtonSyncInterval != 2*time.Minute {
+		t.Fatalf("unexpected proton sync interval: %v", cfg.ProtonSyncInterval)
+	}
+	if cfg.ChangePollInterval != 10*time.Second {
+		t.Fatalf("unexpected change poll interval: %v", cfg.ChangePollInterval)
+	}
+	if cfg.ICSCacheTTL != 30*time.Second {
+		t.Fatalf("unexpected ics cache ttl: %v", cfg.ICSCacheTTL)
+	}
 	if cfg.ProviderType != "ics" {
 		t.Fatalf("unexpected provider type: %q", cfg.ProviderType)
 	}
 }
 
+func TestValidateAPITokens(t *testing.T) {
+	cfg := Config{Provider: "ics", ICSURL: "x", BindAddress: "127.0.0.1:1", RequireBearerToken: true, APITokens: "a|a-secret-0000000001|admin", RequestTimeout: time.Second, LogLevel: "info"}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected named tokens to satisfy token auth, got %v", err)
+	}
+}
+
 func TestValidateErrors(t *testing.T) {
 	cases := []Config{
 		{},
@@ -34,6 +63,10 @@ func TestValidateErrors(t *testing.T) {
 		{Provider: "ics", ICSURL: "", RequireBearerToken: false, RequestTimeout: time.Second, BindAddress: "127.0.0.1:1"},
 		{Provider: "ics", ICSURL: "x", RequireBearerToken: false, RequestTimeout: -1 * time.Second, BindAddress: "127.0.0.1:1"},
 		{Provider: "ics", ICSURL: "x", RequireBearerToken: false, RequestTimeout: time.Second, LogLevel: "trace", BindAddress: "127.0.0.1:1"},
+		{Provider: "ics", ICSURL: "x", ICSCacheTTL: -time.Second, RequireBearerToken: false, RequestTimeout: time.Second, BindAddress: "127.0.0.1:1"},
+		{ProviderType: "proton", ProtonSyncInterval: -time.Second, RequireBearerToken: false, RequestTimeout: time.Second, BindAddress: "127.0.0.1:1"},
+		{Provider: "ics", ICSURL: "x", ChangePollInterval: -time.Second, RequireBearerToken: false, RequestTimeout: time.Second, BindAddress: "127.0.0.1:1"},
+		{Provider: "ics", ICSURL: "x", Webhooks: "a|http://127.0.0.1:1/hook", RequireBearerToken: false, RequestTimeout: time.Second, BindAddress: "127.0.0.1:1"},
 		{ProviderType: "bogus", RequireBearerToken: false, RequestTimeout: time.Second, LogLevel: "info", BindAddress: "127.0.0.1:1"},
 	}
 	for _, tc := range cases {
@@ -50,7 +83,7 @@ func TestValidateErrors(t *testing.T) {
 }
 
 func TestDefaultsWhenEnvInvalid(t *testing.T) {
-	for _, key := range []string{"PCB_PROVIDER", "PCB_ICS_URL", "PCB_BEARER_TOKEN", "PCB_BIND_ADDRESS", "PCB_LOG_LEVEL", "PCB_REQUEST_TIMEOUT", "PCB_REQUIRE_TOKEN", "PCB_ENABLE_TRAY"} {
+	for _, key := range []string{"PCB_PROVIDER", "PCB_ICS_URL", "PCB_BEARER_TOKEN", "PCB_BIND_ADDRESS", "PCB_LOG_LEVEL", "PCB_REQUEST_TIMEOUT", "PCB_REQUIRE_TOKEN", "PCB_ENABLE_TRAY", "PCB_CHANGE_POLL_INTERVAL"} {
 		_ = os.Unsetenv(key)
 	}
 	t.Setenv("PCB_ICS_URL", "https://example.test/calendar.ics")
@@ -68,4 +101,7 @@ func TestDefaultsWhenEnvInvalid(t *testing.T) {
 	if !cfg.RequireBearerToken {
 		t.Fatalf("expected default true for RequireBearerToken")
 	}
+	if cfg.ChangePollInterval != 30*time.Second {
+		t.Fatalf("expected the change feed on by default, got %v", cfg.ChangePollInterval)
+	}
 }
@@ -15,6 +15,9 @@ type DecryptedEvent struct {
 	SharedData   string
 	PersonalData string
 	CalendarData string
+	// AttendeesData holds the ATTENDEE lines, which Proton keeps in parts of
+	// their own encrypted with the shared session key.
+	AttendeesData string
 }
 
 func (d *EventDecryptor) DecryptEvent(event protonapi.CalendarEvent, calKR, addrKR *gopenpgp.KeyRing) (DecryptedEvent, error) {
@@ -40,7 +43,64 @@ func (d *EventDecryptor) DecryptEvent(event protonapi.CalendarEvent, calKR, addr
 		return DecryptedEvent{}, fmt.Errorf("decrypt calendar parts: %w", err)
 	}
 
-	return DecryptedEvent{SharedData: shared, PersonalData: personal, CalendarData: calendar}, nil
+	attendees, err := decryptEventParts(event.AttendeesEvents, calKR, addrKR, event.SharedKeyPacket)
+	if err != nil {
+		return DecryptedEvent{}, fmt.Errorf("decrypt attendee parts: %w", err)
+	}
+
+	return DecryptedEvent{SharedData: shared, PersonalData: personal, CalendarData: calendar, AttendeesData: attendees}, nil
+}
+
+// DecryptEventParts returns the plaintext shared parts of an event, its
+// signed-only calendar part and the personal part of memberID, split the way
+// EventParts holds them, so an update can keep the properties a mutation does
+// not carry.
+func (d *EventDecryptor) DecryptEventParts(event protonapi.CalendarEvent, calKR, addrKR *gopenpgp.KeyRing, memberID string) (EventParts, error) {
+	if calKR == nil {
+		return EventParts{}, fmt.Errorf("calendar keyring is required")
+	}
+	if addrKR == nil {
+		return EventParts{}, fmt.Errorf("address keyring is required")
+	}
+	kp, err := base64.StdEncoding.DecodeString(event.SharedKeyPacket)
+	if err != nil {
+		return EventParts{}, fmt.Errorf("decode shared key packet: %w", err)
+	}
+
+	var out EventParts
+	for _, part := range event.SharedEvents {
+		plain, err := decodePart(part, calKR, addrKR, kp)
+		if err != nil {
+			return EventParts{}, fmt.Errorf("decrypt shared parts: %w", err)
+		}
+		if part.Type&protonapi.CalendarEventTypeEncrypted != 0 {
+			out.SharedEncrypted = plain
+		} else {
+			out.SharedSigned = plain
+		}
+	}
+	for _, part := range event.CalendarEvents {
+		if part.Type&protonapi.CalendarEventTypeEncrypted != 0 {
+			continue
+		}
+		plain, err := decodePart(part, calKR, addrKR, nil)
+		if err != nil {
+			return EventParts{}, fmt.Errorf("decrypt calendar parts: %w", err)
+		}
+		out.CalendarSigned = plain
+	}
+	for _, part := range event.PersonalEvents {
+		if part.MemberID != "" && part.MemberID != memberID {
+			continue
+		}
+		plain, err := decodePart(part, calKR, addrKR, nil)
+		if err != nil {
+			return EventParts{}, fmt.Errorf("decrypt personal parts: %w", err)
+		}
+		out.Personal = plain
+		break
+	}
+	return out, nil
 }
 
 func decryptEventParts(parts []protonapi.CalendarEventPart, calKR, addrKR *gopenpgp.KeyRing, sharedKeyPacket string) (string, error) {
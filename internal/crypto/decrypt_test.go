@@ -41,6 +41,17 @@ func TestEventDecryptorDecryptEvent(t *testing.T) {
 		t.Fatalf("split shared message: %v", err)
 	}
 
+	// Attendee parts are encrypted with the same session key as the shared ones.
+	attendeesPayload := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nATTENDEE;X-PM-TOKEN=tok:mailto:a@example.com\nEND:VEVENT\nEND:VCALENDAR"
+	sessionKey, err := calKR.DecryptSessionKey(sharedSplit.GetBinaryKeyPacket())
+	if err != nil {
+		t.Fatalf("decrypt session key: %v", err)
+	}
+	attendeesData, err := sessionKey.Encrypt(gopenpgp.NewPlainMessageFromString(attendeesPayload))
+	if err != nil {
+		t.Fatalf("encrypt attendees: %v", err)
+	}
+
 	personalEnc, err := calKR.Encrypt(gopenpgp.NewPlainMessageFromString(personalPayload), nil)
 	if err != nil {
 		t.Fatalf("encrypt personal: %v", err)
@@ -56,6 +67,10 @@ func TestEventDecryptorDecryptEvent(t *testing.T) {
 			Type: proton.CalendarEventTypeEncrypted,
 			Data: base64.StdEncoding.EncodeToString(sharedSplit.GetBinaryDataPacket()),
 		}},
+		AttendeesEvents: []proton.CalendarEventPart{{
+			Type: proton.CalendarEventTypeEncrypted,
+			Data: base64.StdEncoding.EncodeToString(attendeesData),
+		}},
 		PersonalEvents: []proton.CalendarEventPart{{
 			Type: proton.CalendarEventTypeEncrypted,
 			Data: personalArmored,
@@ -74,4 +89,7 @@ func TestEventDecryptorDecryptEvent(t *testing.T) {
 	if dec.PersonalData != personalPayload {
 		t.Fatalf("unexpected personal payload: %q", dec.PersonalData)
 	}
+	if dec.AttendeesData != attendeesPayload {
+		t.Fatalf("unexpected attendees payload: %q", dec.AttendeesData)
+	}
 }
@@ -0,0 +1,337 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	gopenpgp "github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/ical"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/protonapi"
+)
+
+// EventParts holds the plaintext VCALENDARs of an event, split the way Proton
+// stores them: signed-only shared properties, encrypted shared properties,
+// the signed-only calendar properties such as STATUS and TRANSP, and the
+// member's personal properties such as alarms.
+type EventParts struct {
+	SharedSigned    string
+	SharedEncrypted string
+	CalendarSigned  string
+	Personal        string
+}
+
+// BuildEventParts serialises a mutation for the event with the given UID.
+// Timed events are written in the mutation's time zone when it names one.
+// owner is the member address email reminders are sent to.
+func BuildEventParts(uid, owner string, sequence int, in domain.EventMutation, stamp time.Time) EventParts {
+	tzid, loc := mutationZone(in)
+	return buildEventParts(uid, owner, sequence, in, stamp, tzid, loc)
+}
+
+// Properties a mutation replaces in the parts of an existing event.
+// Everything else, such as EXDATE, RDATE, RECURRENCE-ID, ORGANIZER and STATUS,
+// is kept.
+var (
+	signedMutationProps    = []string{"DTSTAMP", "DTSTART", "DTEND", "DURATION", "SEQUENCE", "RRULE"}
+	encryptedMutationProps = []string{"DTSTAMP", "SUMMARY", "DESCRIPTION", "LOCATION"}
+	calendarMutationProps  = []string{"DTSTAMP", "TRANSP"}
+)
+
+// UpdateEventParts overlays a mutation on the plaintext parts of an existing
+// event, replacing only the properties a mutation carries and, in the
+// personal part, the alarms. Timed events keep the existing DTSTART zone
+// unless the mutation names one. EXDATEs name instances of the old schedule,
+// so they are dropped when DTSTART or RRULE changes.
+func UpdateEventParts(existing EventParts, uid, owner string, sequence int, in domain.EventMutation, stamp time.Time) (EventParts, error) {
+	tzid, loc := mutationZone(in)
+	if in.TimeZone == "" && !in.AllDay {
+		tzid, loc = partZone(existing.SharedSigned)
+	}
+	fresh := buildEventParts(uid, owner, sequence, in, stamp, tzid, loc)
+
+	signedProps := signedMutationProps
+	if rescheduled(existing.SharedSigned, fresh.SharedSigned) {
+		signedProps = append(slices.Clip(signedProps), "EXDATE")
+	}
+	var out EventParts
+	var err error
+	if out.SharedSigned, err = overlayPart(existing.SharedSigned, fresh.SharedSigned, signedProps, false); err != nil {
+		return EventParts{}, fmt.Errorf("signed part: %w", err)
+	}
+	if out.SharedEncrypted, err = overlayPart(existing.SharedEncrypted, fresh.SharedEncrypted, encryptedMutationProps, false); err != nil {
+		return EventParts{}, fmt.Errorf("encrypted part: %w", err)
+	}
+	if out.CalendarSigned, err = overlayPart(existing.CalendarSigned, fresh.CalendarSigned, calendarMutationProps, false); err != nil {
+		return EventParts{}, fmt.Errorf("calendar part: %w", err)
+	}
+	if out.Personal, err = overlayPart(existing.Personal, fresh.Personal, nil, true); err != nil {
+		return EventParts{}, fmt.Errorf("personal part: %w", err)
+	}
+	return out, nil
+}
+
+func buildEventParts(uid, owner string, sequence int, in domain.EventMutation, stamp time.Time, tzid string, loc *time.Location) EventParts {
+	eventTime := func(name string, t time.Time) ical.Property {
+		if loc != nil {
+			return ical.ZonedDateTimeProp(name, t, tzid, loc)
+		}
+		return ical.DateTimeProp(name, t, in.AllDay)
+	}
+	idProps := []ical.Property{
+		ical.TextProp("UID", uid),
+		ical.DateTimeProp("DTSTAMP", stamp, false),
+	}
+
+	signed := append([]ical.Property{}, idProps...)
+	signed = append(signed,
+		eventTime("DTSTART", in.Start),
+		eventTime("DTEND", in.End),
+		ical.Property{Name: "SEQUENCE", Value: strconv.Itoa(sequence)},
+	)
+	if in.Recurrence != "" {
+		signed = append(signed, ical.Property{Name: "RRULE", Value: in.Recurrence})
+	}
+
+	encrypted := append([]ical.Property{}, idProps...)
+	encrypted = append(encrypted, ical.TextProp("SUMMARY", in.Title))
+	if in.Description != "" {
+		encrypted = append(encrypted, ical.TextProp("DESCRIPTION", in.Description))
+	}
+	if in.Location != "" {
+		encrypted = append(encrypted, ical.TextProp("LOCATION", in.Location))
+	}
+
+	transp := "OPAQUE"
+	if in.Transparent {
+		transp = "TRANSPARENT"
+	}
+	calendar := append([]ical.Property{}, idProps...)
+	calendar = append(calendar,
+		ical.Property{Name: "STATUS", Value: "CONFIRMED"},
+		ical.Property{Name: "TRANSP", Value: transp},
+	)
+
+	parts := EventParts{
+		SharedSigned:    vcalendar(&ical.Component{Name: "VEVENT", Props: signed}),
+		SharedEncrypted: vcalendar(&ical.Component{Name: "VEVENT", Props: encrypted}),
+		CalendarSigned:  vcalendar(&ical.Component{Name: "VEVENT", Props: calendar}),
+	}
+	if len(in.Reminders) > 0 {
+		personal := &ical.Component{Name: "VEVENT", Props: append([]ical.Property{}, idProps...)}
+		for _, r := range in.Reminders {
+			personal.Components = append(personal.Components, ical.Alarm(r, in.Title, owner))
+		}
+		parts.Personal = vcalendar(personal)
+	}
+	return parts
+}
+
+// mutationZone resolves the zone a timed mutation is written in. Callers
+// validate TimeZone, so an unknown zone falls back to UTC.
+func mutationZone(in domain.EventMutation) (string, *time.Location) {
+	if in.AllDay || in.TimeZone == "" {
+		return "", nil
+	}
+	loc, err := time.LoadLocation(in.TimeZone)
+	if err != nil || loc == time.UTC {
+		return "", nil
+	}
+	return in.TimeZone, loc
+}
+
+// partZone returns the zone of the DTSTART in a plaintext part, or none when
+// it is in UTC or cannot be resolved.
+func partZone(data string) (string, *time.Location) {
+	roots, err := ical.ParseString(data)
+	if err != nil {
+		return "", nil
+	}
+	for _, event := range ical.Find(roots, "VEVENT") {
+		start, ok := event.Prop("DTSTART")
+		tzid := start.Params.Get("TZID")
+		if !ok || tzid == "" {
+			return "", nil
+		}
+		loc, err := ical.NewTimezones(roots).Location(tzid)
+		if err != nil {
+			return "", nil
+		}
+		return tzid, loc
+	}
+	return "", nil
+}
+
+// rescheduled reports whether fresh moves the DTSTART or changes the RRULE of
+// the event in existing.
+func rescheduled(existing, fresh string) bool {
+	before, ok := partSchedule(existing)
+	if !ok {
+		return false
+	}
+	after, ok := partSchedule(fresh)
+	return !ok || !before.Start.Equal(after.Start) || before.AllDay != after.AllDay || before.Recurrence != after.Recurrence
+}
+
+func partSchedule(data string) (domain.Event, bool) {
+	roots, err := ical.ParseString(data)
+	if err != nil {
+		return domain.Event{}, false
+	}
+	events := ical.Find(roots, "VEVENT")
+	if len(events) == 0 {
+		return domain.Event{}, false
+	}
+	e, err := ical.DecodeEvent(events[0], ical.NewTimezones(roots))
+	return e, err == nil
+}
+
+// overlayPart replaces the named properties of the VEVENT in existing with
+// those of fresh, and its alarms too when alarms is set. Without an existing
+// VEVENT fresh is used as is.
+func overlayPart(existing, fresh string, props []string, alarms bool) (string, error) {
+	roots, err := ical.ParseString(existing)
+	if err != nil {
+		return "", err
+	}
+	events := ical.Find(roots[:min(len(roots), 1)], "VEVENT")
+	if len(events) == 0 {
+		return fresh, nil
+	}
+	freshRoots, err := ical.ParseString(fresh)
+	if err != nil {
+		return "", err
+	}
+	replacement := &ical.Component{}
+	if found := ical.Find(freshRoots, "VEVENT"); len(found) > 0 {
+		replacement = found[0]
+	}
+
+	event := events[0]
+	kept := event.Props[:0]
+	for _, p := range event.Props {
+		if !slices.Contains(props, p.Name) {
+			kept = append(kept, p)
+		}
+	}
+	for _, p := range replacement.Props {
+		if slices.Contains(props, p.Name) {
+			kept = append(kept, p)
+		}
+	}
+	event.Props = kept
+	if alarms {
+		children := event.Components[:0]
+		for _, c := range event.Components {
+			if c.Name != "VALARM" {
+				children = append(children, c)
+			}
+		}
+		event.Components = append(children, replacement.Children("VALARM")...)
+	}
+	return ical.EncodeString(roots[0]), nil
+}
+
+func vcalendar(event *ical.Component) string {
+	return ical.EncodeString(&ical.Component{
+		Name: "VCALENDAR",
+		Props: []ical.Property{
+			{Name: "VERSION", Value: "2.0"},
+			{Name: "PRODID", Value: ical.ProdID},
+		},
+		Components: []*ical.Component{event},
+	})
+}
+
+type EventEncryptor struct{}
+
+// EncryptEvent signs every part with the address key and encrypts the shared
+// encrypted part with sessionKey. When sessionKey is nil a new one is
+// generated and returned as SharedKeyPacket, encrypted to the calendar key.
+func (e *EventEncryptor) EncryptEvent(parts EventParts, calKR, addrKR *gopenpgp.KeyRing, sessionKey *gopenpgp.SessionKey) (protonapi.CalendarEventData, error) {
+	if calKR == nil {
+		return protonapi.CalendarEventData{}, fmt.Errorf("calendar keyring is required")
+	}
+	if addrKR == nil {
+		return protonapi.CalendarEventData{}, fmt.Errorf("address keyring is required")
+	}
+
+	out := protonapi.CalendarEventData{Permissions: 1, IsOrganizer: 1}
+	if sessionKey == nil {
+		generated, err := gopenpgp.GenerateSessionKey()
+		if err != nil {
+			return protonapi.CalendarEventData{}, fmt.Errorf("generate session key: %w", err)
+		}
+		keyPacket, err := calKR.EncryptSessionKey(generated)
+		if err != nil {
+			return protonapi.CalendarEventData{}, fmt.Errorf("encrypt session key: %w", err)
+		}
+		sessionKey = generated
+		out.SharedKeyPacket = base64.StdEncoding.EncodeToString(keyPacket)
+	}
+
+	signedPart, err := signedContent(parts.SharedSigned, addrKR)
+	if err != nil {
+		return protonapi.CalendarEventData{}, fmt.Errorf("sign shared part: %w", err)
+	}
+	encryptedPart, err := signedContent(parts.SharedEncrypted, addrKR)
+	if err != nil {
+		return protonapi.CalendarEventData{}, fmt.Errorf("sign encrypted part: %w", err)
+	}
+	dataPacket, err := sessionKey.Encrypt(gopenpgp.NewPlainMessageFromString(parts.SharedEncrypted))
+	if err != nil {
+		return protonapi.CalendarEventData{}, fmt.Errorf("encrypt shared part: %w", err)
+	}
+	encryptedPart.Type = protonapi.CalendarEventTypeEncrypted | protonapi.CalendarEventTypeSigned
+	encryptedPart.Data = base64.StdEncoding.EncodeToString(dataPacket)
+	out.SharedEventContent = []protonapi.CalendarEventContent{signedPart, encryptedPart}
+
+	if parts.CalendarSigned != "" {
+		calendarPart, err := signedContent(parts.CalendarSigned, addrKR)
+		if err != nil {
+			return protonapi.CalendarEventData{}, fmt.Errorf("sign calendar part: %w", err)
+		}
+		out.CalendarEventContent = []protonapi.CalendarEventContent{calendarPart}
+	}
+
+	if parts.Personal != "" {
+		personalPart, err := signedContent(parts.Personal, addrKR)
+		if err != nil {
+			return protonapi.CalendarEventData{}, fmt.Errorf("sign personal part: %w", err)
+		}
+		out.PersonalEventContent = &personalPart
+	}
+	return out, nil
+}
+
+// SharedSessionKey recovers the session key of an existing event so updated
+// content can be encrypted without issuing a new key packet.
+func SharedSessionKey(event protonapi.CalendarEvent, calKR *gopenpgp.KeyRing) (*gopenpgp.SessionKey, error) {
+	if calKR == nil {
+		return nil, fmt.Errorf("calendar keyring is required")
+	}
+	keyPacket, err := base64.StdEncoding.DecodeString(event.SharedKeyPacket)
+	if err != nil {
+		return nil, fmt.Errorf("decode shared key packet: %w", err)
+	}
+	sessionKey, err := calKR.DecryptSessionKey(keyPacket)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt shared key packet: %w", err)
+	}
+	return sessionKey, nil
+}
+
+func signedContent(data string, addrKR *gopenpgp.KeyRing) (protonapi.CalendarEventContent, error) {
+	sig, err := addrKR.SignDetached(gopenpgp.NewPlainMessageFromString(data))
+	if err != nil {
+		return protonapi.CalendarEventContent{}, err
+	}
+	armored, err := sig.GetArmored()
+	if err != nil {
+		return protonapi.CalendarEventContent{}, err
+	}
+	return protonapi.CalendarEventContent{Type: protonapi.CalendarEventTypeSigned, Data: data, Signature: armored}, nil
+}
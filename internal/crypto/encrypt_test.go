@@ -0,0 +1,277 @@
+package crypto
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	proton "github.com/ProtonMail/go-proton-api"
+	gopenpgp "github.com/ProtonMail/gopenpgp/v2/crypto"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/protonapi"
+)
+
+func testKeyRing(t *testing.T, name string) *gopenpgp.KeyRing {
+	t.Helper()
+	key, err := gopenpgp.GenerateKey(name, strings.ToLower(name)+"@example.com", "x25519", 0)
+	if err != nil {
+		t.Fatalf("generate %s key: %v", name, err)
+	}
+	kr, err := gopenpgp.NewKeyRing(key)
+	if err != nil {
+		t.Fatalf("%s keyring: %v", name, err)
+	}
+	return kr
+}
+
+// asStoredEvent mirrors how the API returns an event written with data.
+func asStoredEvent(data protonapi.CalendarEventData, keyPacket string) protonapi.CalendarEvent {
+	event := protonapi.CalendarEvent{SharedKeyPacket: keyPacket}
+	for _, part := range data.SharedEventContent {
+		event.SharedEvents = append(event.SharedEvents, proton.CalendarEventPart{Type: part.Type, Data: part.Data, Signature: part.Signature})
+	}
+	for _, part := range data.CalendarEventContent {
+		event.CalendarEvents = append(event.CalendarEvents, proton.CalendarEventPart{Type: part.Type, Data: part.Data, Signature: part.Signature})
+	}
+	if p := data.PersonalEventContent; p != nil {
+		event.PersonalEvents = []proton.CalendarEventPart{{Type: p.Type, Data: p.Data, Signature: p.Signature}}
+	}
+	return event
+}
+
+func TestEncryptEventRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	calKR := testKeyRing(t, "Calendar")
+	addrKR := testKeyRing(t, "Address")
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	in := domain.EventMutation{
+		Title:       "Planning, Q2",
+		Description: "Agenda\nitems",
+		Location:    "Room 1",
+		Start:       start,
+		End:         start.Add(time.Hour),
+		Recurrence:  "FREQ=WEEKLY;COUNT=3",
+		Transparent: true,
+		Reminders: []domain.Reminder{
+			{Type: domain.ReminderDisplay, Offset: "-PT15M"},
+			{Type: domain.ReminderEmail, Offset: "PT0S", Related: domain.RelatedEnd},
+			{Type: domain.ReminderDisplay, At: &start},
+		},
+	}
+	parts := BuildEventParts("uid-1", "me@example.com", 0, in, start)
+	if strings.Contains(parts.SharedSigned, "SUMMARY") || !strings.Contains(parts.SharedEncrypted, `SUMMARY:Planning\, Q2`) {
+		t.Fatalf("unexpected part split:\n%s\n%s", parts.SharedSigned, parts.SharedEncrypted)
+	}
+
+	enc := &EventEncryptor{}
+	data, err := enc.EncryptEvent(parts, calKR, addrKR, nil)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	if data.SharedKeyPacket == "" || len(data.SharedEventContent) != 2 || data.PersonalEventContent == nil {
+		t.Fatalf("unexpected event data: %+v", data)
+	}
+	// Like Proton's own clients, creates send STATUS and TRANSP in a signed
+	// calendar part.
+	if len(data.CalendarEventContent) != 1 || data.CalendarEventContent[0].Type != protonapi.CalendarEventTypeSigned {
+		t.Fatalf("unexpected calendar parts: %+v", data.CalendarEventContent)
+	}
+	if strings.Contains(data.SharedEventContent[1].Data, "Planning") {
+		t.Fatal("encrypted part leaks plaintext")
+	}
+
+	stored := asStoredEvent(data, data.SharedKeyPacket)
+	dec, err := (&EventDecryptor{}).DecryptEvent(stored, calKR, addrKR)
+	if err != nil {
+		t.Fatalf("decrypt: %v", err)
+	}
+	parsed, err := ParseVCalendar(dec.SharedData+"\n"+dec.CalendarData, dec.PersonalData)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if parsed.UID != "uid-1" || parsed.Title != in.Title || parsed.Description != in.Description || parsed.Location != in.Location ||
+		!parsed.Start.Equal(in.Start) || !parsed.End.Equal(in.End) || parsed.Recurrence != in.Recurrence || parsed.Status != "CONFIRMED" || !parsed.Transparent {
+		t.Fatalf("round trip mismatch: %+v", parsed)
+	}
+	if len(parsed.Reminders) != 3 || parsed.Reminders[0].Offset != "-PT15M" || !parsed.Reminders[0].FireAt.Equal(start.Add(-15*time.Minute)) {
+		t.Fatalf("unexpected reminders: %+v", parsed.Reminders)
+	}
+	if r := parsed.Reminders[1]; r.Type != domain.ReminderEmail || r.Related != domain.RelatedEnd || !r.FireAt.Equal(in.End) {
+		t.Fatalf("unexpected end reminder: %+v", r)
+	}
+	if r := parsed.Reminders[2]; r.At == nil || !r.At.Equal(start) || r.Offset != "" {
+		t.Fatalf("unexpected absolute reminder: %+v", r)
+	}
+
+	// Updates reuse the existing session key and send no key packet.
+	sessionKey, err := SharedSessionKey(stored, calKR)
+	if err != nil {
+		t.Fatalf("session key: %v", err)
+	}
+	in.Title = "Renamed"
+	in.AllDay = true
+	in.Reminders = nil
+	updated, err := enc.EncryptEvent(BuildEventParts("uid-1", "me@example.com", parsed.Sequence+1, in, start), calKR, addrKR, sessionKey)
+	if err != nil {
+		t.Fatalf("encrypt update: %v", err)
+	}
+	if updated.SharedKeyPacket != "" || updated.PersonalEventContent != nil {
+		t.Fatalf("unexpected update data: %+v", updated)
+	}
+	dec, err = (&EventDecryptor{}).DecryptEvent(asStoredEvent(updated, stored.SharedKeyPacket), calKR, addrKR)
+	if err != nil {
+		t.Fatalf("decrypt update: %v", err)
+	}
+	parsed, err = ParseVCalendar(dec.SharedData, "")
+	if err != nil || parsed.Title != "Renamed" || !parsed.AllDay || parsed.Sequence != 1 {
+		t.Fatalf("unexpected updated event: %+v err=%v", parsed, err)
+	}
+}
+
+func TestEncryptEventErrors(t *testing.T) {
+	t.Parallel()
+
+	kr := testKeyRing(t, "Calendar")
+	enc := &EventEncryptor{}
+	if _, err := enc.EncryptEvent(EventParts{}, nil, kr, nil); err == nil {
+		t.Fatal("expected missing calendar keyring error")
+	}
+	if _, err := enc.EncryptEvent(EventParts{}, kr, nil, nil); err == nil {
+		t.Fatal("expected missing address keyring error")
+	}
+	if _, err := SharedSessionKey(protonapi.CalendarEvent{SharedKeyPacket: "%%"}, kr); err == nil {
+		t.Fatal("expected key packet decode error")
+	}
+	if _, err := SharedSessionKey(protonapi.CalendarEvent{SharedKeyPacket: "AAAA"}, kr); err == nil {
+		t.Fatal("expected key packet decrypt error")
+	}
+	if _, err := SharedSessionKey(protonapi.CalendarEvent{}, nil); err == nil {
+		t.Fatal("expected missing keyring error")
+	}
+}
+
+func TestUpdateEventPartsKeepsExistingProperties(t *testing.T) {
+	t.Parallel()
+
+	existing := EventParts{
+		SharedSigned: "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:uid-1\r\nDTSTAMP:20260101T000000Z\r\n" +
+			"DTSTART;TZID=Europe/Berlin:20260302T090000\r\nDURATION:PT1H\r\nSEQUENCE:2\r\nRRULE:FREQ=WEEKLY\r\n" +
+			"EXDATE;TZID=Europe/Berlin:20260309T090000\r\nORGANIZER;CN=Ann:mailto:ann@example.com\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+		SharedEncrypted: "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:uid-1\r\nDTSTAMP:20260101T000000Z\r\n" +
+			"SUMMARY:Standup\r\nDESCRIPTION:Old\r\nCREATED:20260101T000000Z\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+		CalendarSigned: "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:uid-1\r\nDTSTAMP:20260101T000000Z\r\n" +
+			"STATUS:TENTATIVE\r\nTRANSP:OPAQUE\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+		Personal: "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:uid-1\r\nDTSTAMP:20260101T000000Z\r\n" +
+			"BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER:-PT5M\r\nEND:VALARM\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+	}
+	stamp := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	// Keeping the schedule keeps the EXDATEs; 08:00 UTC is 09:00 in Berlin.
+	kept := time.Date(2026, 3, 2, 8, 0, 0, 0, time.UTC)
+	parts, err := UpdateEventParts(existing, "uid-1", "me@example.com", 3, domain.EventMutation{Title: "Renamed", Start: kept, End: kept.Add(time.Hour), Recurrence: "FREQ=WEEKLY", Transparent: true}, stamp)
+	if err != nil || !strings.Contains(parts.SharedSigned, "EXDATE;TZID=Europe/Berlin:20260309T090000\r\n") {
+		t.Fatalf("expected the EXDATE to be kept err=%v:\n%s", err, parts.SharedSigned)
+	}
+	if !strings.Contains(parts.CalendarSigned, "STATUS:TENTATIVE\r\n") || !strings.Contains(parts.CalendarSigned, "TRANSP:TRANSPARENT\r\n") || strings.Contains(parts.CalendarSigned, "OPAQUE") {
+		t.Fatalf("unexpected calendar part:\n%s", parts.CalendarSigned)
+	}
+
+	// Summer time: 08:30 UTC is 10:30 in Berlin.
+	start := time.Date(2026, 4, 6, 8, 30, 0, 0, time.UTC)
+	in := domain.EventMutation{Title: "Daily", Start: start, End: start.Add(30 * time.Minute), Recurrence: "FREQ=DAILY"}
+
+	parts, err = UpdateEventParts(existing, "uid-1", "me@example.com", 3, in, stamp)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	for _, want := range []string{
+		"DTSTART;TZID=Europe/Berlin:20260406T103000\r\n",
+		"DTEND;TZID=Europe/Berlin:20260406T110000\r\n",
+		"SEQUENCE:3\r\n",
+		"RRULE:FREQ=DAILY\r\n",
+		"ORGANIZER;CN=Ann:mailto:ann@example.com\r\n",
+		"DTSTAMP:20260401T000000Z\r\n",
+	} {
+		if !strings.Contains(parts.SharedSigned, want) {
+			t.Fatalf("signed part lacks %q:\n%s", want, parts.SharedSigned)
+		}
+	}
+	// The EXDATE names an instance of the old schedule.
+	if strings.Contains(parts.SharedSigned, "DURATION") || strings.Count(parts.SharedSigned, "DTSTART") != 1 || strings.Contains(parts.SharedSigned, "EXDATE") {
+		t.Fatalf("stale times kept:\n%s", parts.SharedSigned)
+	}
+	if !strings.Contains(parts.SharedEncrypted, "SUMMARY:Daily\r\n") || !strings.Contains(parts.SharedEncrypted, "CREATED:") ||
+		strings.Contains(parts.SharedEncrypted, "DESCRIPTION") {
+		t.Fatalf("unexpected encrypted part:\n%s", parts.SharedEncrypted)
+	}
+	if strings.Contains(parts.Personal, "VALARM") || !strings.Contains(parts.Personal, "UID:uid-1") {
+		t.Fatalf("expected the alarms to be cleared:\n%s", parts.Personal)
+	}
+
+	parsed, err := ParseVCalendar(parts.SharedSigned+"\n"+parts.SharedEncrypted, parts.Personal)
+	if err != nil || !parsed.Start.Equal(start) || parsed.TimeZone != "Europe/Berlin" || len(parsed.ExDates) != 0 || parsed.Organizer == nil {
+		t.Fatalf("unexpected parsed update: %+v err=%v", parsed, err)
+	}
+
+	// A named zone replaces the existing one; all-day events carry none.
+	in.TimeZone = "America/New_York"
+	in.Reminders = []domain.Reminder{{Type: domain.ReminderDisplay, Offset: "-PT10M"}}
+	if parts, err = UpdateEventParts(existing, "uid-1", "me@example.com", 3, in, stamp); err != nil ||
+		!strings.Contains(parts.SharedSigned, "DTSTART;TZID=America/New_York:20260406T043000\r\n") ||
+		strings.Count(parts.Personal, "BEGIN:VALARM") != 1 || !strings.Contains(parts.Personal, "TRIGGER:-PT10M") {
+		t.Fatalf("unexpected zoned update err=%v:\n%s\n%s", err, parts.SharedSigned, parts.Personal)
+	}
+	in.AllDay = true
+	if parts, err = UpdateEventParts(existing, "uid-1", "me@example.com", 3, in, stamp); err != nil || !strings.Contains(parts.SharedSigned, "DTSTART;VALUE=DATE:20260406\r\n") {
+		t.Fatalf("unexpected all-day update err=%v:\n%s", err, parts.SharedSigned)
+	}
+
+	// Without existing parts the update is built from the mutation alone.
+	in.AllDay = false
+	parts, err = UpdateEventParts(EventParts{}, "uid-1", "me@example.com", 0, in, stamp)
+	if err != nil || parts != BuildEventParts("uid-1", "me@example.com", 0, in, stamp) {
+		t.Fatalf("unexpected fresh parts err=%v: %+v", err, parts)
+	}
+	if _, err := UpdateEventParts(EventParts{SharedEncrypted: "END:VEVENT\r\n"}, "uid-1", "me@example.com", 0, in, stamp); err == nil {
+		t.Fatal("expected a parse error")
+	}
+}
+
+func TestDecryptEventParts(t *testing.T) {
+	t.Parallel()
+
+	calKR := testKeyRing(t, "Calendar")
+	addrKR := testKeyRing(t, "Address")
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	parts := BuildEventParts("uid-1", "me@example.com", 0, domain.EventMutation{
+		Title: "Standup", Start: start, End: start.Add(time.Hour),
+		Reminders: []domain.Reminder{{Type: domain.ReminderDisplay, Offset: "-PT5M"}},
+	}, start)
+	data, err := (&EventEncryptor{}).EncryptEvent(parts, calKR, addrKR, nil)
+	if err != nil {
+		t.Fatalf("encrypt: %v", err)
+	}
+	stored := asStoredEvent(data, data.SharedKeyPacket)
+	// Personal parts of other members are skipped.
+	stored.PersonalEvents[0].MemberID = "m1"
+	stored.PersonalEvents = append([]proton.CalendarEventPart{{MemberID: "m2", Data: "other"}}, stored.PersonalEvents...)
+
+	dec := &EventDecryptor{}
+	got, err := dec.DecryptEventParts(stored, calKR, addrKR, "m1")
+	// Decryption turns the encrypted part's line endings into LF.
+	got.SharedEncrypted = strings.ReplaceAll(got.SharedEncrypted, "\n", "\r\n")
+	if err != nil || got != parts {
+		t.Fatalf("unexpected parts err=%v:\n%q\nwant\n%q", err, got, parts)
+	}
+	if _, err := dec.DecryptEventParts(stored, nil, addrKR, "m1"); err == nil {
+		t.Fatal("expected missing calendar keyring error")
+	}
+	if _, err := dec.DecryptEventParts(stored, calKR, nil, "m1"); err == nil {
+		t.Fatal("expected missing address keyring error")
+	}
+	stored.SharedKeyPacket = "%%"
+	if _, err := dec.DecryptEventParts(stored, calKR, addrKR, "m1"); err == nil {
+		t.Fatal("expected key packet decode error")
+	}
+}
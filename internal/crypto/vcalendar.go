@@ -2,116 +2,114 @@ package crypto
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/ical"
 )
 
 type ParsedEvent struct {
-	Title       string
-	Description string
-	Location    string
-	Start       time.Time
-	End         time.Time
-	AllDay      bool
-	Recurrence  string
-	Attendees   []string
-	Reminders   []string
+	UID          string
+	RecurrenceID time.Time
+	Status       string
+	Sequence     int
+	Title        string
+	Description  string
+	Location     string
+	Start        time.Time
+	End          time.Time
+	AllDay       bool
+	TimeZone     string
+	Floating     bool
+	Transparent  bool
+	Recurrence   string
+	RDates       []time.Time
+	ExDates      []time.Time
+	Organizer    *domain.Attendee
+	Attendees    []domain.Attendee
+	// AttendeeTokens holds the X-PM-TOKEN of each attendee, which links it to
+	// the participation status Proton keeps outside the event data.
+	AttendeeTokens []string
+	Reminders      []domain.Reminder
 }
 
 func ParseVCalendar(sharedData, personalData string) (ParsedEvent, error) {
-	shared := parseCalendarLines(sharedData)
+	sharedRoots, err := ical.ParseString(sharedData)
+	if err != nil {
+		return ParsedEvent{}, fmt.Errorf("parse shared data: %w", err)
+	}
+	personalRoots, err := ical.ParseString(personalData)
+	if err != nil {
+		return ParsedEvent{}, fmt.Errorf("parse personal data: %w", err)
+	}
+	shared := mergeEvents(ical.Find(sharedRoots, "VEVENT"))
+	tz := ical.NewTimezones(sharedRoots)
 
-	start, allDay, err := parseICalDateTime(shared["DTSTART"])
+	dtstart, _ := shared.Prop("DTSTART")
+	start, err := tz.DateTime(dtstart)
 	if err != nil {
 		return ParsedEvent{}, fmt.Errorf("parse DTSTART: %w", err)
 	}
 
-	end := start
-	if raw := shared["DTEND"]; raw != "" {
-		if parsedEnd, _, err := parseICalDateTime(raw); err == nil {
-			end = parsedEnd
+	end := start.Time
+	if dtend, ok := shared.Prop("DTEND"); ok {
+		if parsedEnd, err := tz.DateTime(dtend); err == nil {
+			end = parsedEnd.Time
 		}
 	}
 
-	return ParsedEvent{
-		Title:       shared["SUMMARY"],
-		Description: shared["DESCRIPTION"],
-		Location:    shared["LOCATION"],
-		Start:       start,
-		End:         end,
-		AllDay:      allDay,
-		Recurrence:  shared["RRULE"],
-		Attendees:   collectCalendarValues(sharedData, "ATTENDEE"),
-		Reminders:   collectCalendarValues(personalData, "TRIGGER"),
-	}, nil
-}
-
-func parseCalendarLines(data string) map[string]string {
-	out := map[string]string{}
-	for _, line := range unfoldLines(data) {
-		k, v, ok := strings.Cut(line, ":")
-		if !ok {
-			continue
-		}
-		key := strings.ToUpper(strings.Split(k, ";")[0])
-		if out[key] == "" {
-			out[key] = v
-		}
+	var tokens []string
+	for _, p := range shared.PropsNamed("ATTENDEE") {
+		tokens = append(tokens, p.Params.Get("X-PM-TOKEN"))
 	}
-	return out
-}
+	alarms := ical.Reminders(mergeEvents(ical.Find(personalRoots, "VEVENT")), tz)
 
-func collectCalendarValues(data, key string) []string {
-	key = strings.ToUpper(strings.TrimSpace(key))
-	if key == "" {
-		return nil
-	}
-	var out []string
-	for _, line := range unfoldLines(data) {
-		k, v, ok := strings.Cut(line, ":")
-		if !ok {
-			continue
-		}
-		if strings.ToUpper(strings.Split(k, ";")[0]) == key {
-			out = append(out, v)
+	var recurrenceID time.Time
+	if rid, ok := shared.Prop("RECURRENCE-ID"); ok {
+		parsed, err := tz.DateTime(rid)
+		if err != nil {
+			return ParsedEvent{}, fmt.Errorf("parse RECURRENCE-ID: %w", err)
 		}
+		recurrenceID = parsed.Time
 	}
-	return out
-}
 
-func unfoldLines(data string) []string {
-	if data == "" {
-		return nil
-	}
-	raw := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
-	out := make([]string, 0, len(raw))
-	for _, line := range raw {
-		if len(out) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
-			out[len(out)-1] += strings.TrimLeft(line, " \t")
-			continue
-		}
-		trimmed := strings.TrimSpace(line)
-		if trimmed == "" {
-			continue
-		}
-		out = append(out, trimmed)
-	}
-	return out
+	sequence, _ := strconv.Atoi(shared.Text("SEQUENCE"))
+
+	rrule, _ := shared.Prop("RRULE")
+	return ParsedEvent{
+		UID:            shared.Text("UID"),
+		RecurrenceID:   recurrenceID,
+		Status:         strings.ToUpper(shared.Text("STATUS")),
+		Sequence:       sequence,
+		Title:          shared.Text("SUMMARY"),
+		Description:    shared.Text("DESCRIPTION"),
+		Location:       shared.Text("LOCATION"),
+		Start:          start.Time,
+		End:            end,
+		AllDay:         start.AllDay,
+		TimeZone:       start.TZID,
+		Floating:       start.Floating,
+		Transparent:    strings.EqualFold(shared.Text("TRANSP"), "TRANSPARENT"),
+		Recurrence:     rrule.Value,
+		RDates:         tz.Times(shared, "RDATE"),
+		ExDates:        tz.Times(shared, "EXDATE"),
+		Organizer:      ical.Organizer(shared),
+		Attendees:      ical.Attendees(shared),
+		AttendeeTokens: tokens,
+		Reminders:      ical.ScheduleReminders(alarms, start.Time, end),
+	}, nil
 }
 
-func parseICalDateTime(v string) (time.Time, bool, error) {
-	v = strings.TrimSpace(v)
-	if v == "" {
-		return time.Time{}, false, fmt.Errorf("empty datetime")
-	}
-	if len(v) == len("20060102") {
-		t, err := time.Parse("20060102", v)
-		return t, true, err
-	}
-	for _, f := range []string{"20060102T150405Z", "20060102T150405", "20060102T1504Z", "20060102T1504"} {
-		if t, err := time.Parse(f, v); err == nil {
-			return t, false, nil
-		}
+// mergeEvents folds the VEVENT properties spread across Proton's signed and
+// encrypted parts into a single component. Sub-components are kept so alarms
+// stay reachable.
+func mergeEvents(vevents []*ical.Component) *ical.Component {
+	merged := &ical.Component{Name: "VEVENT"}
+	for _, v := range vevents {
+		merged.Props = append(merged.Props, v.Props...)
+		merged.Components = append(merged.Components, v.Components...)
 	}
-	return time.Time{}, false, fmt.Errorf("invalid ical datetime: %s", v)
+	return merged
 }
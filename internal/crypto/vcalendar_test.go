@@ -8,7 +8,7 @@ import (
 func TestParseVCalendar(t *testing.T) {
 	t.Parallel()
 
-	shared := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Team Sync\nDESCRIPTION:Weekly status\nLOCATION:Room 42\nDTSTART:20260216T090000Z\nDTEND:20260216T100000Z\nRRULE:FREQ=WEEKLY;BYDAY=MO\nATTENDEE:mailto:a@example.com\nATTENDEE:mailto:b@example.com\nEND:VEVENT\nEND:VCALENDAR"
+	shared := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Team Sync\nDESCRIPTION:Weekly status\nLOCATION:Room 42\nDTSTART:20260216T090000Z\nDTEND:20260216T100000Z\nRRULE:FREQ=WEEKLY;BYDAY=MO\nORGANIZER;CN=Ann:mailto:a@example.com\nATTENDEE;PARTSTAT=ACCEPTED:mailto:a@example.com\nATTENDEE;X-PM-TOKEN=tok-b:mailto:b@example.com\nEND:VEVENT\nEND:VCALENDAR"
 	personal := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nBEGIN:VALARM\nTRIGGER:-PT10M\nEND:VALARM\nEND:VEVENT\nEND:VCALENDAR"
 
 	parsed, err := ParseVCalendar(shared, personal)
@@ -19,14 +19,46 @@ func TestParseVCalendar(t *testing.T) {
 	if parsed.Title != "Team Sync" || parsed.Location != "Room 42" || parsed.Recurrence == "" {
 		t.Fatalf("unexpected parsed fields: %+v", parsed)
 	}
-	if len(parsed.Attendees) != 2 {
+	if len(parsed.Attendees) != 2 || parsed.Attendees[0].Email != "a@example.com" || parsed.Attendees[0].Status != "ACCEPTED" {
 		t.Fatalf("unexpected attendees: %+v", parsed.Attendees)
 	}
-	if len(parsed.Reminders) != 1 || parsed.Reminders[0] != "-PT10M" {
+	if len(parsed.AttendeeTokens) != 2 || parsed.AttendeeTokens[1] != "tok-b" {
+		t.Fatalf("unexpected attendee tokens: %+v", parsed.AttendeeTokens)
+	}
+	if parsed.Organizer == nil || parsed.Organizer.Name != "Ann" {
+		t.Fatalf("unexpected organizer: %+v", parsed.Organizer)
+	}
+	if len(parsed.Reminders) != 1 || parsed.Reminders[0].Offset != "-PT10M" || parsed.Reminders[0].Type != "display" ||
+		!parsed.Reminders[0].FireAt.Equal(time.Date(2026, 2, 16, 8, 50, 0, 0, time.UTC)) {
 		t.Fatalf("unexpected reminders: %+v", parsed.Reminders)
 	}
 }
 
+func TestParseVCalendarMergesPartsAndUnescapes(t *testing.T) {
+	t.Parallel()
+
+	signed := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:abc\nDTSTART:20260216T090000Z\nTRANSP:TRANSPARENT\nEND:VEVENT\nEND:VCALENDAR"
+	encrypted := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Budget\\, Q3\nDESCRIPTION:Agenda:\\n1. numbers\\; 2. plan\nEND:VEVENT\nEND:VCALENDAR"
+
+	parsed, err := ParseVCalendar(signed+"\n"+encrypted, "")
+	if err != nil {
+		t.Fatalf("parse vcalendar: %v", err)
+	}
+	if parsed.Title != "Budget, Q3" || parsed.Description != "Agenda:\n1. numbers; 2. plan" || !parsed.Transparent {
+		t.Fatalf("unexpected parsed fields: %+v", parsed)
+	}
+	if !parsed.End.Equal(parsed.Start) {
+		t.Fatalf("expected end to default to start: %+v", parsed)
+	}
+
+	if _, err := ParseVCalendar("BEGIN:VCALENDAR\nBEGIN:VEVENT", ""); err == nil {
+		t.Fatal("expected shared parse error")
+	}
+	if _, err := ParseVCalendar(signed, "END:VALARM"); err == nil {
+		t.Fatal("expected personal parse error")
+	}
+}
+
 func TestParseVCalendarTZIDLocalDateTime(t *testing.T) {
 	t.Parallel()
 
@@ -37,8 +69,31 @@ func TestParseVCalendarTZIDLocalDateTime(t *testing.T) {
 		t.Fatalf("parse vcalendar: %v", err)
 	}
 
-	want := time.Date(2026, 2, 16, 9, 0, 0, 0, time.UTC)
+	want := time.Date(2026, 2, 16, 8, 0, 0, 0, time.UTC)
 	if !parsed.Start.Equal(want) {
 		t.Fatalf("unexpected DTSTART: got %v want %v", parsed.Start, want)
 	}
+	if parsed.TimeZone != "Europe/Berlin" || parsed.Floating {
+		t.Fatalf("unexpected zone metadata: %+v", parsed)
+	}
+	if got := parsed.Start.Format("15:04 MST"); got != "09:00 CET" {
+		t.Fatalf("expected wall clock to be kept in the event zone, got %s", got)
+	}
+}
+
+func TestParseVCalendarRecurrenceOverride(t *testing.T) {
+	t.Parallel()
+
+	shared := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:abc\nRECURRENCE-ID;TZID=Europe/Berlin:20260217T100000\nDTSTART:20260217T120000Z\nSTATUS:cancelled\nEND:VEVENT\nEND:VCALENDAR"
+	parsed, err := ParseVCalendar(shared, "")
+	if err != nil {
+		t.Fatalf("parse vcalendar: %v", err)
+	}
+	if parsed.UID != "abc" || parsed.Status != "CANCELLED" || !parsed.RecurrenceID.Equal(time.Date(2026, 2, 17, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected parsed fields: %+v", parsed)
+	}
+
+	if _, err := ParseVCalendar("BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:20260217T120000Z\nRECURRENCE-ID:nope\nEND:VEVENT\nEND:VCALENDAR", ""); err == nil {
+		t.Fatal("expected RECURRENCE-ID parse error")
+	}
 }
@@ -2,16 +2,57 @@ package domain
 
 import "time"
 
+// Calendar types.
+const (
+	CalendarTypePersonal   = "personal"
+	CalendarTypeSubscribed = "subscribed"
+	CalendarTypeHolidays   = "holidays"
+)
+
 type Calendar struct {
 	ID          string   `json:"id"`
 	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Color       string   `json:"color,omitempty"`
+	TimeZone    string   `json:"timezone,omitempty"`
+	Type        string   `json:"type,omitempty"`
+	Owner       string   `json:"owner,omitempty"`
+	MemberCount int      `json:"member_count,omitempty"`
+	Order       int      `json:"order"`
 	ReadOnly    bool     `json:"read_only"`
 	Shared      bool     `json:"shared"`
 	Permissions []string `json:"permissions,omitempty"`
 }
 
 type Event struct {
-	ID          string     `json:"id"`
+	ID              string      `json:"id"`
+	UID             string      `json:"uid,omitempty"`
+	SeriesID        string      `json:"series_id,omitempty"`
+	RecurrenceID    *time.Time  `json:"recurrence_id,omitempty"`
+	Status          string      `json:"status,omitempty"`
+	CalendarID      string      `json:"calendar_id"`
+	Title           string      `json:"title"`
+	Description     string      `json:"description,omitempty"`
+	Location        string      `json:"location,omitempty"`
+	Start           time.Time   `json:"start"`
+	End             time.Time   `json:"end"`
+	AllDay          bool        `json:"all_day"`
+	TimeZone        string      `json:"timezone,omitempty"`
+	Floating        bool        `json:"floating,omitempty"`
+	Transparent     bool        `json:"transparent,omitempty"` // TRANSP:TRANSPARENT; does not block time.
+	Recurrence      string      `json:"recurrence,omitempty"`
+	RecurrenceDates []time.Time `json:"recurrence_dates,omitempty"`
+	ExceptionDates  []time.Time `json:"exception_dates,omitempty"`
+	Organizer       *Attendee   `json:"organizer,omitempty"`
+	Attendees       []Attendee  `json:"attendees,omitempty"`
+	Reminders       []Reminder  `json:"reminders,omitempty"`
+	UpdatedAt       *time.Time  `json:"updated_at,omitempty"`
+}
+
+type EventMutation struct {
+	// UID is the iCalendar UID of a new event; empty lets the provider pick
+	// one. An update cannot change it.
+	UID         string     `json:"uid,omitempty"`
 	CalendarID  string     `json:"calendar_id"`
 	Title       string     `json:"title"`
 	Description string     `json:"description,omitempty"`
@@ -20,20 +61,63 @@ type Event struct {
 	End         time.Time  `json:"end"`
 	AllDay      bool       `json:"all_day"`
 	Recurrence  string     `json:"recurrence,omitempty"`
-	Attendees   []string   `json:"attendees,omitempty"`
-	Reminders   []string   `json:"reminders,omitempty"`
-	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	Attendees   []Attendee `json:"attendees,omitempty"`
+	Reminders   []Reminder `json:"reminders,omitempty"`
+	Transparent bool       `json:"transparent,omitempty"` // TRANSP:TRANSPARENT; does not block time.
+	// TimeZone is the IANA zone DTSTART and DTEND are written in. Empty keeps
+	// an existing event's zone, or writes UTC.
+	TimeZone string `json:"timezone,omitempty"`
 }
 
-type EventMutation struct {
-	CalendarID  string    `json:"calendar_id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description,omitempty"`
-	Location    string    `json:"location,omitempty"`
-	Start       time.Time `json:"start"`
-	End         time.Time `json:"end"`
-	AllDay      bool      `json:"all_day"`
-	Recurrence  string    `json:"recurrence,omitempty"`
-	Attendees   []string  `json:"attendees,omitempty"`
-	Reminders   []string  `json:"reminders,omitempty"`
+// Reminder types and the event edges a relative reminder can be related to.
+const (
+	ReminderDisplay = "display"
+	ReminderEmail   = "email"
+
+	RelatedStart = "start"
+	RelatedEnd   = "end"
+)
+
+// Reminder is a VALARM. A relative reminder fires Offset, an RFC 5545
+// duration such as "-PT15M", from the event's start or, when Related is
+// "end", its end. An absolute reminder fires at At instead. FireAt is the
+// resulting time for the event or occurrence it is attached to.
+type Reminder struct {
+	Type    string     `json:"type"`
+	Offset  string     `json:"offset,omitempty"`
+	Related string     `json:"related,omitempty"`
+	At      *time.Time `json:"at,omitempty"`
+	FireAt  *time.Time `json:"fire_at,omitempty"`
+}
+
+// Attendee is an ATTENDEE or ORGANIZER of an event. Role, Status and Type
+// hold the RFC 5545 ROLE, PARTSTAT and CUTYPE values, such as
+// "REQ-PARTICIPANT", "ACCEPTED" and "INDIVIDUAL".
+type Attendee struct {
+	Email  string `json:"email"`
+	Name   string `json:"name,omitempty"`
+	Role   string `json:"role,omitempty"`
+	Status string `json:"status,omitempty"`
+	RSVP   bool   `json:"rsvp,omitempty"`
+	Type   string `json:"type,omitempty"`
+}
+
+// Busy period types, after the RFC 5545 FBTYPE values BUSY and
+// BUSY-TENTATIVE.
+const (
+	BusyTypeBusy      = "busy"
+	BusyTypeTentative = "tentative"
+)
+
+// BusyPeriod is an interval during which a calendar is occupied.
+type BusyPeriod struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Type  string    `json:"type"`
+}
+
+// Slot is a free interval long enough for the requested meeting.
+type Slot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
 }
@@ -0,0 +1,116 @@
+// Package freebusy reduces events to the merged busy time they block.
+package freebusy
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+)
+
+// Busy merges the time blocked by events within [from, to] into sorted,
+// non-overlapping periods. Transparent and cancelled events are ignored,
+// tentative ones yield tentative periods, and time that is both busy and
+// tentative is reported as busy. All-day events block their dates from
+// midnight to midnight in from's zone rather than in UTC. Events are expected
+// to be expanded instances.
+func Busy(events []domain.Event, from, to time.Time) []domain.BusyPeriod {
+	var busy, tentative []interval
+	for _, e := range events {
+		status := strings.ToUpper(e.Status)
+		if e.Transparent || status == "CANCELLED" {
+			continue
+		}
+		start, end := e.Start, e.End
+		if e.AllDay {
+			start, end = localDate(start, from.Location()), localDate(end, from.Location())
+		}
+		start, end = clip(start, end, from, to)
+		if !end.After(start) {
+			continue
+		}
+		if status == "TENTATIVE" {
+			tentative = append(tentative, interval{start, end})
+		} else {
+			busy = append(busy, interval{start, end})
+		}
+	}
+	busy = merge(busy)
+	tentative = subtract(merge(tentative), busy)
+
+	out := make([]domain.BusyPeriod, 0, len(busy)+len(tentative))
+	for _, iv := range busy {
+		out = append(out, domain.BusyPeriod{Start: iv.start, End: iv.end, Type: domain.BusyTypeBusy})
+	}
+	for _, iv := range tentative {
+		out = append(out, domain.BusyPeriod{Start: iv.start, End: iv.end, Type: domain.BusyTypeTentative})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out
+}
+
+type interval struct {
+	start, end time.Time
+}
+
+// localDate is midnight in loc of the date an all-day time, stored as UTC
+// midnight, stands for.
+func localDate(t time.Time, loc *time.Location) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// clip bounds an event to the window; a zero bound leaves that side open.
+func clip(start, end, from, to time.Time) (time.Time, time.Time) {
+	if !from.IsZero() && start.Before(from) {
+		start = from
+	}
+	if !to.IsZero() && end.After(to) {
+		end = to
+	}
+	return start.UTC(), end.UTC()
+}
+
+// merge sorts intervals and joins the ones that overlap or touch.
+func merge(ivs []interval) []interval {
+	if len(ivs) == 0 {
+		return nil
+	}
+	sort.Slice(ivs, func(i, j int) bool { return ivs[i].start.Before(ivs[j].start) })
+	out := []interval{ivs[0]}
+	for _, iv := range ivs[1:] {
+		last := &out[len(out)-1]
+		if iv.start.After(last.end) {
+			out = append(out, iv)
+			continue
+		}
+		if iv.end.After(last.end) {
+			last.end = iv.end
+		}
+	}
+	return out
+}
+
+// subtract removes the sorted, merged intervals cut from ivs.
+func subtract(ivs, cut []interval) []interval {
+	var out []interval
+	for _, iv := range ivs {
+		for _, c := range cut {
+			if !c.end.After(iv.start) || !c.start.Before(iv.end) {
+				continue
+			}
+			if c.start.After(iv.start) {
+				out = append(out, interval{iv.start, c.start})
+			}
+			iv.start = c.end
+			if !iv.end.After(iv.start) {
+				break
+			}
+		}
+		if iv.end.After(iv.start) {
+			out = append(out, iv)
+		}
+	}
+	return out
+}
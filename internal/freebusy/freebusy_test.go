@@ -0,0 +1,75 @@
+package freebusy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+)
+
+func at(hour, minute int) time.Time {
+	return time.Date(2026, 3, 2, hour, minute, 0, 0, time.UTC)
+}
+
+func TestBusy(t *testing.T) {
+	t.Parallel()
+
+	events := []domain.Event{
+		{Start: at(9, 0), End: at(10, 0)},
+		{Start: at(9, 30), End: at(11, 0), Status: "CONFIRMED"},
+		{Start: at(11, 0), End: at(11, 30)},
+		{Start: at(10, 30), End: at(13, 0), Status: "tentative"},
+		{Start: at(14, 0), End: at(15, 0), Transparent: true},
+		{Start: at(15, 0), End: at(16, 0), Status: "CANCELLED"},
+		{Start: at(7, 0), End: at(8, 30)},
+		{Start: at(17, 0), End: at(19, 0)},
+		{Start: at(20, 0), End: at(21, 0)},
+	}
+	got := Busy(events, at(8, 0), at(18, 0))
+	want := []domain.BusyPeriod{
+		{Start: at(8, 0), End: at(8, 30), Type: domain.BusyTypeBusy},
+		{Start: at(9, 0), End: at(11, 30), Type: domain.BusyTypeBusy},
+		{Start: at(11, 30), End: at(13, 0), Type: domain.BusyTypeTentative},
+		{Start: at(17, 0), End: at(18, 0), Type: domain.BusyTypeBusy},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v", got)
+	}
+	for i := range want {
+		if !got[i].Start.Equal(want[i].Start) || !got[i].End.Equal(want[i].End) || got[i].Type != want[i].Type {
+			t.Fatalf("period %d: got %+v want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSubtractSplitsTentative(t *testing.T) {
+	t.Parallel()
+
+	got := subtract([]interval{{at(8, 0), at(12, 0)}}, []interval{{at(9, 0), at(10, 0)}, {at(11, 0), at(13, 0)}})
+	if len(got) != 2 || !got[0].end.Equal(at(9, 0)) || !got[1].start.Equal(at(10, 0)) || !got[1].end.Equal(at(11, 0)) {
+		t.Fatalf("got %+v", got)
+	}
+	if got := Busy(nil, time.Time{}, time.Time{}); len(got) != 0 {
+		t.Fatalf("expected no periods, got %+v", got)
+	}
+}
+
+func TestBusyAllDayInRequestZone(t *testing.T) {
+	t.Parallel()
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	holiday := domain.Event{Start: at(0, 0), End: at(0, 0).AddDate(0, 0, 1), AllDay: true}
+	from := time.Date(2026, 3, 1, 12, 0, 0, 0, berlin)
+	got := Busy([]domain.Event{holiday}, from, from.AddDate(0, 0, 3))
+	want := domain.BusyPeriod{Start: time.Date(2026, 3, 2, 0, 0, 0, 0, berlin), End: time.Date(2026, 3, 3, 0, 0, 0, 0, berlin)}
+	if len(got) != 1 || !got[0].Start.Equal(want.Start) || !got[0].End.Equal(want.End) {
+		t.Fatalf("expected March 2 in Berlin, got %+v", got)
+	}
+	// A UTC window keeps UTC days.
+	if got := Busy([]domain.Event{holiday}, at(0, 0), at(23, 0)); len(got) != 1 || !got[0].Start.Equal(at(0, 0)) {
+		t.Fatalf("unexpected UTC period %+v", got)
+	}
+}
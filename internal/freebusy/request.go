@@ -0,0 +1,183 @@
+package freebusy
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
+)
+
+// Limits that keep a single slot search cheap.
+const (
+	MaxSlotWindow    = 92 * 24 * time.Hour
+	DefaultSlotLimit = 50
+	MaxSlotLimit     = 500
+)
+
+// SlotRequest is the wire form of a slot search, shared by the HTTP API and
+// the MCP tool.
+type SlotRequest struct {
+	CalendarIDs         []string                 `json:"calendar_ids"`
+	From                time.Time                `json:"from"`
+	To                  time.Time                `json:"to"`
+	DurationMinutes     int                      `json:"duration_minutes"`
+	StepMinutes         int                      `json:"step_minutes"`
+	BufferBeforeMinutes int                      `json:"buffer_before_minutes"`
+	BufferAfterMinutes  int                      `json:"buffer_after_minutes"`
+	TimeZone            string                   `json:"timezone"`
+	WorkingHours        map[string][]HoursWindow `json:"working_hours"`
+	AllowTentative      bool                     `json:"allow_tentative"`
+	MaxResults          int                      `json:"max_results"`
+}
+
+// HoursWindow is a working range in "HH:MM" wall clock time; End may be
+// "24:00".
+type HoursWindow struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+// Query validates the request, reporting every bad field at once.
+func (in SlotRequest) Query() (SlotQuery, []provider.InvalidInputError) {
+	var details []provider.InvalidInputError
+	invalid := func(field, reason string) {
+		details = append(details, provider.InvalidInputError{Field: field, Reason: reason})
+	}
+	q := SlotQuery{
+		From:           in.From,
+		To:             in.To,
+		Duration:       time.Duration(in.DurationMinutes) * time.Minute,
+		Step:           time.Duration(in.StepMinutes) * time.Minute,
+		BufferBefore:   time.Duration(in.BufferBeforeMinutes) * time.Minute,
+		BufferAfter:    time.Duration(in.BufferAfterMinutes) * time.Minute,
+		AllowTentative: in.AllowTentative,
+		Limit:          in.MaxResults,
+	}
+	switch {
+	case in.From.IsZero():
+		invalid("from", "required")
+	case in.To.IsZero():
+		invalid("to", "required")
+	case !in.To.After(in.From):
+		invalid("to", "must be after from")
+	case in.To.Sub(in.From) > MaxSlotWindow:
+		invalid("to", "window must not exceed 92 days")
+	}
+	if in.DurationMinutes <= 0 {
+		invalid("duration_minutes", "must be positive")
+	}
+	if in.StepMinutes < 0 {
+		invalid("step_minutes", "must not be negative")
+	}
+	if in.BufferBeforeMinutes < 0 {
+		invalid("buffer_before_minutes", "must not be negative")
+	}
+	if in.BufferAfterMinutes < 0 {
+		invalid("buffer_after_minutes", "must not be negative")
+	}
+	switch {
+	case in.MaxResults < 0 || in.MaxResults > MaxSlotLimit:
+		invalid("max_results", "must be between 1 and "+strconv.Itoa(MaxSlotLimit))
+	case in.MaxResults == 0:
+		q.Limit = DefaultSlotLimit
+	}
+
+	zone := in.TimeZone
+	if zone == "" {
+		zone = "UTC"
+	}
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		invalid("timezone", "unknown time zone")
+	}
+	q.Location = loc
+
+	if in.WorkingHours != nil {
+		q.Hours = WorkingHours{}
+		days := make([]string, 0, len(in.WorkingHours))
+		for day := range in.WorkingHours {
+			days = append(days, day)
+		}
+		sort.Strings(days)
+		for _, day := range days {
+			weekday, ok := weekdays[strings.ToLower(day)]
+			if !ok {
+				invalid("working_hours."+day, "unknown weekday")
+				continue
+			}
+			for i, win := range in.WorkingHours[day] {
+				start, okStart := parseClock(win.Start)
+				end, okEnd := parseClock(win.End)
+				if !okStart || !okEnd || end <= start {
+					invalid("working_hours."+day+"["+strconv.Itoa(i)+"]", "must be HH:MM with start before end")
+					continue
+				}
+				q.Hours[weekday] = append(q.Hours[weekday], Hours{Start: start, End: end})
+			}
+		}
+	}
+	return q, details
+}
+
+// Collect merges the busy time of calendarIDs, or of every calendar when none
+// are given, and returns the calendars it looked at. All-day events are
+// placed in from's zone, so events are listed a day beyond each bound to
+// catch the dates that only reach the window there.
+func Collect(ctx context.Context, p provider.CalendarProvider, calendarIDs []string, from, to time.Time) ([]string, []domain.BusyPeriod, error) {
+	if len(calendarIDs) == 0 {
+		cals, err := p.ListCalendars(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		calendarIDs = make([]string, 0, len(cals))
+		for _, c := range cals {
+			calendarIDs = append(calendarIDs, c.ID)
+		}
+	}
+	var events []domain.Event
+	for _, id := range calendarIDs {
+		items, err := p.ListEvents(ctx, id, from.AddDate(0, 0, -1), to.AddDate(0, 0, 1))
+		if err != nil {
+			return nil, nil, err
+		}
+		events = append(events, items...)
+	}
+	return calendarIDs, Busy(events, from, to), nil
+}
+
+// FindSlots fetches the busy time q needs, widened by its buffers and with
+// all-day events in q's zone, and places slots around it.
+func FindSlots(ctx context.Context, p provider.CalendarProvider, calendarIDs []string, q SlotQuery) ([]string, []domain.Slot, error) {
+	from, to := q.From.Add(-q.BufferBefore), q.To.Add(q.BufferAfter)
+	if q.Location != nil {
+		from = from.In(q.Location)
+	}
+	ids, busy, err := Collect(ctx, p, calendarIDs, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ids, Slots(busy, q), nil
+}
+
+// parseClock reads an "HH:MM" wall clock time between 00:00 and 24:00.
+func parseClock(v string) (time.Duration, bool) {
+	h, m, ok := strings.Cut(v, ":")
+	if !ok || len(h) != 2 || len(m) != 2 {
+		return 0, false
+	}
+	hours, err1 := strconv.Atoi(h)
+	minutes, err2 := strconv.Atoi(m)
+	if err1 != nil || err2 != nil || hours < 0 || minutes < 0 || minutes > 59 || hours > 24 || (hours == 24 && minutes != 0) {
+		return 0, false
+	}
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, true
+}
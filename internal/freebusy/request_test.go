@@ -0,0 +1,103 @@
+package freebusy
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
+)
+
+// fakeProvider has one busy hour per calendar.
+type fakeProvider struct{ err error }
+
+func (fakeProvider) Name() string { return "fake" }
+func (p fakeProvider) ListCalendars(context.Context) ([]domain.Calendar, error) {
+	return []domain.Calendar{{ID: "c1"}, {ID: "c2"}}, p.err
+}
+func (fakeProvider) ListEvents(_ context.Context, calendarID string, _, _ time.Time) ([]domain.Event, error) {
+	switch calendarID {
+	case "c1":
+		return []domain.Event{{Start: at(9, 0), End: at(10, 0)}}, nil
+	case "c2":
+		return []domain.Event{{Start: at(11, 0), End: at(12, 0)}}, nil
+	}
+	return nil, provider.ErrCalendarNotFound
+}
+func (fakeProvider) CreateEvent(context.Context, domain.EventMutation) (domain.Event, error) {
+	return domain.Event{}, provider.NotSupportedError{}
+}
+func (fakeProvider) UpdateEvent(context.Context, string, domain.EventMutation) (domain.Event, error) {
+	return domain.Event{}, provider.NotSupportedError{}
+}
+func (fakeProvider) DeleteEvent(context.Context, string) error { return provider.NotSupportedError{} }
+
+func TestSlotRequestQuery(t *testing.T) {
+	t.Parallel()
+
+	q, invalid := SlotRequest{
+		From:            at(0, 0),
+		To:              at(23, 0),
+		DurationMinutes: 30,
+		TimeZone:        "Europe/Berlin",
+		WorkingHours:    map[string][]HoursWindow{"Monday": {{Start: "08:00", End: "24:00"}}},
+	}.Query()
+	if len(invalid) != 0 {
+		t.Fatalf("unexpected errors %+v", invalid)
+	}
+	if q.Limit != DefaultSlotLimit || q.Location.String() != "Europe/Berlin" || q.Hours[time.Monday][0] != (Hours{8 * time.Hour, 24 * time.Hour}) {
+		t.Fatalf("unexpected query %+v", q)
+	}
+
+	_, invalid = SlotRequest{
+		From:                at(9, 0),
+		To:                  at(9, 0),
+		StepMinutes:         -1,
+		BufferBeforeMinutes: -1,
+		BufferAfterMinutes:  -1,
+		MaxResults:          MaxSlotLimit + 1,
+		TimeZone:            "Nowhere/Special",
+		WorkingHours:        map[string][]HoursWindow{"caturday": nil, "friday": {{Start: "17:00", End: "09:00"}}},
+	}.Query()
+	want := []string{"to", "duration_minutes", "step_minutes", "buffer_before_minutes", "buffer_after_minutes", "max_results", "timezone", "working_hours.caturday", "working_hours.friday[0]"}
+	if len(invalid) != len(want) {
+		t.Fatalf("got %+v", invalid)
+	}
+	for i, field := range want {
+		if invalid[i].Field != field {
+			t.Fatalf("error %d: got %+v want field %s", i, invalid[i], field)
+		}
+	}
+	for field, in := range map[string]SlotRequest{
+		"from": {To: at(9, 0)},
+		"to":   {From: at(0, 0), To: at(0, 0).AddDate(0, 4, 0)},
+	} {
+		if _, invalid := in.Query(); invalid[0].Field != field {
+			t.Fatalf("expected a %s error, got %+v", field, invalid)
+		}
+	}
+}
+
+func TestFindSlots(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	q := SlotQuery{From: at(9, 0), To: at(13, 0), Duration: time.Hour, BufferAfter: 30 * time.Minute}
+	ids, slots, err := FindSlots(ctx, fakeProvider{}, nil, q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// c1 busy 09-10 and c2 busy 11-12; a slot before 11:00 needs 30 minutes
+	// after it.
+	if len(ids) != 2 || len(slots) != 1 || !slots[0].Start.Equal(at(12, 0)) {
+		t.Fatalf("unexpected slots %v %+v", ids, slots)
+	}
+	if _, _, err := FindSlots(ctx, fakeProvider{}, []string{"missing"}, q); !errors.Is(err, provider.ErrCalendarNotFound) {
+		t.Fatalf("expected calendar not found, got %v", err)
+	}
+	if _, _, err := Collect(ctx, fakeProvider{err: errors.New("down")}, nil, q.From, q.To); err == nil {
+		t.Fatal("expected the listing error")
+	}
+}
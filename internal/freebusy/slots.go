@@ -0,0 +1,118 @@
+package freebusy
+
+import (
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+)
+
+// Hours is a range of the working day, as offsets from local midnight.
+type Hours struct {
+	Start, End time.Duration
+}
+
+// WorkingHours lists the working ranges of each weekday. Days without
+// entries have no availability.
+type WorkingHours map[time.Weekday][]Hours
+
+// DefaultWorkingHours is Monday to Friday, 09:00 to 17:00.
+var DefaultWorkingHours = WorkingHours{
+	time.Monday:    {{9 * time.Hour, 17 * time.Hour}},
+	time.Tuesday:   {{9 * time.Hour, 17 * time.Hour}},
+	time.Wednesday: {{9 * time.Hour, 17 * time.Hour}},
+	time.Thursday:  {{9 * time.Hour, 17 * time.Hour}},
+	time.Friday:    {{9 * time.Hour, 17 * time.Hour}},
+}
+
+// SlotQuery describes the meeting to place.
+type SlotQuery struct {
+	From, To time.Time
+	Duration time.Duration
+	// Step spaces candidate starts, counted from the start of each working
+	// range. It defaults to Duration.
+	Step time.Duration
+	// BufferBefore and BufferAfter are kept free around each slot.
+	BufferBefore, BufferAfter time.Duration
+	// Location is the zone working hours are read in; it defaults to UTC.
+	Location *time.Location
+	// Hours defaults to DefaultWorkingHours.
+	Hours WorkingHours
+	// AllowTentative lets slots overlap tentative periods.
+	AllowTentative bool
+	// Limit caps the number of slots; zero means no limit.
+	Limit int
+}
+
+// Slots returns the candidate slots within working hours between q.From and
+// q.To that do not overlap busy, in chronological order.
+func Slots(busy []domain.BusyPeriod, q SlotQuery) []domain.Slot {
+	if q.Duration <= 0 || !q.To.After(q.From) {
+		return nil
+	}
+	loc := q.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	hours := q.Hours
+	if hours == nil {
+		hours = DefaultWorkingHours
+	}
+	step := q.Step
+	if step <= 0 {
+		step = q.Duration
+	}
+	// A slot fits when no busy period, widened by the buffers, overlaps it.
+	blocked := make([]interval, 0, len(busy))
+	for _, b := range busy {
+		if q.AllowTentative && b.Type == domain.BusyTypeTentative {
+			continue
+		}
+		blocked = append(blocked, interval{b.Start.Add(-q.BufferAfter), b.End.Add(q.BufferBefore)})
+	}
+	blocked = merge(blocked)
+
+	out := []domain.Slot{}
+	first := q.From.In(loc)
+	for day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc); day.Before(q.To); day = day.AddDate(0, 0, 1) {
+		for _, window := range workingWindows(day, hours[day.Weekday()]) {
+			base := window.start
+			start, end := clip(window.start, window.end, q.From, q.To)
+			for _, free := range subtract([]interval{{start, end}}, blocked) {
+				for s := alignUp(free.start, base, step); !s.Add(q.Duration).After(free.end); s = s.Add(step) {
+					out = append(out, domain.Slot{Start: s.In(loc), End: s.Add(q.Duration).In(loc)})
+					if q.Limit > 0 && len(out) >= q.Limit {
+						return out
+					}
+				}
+			}
+		}
+	}
+	return out
+}
+
+// workingWindows turns the ranges of one day into merged instants. Wall
+// clock times are resolved in the day's zone, so DST changes are honoured.
+func workingWindows(day time.Time, ranges []Hours) []interval {
+	ivs := make([]interval, 0, len(ranges))
+	for _, r := range ranges {
+		start, end := atClock(day, r.Start), atClock(day, r.End)
+		if end.After(start) {
+			ivs = append(ivs, interval{start, end})
+		}
+	}
+	return merge(ivs)
+}
+
+func atClock(day time.Time, offset time.Duration) time.Time {
+	h, m := int(offset/time.Hour), int(offset%time.Hour/time.Minute)
+	return time.Date(day.Year(), day.Month(), day.Day(), h, m, 0, 0, day.Location())
+}
+
+// alignUp returns the first base + n*step at or after t.
+func alignUp(t, base time.Time, step time.Duration) time.Time {
+	if !t.After(base) {
+		return base
+	}
+	n := (t.Sub(base) + step - 1) / step
+	return base.Add(n * step)
+}
@@ -0,0 +1,86 @@
+package freebusy
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+)
+
+func TestSlots(t *testing.T) {
+	t.Parallel()
+
+	// Monday 2 March 2026.
+	busy := []domain.BusyPeriod{
+		{Start: at(10, 0), End: at(11, 0), Type: domain.BusyTypeBusy},
+		{Start: at(13, 0), End: at(14, 0), Type: domain.BusyTypeTentative},
+	}
+	q := SlotQuery{
+		From:         at(0, 0),
+		To:           at(23, 59),
+		Duration:     time.Hour,
+		Step:         30 * time.Minute,
+		BufferBefore: 15 * time.Minute,
+		Hours:        WorkingHours{time.Monday: {{9 * time.Hour, 12*time.Hour + 30*time.Minute}, {13 * time.Hour, 15*time.Hour + 30*time.Minute}}},
+	}
+	got := Slots(busy, q)
+	// 09:00 ends at the meeting; 11:00 lacks the 15 minute buffer, so the
+	// first slot after it is 11:30. The tentative hour and its buffer push the
+	// afternoon to 14:30.
+	want := []time.Time{at(9, 0), at(11, 30), at(14, 30)}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v", got)
+	}
+	for i, w := range want {
+		if !got[i].Start.Equal(w) || !got[i].End.Equal(w.Add(time.Hour)) {
+			t.Fatalf("slot %d: got %+v want start %v", i, got[i], w)
+		}
+	}
+
+	q.AllowTentative = true
+	if got := Slots(busy, q); len(got) != 6 {
+		t.Fatalf("expected tentative time to be usable, got %+v", got)
+	}
+	q.Limit = 2
+	if got := Slots(busy, q); len(got) != 2 {
+		t.Fatalf("expected limit, got %+v", got)
+	}
+	if got := Slots(busy, SlotQuery{From: at(9, 0), To: at(8, 0), Duration: time.Hour}); got != nil {
+		t.Fatalf("expected no slots for an empty window, got %+v", got)
+	}
+}
+
+func TestSlotsDefaultsAndZones(t *testing.T) {
+	t.Parallel()
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Saturday 7 to Monday 9 March 2026; DST starts on Sunday 8 March.
+	from := time.Date(2026, 3, 7, 0, 0, 0, 0, ny)
+	got := Slots(nil, SlotQuery{From: from, To: from.AddDate(0, 0, 3), Duration: 8 * time.Hour, Location: ny})
+	if len(got) != 1 {
+		t.Fatalf("expected one Monday slot, got %+v", got)
+	}
+	if want := time.Date(2026, 3, 9, 13, 0, 0, 0, time.UTC); !got[0].Start.Equal(want) || got[0].Start.Location() != ny {
+		t.Fatalf("expected 09:00 EDT, got %v", got[0].Start)
+	}
+
+	// Candidates align to the working range even when the window starts
+	// mid-range.
+	got = Slots(nil, SlotQuery{From: at(9, 7), To: at(10, 0), Duration: 15 * time.Minute, Step: 15 * time.Minute})
+	if len(got) != 3 || !got[0].Start.Equal(at(9, 15)) {
+		t.Fatalf("unexpected aligned slots %+v", got)
+	}
+}
+
+func TestParseClock(t *testing.T) {
+	t.Parallel()
+
+	for in, ok := range map[string]bool{"00:00": true, "09:30": true, "24:00": true, "24:01": false, "9:00": false, "12:60": false, "noon": false} {
+		if _, got := parseClock(in); got != ok {
+			t.Fatalf("parseClock(%q) ok=%v want %v", in, got, ok)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package ical
+
+import (
+	"strings"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+)
+
+// Attendees returns the ATTENDEE properties of c. Missing ROLE, PARTSTAT and
+// CUTYPE parameters are filled in with their RFC 5545 defaults.
+func Attendees(c *Component) []domain.Attendee {
+	props := c.PropsNamed("ATTENDEE")
+	if len(props) == 0 {
+		return nil
+	}
+	out := make([]domain.Attendee, 0, len(props))
+	for _, p := range props {
+		a := address(p)
+		a.Role = paramDefault(p, "ROLE", "REQ-PARTICIPANT")
+		a.Status = paramDefault(p, "PARTSTAT", "NEEDS-ACTION")
+		a.Type = paramDefault(p, "CUTYPE", "INDIVIDUAL")
+		a.RSVP = strings.EqualFold(p.Params.Get("RSVP"), "TRUE")
+		out = append(out, a)
+	}
+	return out
+}
+
+// Organizer returns the ORGANIZER of c, or nil if it has none.
+func Organizer(c *Component) *domain.Attendee {
+	p, ok := c.Prop("ORGANIZER")
+	if !ok {
+		return nil
+	}
+	a := address(p)
+	return &a
+}
+
+// address reads the email and common name of a CAL-ADDRESS property. The
+// RFC 7986 EMAIL parameter wins over the URI, which is not always mailto:.
+func address(p Property) domain.Attendee {
+	email := p.Params.Get("EMAIL")
+	if email == "" {
+		email = p.Value
+		if len(email) >= 7 && strings.EqualFold(email[:7], "mailto:") {
+			email = email[7:]
+		}
+	}
+	return domain.Attendee{Email: email, Name: p.Params.Get("CN")}
+}
+
+func paramDefault(p Property, name, fallback string) string {
+	if v := p.Params.Get(name); v != "" {
+		return strings.ToUpper(v)
+	}
+	return fallback
+}
+
+// AddressProp encodes a as an ATTENDEE or ORGANIZER property. Empty fields
+// are left out so the RFC 5545 defaults apply.
+func AddressProp(name string, a domain.Attendee) Property {
+	params := Params{}
+	set := func(key, value string) {
+		if value != "" {
+			params[key] = []string{value}
+		}
+	}
+	set("CN", a.Name)
+	set("ROLE", a.Role)
+	set("PARTSTAT", a.Status)
+	set("CUTYPE", a.Type)
+	if a.RSVP {
+		set("RSVP", "TRUE")
+	}
+	if len(params) == 0 {
+		params = nil
+	}
+	return Property{Name: name, Params: params, Value: "mailto:" + a.Email}
+}
@@ -0,0 +1,41 @@
+package ical
+
+import "testing"
+
+func TestAttendeesAndOrganizer(t *testing.T) {
+	t.Parallel()
+
+	roots, err := ParseString("BEGIN:VEVENT\n" +
+		"ORGANIZER;CN=\"Doe, Jane\":MAILTO:jane@example.com\n" +
+		"ATTENDEE;CN=Bob;ROLE=opt-participant;PARTSTAT=ACCEPTED;RSVP=TRUE;CUTYPE=INDIVIDUAL:mailto:bob@example.com\n" +
+		"ATTENDEE:mailto:room@example.com\n" +
+		"ATTENDEE;EMAIL=carol@example.com;CUTYPE=GROUP:urn:uuid:1234\n" +
+		"END:VEVENT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := roots[0]
+
+	org := Organizer(c)
+	if org == nil || org.Email != "jane@example.com" || org.Name != "Doe, Jane" || org.Status != "" {
+		t.Fatalf("unexpected organizer: %+v", org)
+	}
+	got := Attendees(c)
+	if len(got) != 3 {
+		t.Fatalf("unexpected attendees: %+v", got)
+	}
+	if a := got[0]; a.Email != "bob@example.com" || a.Name != "Bob" || a.Role != "OPT-PARTICIPANT" || a.Status != "ACCEPTED" || !a.RSVP || a.Type != "INDIVIDUAL" {
+		t.Fatalf("unexpected attendee: %+v", a)
+	}
+	if a := got[1]; a.Role != "REQ-PARTICIPANT" || a.Status != "NEEDS-ACTION" || a.Type != "INDIVIDUAL" || a.RSVP {
+		t.Fatalf("expected defaults, got %+v", a)
+	}
+	if a := got[2]; a.Email != "carol@example.com" || a.Type != "GROUP" {
+		t.Fatalf("expected EMAIL parameter, got %+v", a)
+	}
+
+	empty := &Component{Name: "VEVENT"}
+	if Organizer(empty) != nil || Attendees(empty) != nil {
+		t.Fatal("expected no organizer or attendees")
+	}
+}
@@ -0,0 +1,166 @@
+package ical
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+)
+
+// Reminders decodes the VALARM components below c. Alarms with a trigger
+// that cannot be parsed are skipped.
+func Reminders(c *Component, tz *Timezones) []domain.Reminder {
+	var out []domain.Reminder
+	for _, alarm := range c.Children("VALARM") {
+		trigger, ok := alarm.Prop("TRIGGER")
+		if !ok {
+			continue
+		}
+		r := domain.Reminder{Type: strings.ToLower(alarm.Text("ACTION"))}
+		if r.Type == "" {
+			r.Type = domain.ReminderDisplay
+		}
+		if strings.EqualFold(trigger.Params.Get("VALUE"), "DATE-TIME") {
+			at, err := tz.DateTime(trigger)
+			if err != nil {
+				continue
+			}
+			r.At = &at.Time
+		} else {
+			if _, err := ParseDuration(trigger.Value); err != nil {
+				continue
+			}
+			r.Offset = strings.TrimSpace(trigger.Value)
+			r.Related = domain.RelatedStart
+			if strings.EqualFold(trigger.Params.Get("RELATED"), "END") {
+				r.Related = domain.RelatedEnd
+			}
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// ScheduleReminders returns a copy of reminders with FireAt set for an event
+// or occurrence spanning start to end.
+func ScheduleReminders(reminders []domain.Reminder, start, end time.Time) []domain.Reminder {
+	if len(reminders) == 0 {
+		return nil
+	}
+	out := make([]domain.Reminder, 0, len(reminders))
+	for _, r := range reminders {
+		r.FireAt = nil
+		switch {
+		case r.At != nil:
+			at := *r.At
+			r.FireAt = &at
+		case r.Offset != "":
+			offset, err := ParseDuration(r.Offset)
+			if err != nil {
+				break
+			}
+			base := start
+			if r.Related == domain.RelatedEnd {
+				base = end
+			}
+			fire := base.Add(offset)
+			r.FireAt = &fire
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// Alarm encodes a reminder as a VALARM component carrying the properties
+// RFC 5545 requires of its action: DISPLAY alarms get a DESCRIPTION, EMAIL
+// alarms a DESCRIPTION, SUMMARY and, when recipient is known, an ATTENDEE.
+// summary, the event's title, is the alarm text.
+func Alarm(r domain.Reminder, summary, recipient string) *Component {
+	action := strings.ToUpper(r.Type)
+	if action == "" {
+		action = "DISPLAY"
+	}
+	trigger := Property{Name: "TRIGGER", Value: r.Offset}
+	switch {
+	case r.At != nil:
+		trigger = DateTimeProp("TRIGGER", *r.At, false)
+		trigger.Params = Params{"VALUE": {"DATE-TIME"}}
+	case r.Related == domain.RelatedEnd:
+		trigger.Params = Params{"RELATED": {"END"}}
+	}
+	if summary == "" {
+		summary = "Reminder"
+	}
+	alarm := &Component{Name: "VALARM", Props: []Property{{Name: "ACTION", Value: action}, trigger, TextProp("DESCRIPTION", summary)}}
+	if action == "EMAIL" {
+		alarm.Props = append(alarm.Props, TextProp("SUMMARY", summary))
+		if recipient != "" {
+			alarm.Props = append(alarm.Props, AddressProp("ATTENDEE", domain.Attendee{Email: recipient}))
+		}
+	}
+	return alarm
+}
+
+// ParseDuration decodes an RFC 5545 DURATION value such as "-PT15M" or "P1D".
+// Days and weeks are taken as 24 hours and 7 days.
+func ParseDuration(v string) (time.Duration, error) {
+	s := strings.ToUpper(strings.TrimSpace(v))
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign, s = -1, s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("invalid ical duration: %q", v)
+	}
+	s = s[1:]
+	var total time.Duration
+	inTime := false
+	for len(s) > 0 {
+		if s[0] == 'T' {
+			if inTime || len(s) == 1 {
+				return 0, fmt.Errorf("invalid ical duration: %q", v)
+			}
+			inTime, s = true, s[1:]
+			continue
+		}
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if i == 0 || i == len(s) {
+			return 0, fmt.Errorf("invalid ical duration: %q", v)
+		}
+		n, err := strconv.Atoi(s[:i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid ical duration: %q", v)
+		}
+		unit, ok := durationUnit(s[i], inTime)
+		if !ok {
+			return 0, fmt.Errorf("invalid ical duration: %q", v)
+		}
+		total += time.Duration(n) * unit
+		s = s[i+1:]
+	}
+	return sign * total, nil
+}
+
+func durationUnit(c byte, inTime bool) (time.Duration, bool) {
+	switch {
+	case c == 'W' && !inTime:
+		return 7 * 24 * time.Hour, true
+	case c == 'D' && !inTime:
+		return 24 * time.Hour, true
+	case c == 'H' && inTime:
+		return time.Hour, true
+	case c == 'M' && inTime:
+		return time.Minute, true
+	case c == 'S' && inTime:
+		return time.Second, true
+	}
+	return 0, false
+}
@@ -0,0 +1,100 @@
+package ical
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+)
+
+func TestParseDuration(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]time.Duration{
+		"-PT15M":     -15 * time.Minute,
+		"PT0S":       0,
+		"+P1D":       24 * time.Hour,
+		"P1W":        7 * 24 * time.Hour,
+		"-P1DT2H30M": -(26*time.Hour + 30*time.Minute),
+		"pt1h":       time.Hour,
+	}
+	for in, want := range cases {
+		got, err := ParseDuration(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseDuration(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "P", "-PT", "PT15", "P1H", "PT1D", "PTT1H", "PXM", "15M"} {
+		if _, err := ParseDuration(in); err == nil {
+			t.Fatalf("expected error for %q", in)
+		}
+	}
+}
+
+func TestReminders(t *testing.T) {
+	t.Parallel()
+
+	roots, err := ParseString("BEGIN:VEVENT\n" +
+		"BEGIN:VALARM\nACTION:EMAIL\nTRIGGER;RELATED=END:-PT5M\nEND:VALARM\n" +
+		"BEGIN:VALARM\nACTION:DISPLAY\nTRIGGER;VALUE=DATE-TIME:20260301T080000Z\nEND:VALARM\n" +
+		"BEGIN:VALARM\nTRIGGER:-P1D\nEND:VALARM\n" +
+		"BEGIN:VALARM\nACTION:DISPLAY\nTRIGGER:soon\nEND:VALARM\n" +
+		"BEGIN:VALARM\nACTION:DISPLAY\nTRIGGER;VALUE=DATE-TIME:bad\nEND:VALARM\n" +
+		"BEGIN:VALARM\nACTION:DISPLAY\nEND:VALARM\n" +
+		"END:VEVENT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Reminders(roots[0], NewTimezones(roots))
+	if len(got) != 3 {
+		t.Fatalf("expected invalid alarms to be skipped, got %+v", got)
+	}
+	if r := got[0]; r.Type != domain.ReminderEmail || r.Offset != "-PT5M" || r.Related != domain.RelatedEnd {
+		t.Fatalf("unexpected relative reminder: %+v", r)
+	}
+	at := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	if r := got[1]; r.At == nil || !r.At.Equal(at) || r.Offset != "" || r.Related != "" {
+		t.Fatalf("unexpected absolute reminder: %+v", r)
+	}
+	if r := got[2]; r.Type != domain.ReminderDisplay || r.Related != domain.RelatedStart {
+		t.Fatalf("expected display default: %+v", r)
+	}
+
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	scheduled := ScheduleReminders(append(got, domain.Reminder{Offset: "bad"}), start, start.Add(time.Hour))
+	want := []time.Time{start.Add(55 * time.Minute), at, start.Add(-24 * time.Hour)}
+	for i, w := range want {
+		if scheduled[i].FireAt == nil || !scheduled[i].FireAt.Equal(w) {
+			t.Fatalf("reminder %d fires at %v, want %v", i, scheduled[i].FireAt, w)
+		}
+	}
+	if scheduled[3].FireAt != nil || got[0].FireAt != nil {
+		t.Fatalf("unexpected fire times: %+v / %+v", scheduled[3], got[0])
+	}
+	if ScheduleReminders(nil, start, start) != nil {
+		t.Fatal("expected nil for no reminders")
+	}
+}
+
+func TestAlarm(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	cases := []struct {
+		in                 domain.Reminder
+		summary, recipient string
+		want               string
+	}{
+		{domain.Reminder{Offset: "-PT10M"}, "", "", "BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER:-PT10M\r\nDESCRIPTION:Reminder\r\nEND:VALARM\r\n"},
+		{domain.Reminder{Type: "email", Offset: "PT0S", Related: "end"}, "Standup, daily", "me@example.com",
+			"BEGIN:VALARM\r\nACTION:EMAIL\r\nTRIGGER;RELATED=END:PT0S\r\nDESCRIPTION:Standup\\, daily\r\nSUMMARY:Standup\\, daily\r\nATTENDEE:mailto:me@example.com\r\nEND:VALARM\r\n"},
+		{domain.Reminder{Type: "email", Offset: "PT0S"}, "", "", "BEGIN:VALARM\r\nACTION:EMAIL\r\nTRIGGER:PT0S\r\nDESCRIPTION:Reminder\r\nSUMMARY:Reminder\r\nEND:VALARM\r\n"},
+		{domain.Reminder{Type: "display", At: &at}, "Standup", "me@example.com",
+			"BEGIN:VALARM\r\nACTION:DISPLAY\r\nTRIGGER;VALUE=DATE-TIME:20260301T080000Z\r\nDESCRIPTION:Standup\r\nEND:VALARM\r\n"},
+	}
+	for _, tc := range cases {
+		if got := EncodeString(Alarm(tc.in, tc.summary, tc.recipient)); got != tc.want {
+			t.Fatalf("Alarm(%+v) = %q", tc.in, got)
+		}
+	}
+}
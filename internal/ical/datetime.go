@@ -0,0 +1,171 @@
+package ical
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // Resolve IANA zones on hosts without a system database.
+)
+
+// DateTime is a decoded DATE or DATE-TIME value.
+type DateTime struct {
+	Time time.Time
+	// AllDay is set for VALUE=DATE values, which are returned as UTC midnight.
+	AllDay bool
+	// Floating is set for local times that carry neither a UTC marker nor a
+	// TZID. They are interpreted in Timezones.Floating.
+	Floating bool
+	// TZID is the zone identifier the value was expressed in, if any.
+	TZID string
+}
+
+// Timezones resolves TZID parameters against the VTIMEZONE components of a
+// calendar and the Go time zone database.
+type Timezones struct {
+	// Floating is the location used for floating local times.
+	Floating *time.Location
+
+	defs map[string]*Component
+	mu   sync.Mutex
+	locs map[string]*time.Location
+}
+
+// NewTimezones collects the VTIMEZONE definitions found below roots. Floating
+// times resolve to the host's local zone until Floating is overridden.
+func NewTimezones(roots []*Component) *Timezones {
+	tz := &Timezones{Floating: time.Local, defs: map[string]*Component{}, locs: map[string]*time.Location{}}
+	for _, def := range Find(roots, "VTIMEZONE") {
+		if id := def.Text("TZID"); id != "" {
+			tz.defs[id] = def
+		}
+	}
+	return tz
+}
+
+// Location resolves tzid. IANA names win over embedded definitions because the
+// tz database carries the full transition history; VTIMEZONE definitions cover
+// vendor identifiers such as Outlook's Windows zone names.
+func (tz *Timezones) Location(tzid string) (*time.Location, error) {
+	tz.mu.Lock()
+	defer tz.mu.Unlock()
+	if tz.locs == nil {
+		tz.locs = map[string]*time.Location{}
+	}
+	if loc, ok := tz.locs[tzid]; ok {
+		return loc, nil
+	}
+	loc, err := tz.resolve(tzid)
+	if err != nil {
+		return nil, err
+	}
+	tz.locs[tzid] = loc
+	return loc, nil
+}
+
+func (tz *Timezones) resolve(tzid string) (*time.Location, error) {
+	if loc, err := loadIANA(tzid); err == nil {
+		return loc, nil
+	}
+	if def, ok := tz.defs[tzid]; ok {
+		loc, err := vtimezoneLocation(tzid, def)
+		if err != nil {
+			return nil, fmt.Errorf("VTIMEZONE %s: %w", tzid, err)
+		}
+		return loc, nil
+	}
+	return nil, fmt.Errorf("unknown TZID %q", tzid)
+}
+
+// loadIANA strips the vendor prefixes some producers put in front of IANA
+// names, such as "/mozilla.org/20050126_1/Europe/Berlin".
+func loadIANA(tzid string) (*time.Location, error) {
+	name := strings.TrimSpace(tzid)
+	if loc, err := time.LoadLocation(name); err == nil && name != "" && name != "Local" {
+		return loc, nil
+	}
+	parts := strings.Split(strings.Trim(name, "/"), "/")
+	for i := len(parts) - 2; i >= 0 && i >= len(parts)-3; i-- {
+		candidate := strings.Join(parts[i:], "/")
+		if loc, err := time.LoadLocation(candidate); err == nil {
+			return loc, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown IANA zone %q", tzid)
+}
+
+// DateTime decodes a DATE or DATE-TIME property such as DTSTART.
+func (tz *Timezones) DateTime(p Property) (DateTime, error) {
+	v := strings.TrimSpace(p.Value)
+	if v == "" {
+		return DateTime{}, fmt.Errorf("empty datetime")
+	}
+	if strings.EqualFold(p.Params.Get("VALUE"), "DATE") || len(v) == len("20060102") {
+		t, err := time.Parse("20060102", v)
+		if err != nil {
+			return DateTime{}, fmt.Errorf("invalid ical date: %s", v)
+		}
+		return DateTime{Time: t, AllDay: true}, nil
+	}
+	if strings.HasSuffix(v, "Z") {
+		for _, f := range []string{"20060102T150405Z", "20060102T1504Z"} {
+			if t, err := time.Parse(f, v); err == nil {
+				return DateTime{Time: t}, nil
+			}
+		}
+		return DateTime{}, fmt.Errorf("invalid ical datetime: %s", v)
+	}
+
+	loc := tz.Floating
+	if loc == nil {
+		loc = time.UTC
+	}
+	out := DateTime{Floating: true}
+	if tzid := p.Params.Get("TZID"); tzid != "" {
+		// An unresolvable zone degrades to floating time rather than dropping
+		// the event; TZID is kept so callers can still see the intent.
+		out.TZID = tzid
+		if resolved, err := tz.Location(tzid); err == nil {
+			loc = resolved
+			out.Floating = false
+		}
+	}
+	for _, f := range []string{"20060102T150405", "20060102T1504"} {
+		if t, err := time.ParseInLocation(f, v, loc); err == nil {
+			out.Time = t
+			return out, nil
+		}
+	}
+	return DateTime{}, fmt.Errorf("invalid ical datetime: %s", v)
+}
+
+// DateTimes decodes a multi-valued date list such as RDATE or EXDATE. PERIOD
+// values are reduced to their start.
+func (tz *Timezones) DateTimes(p Property) ([]DateTime, error) {
+	var out []DateTime
+	for _, v := range strings.Split(p.Value, ",") {
+		v, _, _ = strings.Cut(v, "/")
+		dt, err := tz.DateTime(Property{Name: p.Name, Params: p.Params, Value: v})
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, dt)
+	}
+	return out, nil
+}
+
+// Times collects the instants of every named date-list property on c, such as
+// all EXDATE lines of a VEVENT. Unparsable entries are skipped.
+func (tz *Timezones) Times(c *Component, name string) []time.Time {
+	var out []time.Time
+	for _, p := range c.PropsNamed(name) {
+		values, err := tz.DateTimes(p)
+		if err != nil {
+			continue
+		}
+		for _, v := range values {
+			out = append(out, v.Time)
+		}
+	}
+	return out
+}
@@ -0,0 +1,78 @@
+package ical
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDateTimeForms(t *testing.T) {
+	t.Parallel()
+
+	tz := NewTimezones(nil)
+	tz.Floating = time.UTC
+
+	cases := []struct {
+		name  string
+		prop  Property
+		want  time.Time
+		check func(DateTime) bool
+	}{
+		{"utc", Property{Value: "20260216T090000Z"}, time.Date(2026, 2, 16, 9, 0, 0, 0, time.UTC), func(d DateTime) bool { return !d.Floating && d.TZID == "" }},
+		{"utc no seconds", Property{Value: "20260216T0900Z"}, time.Date(2026, 2, 16, 9, 0, 0, 0, time.UTC), func(d DateTime) bool { return !d.Floating }},
+		{"date", Property{Value: "20260216"}, time.Date(2026, 2, 16, 0, 0, 0, 0, time.UTC), func(d DateTime) bool { return d.AllDay }},
+		{"value date", Property{Params: Params{"VALUE": {"DATE"}}, Value: "20260216"}, time.Date(2026, 2, 16, 0, 0, 0, 0, time.UTC), func(d DateTime) bool { return d.AllDay }},
+		{"floating", Property{Value: "20260216T090000"}, time.Date(2026, 2, 16, 9, 0, 0, 0, time.UTC), func(d DateTime) bool { return d.Floating && d.TZID == "" }},
+		{"iana", Property{Params: Params{"TZID": {"America/New_York"}}, Value: "20260716T090000"}, time.Date(2026, 7, 16, 13, 0, 0, 0, time.UTC), func(d DateTime) bool { return !d.Floating && d.TZID == "America/New_York" }},
+		{"vendor prefix", Property{Params: Params{"TZID": {"/mozilla.org/20050126_1/Europe/Berlin"}}, Value: "20260216T090000"}, time.Date(2026, 2, 16, 8, 0, 0, 0, time.UTC), func(d DateTime) bool { return !d.Floating }},
+		{"unknown zone", Property{Params: Params{"TZID": {"Nowhere/Special"}}, Value: "20260216T090000"}, time.Date(2026, 2, 16, 9, 0, 0, 0, time.UTC), func(d DateTime) bool { return d.Floating && d.TZID == "Nowhere/Special" }},
+	}
+	for _, tc := range cases {
+		got, err := tz.DateTime(tc.prop)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !got.Time.Equal(tc.want) || !tc.check(got) {
+			t.Fatalf("%s: unexpected value %+v", tc.name, got)
+		}
+	}
+
+	for _, bad := range []string{"", "2026021", "20260216T09", "20260216T09Z", "2026-02-16"} {
+		if _, err := tz.DateTime(Property{Value: bad}); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+func TestDateTimeDefaultsFloatingToLocal(t *testing.T) {
+	t.Parallel()
+
+	got, err := NewTimezones(nil).DateTime(Property{Value: "20260216T090000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Time.Location() != time.Local {
+		t.Fatalf("expected host local zone, got %v", got.Time.Location())
+	}
+}
+
+func TestDateTimesList(t *testing.T) {
+	t.Parallel()
+
+	tz := NewTimezones(nil)
+	got, err := tz.DateTimes(Property{Name: "RDATE", Params: Params{"VALUE": {"PERIOD"}}, Value: "20260216T090000Z/PT1H,20260217T090000Z"})
+	if err != nil || len(got) != 2 || !got[1].Time.Equal(time.Date(2026, 2, 17, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected list: %+v err=%v", got, err)
+	}
+	if _, err := tz.DateTimes(Property{Name: "EXDATE", Value: "20260216T090000Z,bad"}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	c := &Component{Name: "VEVENT", Props: []Property{
+		{Name: "EXDATE", Value: "20260216T090000Z,20260217T090000Z"},
+		{Name: "EXDATE", Value: "bad"},
+		{Name: "EXDATE", Params: Params{"VALUE": {"DATE"}}, Value: "20260218"},
+	}}
+	if got := tz.Times(c, "EXDATE"); len(got) != 3 {
+		t.Fatalf("unexpected times: %v", got)
+	}
+}
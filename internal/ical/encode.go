@@ -0,0 +1,116 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxLineOctets is the RFC 5545 content line limit, excluding the CRLF.
+const maxLineOctets = 75
+
+// Encode writes c and its sub-components as CRLF terminated, folded content
+// lines. Property values are written as-is; use EscapeText for TEXT values.
+func Encode(w io.Writer, c *Component) error {
+	bw := bufio.NewWriter(w)
+	writeComponent(bw, c)
+	return bw.Flush()
+}
+
+// EncodeString is a convenience wrapper around Encode.
+func EncodeString(c *Component) string {
+	var b strings.Builder
+	_ = Encode(&b, c)
+	return b.String()
+}
+
+func writeComponent(w *bufio.Writer, c *Component) {
+	writeFolded(w, "BEGIN:"+c.Name)
+	for _, p := range c.Props {
+		writeFolded(w, p.String())
+	}
+	for _, child := range c.Components {
+		writeComponent(w, child)
+	}
+	writeFolded(w, "END:"+c.Name)
+}
+
+// String renders p as an unfolded content line. Parameters are written in
+// name order so output is deterministic.
+func (p Property) String() string {
+	var b strings.Builder
+	b.WriteString(p.Name)
+	names := make([]string, 0, len(p.Params))
+	for name := range p.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		b.WriteByte(';')
+		b.WriteString(name)
+		b.WriteByte('=')
+		for i, v := range p.Params[name] {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(encodeParamValue(v))
+		}
+	}
+	b.WriteByte(':')
+	b.WriteString(p.Value)
+	return b.String()
+}
+
+// encodeParamValue applies the RFC 6868 caret encoding and quotes values that
+// contain delimiters.
+func encodeParamValue(v string) string {
+	v = strings.NewReplacer("^", "^^", "\n", "^n", `"`, "^'").Replace(v)
+	if strings.ContainsAny(v, ";:,") {
+		return `"` + v + `"`
+	}
+	return v
+}
+
+// writeFolded splits line into chunks of at most 75 octets without breaking
+// UTF-8 sequences.
+func writeFolded(w *bufio.Writer, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !isRuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines lose one octet to the leading space.
+		limit = maxLineOctets - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
+
+// DateTimeProp builds a DATE or UTC DATE-TIME property.
+func DateTimeProp(name string, t time.Time, allDay bool) Property {
+	if allDay {
+		return Property{Name: name, Params: Params{"VALUE": {"DATE"}}, Value: t.Format("20060102")}
+	}
+	return Property{Name: name, Value: t.UTC().Format("20060102T150405Z")}
+}
+
+// ZonedDateTimeProp builds a DATE-TIME property holding t as local time in
+// loc, tagged with tzid, so recurrences follow the zone's offset changes.
+func ZonedDateTimeProp(name string, t time.Time, tzid string, loc *time.Location) Property {
+	return Property{Name: name, Params: Params{"TZID": {tzid}}, Value: t.In(loc).Format("20060102T150405")}
+}
+
+// TextProp builds a property holding an escaped TEXT value.
+func TextProp(name, value string) Property {
+	return Property{Name: name, Value: EscapeText(value)}
+}
@@ -0,0 +1,49 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	long := strings.Repeat("é", 60)
+	cal := &Component{Name: "VCALENDAR", Props: []Property{{Name: "VERSION", Value: "2.0"}}}
+	event := &Component{Name: "VEVENT", Props: []Property{
+		TextProp("SUMMARY", "Plan, review; "+long),
+		DateTimeProp("DTSTART", time.Date(2026, 3, 2, 9, 30, 0, 0, time.FixedZone("CET", 3600)), false),
+		DateTimeProp("DTEND", time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC), true),
+		ZonedDateTimeProp("RECURRENCE-ID", time.Date(2026, 3, 2, 8, 30, 0, 0, time.UTC), "CET", time.FixedZone("CET", 3600)),
+		{Name: "ATTENDEE", Params: Params{"CN": {`Doe, "Jane"`}, "ROLE": {"REQ-PARTICIPANT"}}, Value: "mailto:jane@example.com"},
+	}}
+	cal.Components = []*Component{event}
+
+	out := EncodeString(cal)
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Fatalf("line exceeds 75 octets: %q", line)
+		}
+	}
+	if !strings.Contains(out, "DTSTART:20260302T083000Z\r\n") || !strings.Contains(out, "DTEND;VALUE=DATE:20260303\r\n") ||
+		!strings.Contains(out, "RECURRENCE-ID;TZID=CET:20260302T093000\r\n") {
+		t.Fatalf("unexpected datetime encoding:\n%s", out)
+	}
+	if !strings.Contains(out, `ATTENDEE;CN="Doe, ^'Jane^'";ROLE=REQ-PARTICIPANT:mailto:jane@example.com`) {
+		t.Fatalf("unexpected parameter encoding:\n%s", out)
+	}
+
+	roots, err := ParseString(out)
+	if err != nil {
+		t.Fatalf("parse encoded: %v", err)
+	}
+	got := roots[0].Children("VEVENT")[0]
+	if got.Text("SUMMARY") != "Plan, review; "+long {
+		t.Fatalf("summary did not survive folding: %q", got.Text("SUMMARY"))
+	}
+	attendee, _ := got.Prop("ATTENDEE")
+	if attendee.Params.Get("CN") != `Doe, "Jane"` {
+		t.Fatalf("unexpected CN: %q", attendee.Params.Get("CN"))
+	}
+}
@@ -0,0 +1,170 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+)
+
+// DecodeEvent reads a VEVENT into an event identified by its UID. DTEND
+// falls back to DTSTART plus DURATION, then to one day after a DATE DTSTART
+// or DTSTART itself (RFC 5545 §3.6.1).
+func DecodeEvent(vevent *Component, tz *Timezones) (domain.Event, error) {
+	uid := vevent.Text("UID")
+	if uid == "" {
+		return domain.Event{}, errors.New("VEVENT has no UID")
+	}
+	dtstart, ok := vevent.Prop("DTSTART")
+	if !ok {
+		return domain.Event{}, errors.New("VEVENT has no DTSTART")
+	}
+	start, err := tz.DateTime(dtstart)
+	if err != nil {
+		return domain.Event{}, fmt.Errorf("DTSTART: %w", err)
+	}
+	end := start.Time
+	if dtend, ok := vevent.Prop("DTEND"); ok {
+		if parsed, err := tz.DateTime(dtend); err == nil {
+			end = parsed.Time
+		}
+	} else if d, err := ParseDuration(vevent.Text("DURATION")); err == nil {
+		end = start.Time.Add(d)
+	} else if start.AllDay {
+		end = start.Time.AddDate(0, 0, 1)
+	}
+	var recurrenceID *time.Time
+	if rid, ok := vevent.Prop("RECURRENCE-ID"); ok {
+		parsed, err := tz.DateTime(rid)
+		if err != nil {
+			return domain.Event{}, fmt.Errorf("RECURRENCE-ID: %w", err)
+		}
+		recurrenceID = &parsed.Time
+	}
+	rrule, _ := vevent.Prop("RRULE")
+	return domain.Event{
+		ID:              uid,
+		UID:             uid,
+		RecurrenceID:    recurrenceID,
+		Status:          strings.ToUpper(vevent.Text("STATUS")),
+		Title:           vevent.Text("SUMMARY"),
+		Description:     vevent.Text("DESCRIPTION"),
+		Location:        vevent.Text("LOCATION"),
+		Start:           start.Time,
+		End:             end,
+		AllDay:          start.AllDay,
+		TimeZone:        start.TZID,
+		Floating:        start.Floating,
+		Transparent:     strings.EqualFold(vevent.Text("TRANSP"), "TRANSPARENT"),
+		Recurrence:      rrule.Value,
+		RecurrenceDates: tz.Times(vevent, "RDATE"),
+		ExceptionDates:  tz.Times(vevent, "EXDATE"),
+		Organizer:       Organizer(vevent),
+		Attendees:       Attendees(vevent),
+		Reminders:       ScheduleReminders(Reminders(vevent, tz), start.Time, end),
+	}, nil
+}
+
+// EventComponent encodes e as a VEVENT. Times keep the event's zone as a
+// TZID when it can be resolved and fall back to UTC otherwise. Overrides and
+// expanded instances carry a RECURRENCE-ID and no recurrence rule. Email
+// reminders are addressed to owner.
+func EventComponent(e domain.Event, owner string, stamp time.Time) *Component {
+	uid := e.UID
+	if uid == "" {
+		uid = e.ID
+	}
+	if e.UpdatedAt != nil {
+		stamp = *e.UpdatedAt
+	}
+	tzid, loc := eventZone(e)
+	vevent := &Component{Name: "VEVENT", Props: []Property{
+		TextProp("UID", uid),
+		DateTimeProp("DTSTAMP", stamp, false),
+		eventTime("DTSTART", e, tzid, loc, e.Start),
+		eventTime("DTEND", e, tzid, loc, e.End),
+	}}
+	if e.RecurrenceID != nil {
+		vevent.Props = append(vevent.Props, eventTime("RECURRENCE-ID", e, tzid, loc, *e.RecurrenceID))
+	} else {
+		if e.Recurrence != "" {
+			vevent.Props = append(vevent.Props, Property{Name: "RRULE", Value: e.Recurrence})
+		}
+		if p, ok := eventTimes("RDATE", e, tzid, loc, e.RecurrenceDates); ok {
+			vevent.Props = append(vevent.Props, p)
+		}
+		if p, ok := eventTimes("EXDATE", e, tzid, loc, e.ExceptionDates); ok {
+			vevent.Props = append(vevent.Props, p)
+		}
+	}
+	if e.Status != "" {
+		vevent.Props = append(vevent.Props, Property{Name: "STATUS", Value: strings.ToUpper(e.Status)})
+	}
+	if e.Transparent {
+		vevent.Props = append(vevent.Props, Property{Name: "TRANSP", Value: "TRANSPARENT"})
+	}
+	vevent.Props = append(vevent.Props, TextProp("SUMMARY", e.Title))
+	if e.Description != "" {
+		vevent.Props = append(vevent.Props, TextProp("DESCRIPTION", e.Description))
+	}
+	if e.Location != "" {
+		vevent.Props = append(vevent.Props, TextProp("LOCATION", e.Location))
+	}
+	if e.Organizer != nil && e.Organizer.Email != "" {
+		vevent.Props = append(vevent.Props, AddressProp("ORGANIZER", *e.Organizer))
+	}
+	for _, a := range e.Attendees {
+		vevent.Props = append(vevent.Props, AddressProp("ATTENDEE", a))
+	}
+	for _, r := range e.Reminders {
+		vevent.Components = append(vevent.Components, Alarm(r, e.Title, owner))
+	}
+	return vevent
+}
+
+// eventZone resolves the zone an event's times should be written in. All-day
+// and floating events, and events in UTC, have none.
+func eventZone(e domain.Event) (string, *time.Location) {
+	if e.AllDay || e.Floating || e.TimeZone == "" {
+		return "", nil
+	}
+	if loc, err := loadIANA(e.TimeZone); err == nil {
+		if loc == time.UTC {
+			return "", nil
+		}
+		return e.TimeZone, loc
+	}
+	// Zones built from an embedded VTIMEZONE only survive on the parsed times.
+	if loc := e.Start.Location(); loc != time.UTC && loc != time.Local {
+		return e.TimeZone, loc
+	}
+	return "", nil
+}
+
+func eventTime(name string, e domain.Event, tzid string, loc *time.Location, t time.Time) Property {
+	switch {
+	case e.AllDay:
+		return DateTimeProp(name, t, true)
+	case e.Floating:
+		return Property{Name: name, Value: t.Format("20060102T150405")}
+	case loc != nil:
+		return ZonedDateTimeProp(name, t, tzid, loc)
+	}
+	return DateTimeProp(name, t, false)
+}
+
+// eventTimes encodes a date list such as EXDATE in the same form as DTSTART.
+func eventTimes(name string, e domain.Event, tzid string, loc *time.Location, ts []time.Time) (Property, bool) {
+	if len(ts) == 0 {
+		return Property{}, false
+	}
+	out := eventTime(name, e, tzid, loc, ts[0])
+	values := make([]string, len(ts))
+	for i, t := range ts {
+		values[i] = eventTime(name, e, tzid, loc, t).Value
+	}
+	out.Value = strings.Join(values, ",")
+	return out, true
+}
@@ -0,0 +1,30 @@
+package ical
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecodeEventDefaultEnd(t *testing.T) {
+	t.Parallel()
+
+	roots, err := ParseString("BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:day\r\nDTSTART;VALUE=DATE:20260403\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:instant\r\nDTSTART:20260403T090000Z\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:span\r\nDTSTART;VALUE=DATE:20260403\r\nDURATION:P2D\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tz := NewTimezones(roots)
+	want := map[string]time.Duration{"day": 24 * time.Hour, "instant": 0, "span": 48 * time.Hour}
+	for _, vevent := range Find(roots, "VEVENT") {
+		e, err := DecodeEvent(vevent, tz)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := e.End.Sub(e.Start); got != want[e.UID] {
+			t.Fatalf("%s lasts %v, want %v", e.UID, got, want[e.UID])
+		}
+	}
+}
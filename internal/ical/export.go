@@ -0,0 +1,110 @@
+package ical
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+)
+
+// ProdID identifies the bridge as the producer of the calendars it writes.
+const ProdID = "-//proton-calendar-bridge//EN"
+
+// tzHorizon is how far past the last event, or past now for recurring
+// series, exported VTIMEZONEs describe transitions.
+const tzHorizon = 5
+
+// Export builds a VCALENDAR for cal holding events. Every zone an event is
+// expressed in gets a VTIMEZONE, so the file does not depend on the reader
+// knowing the zone. stamp is used as DTSTAMP for events without an update
+// time. Email reminders are addressed to the calendar's owner.
+func Export(cal domain.Calendar, events []domain.Event, stamp time.Time) *Component {
+	vcal := &Component{Name: "VCALENDAR", Props: []Property{
+		{Name: "VERSION", Value: "2.0"},
+		{Name: "PRODID", Value: ProdID},
+		{Name: "CALSCALE", Value: "GREGORIAN"},
+		{Name: "METHOD", Value: "PUBLISH"},
+	}}
+	for _, p := range []struct{ name, value string }{
+		{"X-WR-CALNAME", cal.Name},
+		{"X-WR-CALDESC", cal.Description},
+		{"X-WR-TIMEZONE", cal.TimeZone},
+		{"X-APPLE-CALENDAR-COLOR", cal.Color},
+	} {
+		if p.value != "" {
+			vcal.Props = append(vcal.Props, TextProp(p.name, p.value))
+		}
+	}
+
+	type span struct {
+		loc      *time.Location
+		from, to time.Time
+	}
+	zones := map[string]*span{}
+	for _, e := range events {
+		tzid, loc := eventZone(e)
+		if loc == nil {
+			continue
+		}
+		to := e.End
+		if e.RecurrenceID == nil && e.Recurrence != "" && stamp.After(to) {
+			to = stamp
+		}
+		s, ok := zones[tzid]
+		if !ok {
+			zones[tzid] = &span{loc: loc, from: e.Start, to: to}
+			continue
+		}
+		if e.Start.Before(s.from) {
+			s.from = e.Start
+		}
+		if to.After(s.to) {
+			s.to = to
+		}
+	}
+	tzids := make([]string, 0, len(zones))
+	for tzid := range zones {
+		tzids = append(tzids, tzid)
+	}
+	sort.Strings(tzids)
+	for _, tzid := range tzids {
+		s := zones[tzid]
+		vcal.Components = append(vcal.Components, VTimezone(tzid, s.loc, s.from, s.to.AddDate(tzHorizon, 0, 0)))
+	}
+	for _, e := range events {
+		vcal.Components = append(vcal.Components, EventComponent(e, cal.Owner, stamp))
+	}
+	return vcal
+}
+
+// FreeBusy builds a VCALENDAR holding one VFREEBUSY that lists periods in UTC
+// for the window from to to.
+func FreeBusy(periods []domain.BusyPeriod, from, to, stamp time.Time) *Component {
+	vfb := &Component{Name: "VFREEBUSY", Props: []Property{
+		TextProp("UID", "freebusy-"+strconv.FormatInt(stamp.UnixNano(), 36)+"@proton-calendar-bridge"),
+		DateTimeProp("DTSTAMP", stamp, false),
+	}}
+	if !from.IsZero() {
+		vfb.Props = append(vfb.Props, DateTimeProp("DTSTART", from, false))
+	}
+	if !to.IsZero() {
+		vfb.Props = append(vfb.Props, DateTimeProp("DTEND", to, false))
+	}
+	for _, p := range periods {
+		fbtype := "BUSY"
+		if p.Type == domain.BusyTypeTentative {
+			fbtype = "BUSY-TENTATIVE"
+		}
+		vfb.Props = append(vfb.Props, Property{
+			Name:   "FREEBUSY",
+			Params: Params{"FBTYPE": {fbtype}},
+			Value:  p.Start.UTC().Format("20060102T150405Z") + "/" + p.End.UTC().Format("20060102T150405Z"),
+		})
+	}
+	return &Component{Name: "VCALENDAR", Props: []Property{
+		{Name: "VERSION", Value: "2.0"},
+		{Name: "PRODID", Value: ProdID},
+		{Name: "METHOD", Value: "PUBLISH"},
+	}, Components: []*Component{vfb}}
+}
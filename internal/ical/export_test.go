@@ -0,0 +1,160 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+)
+
+func TestVTimezoneRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	vtz := VTimezone("Custom Berlin", berlin, from, from.AddDate(2, 0, 0))
+	if len(vtz.Children("DAYLIGHT")) != 2 || len(vtz.Children("STANDARD")) != 3 {
+		t.Fatalf("unexpected observances:\n%s", EncodeString(vtz))
+	}
+	loc, err := vtimezoneLocation("Custom Berlin", vtz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, at := range []time.Time{
+		time.Date(2026, 2, 1, 12, 0, 0, 0, time.UTC),
+		time.Date(2026, 3, 29, 1, 30, 0, 0, time.UTC),
+		time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC),
+		time.Date(2027, 10, 31, 0, 30, 0, 0, time.UTC),
+		time.Date(2027, 12, 1, 12, 0, 0, 0, time.UTC),
+	} {
+		_, want := at.In(berlin).Zone()
+		if _, got := at.In(loc).Zone(); got != want {
+			t.Fatalf("offset at %v: got %d want %d", at, got, want)
+		}
+	}
+
+	fixed := VTimezone("Fixed", time.FixedZone("X", -(3*3600+30*60+15)), from, from.AddDate(1, 0, 0))
+	obs := fixed.Children("STANDARD")
+	if len(obs) != 1 || obs[0].Text("TZOFFSETTO") != "-033015" || obs[0].Text("DTSTART") != "19700101T000000" {
+		t.Fatalf("unexpected fixed zone:\n%s", EncodeString(fixed))
+	}
+}
+
+func TestExportRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, ny)
+	override := time.Date(2026, 3, 9, 9, 0, 0, 0, ny)
+	updated := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	events := []domain.Event{
+		{
+			ID: "s1", UID: "series@x", Title: "Standup, daily", Start: start, End: start.Add(30 * time.Minute),
+			TimeZone: "America/New_York", Recurrence: "FREQ=WEEKLY;COUNT=4",
+			ExceptionDates: []time.Time{time.Date(2026, 3, 16, 9, 0, 0, 0, ny)},
+			Organizer:      &domain.Attendee{Email: "boss@example.com", Name: "Boss"},
+			Attendees:      []domain.Attendee{{Email: "me@example.com", Role: "REQ-PARTICIPANT", Status: "ACCEPTED", RSVP: true}},
+			Reminders: []domain.Reminder{
+				{Type: domain.ReminderDisplay, Offset: "-PT10M", Related: domain.RelatedStart},
+				{Type: domain.ReminderEmail, Offset: "-PT1H"},
+			},
+			UpdatedAt: &updated,
+		},
+		{
+			ID: "o1", UID: "series@x", RecurrenceID: &override, Title: "Moved", Start: override.Add(time.Hour),
+			End: override.Add(90 * time.Minute), TimeZone: "America/New_York", Recurrence: "FREQ=WEEKLY;COUNT=4",
+		},
+		{ID: "a1", Title: "Holiday", Start: time.Date(2026, 4, 3, 0, 0, 0, 0, time.UTC), End: time.Date(2026, 4, 4, 0, 0, 0, 0, time.UTC), AllDay: true, Status: "confirmed", Transparent: true},
+	}
+	cal := domain.Calendar{ID: "c1", Name: "Work", Description: "Team", TimeZone: "America/New_York", Owner: "owner@example.com"}
+	out := EncodeString(Export(cal, events, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)))
+
+	for _, want := range []string{
+		"PRODID:" + ProdID, "X-WR-CALNAME:Work", "TZID:America/New_York",
+		"DTSTART;TZID=America/New_York:20260302T090000", "RRULE:FREQ=WEEKLY;COUNT=4",
+		"EXDATE;TZID=America/New_York:20260316T090000", "RECURRENCE-ID;TZID=America/New_York:20260309T090000",
+		"SUMMARY:Standup\\, daily", "DTSTAMP:20260201T000000Z", "DTSTART;VALUE=DATE:20260403", "STATUS:CONFIRMED",
+		"ORGANIZER;CN=Boss:mailto:boss@example.com", "BEGIN:VALARM", "TRIGGER:-PT10M", "TRANSP:TRANSPARENT",
+		"DESCRIPTION:Standup\\, daily", "ACTION:EMAIL", "ATTENDEE:mailto:owner@example.com",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "RRULE") != 1 {
+		t.Fatalf("override must not repeat the rule:\n%s", out)
+	}
+
+	roots, err := ParseString(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tz := NewTimezones(roots)
+	vevents := Find(roots, "VEVENT")
+	if len(vevents) != 3 || len(Find(roots, "VTIMEZONE")) != 1 {
+		t.Fatalf("unexpected components:\n%s", out)
+	}
+	dtstart, _ := vevents[0].Prop("DTSTART")
+	got, err := tz.DateTime(dtstart)
+	if err != nil || !got.Time.Equal(start) {
+		t.Fatalf("start round trip = %v, %v", got.Time, err)
+	}
+	attendees := Attendees(vevents[0])
+	if len(attendees) != 1 || attendees[0].Status != "ACCEPTED" || !attendees[0].RSVP {
+		t.Fatalf("attendees round trip = %+v", attendees)
+	}
+	if rs := Reminders(vevents[0], tz); len(rs) != 2 || rs[0].Offset != "-PT10M" {
+		t.Fatalf("reminders round trip = %+v", rs)
+	}
+	if e, err := DecodeEvent(vevents[2], tz); err != nil || !e.Transparent || !e.AllDay {
+		t.Fatalf("decode all-day event = %+v, %v", e, err)
+	}
+}
+
+func TestEventComponentZones(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2026, 5, 1, 8, 0, 0, 0, time.UTC)
+	floating := EventComponent(domain.Event{ID: "f", Start: at, End: at.Add(time.Hour), Floating: true}, "", at)
+	if p, _ := floating.Prop("DTSTART"); p.String() != "DTSTART:20260501T080000" {
+		t.Fatalf("floating start = %s", p)
+	}
+	utc := EventComponent(domain.Event{ID: "u", Start: at, End: at.Add(time.Hour), TimeZone: "UTC"}, "", at)
+	if p, _ := utc.Prop("DTSTART"); p.String() != "DTSTART:20260501T080000Z" {
+		t.Fatalf("utc start = %s", p)
+	}
+	unknown := EventComponent(domain.Event{ID: "x", Start: at, End: at.Add(time.Hour), TimeZone: "Nowhere/Special"}, "", at)
+	if p, _ := unknown.Prop("DTSTART"); p.String() != "DTSTART:20260501T080000Z" {
+		t.Fatalf("unknown zone start = %s", p)
+	}
+	if uid := unknown.Text("UID"); uid != "x" {
+		t.Fatalf("uid fallback = %q", uid)
+	}
+}
+
+func TestFreeBusy(t *testing.T) {
+	t.Parallel()
+
+	from := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	periods := []domain.BusyPeriod{
+		{Start: from.Add(9 * time.Hour), End: from.Add(10 * time.Hour), Type: domain.BusyTypeBusy},
+		{Start: from.Add(11 * time.Hour), End: from.Add(12 * time.Hour), Type: domain.BusyTypeTentative},
+	}
+	out := EncodeString(FreeBusy(periods, from, from.AddDate(0, 0, 1), from))
+	for _, want := range []string{
+		"BEGIN:VFREEBUSY\r\n", "DTSTART:20260302T000000Z\r\n", "DTEND:20260303T000000Z\r\n",
+		"FREEBUSY;FBTYPE=BUSY:20260302T090000Z/20260302T100000Z\r\n",
+		"FREEBUSY;FBTYPE=BUSY-TENTATIVE:20260302T110000Z/20260302T120000Z\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in:\n%s", want, out)
+		}
+	}
+}
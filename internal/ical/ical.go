@@ -0,0 +1,176 @@
+// Package ical implements an RFC 5545 iCalendar lexer and parser shared by the
+// calendar providers.
+package ical
+
+import "strings"
+
+// Params holds the parameters of a content line keyed by upper-cased name.
+type Params map[string][]string
+
+// Get returns the first value of the named parameter.
+func (p Params) Get(name string) string {
+	values := p[strings.ToUpper(name)]
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// Property is a single unfolded content line. Value is kept in its raw,
+// escaped form; use Text or Values to decode TEXT values.
+type Property struct {
+	Name   string
+	Params Params
+	Value  string
+}
+
+// Text returns the property value decoded as a TEXT value.
+func (p Property) Text() string {
+	return UnescapeText(p.Value)
+}
+
+// Values splits a multi-valued TEXT property on unescaped commas and decodes
+// each element.
+func (p Property) Values() []string {
+	parts := splitUnescaped(p.Value, ',')
+	out := make([]string, 0, len(parts))
+	for _, part := range parts {
+		out = append(out, UnescapeText(part))
+	}
+	return out
+}
+
+// Component is a BEGIN/END delimited block such as VCALENDAR or VEVENT.
+type Component struct {
+	Name       string
+	Props      []Property
+	Components []*Component
+}
+
+// Prop returns the first property with the given name.
+func (c *Component) Prop(name string) (Property, bool) {
+	name = strings.ToUpper(name)
+	for _, p := range c.Props {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Property{}, false
+}
+
+// PropsNamed returns every property with the given name in document order.
+func (c *Component) PropsNamed(name string) []Property {
+	name = strings.ToUpper(name)
+	var out []Property
+	for _, p := range c.Props {
+		if p.Name == name {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// Text returns the decoded TEXT value of the first property with the given
+// name, or an empty string when it is absent.
+func (c *Component) Text(name string) string {
+	p, ok := c.Prop(name)
+	if !ok {
+		return ""
+	}
+	return p.Text()
+}
+
+// Children returns the direct sub-components with the given name.
+func (c *Component) Children(name string) []*Component {
+	name = strings.ToUpper(name)
+	var out []*Component
+	for _, child := range c.Components {
+		if child.Name == name {
+			out = append(out, child)
+		}
+	}
+	return out
+}
+
+// Find returns every component with the given name found anywhere below
+// roots, including the roots themselves, in document order.
+func Find(roots []*Component, name string) []*Component {
+	name = strings.ToUpper(name)
+	var out []*Component
+	var walk func([]*Component)
+	walk = func(items []*Component) {
+		for _, c := range items {
+			if c.Name == name {
+				out = append(out, c)
+			}
+			walk(c.Components)
+		}
+	}
+	walk(roots)
+	return out
+}
+
+// UnescapeText decodes the backslash escapes permitted in TEXT values.
+func UnescapeText(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch != '\\' || i+1 == len(s) {
+			b.WriteByte(ch)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n', 'N':
+			b.WriteByte('\n')
+		case '\\', ';', ',', ':', '"':
+			b.WriteByte(s[i])
+		default:
+			b.WriteByte('\\')
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// EscapeText encodes s as a TEXT value.
+func EscapeText(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		switch ch := s[i]; ch {
+		case '\\', ';', ',':
+			b.WriteByte('\\')
+			b.WriteByte(ch)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				continue
+			}
+			b.WriteString(`\n`)
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String()
+}
+
+func splitUnescaped(s string, sep byte) []string {
+	var out []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			out = append(out, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, s[start:])
+}
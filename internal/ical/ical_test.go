@@ -0,0 +1,54 @@
+package ical
+
+import "testing"
+
+func TestTextEscaping(t *testing.T) {
+	t.Parallel()
+
+	in := "a, b; c\\d\nnext"
+	escaped := EscapeText(in)
+	if escaped != `a\, b\; c\\d\nnext` {
+		t.Fatalf("unexpected escape: %q", escaped)
+	}
+	if got := UnescapeText(escaped); got != in {
+		t.Fatalf("round trip mismatch: %q", got)
+	}
+	if got := EscapeText("x\r\ny\rz"); got != `x\ny\nz` {
+		t.Fatalf("unexpected CR handling: %q", got)
+	}
+	if got := UnescapeText(`keep \x and trailing \`); got != `keep \x and trailing \` {
+		t.Fatalf("unexpected passthrough: %q", got)
+	}
+}
+
+func TestPropertyValues(t *testing.T) {
+	t.Parallel()
+
+	p := Property{Name: "CATEGORIES", Value: `Work,Plan\, later,Home`}
+	got := p.Values()
+	if len(got) != 3 || got[1] != "Plan, later" {
+		t.Fatalf("unexpected values: %+v", got)
+	}
+}
+
+func TestComponentLookups(t *testing.T) {
+	t.Parallel()
+
+	c := &Component{
+		Name:       "VEVENT",
+		Props:      []Property{{Name: "ATTENDEE", Value: "a"}, {Name: "ATTENDEE", Value: "b"}},
+		Components: []*Component{{Name: "VALARM"}, {Name: "X-OTHER"}},
+	}
+	if _, ok := c.Prop("SUMMARY"); ok {
+		t.Fatal("expected missing property")
+	}
+	if c.Text("SUMMARY") != "" {
+		t.Fatal("expected empty text")
+	}
+	if len(c.PropsNamed("attendee")) != 2 {
+		t.Fatal("expected two attendees")
+	}
+	if len(c.Children("valarm")) != 1 {
+		t.Fatal("expected one alarm")
+	}
+}
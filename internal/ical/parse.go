@@ -0,0 +1,244 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Parse reads an iCalendar stream and returns its top-level components.
+// Folded lines are unfolded, and CRLF and bare LF line endings are accepted.
+// Malformed content lines are skipped, since real-world feeds often carry a
+// few; only broken BEGIN/END structure fails the parse.
+func Parse(r io.Reader) ([]*Component, error) {
+	roots, _, err := ParseReport(r)
+	return roots, err
+}
+
+// ParseReport is Parse that also returns an error for each skipped line.
+func ParseReport(r io.Reader) ([]*Component, []error, error) {
+	lines := newLineReader(r)
+	var roots []*Component
+	var stack []*Component
+	var skipped []error
+	for {
+		line, num, err := lines.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, skipped, err
+		}
+		prop, err := ParseLine(line)
+		if err != nil {
+			skipped = append(skipped, fmt.Errorf("line %d: %w", num, err))
+			continue
+		}
+		switch prop.Name {
+		case "BEGIN":
+			comp := &Component{Name: strings.ToUpper(strings.TrimSpace(prop.Value))}
+			if comp.Name == "" {
+				return nil, skipped, fmt.Errorf("line %d: empty component name", num)
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, comp)
+			} else {
+				roots = append(roots, comp)
+			}
+			stack = append(stack, comp)
+		case "END":
+			name := strings.ToUpper(strings.TrimSpace(prop.Value))
+			if len(stack) == 0 {
+				return nil, skipped, fmt.Errorf("line %d: END:%s without BEGIN", num, name)
+			}
+			if open := stack[len(stack)-1]; open.Name != name {
+				return nil, skipped, fmt.Errorf("line %d: END:%s does not match BEGIN:%s", num, name, open.Name)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				skipped = append(skipped, fmt.Errorf("line %d: property %s outside of a component", num, prop.Name))
+				continue
+			}
+			open := stack[len(stack)-1]
+			open.Props = append(open.Props, prop)
+		}
+	}
+	if len(stack) > 0 {
+		return nil, skipped, fmt.Errorf("unterminated component %s", stack[len(stack)-1].Name)
+	}
+	return roots, skipped, nil
+}
+
+// ParseString is a convenience wrapper around Parse.
+func ParseString(data string) ([]*Component, error) {
+	return Parse(strings.NewReader(data))
+}
+
+// ParseLine parses a single unfolded content line.
+func ParseLine(line string) (Property, error) {
+	i := 0
+	for i < len(line) && isNameChar(line[i]) {
+		i++
+	}
+	if i == 0 {
+		return Property{}, fmt.Errorf("invalid property name in %q", line)
+	}
+	prop := Property{Name: strings.ToUpper(line[:i])}
+
+	for i < len(line) && line[i] == ';' {
+		i++
+		start := i
+		for i < len(line) && isNameChar(line[i]) {
+			i++
+		}
+		if i == start || i >= len(line) || line[i] != '=' {
+			return Property{}, fmt.Errorf("invalid parameter in %s", prop.Name)
+		}
+		name := strings.ToUpper(line[start:i])
+		i++
+		for {
+			value, next, err := parseParamValue(line, i)
+			if err != nil {
+				return Property{}, fmt.Errorf("parameter %s of %s: %w", name, prop.Name, err)
+			}
+			if prop.Params == nil {
+				prop.Params = Params{}
+			}
+			prop.Params[name] = append(prop.Params[name], value)
+			i = next
+			if i < len(line) && line[i] == ',' {
+				i++
+				continue
+			}
+			break
+		}
+	}
+
+	if i >= len(line) || line[i] != ':' {
+		return Property{}, fmt.Errorf("missing ':' after %s", prop.Name)
+	}
+	prop.Value = line[i+1:]
+	return prop, nil
+}
+
+func parseParamValue(line string, i int) (string, int, error) {
+	if i < len(line) && line[i] == '"' {
+		end := strings.IndexByte(line[i+1:], '"')
+		if end < 0 {
+			return "", 0, errors.New("unterminated quoted value")
+		}
+		return decodeParamValue(line[i+1 : i+1+end]), i + end + 2, nil
+	}
+	start := i
+	for i < len(line) {
+		ch := line[i]
+		if ch == ';' || ch == ':' || ch == ',' {
+			break
+		}
+		if ch == '"' {
+			return "", 0, errors.New("unexpected quote")
+		}
+		i++
+	}
+	return decodeParamValue(line[start:i]), i, nil
+}
+
+// decodeParamValue applies the RFC 6868 caret encoding.
+func decodeParamValue(v string) string {
+	if !strings.Contains(v, "^") {
+		return v
+	}
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] != '^' || i+1 == len(v) {
+			b.WriteByte(v[i])
+			continue
+		}
+		switch v[i+1] {
+		case 'n':
+			b.WriteByte('\n')
+		case '\'':
+			b.WriteByte('"')
+		case '^':
+			b.WriteByte('^')
+		default:
+			b.WriteByte('^')
+			continue
+		}
+		i++
+	}
+	return b.String()
+}
+
+func isNameChar(ch byte) bool {
+	return ch == '-' || ch == '_' || ch >= '0' && ch <= '9' || ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z'
+}
+
+// lineReader yields unfolded content lines together with the physical line
+// number on which each one started.
+type lineReader struct {
+	r       *bufio.Reader
+	num     int
+	pending string
+	hasNext bool
+	nextNum int
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReader(r)}
+}
+
+func (l *lineReader) readPhysical() (string, error) {
+	raw, err := l.r.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && raw != "") {
+		if errors.Is(err, io.EOF) {
+			return "", io.EOF
+		}
+		return "", fmt.Errorf("read ical: %w", err)
+	}
+	l.num++
+	return strings.TrimRight(raw, "\r\n"), nil
+}
+
+func (l *lineReader) next() (string, int, error) {
+	var line string
+	var start int
+	if l.hasNext {
+		line, start = l.pending, l.nextNum
+		l.hasNext = false
+	} else {
+		for {
+			raw, err := l.readPhysical()
+			if err != nil {
+				return "", 0, err
+			}
+			if strings.TrimSpace(raw) == "" {
+				continue
+			}
+			line, start = raw, l.num
+			break
+		}
+	}
+	for {
+		raw, err := l.readPhysical()
+		if errors.Is(err, io.EOF) {
+			return line, start, nil
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		if raw != "" && (raw[0] == ' ' || raw[0] == '\t') {
+			line += raw[1:]
+			continue
+		}
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		l.pending, l.nextNum, l.hasNext = raw, l.num, true
+		return line, start, nil
+	}
+}
@@ -0,0 +1,138 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseComponentsFoldingAndEscapes(t *testing.T) {
+	t.Parallel()
+
+	data := "BEGIN:VCALENDAR\r\n" +
+		"X-WR-CALNAME:Team\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:1\r\n" +
+		"SUMMARY:Plan\\, review\\; ship\r\n" +
+		"DESCRIPTION:Line one\\nLine two that is long enough to be \r\n" +
+		" folded by the producer\r\n" +
+		"DTSTART;TZID=Europe/Berlin:20260216T090000\r\n" +
+		"ATTENDEE;CN=\"Doe, Jane\";ROLE=REQ-PARTICIPANT:mailto:jane@example.com\r\n" +
+		"BEGIN:VALARM\r\n" +
+		"TRIGGER:-PT10M\r\n" +
+		"END:VALARM\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	roots, err := ParseString(data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(roots) != 1 || roots[0].Name != "VCALENDAR" {
+		t.Fatalf("unexpected roots: %+v", roots)
+	}
+	if roots[0].Text("X-WR-CALNAME") != "Team" {
+		t.Fatalf("unexpected calendar name: %q", roots[0].Text("X-WR-CALNAME"))
+	}
+	events := roots[0].Children("VEVENT")
+	if len(events) != 1 {
+		t.Fatalf("expected one VEVENT, got %d", len(events))
+	}
+	ev := events[0]
+	if got := ev.Text("SUMMARY"); got != "Plan, review; ship" {
+		t.Fatalf("unexpected summary: %q", got)
+	}
+	if got := ev.Text("DESCRIPTION"); got != "Line one\nLine two that is long enough to be folded by the producer" {
+		t.Fatalf("unexpected description: %q", got)
+	}
+	dtstart, ok := ev.Prop("dtstart")
+	if !ok || dtstart.Params.Get("tzid") != "Europe/Berlin" || dtstart.Value != "20260216T090000" {
+		t.Fatalf("unexpected DTSTART: %+v", dtstart)
+	}
+	att := ev.PropsNamed("ATTENDEE")
+	if len(att) != 1 || att[0].Params.Get("CN") != "Doe, Jane" || att[0].Params.Get("ROLE") != "REQ-PARTICIPANT" || att[0].Value != "mailto:jane@example.com" {
+		t.Fatalf("unexpected attendee: %+v", att)
+	}
+	if alarms := Find(roots, "VALARM"); len(alarms) != 1 || alarms[0].Text("TRIGGER") != "-PT10M" {
+		t.Fatalf("unexpected alarms: %+v", alarms)
+	}
+}
+
+func TestParseLineParams(t *testing.T) {
+	t.Parallel()
+
+	prop, err := ParseLine(`X-TEST;MEMBER="mailto:a@x","mailto:b@x";X-NOTE=say ^'hi^'^n:value:with:colons`)
+	if err != nil {
+		t.Fatalf("parse line: %v", err)
+	}
+	if prop.Name != "X-TEST" || prop.Value != "value:with:colons" {
+		t.Fatalf("unexpected property: %+v", prop)
+	}
+	if got := prop.Params["MEMBER"]; len(got) != 2 || got[1] != "mailto:b@x" {
+		t.Fatalf("unexpected multi-valued param: %+v", got)
+	}
+	if got := prop.Params.Get("X-NOTE"); got != "say \"hi\"\n" {
+		t.Fatalf("unexpected caret decoding: %q", got)
+	}
+	if prop.Params.Get("MISSING") != "" {
+		t.Fatal("expected empty missing param")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"mismatched end": "BEGIN:VCALENDAR\nBEGIN:VEVENT\nEND:VCALENDAR",
+		"stray end":      "END:VEVENT",
+		"unterminated":   "BEGIN:VCALENDAR\nBEGIN:VEVENT\nEND:VEVENT",
+		"empty begin":    "BEGIN:\nEND:",
+	}
+	for name, data := range cases {
+		if _, err := Parse(strings.NewReader(data)); err == nil {
+			t.Fatalf("%s: expected parse error", name)
+		}
+	}
+}
+
+func TestParseSkipsMalformedLines(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"missing colon":   "SUMMARY",
+		"only the orphan": "",
+		"bad param":       "X;=1:v",
+		"unclosed quote":  "X;A=\"v:w",
+		"stray quote":     "X;A=v\"w:x",
+	}
+	for name, bad := range cases {
+		data := "SUMMARY:orphan\nBEGIN:VCALENDAR\nBEGIN:VEVENT\n" + bad + "\nSUMMARY:kept\nEND:VEVENT\nEND:VCALENDAR"
+		roots, skipped, err := ParseReport(strings.NewReader(data))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		want := 2
+		if bad == "" {
+			want = 1
+		}
+		if len(skipped) != want {
+			t.Fatalf("%s: expected %d skipped lines, got %v", name, want, skipped)
+		}
+		events := Find(roots, "VEVENT")
+		if len(events) != 1 || events[0].Text("SUMMARY") != "kept" {
+			t.Fatalf("%s: expected the event to survive, got %+v", name, roots)
+		}
+	}
+}
+
+func TestParseEmptyAndMultipleRoots(t *testing.T) {
+	t.Parallel()
+
+	roots, err := ParseString("")
+	if err != nil || len(roots) != 0 {
+		t.Fatalf("unexpected empty parse: %+v err=%v", roots, err)
+	}
+	roots, err = ParseString("BEGIN:VCALENDAR\n\nEND:VCALENDAR\nBEGIN:VCALENDAR\nEND:VCALENDAR")
+	if err != nil || len(roots) != 2 {
+		t.Fatalf("expected two roots, got %+v err=%v", roots, err)
+	}
+}
@@ -0,0 +1,242 @@
+package ical
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/recurrence"
+)
+
+// vtimezoneHorizon bounds how far recurring observances are expanded.
+const vtimezoneHorizon = 2100
+
+// vtimezoneLocation builds a *time.Location from a VTIMEZONE definition by
+// expanding its STANDARD and DAYLIGHT observances into TZif transition data.
+func vtimezoneLocation(name string, def *Component) (*time.Location, error) {
+	type zoneInfo struct {
+		offset int
+		dst    bool
+		abbr   string
+	}
+	type transition struct {
+		at   int64
+		zone int
+	}
+
+	var zones []zoneInfo
+	var transitions []transition
+	for _, obs := range def.Components {
+		if obs.Name != "STANDARD" && obs.Name != "DAYLIGHT" {
+			continue
+		}
+		from, err := parseUTCOffset(obs.Text("TZOFFSETFROM"))
+		if err != nil {
+			return nil, fmt.Errorf("%s TZOFFSETFROM: %w", obs.Name, err)
+		}
+		to, err := parseUTCOffset(obs.Text("TZOFFSETTO"))
+		if err != nil {
+			return nil, fmt.Errorf("%s TZOFFSETTO: %w", obs.Name, err)
+		}
+		abbr := obs.Text("TZNAME")
+		if abbr == "" {
+			abbr = formatOffsetAbbr(to)
+		}
+		info := zoneInfo{offset: to, dst: obs.Name == "DAYLIGHT", abbr: abbr}
+		idx := -1
+		for i, z := range zones {
+			if z == info {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			zones = append(zones, info)
+			idx = len(zones) - 1
+		}
+
+		onsets, err := observanceOnsets(obs)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", obs.Name, err)
+		}
+		for _, onset := range onsets {
+			transitions = append(transitions, transition{at: onset.Unix() - int64(from), zone: idx})
+		}
+	}
+	if len(zones) == 0 {
+		return nil, errors.New("no STANDARD or DAYLIGHT observances")
+	}
+	sort.Slice(transitions, func(i, j int) bool { return transitions[i].at < transitions[j].at })
+
+	var abbrevs bytes.Buffer
+	abbrevIndex := make([]int, len(zones))
+	for i, z := range zones {
+		abbrevIndex[i] = abbrevs.Len()
+		abbrevs.WriteString(z.abbr)
+		abbrevs.WriteByte(0)
+	}
+
+	// Version 2 TZif with an empty 32-bit block followed by the 64-bit data.
+	var buf bytes.Buffer
+	writeHeader := func(counts ...uint32) {
+		buf.WriteString("TZif2")
+		buf.Write(make([]byte, 15))
+		for _, c := range counts {
+			_ = binary.Write(&buf, binary.BigEndian, c)
+		}
+	}
+	writeHeader(0, 0, 0, 0, 0, 0)
+	writeHeader(0, 0, 0, uint32(len(transitions)), uint32(len(zones)), uint32(abbrevs.Len()))
+	for _, tr := range transitions {
+		_ = binary.Write(&buf, binary.BigEndian, tr.at)
+	}
+	for _, tr := range transitions {
+		buf.WriteByte(byte(tr.zone))
+	}
+	for i, z := range zones {
+		_ = binary.Write(&buf, binary.BigEndian, int32(z.offset))
+		if z.dst {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+		buf.WriteByte(byte(abbrevIndex[i]))
+	}
+	buf.Write(abbrevs.Bytes())
+	return time.LoadLocationFromTZData(name, buf.Bytes())
+}
+
+// observanceOnsets returns the local wall-clock onsets of an observance,
+// encoded as UTC times.
+func observanceOnsets(obs *Component) ([]time.Time, error) {
+	dtstart, ok := obs.Prop("DTSTART")
+	if !ok {
+		return nil, errors.New("missing DTSTART")
+	}
+	start, err := parseLocalDateTime(dtstart.Value)
+	if err != nil {
+		return nil, err
+	}
+	set := recurrence.Set{Start: start}
+	for _, rdate := range obs.PropsNamed("RDATE") {
+		for _, v := range strings.Split(rdate.Value, ",") {
+			t, err := parseLocalDateTime(v)
+			if err != nil {
+				return nil, fmt.Errorf("RDATE: %w", err)
+			}
+			set.RDates = append(set.RDates, t)
+		}
+	}
+	for _, rrule := range obs.PropsNamed("RRULE") {
+		rule, err := recurrence.ParseRule(rrule.Value)
+		if err != nil {
+			return nil, fmt.Errorf("RRULE: %w", err)
+		}
+		set.Rules = append(set.Rules, rule)
+	}
+	return set.Between(time.Time{}, time.Date(vtimezoneHorizon, 1, 1, 0, 0, 0, 0, time.UTC)), nil
+}
+
+func parseLocalDateTime(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	for _, f := range []string{"20060102T150405", "20060102T1504", "20060102"} {
+		if t, err := time.Parse(f, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid local datetime %q", v)
+}
+
+// parseUTCOffset parses a UTC-OFFSET value such as "+0100" or "-053000" into
+// seconds east of UTC.
+func parseUTCOffset(v string) (int, error) {
+	v = strings.TrimSpace(v)
+	if len(v) != 5 && len(v) != 7 || (v[0] != '+' && v[0] != '-') {
+		return 0, fmt.Errorf("invalid UTC offset %q", v)
+	}
+	var parts [3]int
+	for i := 0; i*2+1 < len(v); i++ {
+		n, err := strconv.Atoi(v[1+i*2 : 3+i*2])
+		if err != nil {
+			return 0, fmt.Errorf("invalid UTC offset %q", v)
+		}
+		parts[i] = n
+	}
+	secs := parts[0]*3600 + parts[1]*60 + parts[2]
+	if v[0] == '-' {
+		secs = -secs
+	}
+	return secs, nil
+}
+
+func formatOffsetAbbr(secs int) string {
+	sign := '+'
+	if secs < 0 {
+		sign = '-'
+		secs = -secs
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, secs/3600, secs%3600/60)
+}
+
+// formatUTCOffset renders seconds east of UTC as a UTC-OFFSET value, adding
+// the seconds field only when it is non-zero.
+func formatUTCOffset(secs int) string {
+	out := formatOffsetAbbr(secs)
+	if secs%60 != 0 {
+		if secs < 0 {
+			secs = -secs
+		}
+		out += fmt.Sprintf("%02d", secs%60)
+	}
+	return out
+}
+
+// maxObservances caps the transitions written for one VTIMEZONE.
+const maxObservances = 512
+
+// VTimezone describes loc between from and to as a VTIMEZONE with one
+// STANDARD or DAYLIGHT observance per transition. Zones without transitions
+// get a single observance.
+func VTimezone(tzid string, loc *time.Location, from, to time.Time) *Component {
+	vtz := &Component{Name: "VTIMEZONE", Props: []Property{{Name: "TZID", Value: tzid}}}
+	at := from.In(loc)
+	start, end := at.ZoneBounds()
+	name, offset := at.Zone()
+	if start.IsZero() {
+		vtz.Components = append(vtz.Components, observance(time.Date(1970, 1, 1, 0, 0, 0, 0, time.FixedZone("", offset)), offset, offset, name, at.IsDST()))
+	} else {
+		_, before := start.Add(-time.Second).In(loc).Zone()
+		vtz.Components = append(vtz.Components, observance(start, before, offset, name, at.IsDST()))
+	}
+	for !end.IsZero() && end.Before(to) && len(vtz.Components) < maxObservances {
+		next := end.In(loc)
+		nextName, nextOffset := next.Zone()
+		vtz.Components = append(vtz.Components, observance(end, offset, nextOffset, nextName, next.IsDST()))
+		offset = nextOffset
+		_, end = next.ZoneBounds()
+	}
+	return vtz
+}
+
+// observance builds a STANDARD or DAYLIGHT component for a transition at the
+// instant at. DTSTART is the local time under the offset in effect before it.
+func observance(at time.Time, from, to int, abbr string, dst bool) *Component {
+	name := "STANDARD"
+	if dst {
+		name = "DAYLIGHT"
+	}
+	props := []Property{
+		{Name: "DTSTART", Value: at.In(time.FixedZone("", from)).Format("20060102T150405")},
+		{Name: "TZOFFSETFROM", Value: formatUTCOffset(from)},
+		{Name: "TZOFFSETTO", Value: formatUTCOffset(to)},
+	}
+	if abbr != "" {
+		props = append(props, TextProp("TZNAME", abbr))
+	}
+	return &Component{Name: name, Props: props}
+}
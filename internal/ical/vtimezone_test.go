@@ -0,0 +1,122 @@
+package ical
+
+import (
+	"testing"
+	"time"
+)
+
+const outlookCalendar = "BEGIN:VCALENDAR\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:W. Europe Standard Time\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:16011028T030000\r\n" +
+	"RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=10\r\n" +
+	"TZOFFSETFROM:+0200\r\n" +
+	"TZOFFSETTO:+0100\r\n" +
+	"END:STANDARD\r\n" +
+	"BEGIN:DAYLIGHT\r\n" +
+	"DTSTART:16010325T020000\r\n" +
+	"RRULE:FREQ=YEARLY;BYDAY=-1SU;BYMONTH=3\r\n" +
+	"TZOFFSETFROM:+0100\r\n" +
+	"TZOFFSETTO:+0200\r\n" +
+	"TZNAME:CEST\r\n" +
+	"END:DAYLIGHT\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:Custom Fixed\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:19700101T000000\r\n" +
+	"RDATE:19800101T000000\r\n" +
+	"TZOFFSETFROM:+0530\r\n" +
+	"TZOFFSETTO:-033000\r\n" +
+	"END:STANDARD\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestVTimezoneResolution(t *testing.T) {
+	t.Parallel()
+
+	roots, err := ParseString(outlookCalendar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tz := NewTimezones(roots)
+
+	winter, err := tz.DateTime(Property{Params: Params{"TZID": {"W. Europe Standard Time"}}, Value: "20260216T090000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !winter.Time.Equal(time.Date(2026, 2, 16, 8, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected winter instant: %v", winter.Time.UTC())
+	}
+	summer, err := tz.DateTime(Property{Params: Params{"TZID": {"W. Europe Standard Time"}}, Value: "20260716T090000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !summer.Time.Equal(time.Date(2026, 7, 16, 7, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected summer instant: %v", summer.Time.UTC())
+	}
+	if name, _ := summer.Time.Zone(); name != "CEST" {
+		t.Fatalf("expected TZNAME abbreviation, got %s", name)
+	}
+	// The day after the last Sunday of March 2026 is already on daylight time.
+	after, _ := tz.DateTime(Property{Params: Params{"TZID": {"W. Europe Standard Time"}}, Value: "20260330T090000"})
+	if !after.Time.Equal(time.Date(2026, 3, 30, 7, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected transition handling: %v", after.Time.UTC())
+	}
+
+	fixed, err := tz.DateTime(Property{Params: Params{"TZID": {"Custom Fixed"}}, Value: "20260216T090000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fixed.Time.Equal(time.Date(2026, 2, 16, 12, 30, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected fixed offset instant: %v", fixed.Time.UTC())
+	}
+
+	loc1, _ := tz.Location("Custom Fixed")
+	loc2, _ := tz.Location("Custom Fixed")
+	if loc1 != loc2 {
+		t.Fatal("expected cached location")
+	}
+}
+
+func TestVTimezoneErrors(t *testing.T) {
+	t.Parallel()
+
+	cases := map[string]string{
+		"no observances": "BEGIN:VTIMEZONE\nTZID:X\nEND:VTIMEZONE",
+		"bad offset":     "BEGIN:VTIMEZONE\nTZID:X\nBEGIN:STANDARD\nDTSTART:19700101T000000\nTZOFFSETFROM:0100\nTZOFFSETTO:+0100\nEND:STANDARD\nEND:VTIMEZONE",
+		"bad offset to":  "BEGIN:VTIMEZONE\nTZID:X\nBEGIN:STANDARD\nDTSTART:19700101T000000\nTZOFFSETFROM:+0100\nTZOFFSETTO:+01x0\nEND:STANDARD\nEND:VTIMEZONE",
+		"no dtstart":     "BEGIN:VTIMEZONE\nTZID:X\nBEGIN:STANDARD\nTZOFFSETFROM:+0100\nTZOFFSETTO:+0100\nEND:STANDARD\nEND:VTIMEZONE",
+		"bad rdate":      "BEGIN:VTIMEZONE\nTZID:X\nBEGIN:STANDARD\nDTSTART:19700101T000000\nRDATE:nope\nTZOFFSETFROM:+0100\nTZOFFSETTO:+0100\nEND:STANDARD\nEND:VTIMEZONE",
+		"bad rrule":      "BEGIN:VTIMEZONE\nTZID:X\nBEGIN:STANDARD\nDTSTART:19700101T000000\nRRULE:FREQ=HOURLY\nTZOFFSETFROM:+0100\nTZOFFSETTO:+0100\nEND:STANDARD\nEND:VTIMEZONE",
+	}
+	for name, data := range cases {
+		roots, err := ParseString(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if _, err := NewTimezones(roots).Location("X"); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+	if _, err := NewTimezones(nil).Location("Nowhere/Special"); err == nil {
+		t.Fatal("expected unknown zone error")
+	}
+}
+
+func TestObservanceOnsetsRecurring(t *testing.T) {
+	t.Parallel()
+
+	roots, err := ParseString("BEGIN:DAYLIGHT\nDTSTART:20000326T020000\nRRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU;COUNT=3\nEND:DAYLIGHT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := observanceOnsets(roots[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got[2].Year() != 2002 || got[2].Day() != 31 {
+		t.Fatalf("unexpected onsets: %v", got)
+	}
+}
@@ -0,0 +1,273 @@
+// Package mcp serves the calendar provider as Model Context Protocol tools,
+// over stdio or the streamable HTTP transport.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/security"
+)
+
+// ProtocolVersion is the newest protocol revision the server speaks. Clients
+// asking for an older supported revision get that one instead.
+const ProtocolVersion = "2025-06-18"
+
+var supportedVersions = map[string]bool{"2025-06-18": true, "2025-03-26": true, "2024-11-05": true}
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// maxMessageSize bounds a single JSON-RPC message on either transport.
+const maxMessageSize = 4 << 20
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Server answers MCP requests with the tools built for one provider.
+type Server struct {
+	provider provider.CalendarProvider
+	version  string
+	tools    []tool
+}
+
+// New builds the tool set of p. Write tools are only registered when the
+// provider reports WriteSupported, so agents never see tools that would fail.
+func New(ctx context.Context, p provider.CalendarProvider, version string) *Server {
+	s := &Server{provider: p, version: version}
+	writable := false
+	if cp, ok := p.(provider.CapabilityProvider); ok {
+		if caps, err := cp.Capabilities(ctx); err == nil {
+			writable = caps.WriteSupported
+		}
+	}
+	s.tools = s.readTools()
+	if writable {
+		s.tools = append(s.tools, s.writeTools()...)
+	}
+	return s
+}
+
+// Handle answers one JSON-RPC message. It returns nil for notifications and
+// responses, which get no reply.
+func (s *Server) Handle(ctx context.Context, msg []byte) []byte {
+	var req request
+	if err := json.Unmarshal(msg, &req); err != nil {
+		return encode(response{Error: &rpcError{Code: codeParseError, Message: "parse error: " + err.Error()}})
+	}
+	if req.Method == "" {
+		if len(req.ID) == 0 {
+			return encode(response{Error: &rpcError{Code: codeInvalidRequest, Message: "invalid request"}})
+		}
+		// A response to a server request; the server sends none.
+		return nil
+	}
+	result, rpcErr := s.dispatch(ctx, req)
+	if len(req.ID) == 0 {
+		return nil
+	}
+	return encode(response{ID: req.ID, Result: result, Error: rpcErr})
+}
+
+func (s *Server) dispatch(ctx context.Context, req request) (any, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		_ = json.Unmarshal(req.Params, &params)
+		version := ProtocolVersion
+		if supportedVersions[params.ProtocolVersion] {
+			version = params.ProtocolVersion
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{"listChanged": false}},
+			"serverInfo":      map[string]any{"name": "proton-calendar-bridge", "version": s.version},
+			"instructions":    "Calendar tools for the " + s.provider.Name() + " provider. Times are RFC 3339.",
+		}, nil
+	case "ping":
+		return map[string]any{}, nil
+	case "tools/list":
+		// Tokens see only the tools their scopes allow.
+		token := security.FromContext(ctx)
+		tools := make([]tool, 0, len(s.tools))
+		for _, t := range s.tools {
+			if token.Allows(t.scope) {
+				tools = append(tools, t)
+			}
+		}
+		return map[string]any{"tools": tools}, nil
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "invalid params: " + err.Error()}
+		}
+		for _, t := range s.tools {
+			if t.Name == params.Name {
+				if err := security.FromContext(ctx).Check(t.scope); err != nil {
+					return callResult(nil, err), nil
+				}
+				return callResult(t.call(ctx, params.Arguments)), nil
+			}
+		}
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool %q", params.Name)}
+	}
+	if len(req.ID) == 0 {
+		// Notifications such as notifications/initialized need no action.
+		return nil, nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", req.Method)}
+}
+
+// callResult reports a tool outcome. Tool failures are results flagged with
+// isError, so the model sees them, rather than protocol errors.
+func callResult(out any, err error) map[string]any {
+	if err != nil {
+		return map[string]any{
+			"content": []map[string]any{{"type": "text", "text": err.Error()}},
+			"isError": true,
+		}
+	}
+	text, _ := json.Marshal(out)
+	return map[string]any{
+		"content":           []map[string]any{{"type": "text", "text": string(text)}},
+		"structuredContent": out,
+		"isError":           false,
+	}
+}
+
+// ServeStdio reads newline-delimited messages from in and writes replies to
+// out until in is exhausted or ctx is done.
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	lines := make(chan []byte)
+	errc := make(chan error, 1)
+	go func() {
+		sc := bufio.NewScanner(in)
+		sc.Buffer(make([]byte, 64<<10), maxMessageSize)
+		for sc.Scan() {
+			line := append([]byte(nil), sc.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		errc <- sc.Err()
+		close(lines)
+	}()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case line, ok := <-lines:
+			if !ok {
+				return <-errc
+			}
+			if len(line) == 0 {
+				continue
+			}
+			if reply := s.Handle(ctx, line); reply != nil {
+				if _, err := out.Write(append(reply, '\n')); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// ServeHTTP implements the streamable HTTP transport for single messages.
+// Replies are plain JSON; the server never opens an SSE stream, so GET is
+// refused as the transport allows.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !localOrigin(r.Header.Get("Origin")) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	if v := r.Header.Get("MCP-Protocol-Version"); v != "" && !supportedVersions[v] {
+		http.Error(w, "unsupported MCP-Protocol-Version", http.StatusBadRequest)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "message too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "read failed", http.StatusBadRequest)
+		return
+	}
+	reply := s.Handle(r.Context(), body)
+	if reply == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	var probe response
+	if json.Unmarshal(reply, &probe) == nil && probe.Error != nil && probe.Error.Code == codeParseError {
+		w.WriteHeader(http.StatusBadRequest)
+	}
+	_, _ = w.Write(reply)
+}
+
+// localOrigin guards against DNS rebinding: browsers on other sites must not
+// reach the server through the loopback interface.
+func localOrigin(origin string) bool {
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	ip := net.ParseIP(host)
+	return host == "localhost" || (ip != nil && ip.IsLoopback())
+}
+
+func encode(r response) []byte {
+	r.JSONRPC = "2.0"
+	if r.ID == nil {
+		r.ID = json.RawMessage("null")
+	}
+	b, _ := json.Marshal(r)
+	return b
+}
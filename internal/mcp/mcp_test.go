@@ -0,0 +1,448 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/security"
+)
+
+var day = time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+// readProvider has one calendar with one event from 09:00 to 10:00.
+type readProvider struct{}
+
+func (readProvider) Name() string { return "fake" }
+func (readProvider) ListCalendars(context.Context) ([]domain.Calendar, error) {
+	return []domain.Calendar{{ID: "c1", Name: "Work"}}, nil
+}
+func (readProvider) ListEvents(_ context.Context, calendarID string, _, _ time.Time) ([]domain.Event, error) {
+	if calendarID != "c1" {
+		return nil, provider.ErrCalendarNotFound
+	}
+	return []domain.Event{{ID: "e1", CalendarID: "c1", Title: "Standup", Start: day.Add(9 * time.Hour), End: day.Add(10 * time.Hour)}}, nil
+}
+func (readProvider) CreateEvent(context.Context, domain.EventMutation) (domain.Event, error) {
+	return domain.Event{}, provider.NotSupportedError{Operation: "create"}
+}
+func (readProvider) UpdateEvent(context.Context, string, domain.EventMutation) (domain.Event, error) {
+	return domain.Event{}, provider.NotSupportedError{Operation: "update"}
+}
+func (readProvider) DeleteEvent(context.Context, string) error {
+	return provider.NotSupportedError{Operation: "delete"}
+}
+
+// writeProvider reports write support, lists series and looks up events.
+type writeProvider struct{ readProvider }
+
+func (writeProvider) Capabilities(context.Context) (provider.CapabilitySet, error) {
+	return provider.CapabilitySet{WriteSupported: true}, nil
+}
+func (writeProvider) CreateEvent(_ context.Context, in domain.EventMutation) (domain.Event, error) {
+	return domain.Event{ID: "new", CalendarID: in.CalendarID, Title: in.Title, Start: in.Start, End: in.End}, nil
+}
+func (writeProvider) UpdateEvent(_ context.Context, id string, in domain.EventMutation) (domain.Event, error) {
+	return domain.Event{ID: id, Title: in.Title, Location: in.Location}, nil
+}
+func (writeProvider) DeleteEvent(_ context.Context, id string) error {
+	if id != "e1" {
+		return provider.ErrEventNotFound
+	}
+	return nil
+}
+func (writeProvider) ListSeries(context.Context, string, time.Time, time.Time) ([]domain.Event, error) {
+	return []domain.Event{{ID: "series"}}, nil
+}
+func (writeProvider) GetEvent(_ context.Context, calendarID, eventID string) (domain.Event, error) {
+	e := domain.Event{ID: eventID, CalendarID: calendarID, Title: "Standup", Location: "Room 1"}
+	if series, _, ok := strings.Cut(eventID, "_"); ok {
+		e.SeriesID = series
+	}
+	return e, nil
+}
+
+type rpcReply struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+type toolResult struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StructuredContent json.RawMessage `json:"structuredContent"`
+	IsError           bool            `json:"isError"`
+}
+
+func call(t *testing.T, s *Server, method string, params any) rpcReply {
+	t.Helper()
+	return callAs(t, context.Background(), s, method, params)
+}
+
+// callAs sends a request from the caller whose token ctx carries.
+func callAs(t *testing.T, ctx context.Context, s *Server, method string, params any) rpcReply {
+	t.Helper()
+	raw, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	var reply rpcReply
+	if err := json.Unmarshal(s.Handle(ctx, raw), &reply); err != nil {
+		t.Fatal(err)
+	}
+	return reply
+}
+
+func callTool(t *testing.T, s *Server, name string, args any) toolResult {
+	t.Helper()
+	return callToolAs(t, context.Background(), s, name, args)
+}
+
+func callToolAs(t *testing.T, ctx context.Context, s *Server, name string, args any) toolResult {
+	t.Helper()
+	reply := callAs(t, ctx, s, "tools/call", map[string]any{"name": name, "arguments": args})
+	if reply.Error != nil {
+		t.Fatalf("%s: %+v", name, reply.Error)
+	}
+	var res toolResult
+	if err := json.Unmarshal(reply.Result, &res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func toolNames(t *testing.T, s *Server) []string {
+	t.Helper()
+	var list struct {
+		Tools []struct {
+			Name        string         `json:"name"`
+			InputSchema map[string]any `json:"inputSchema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(call(t, s, "tools/list", nil).Result, &list); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tool := range list.Tools {
+		if tool.InputSchema["type"] != "object" {
+			t.Fatalf("%s: input schema must be an object, got %v", tool.Name, tool.InputSchema)
+		}
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
+func TestInitializeAndTools(t *testing.T) {
+	t.Parallel()
+
+	ro := New(context.Background(), readProvider{}, "1.2.3")
+	var init struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		ServerInfo      struct {
+			Version string `json:"version"`
+		} `json:"serverInfo"`
+	}
+	_ = json.Unmarshal(call(t, ro, "initialize", map[string]any{"protocolVersion": "2025-03-26"}).Result, &init)
+	if init.ProtocolVersion != "2025-03-26" || init.ServerInfo.Version != "1.2.3" {
+		t.Fatalf("unexpected initialize result %+v", init)
+	}
+	_ = json.Unmarshal(call(t, ro, "initialize", map[string]any{"protocolVersion": "1999-01-01"}).Result, &init)
+	if init.ProtocolVersion != ProtocolVersion {
+		t.Fatalf("expected the latest version for an unknown one, got %q", init.ProtocolVersion)
+	}
+	if reply := call(t, ro, "ping", nil); reply.Error != nil || string(reply.Result) != "{}" {
+		t.Fatalf("unexpected ping reply %+v", reply)
+	}
+
+	if got := strings.Join(toolNames(t, ro), ","); got != "list_calendars,list_events,get_free_busy,find_free_slots" {
+		t.Fatalf("unexpected read-only tools %s", got)
+	}
+	rw := New(context.Background(), writeProvider{}, "dev")
+	if got := strings.Join(toolNames(t, rw), ","); got != "list_calendars,list_events,get_free_busy,find_free_slots,get_event,create_event,update_event,delete_event" {
+		t.Fatalf("unexpected writable tools %s", got)
+	}
+}
+
+func TestReadTools(t *testing.T) {
+	t.Parallel()
+
+	s := New(context.Background(), writeProvider{}, "dev")
+	res := callTool(t, s, "list_calendars", nil)
+	if res.IsError || !strings.Contains(res.Content[0].Text, `"Work"`) || !bytes.Contains(res.StructuredContent, []byte(`"calendars"`)) {
+		t.Fatalf("unexpected list_calendars %+v", res)
+	}
+	res = callTool(t, s, "list_events", map[string]any{"calendar_id": "c1", "from": "2026-03-02T00:00:00Z"})
+	var events struct {
+		To     time.Time      `json:"to"`
+		Events []domain.Event `json:"events"`
+	}
+	_ = json.Unmarshal(res.StructuredContent, &events)
+	if len(events.Events) != 1 || events.Events[0].ID != "e1" || !events.To.Equal(day.AddDate(0, 0, 7)) {
+		t.Fatalf("unexpected list_events %+v", events)
+	}
+	res = callTool(t, s, "list_events", map[string]any{"calendar_id": "c1", "expand": false})
+	if !strings.Contains(res.Content[0].Text, `"series"`) {
+		t.Fatalf("expected series with expand=false, got %+v", res)
+	}
+	res = callTool(t, s, "get_event", map[string]any{"calendar_id": "c1", "event_id": "e9"})
+	if res.IsError || !strings.Contains(res.Content[0].Text, `"e9"`) {
+		t.Fatalf("unexpected get_event %+v", res)
+	}
+	res = callTool(t, s, "get_free_busy", map[string]any{"from": "2026-03-02T00:00:00Z", "to": "2026-03-03T00:00:00Z"})
+	if res.IsError || !strings.Contains(res.Content[0].Text, `"start":"2026-03-02T09:00:00Z"`) {
+		t.Fatalf("unexpected get_free_busy %+v", res)
+	}
+	res = callTool(t, s, "find_free_slots", map[string]any{
+		"from": "2026-03-02T08:00:00Z", "to": "2026-03-02T12:00:00Z", "duration_minutes": 60,
+		"working_hours": map[string]any{"monday": []map[string]string{{"start": "08:00", "end": "12:00"}}},
+	})
+	var slots struct {
+		Slots []domain.Slot `json:"slots"`
+	}
+	_ = json.Unmarshal(res.StructuredContent, &slots)
+	if len(slots.Slots) != 3 || slots.Slots[1].Start.Hour() != 10 {
+		t.Fatalf("unexpected find_free_slots %+v", res)
+	}
+
+	for name, args := range map[string]any{
+		"list_events":     map[string]any{"calendar_id": "missing"},
+		"get_free_busy":   map[string]any{"from": "2026-03-02T00:00:00Z"},
+		"find_free_slots": map[string]any{"from": "2026-03-02T00:00:00Z", "to": "2026-03-02T12:00:00Z", "timezone": "Nowhere"},
+		"get_event":       map[string]any{"event_id": "e1", "typo": true},
+	} {
+		if res := callTool(t, s, name, args); !res.IsError || res.Content[0].Text == "" {
+			t.Fatalf("%s: expected a tool error, got %+v", name, res)
+		}
+	}
+	if res := callTool(t, s, "list_events", map[string]any{"from": "2026-03-02T00:00:00Z", "to": "2026-03-01T00:00:00Z"}); !res.IsError {
+		t.Fatalf("expected an inverted window to fail, got %+v", res)
+	}
+}
+
+func TestWriteTools(t *testing.T) {
+	t.Parallel()
+
+	s := New(context.Background(), writeProvider{}, "dev")
+	res := callTool(t, s, "create_event", map[string]any{"calendar_id": "c1", "title": "Lunch", "start": "2026-03-02T12:00:00Z", "end": "2026-03-02T13:00:00Z"})
+	var created domain.Event
+	_ = json.Unmarshal(res.StructuredContent, &created)
+	if res.IsError || created.ID != "new" || created.Title != "Lunch" {
+		t.Fatalf("unexpected create_event %+v", res)
+	}
+	res = callTool(t, s, "update_event", map[string]any{"event_id": "e1", "calendar_id": "c1", "title": "Renamed"})
+	var updated domain.Event
+	_ = json.Unmarshal(res.StructuredContent, &updated)
+	if res.IsError || updated.Title != "Renamed" || updated.Location != "Room 1" {
+		t.Fatalf("expected the other fields to be kept, got %+v", res)
+	}
+	res = callTool(t, s, "update_event", map[string]any{"event_id": "s1_20260302T090000Z", "calendar_id": "c1", "title": "Renamed"})
+	if !res.IsError || !strings.Contains(res.Content[0].Text, "update_instance") {
+		t.Fatalf("expected instances to be refused, got %+v", res)
+	}
+	if res = callTool(t, s, "delete_event", map[string]any{"event_id": "e1"}); res.IsError {
+		t.Fatalf("unexpected delete_event %+v", res)
+	}
+	if res = callTool(t, s, "delete_event", map[string]any{"event_id": "e2"}); !res.IsError || !strings.Contains(res.Content[0].Text, provider.ErrEventNotFound.Error()) {
+		t.Fatalf("expected not found, got %+v", res)
+	}
+	for _, name := range []string{"create_event", "update_event", "delete_event"} {
+		if res := callTool(t, s, name, "not an object"); !res.IsError {
+			t.Fatalf("%s: expected bad arguments to fail", name)
+		}
+	}
+
+	// A read-only provider never exposes the write tools.
+	ro := New(context.Background(), readProvider{}, "dev")
+	if reply := call(t, ro, "tools/call", map[string]any{"name": "create_event"}); reply.Error == nil || reply.Error.Code != codeInvalidParams {
+		t.Fatalf("expected an unknown tool, got %+v", reply)
+	}
+}
+
+func TestScopedTools(t *testing.T) {
+	t.Parallel()
+
+	s := New(context.Background(), writeProvider{}, "dev")
+	reader := security.NewContext(context.Background(), security.Token{Name: "reader", Scopes: []security.Scope{security.ScopeCalendarsRead, security.ScopeEventsRead}, Calendars: []string{"c1"}})
+	var list struct {
+		Tools []struct {
+			Name string `json:"name"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(callAs(t, reader, s, "tools/list", nil).Result, &list); err != nil {
+		t.Fatal(err)
+	}
+	for _, tool := range list.Tools {
+		if strings.HasSuffix(tool.Name, "_event") && tool.Name != "get_event" {
+			t.Fatalf("read-only token sees %s", tool.Name)
+		}
+	}
+	if len(list.Tools) != 5 {
+		t.Fatalf("expected the five read tools, got %+v", list.Tools)
+	}
+
+	forbidden := func(ctx context.Context, name string, args any) {
+		t.Helper()
+		res := callToolAs(t, ctx, s, name, args)
+		if !res.IsError || !strings.Contains(res.Content[0].Text, "forbidden") {
+			t.Fatalf("%s: expected forbidden, got %+v", name, res)
+		}
+	}
+	forbidden(reader, "delete_event", map[string]any{"event_id": "e1"})
+	forbidden(reader, "list_events", map[string]any{})
+	forbidden(reader, "get_event", map[string]any{"calendar_id": "c2", "event_id": "e1"})
+	forbidden(reader, "get_free_busy", map[string]any{"calendar_ids": []string{"c2"}, "from": day, "to": day.Add(time.Hour)})
+	forbidden(reader, "find_free_slots", map[string]any{"calendar_ids": []string{"c2"}, "from": day, "to": day.AddDate(0, 0, 1), "duration_minutes": 30})
+	if res := callToolAs(t, reader, s, "get_free_busy", map[string]any{"from": day, "to": day.AddDate(0, 0, 1)}); res.IsError || !strings.Contains(string(res.StructuredContent), `"calendars":["c1"]`) {
+		t.Fatalf("expected free/busy of c1, got %+v", res)
+	}
+	if res := callToolAs(t, reader, s, "find_free_slots", map[string]any{"from": day, "to": day.AddDate(0, 0, 1), "duration_minutes": 30}); res.IsError {
+		t.Fatalf("unexpected error %+v", res)
+	}
+	if res := callToolAs(t, reader, s, "list_calendars", nil); res.IsError || !strings.Contains(string(res.StructuredContent), `"c1"`) {
+		t.Fatalf("unexpected calendars %+v", res)
+	}
+
+	writer := security.NewContext(context.Background(), security.Token{Name: "writer", Scopes: []security.Scope{security.ScopeEventsWrite}, Calendars: []string{"c1"}})
+	forbidden(writer, "create_event", map[string]any{"calendar_id": "c2", "title": "x", "start": day, "end": day.Add(time.Hour)})
+	forbidden(writer, "delete_event", map[string]any{"event_id": "e1"})
+	forbidden(writer, "list_calendars", nil)
+	if res := callToolAs(t, writer, s, "delete_event", map[string]any{"event_id": "e1", "calendar_id": "c1"}); res.IsError {
+		t.Fatalf("unexpected error %+v", res)
+	}
+	if res := callToolAs(t, writer, s, "update_event", map[string]any{"event_id": "e1", "calendar_id": "c1", "title": "y", "start": day, "end": day.Add(time.Hour)}); res.IsError {
+		t.Fatalf("unexpected error %+v", res)
+	}
+
+	// Without event lookup a restricted token cannot change events.
+	limited := New(context.Background(), lookuplessProvider{}, "dev")
+	if res := callToolAs(t, writer, limited, "delete_event", map[string]any{"event_id": "e1", "calendar_id": "c1"}); !res.IsError || !strings.Contains(res.Content[0].Text, "cannot be verified") {
+		t.Fatalf("expected the lookup to be required, got %+v", res)
+	}
+}
+
+// lookuplessProvider accepts writes but cannot look up events.
+type lookuplessProvider struct{ readProvider }
+
+func (lookuplessProvider) Capabilities(context.Context) (provider.CapabilitySet, error) {
+	return provider.CapabilitySet{WriteSupported: true}, nil
+}
+
+func TestProtocolErrors(t *testing.T) {
+	t.Parallel()
+
+	s := New(context.Background(), readProvider{}, "dev")
+	ctx := context.Background()
+	for msg, code := range map[string]int{
+		`{`: codeParseError,
+		`{"jsonrpc":"2.0","id":1,"method":"resources/list"}`:        codeMethodNotFound,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":7}`: codeInvalidParams,
+		`{"jsonrpc":"2.0"}`: codeInvalidRequest,
+	} {
+		var reply rpcReply
+		if err := json.Unmarshal(s.Handle(ctx, []byte(msg)), &reply); err != nil || reply.Error == nil || reply.Error.Code != code {
+			t.Fatalf("%s: expected code %d, got %+v (%v)", msg, code, reply.Error, err)
+		}
+	}
+	for _, msg := range []string{
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":5,"result":{}}`,
+	} {
+		if reply := s.Handle(ctx, []byte(msg)); reply != nil {
+			t.Fatalf("%s: expected no reply, got %s", msg, reply)
+		}
+	}
+}
+
+func TestServeStdio(t *testing.T) {
+	t.Parallel()
+
+	s := New(context.Background(), readProvider{}, "dev")
+	in := strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}` + "\n\n" +
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n" +
+		`{"jsonrpc":"2.0","id":"two","method":"tools/list"}` + "\n")
+	var out bytes.Buffer
+	if err := s.ServeStdio(context.Background(), in, &out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"id":1`) || !strings.Contains(lines[1], `"id":"two"`) {
+		t.Fatalf("unexpected replies:\n%s", out.String())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.ServeStdio(ctx, blockingReader{}, &out); err != nil {
+		t.Fatalf("expected a clean stop, got %v", err)
+	}
+	if err := s.ServeStdio(context.Background(), errReader{}, &out); err == nil {
+		t.Fatal("expected the read error")
+	}
+}
+
+type blockingReader struct{}
+
+func (blockingReader) Read([]byte) (int, error) { select {} }
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("broken pipe") }
+
+func TestServeHTTP(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(New(context.Background(), readProvider{}, "dev"))
+	defer ts.Close()
+	post := func(body string, header http.Header) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res := post(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, http.Header{"Origin": {"http://localhost:3000"}, "Mcp-Protocol-Version": {ProtocolVersion}})
+	var reply rpcReply
+	_ = json.NewDecoder(res.Body).Decode(&reply)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || res.Header.Get("Content-Type") != "application/json" || reply.Error != nil {
+		t.Fatalf("unexpected reply %d %+v", res.StatusCode, reply)
+	}
+	for _, tc := range []struct {
+		body   string
+		header http.Header
+		status int
+	}{
+		{`{"jsonrpc":"2.0","method":"notifications/initialized"}`, nil, http.StatusAccepted},
+		{`{`, nil, http.StatusBadRequest},
+		{`{}`, http.Header{"Origin": {"https://evil.example"}}, http.StatusForbidden},
+		{`{}`, http.Header{"Origin": {"%zz"}}, http.StatusForbidden},
+		{`{}`, http.Header{"Mcp-Protocol-Version": {"1999-01-01"}}, http.StatusBadRequest},
+		{strings.Repeat(" ", maxMessageSize+1), nil, http.StatusRequestEntityTooLarge},
+	} {
+		res := post(tc.body, tc.header)
+		res.Body.Close()
+		if res.StatusCode != tc.status {
+			t.Fatalf("%.40q %v: expected %d got %d", tc.body, tc.header, tc.status, res.StatusCode)
+		}
+	}
+	res, _ = http.Get(ts.URL)
+	res.Body.Close()
+	if res.StatusCode != http.StatusMethodNotAllowed || res.Header.Get("Allow") != http.MethodPost {
+		t.Fatalf("expected GET to be refused, got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,404 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/freebusy"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/security"
+)
+
+// defaultListWindow is how far ahead list_events looks when no end is given.
+const defaultListWindow = 7 * 24 * time.Hour
+
+type tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+	Annotations map[string]any `json:"annotations,omitempty"`
+
+	// scope is what an HTTP caller's token needs to list and call the tool.
+	scope security.Scope
+	call  func(ctx context.Context, args json.RawMessage) (any, error)
+}
+
+var readOnly = map[string]any{"readOnlyHint": true}
+
+func (s *Server) readTools() []tool {
+	tools := []tool{
+		{
+			Name:        "list_calendars",
+			Description: "List the calendars available through the bridge.",
+			InputSchema: object(nil),
+			Annotations: readOnly,
+			scope:       security.ScopeCalendarsRead,
+			call:        s.listCalendars,
+		},
+		{
+			Name:        "list_events",
+			Description: "List the events of a calendar between from and to. from defaults to now and to to a week after from. Recurring events are expanded into instances unless expand is false.",
+			InputSchema: object(map[string]any{
+				"calendar_id": str("Calendar ID from list_calendars."),
+				"from":        dateTime("Start of the window."),
+				"to":          dateTime("End of the window."),
+				"expand":      boolean("Expand recurring events into instances; defaults to true."),
+			}),
+			Annotations: readOnly,
+			scope:       security.ScopeEventsRead,
+			call:        s.listEvents,
+		},
+		{
+			Name:        "get_free_busy",
+			Description: "Report the merged busy and tentative periods of calendars between from and to, without event details.",
+			InputSchema: object(map[string]any{
+				"calendar_ids": strs("Calendars to include; defaults to every calendar."),
+				"from":         dateTime("Start of the window."),
+				"to":           dateTime("End of the window."),
+			}, "from", "to"),
+			Annotations: readOnly,
+			scope:       security.ScopeEventsRead,
+			call:        s.freeBusy,
+		},
+		{
+			Name:        "find_free_slots",
+			Description: "Find meeting slots of duration_minutes that are free in every selected calendar, within working hours and keeping buffers around busy time.",
+			InputSchema: object(map[string]any{
+				"calendar_ids":          strs("Calendars to check; defaults to every calendar."),
+				"from":                  dateTime("Start of the search window."),
+				"to":                    dateTime("End of the search window, at most 92 days after from."),
+				"duration_minutes":      integer("Meeting length in minutes."),
+				"step_minutes":          integer("Spacing of candidate starts; defaults to the duration."),
+				"buffer_before_minutes": integer("Free time required before each slot."),
+				"buffer_after_minutes":  integer("Free time required after each slot."),
+				"timezone":              str("IANA zone for working hours and results; defaults to UTC."),
+				"working_hours": map[string]any{
+					"type":        "object",
+					"description": `Working ranges by lowercase weekday name, e.g. {"monday":[{"start":"09:00","end":"17:00"}]}. Defaults to Monday to Friday 09:00-17:00.`,
+					"additionalProperties": map[string]any{"type": "array", "items": object(map[string]any{
+						"start": str("HH:MM"),
+						"end":   str("HH:MM, up to 24:00"),
+					}, "start", "end")},
+				},
+				"allow_tentative": boolean("Allow slots over tentative events."),
+				"max_results":     integer("Maximum number of slots; defaults to 50, at most 500."),
+			}, "from", "to", "duration_minutes"),
+			Annotations: readOnly,
+			scope:       security.ScopeEventsRead,
+			call:        s.findSlots,
+		},
+	}
+	if _, ok := s.provider.(provider.EventGetter); ok {
+		tools = append(tools, tool{
+			Name:        "get_event",
+			Description: "Get one event, series master or expanded instance by ID.",
+			InputSchema: object(map[string]any{
+				"calendar_id": str("Calendar ID."),
+				"event_id":    str("Event ID as reported by list_events."),
+			}, "calendar_id", "event_id"),
+			Annotations: readOnly,
+			scope:       security.ScopeEventsRead,
+			call:        s.getEvent,
+		})
+	}
+	return tools
+}
+
+func (s *Server) writeTools() []tool {
+	return []tool{
+		{
+			Name:        "create_event",
+			Description: "Create an event in a calendar.",
+			InputSchema: object(mutationProperties(), "calendar_id", "title", "start", "end"),
+			scope:       security.ScopeEventsWrite,
+			call: func(ctx context.Context, args json.RawMessage) (any, error) {
+				var in domain.EventMutation
+				if err := decode(args, &in); err != nil {
+					return nil, err
+				}
+				if err := security.FromContext(ctx).CheckCalendar(in.CalendarID); err != nil {
+					return nil, err
+				}
+				return s.provider.CreateEvent(ctx, in)
+			},
+		},
+		{
+			Name:        "update_event",
+			Description: "Change an event. Fields left out keep their current values. Single instances of a series cannot be changed; update the series instead.",
+			InputSchema: object(withEventID(mutationProperties()), "event_id", "calendar_id"),
+			Annotations: map[string]any{"idempotentHint": true},
+			scope:       security.ScopeEventsWrite,
+			call:        s.updateEvent,
+		},
+		{
+			Name:        "delete_event",
+			Description: "Delete an event.",
+			InputSchema: object(map[string]any{
+				"event_id":    str("Event ID."),
+				"calendar_id": str("Calendar of the event; required for tokens limited to some calendars."),
+			}, "event_id"),
+			Annotations: map[string]any{"destructiveHint": true, "idempotentHint": true},
+			scope:       security.ScopeEventsWrite,
+			call: func(ctx context.Context, args json.RawMessage) (any, error) {
+				var in struct {
+					EventID    string `json:"event_id"`
+					CalendarID string `json:"calendar_id"`
+				}
+				if err := decode(args, &in); err != nil {
+					return nil, err
+				}
+				if err := s.checkEvent(ctx, in.CalendarID, in.EventID); err != nil {
+					return nil, err
+				}
+				if err := s.provider.DeleteEvent(ctx, in.EventID); err != nil {
+					return nil, err
+				}
+				return map[string]any{"event_id": in.EventID, "deleted": true}, nil
+			},
+		},
+	}
+}
+
+func (s *Server) listCalendars(ctx context.Context, _ json.RawMessage) (any, error) {
+	cals, err := s.provider.ListCalendars(ctx)
+	if err != nil {
+		return nil, err
+	}
+	token := security.FromContext(ctx)
+	visible := make([]domain.Calendar, 0, len(cals))
+	for _, cal := range cals {
+		if token.AllowsCalendar(cal.ID) {
+			visible = append(visible, cal)
+		}
+	}
+	return map[string]any{"calendars": visible}, nil
+}
+
+// checkEvent confirms that a token limited to some calendars may change the
+// event, which must be found in an allowed calendar first.
+func (s *Server) checkEvent(ctx context.Context, calendarID, eventID string) error {
+	token := security.FromContext(ctx)
+	if !token.Restricted() {
+		return nil
+	}
+	if err := token.CheckCalendar(calendarID); err != nil {
+		return err
+	}
+	getter, ok := s.provider.(provider.EventGetter)
+	if !ok {
+		return token.CheckAllCalendars()
+	}
+	_, err := getter.GetEvent(ctx, calendarID, eventID)
+	return err
+}
+
+// updateEvent applies the given fields over the current event, like a PATCH
+// of the HTTP API. Providers that cannot look events up get the fields as a
+// full replacement.
+func (s *Server) updateEvent(ctx context.Context, args json.RawMessage) (any, error) {
+	var in struct {
+		EventID string `json:"event_id"`
+		domain.EventMutation
+	}
+	if err := decode(args, &in); err != nil {
+		return nil, err
+	}
+	token := security.FromContext(ctx)
+	if err := token.CheckCalendar(in.CalendarID); err != nil {
+		return nil, err
+	}
+	getter, ok := s.provider.(provider.EventGetter)
+	if !ok {
+		if err := token.CheckAllCalendars(); err != nil {
+			return nil, err
+		}
+		return s.provider.UpdateEvent(ctx, in.EventID, in.EventMutation)
+	}
+	current, err := getter.GetEvent(ctx, in.CalendarID, in.EventID)
+	if err != nil {
+		return nil, err
+	}
+	if provider.IsInstance(current, in.EventID) {
+		return nil, provider.NotSupportedError{Operation: "update_instance"}
+	}
+	mutation := provider.MutationFromEvent(current)
+	overlay := struct {
+		EventID string `json:"event_id"`
+		*domain.EventMutation
+	}{EventMutation: &mutation}
+	if err := decode(args, &overlay); err != nil {
+		return nil, err
+	}
+	mutation.CalendarID = in.CalendarID
+	return s.provider.UpdateEvent(ctx, in.EventID, mutation)
+}
+
+func (s *Server) listEvents(ctx context.Context, args json.RawMessage) (any, error) {
+	var in struct {
+		CalendarID string    `json:"calendar_id"`
+		From       time.Time `json:"from"`
+		To         time.Time `json:"to"`
+		Expand     *bool     `json:"expand"`
+	}
+	if err := decode(args, &in); err != nil {
+		return nil, err
+	}
+	if in.From.IsZero() {
+		in.From = time.Now().UTC()
+	}
+	if in.To.IsZero() {
+		in.To = in.From.Add(defaultListWindow)
+	}
+	if in.To.Before(in.From) {
+		return nil, provider.InvalidInputError{Field: "to", Reason: "must not be before from"}
+	}
+	if err := security.FromContext(ctx).CheckCalendar(in.CalendarID); err != nil {
+		return nil, err
+	}
+	list := s.provider.ListEvents
+	if lister, ok := s.provider.(provider.SeriesLister); ok && in.Expand != nil && !*in.Expand {
+		list = lister.ListSeries
+	}
+	events, err := list(ctx, in.CalendarID, in.From, in.To)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"from": in.From, "to": in.To, "events": events}, nil
+}
+
+func (s *Server) getEvent(ctx context.Context, args json.RawMessage) (any, error) {
+	var in struct {
+		CalendarID string `json:"calendar_id"`
+		EventID    string `json:"event_id"`
+	}
+	if err := decode(args, &in); err != nil {
+		return nil, err
+	}
+	if err := security.FromContext(ctx).CheckCalendar(in.CalendarID); err != nil {
+		return nil, err
+	}
+	return s.provider.(provider.EventGetter).GetEvent(ctx, in.CalendarID, in.EventID)
+}
+
+func (s *Server) freeBusy(ctx context.Context, args json.RawMessage) (any, error) {
+	var in struct {
+		CalendarIDs []string  `json:"calendar_ids"`
+		From        time.Time `json:"from"`
+		To          time.Time `json:"to"`
+	}
+	if err := decode(args, &in); err != nil {
+		return nil, err
+	}
+	if in.From.IsZero() || in.To.IsZero() || in.To.Before(in.From) {
+		return nil, provider.InvalidInputError{Field: "to", Reason: "from and to are required and to must not be before from"}
+	}
+	ids, err := security.FromContext(ctx).CheckCalendars(in.CalendarIDs)
+	if err != nil {
+		return nil, err
+	}
+	ids, busy, err := freebusy.Collect(ctx, s.provider, ids, in.From, in.To)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"calendars": ids, "busy": busy}, nil
+}
+
+func (s *Server) findSlots(ctx context.Context, args json.RawMessage) (any, error) {
+	var in freebusy.SlotRequest
+	if err := decode(args, &in); err != nil {
+		return nil, err
+	}
+	q, invalid := in.Query()
+	if len(invalid) > 0 {
+		errs := make([]error, 0, len(invalid))
+		for _, e := range invalid {
+			errs = append(errs, e)
+		}
+		return nil, errors.Join(errs...)
+	}
+	ids, err := security.FromContext(ctx).CheckCalendars(in.CalendarIDs)
+	if err != nil {
+		return nil, err
+	}
+	ids, slots, err := freebusy.FindSlots(ctx, s.provider, ids, q)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"timezone": q.Location.String(), "calendars": ids, "slots": slots}, nil
+}
+
+// decode reads tool arguments strictly, so a misspelt argument is reported
+// instead of silently ignored.
+func decode(args json.RawMessage, v any) error {
+	if len(bytes.TrimSpace(args)) == 0 || string(args) == "null" {
+		args = json.RawMessage("{}")
+	}
+	dec := json.NewDecoder(bytes.NewReader(args))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid arguments: %w", err)
+	}
+	return nil
+}
+
+func mutationProperties() map[string]any {
+	return map[string]any{
+		"calendar_id": str("Calendar ID."),
+		"title":       str("Event title."),
+		"description": str("Event description."),
+		"location":    str("Event location."),
+		"start":       dateTime("Event start."),
+		"end":         dateTime("Event end."),
+		"all_day":     boolean("All-day event."),
+		"transparent": boolean("The event does not block time."),
+		"timezone":    str("IANA time zone the times are written in, e.g. Europe/Berlin. Updates keep the event's zone when omitted."),
+		"recurrence":  str("RFC 5545 RRULE value, e.g. FREQ=WEEKLY;BYDAY=MO."),
+		"attendees": map[string]any{"type": "array", "description": "Attendees.", "items": object(map[string]any{
+			"email": str("Attendee email."),
+			"name":  str("Display name."),
+		}, "email")},
+		"reminders": map[string]any{"type": "array", "description": "Reminders.", "items": object(map[string]any{
+			"type":   str("display or email."),
+			"offset": str("RFC 5545 duration relative to the start, e.g. -PT15M."),
+		})},
+	}
+}
+
+func withEventID(props map[string]any) map[string]any {
+	props["event_id"] = str("Event ID.")
+	return props
+}
+
+func object(props map[string]any, required ...string) map[string]any {
+	if props == nil {
+		props = map[string]any{}
+	}
+	schema := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func str(desc string) map[string]any { return map[string]any{"type": "string", "description": desc} }
+
+func strs(desc string) map[string]any {
+	return map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "description": desc}
+}
+
+func dateTime(desc string) map[string]any {
+	return map[string]any{"type": "string", "format": "date-time", "description": desc}
+}
+
+func integer(desc string) map[string]any {
+	return map[string]any{"type": "integer", "description": desc}
+}
+
+func boolean(desc string) map[string]any {
+	return map[string]any{"type": "boolean", "description": desc}
+}
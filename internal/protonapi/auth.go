@@ -27,7 +27,7 @@ func (a *Authenticator) Login(ctx context.Context, username, password string) (A
 		a.client.setStatus(StatusDisconnected)
 		return Auth{}, err
 	}
-	a.client.setClient(client, auth)
+	a.client.adopt(client, auth)
 	return auth, nil
 }
 
@@ -47,6 +47,52 @@ func (a *Authenticator) Submit2FA(ctx context.Context, totpCode string) error {
 	return nil
 }
 
+// SaltKeyPassword derives the passphrase of the user's keys from the mailbox
+// password, which is the login password in single-password mode.
+func (a *Authenticator) SaltKeyPassword(ctx context.Context, mailboxPassword []byte) ([]byte, error) {
+	client, err := a.client.requireClient()
+	if err != nil {
+		return nil, err
+	}
+	user, err := client.GetUser(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+	if len(user.Keys) == 0 {
+		return nil, fmt.Errorf("user has no keys")
+	}
+	salts, err := client.GetSalts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get salts: %w", err)
+	}
+	salted, err := salts.SaltForKey(mailboxPassword, user.Keys.Primary().ID)
+	if err != nil {
+		return nil, fmt.Errorf("salt key password: %w", err)
+	}
+	if len(salted) == 0 {
+		return nil, fmt.Errorf("salt key password: invalid key salt")
+	}
+	return salted, nil
+}
+
+// Logout revokes the session upstream and forgets it locally.
+func (a *Authenticator) Logout(ctx context.Context) error {
+	client, err := a.client.requireClient()
+	if err != nil {
+		return err
+	}
+	err = client.AuthDelete(ctx)
+	a.client.mu.Lock()
+	a.client.client = nil
+	a.client.auth = Auth{}
+	a.client.status = StatusDisconnected
+	a.client.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("revoke session: %w", err)
+	}
+	return nil
+}
+
 func (a *Authenticator) Refresh(ctx context.Context, uid, refreshToken string) (Auth, error) {
 	if uid == "" || refreshToken == "" {
 		_, auth := a.client.session()
@@ -61,9 +107,9 @@ func (a *Authenticator) Refresh(ctx context.Context, uid, refreshToken string) (
 	}
 	client, auth, err := a.client.manager.NewClientWithRefresh(ctx, uid, refreshToken)
 	if err != nil {
-		a.client.setStatus(StatusDisconnected)
-		return Auth{}, err
+		return Auth{}, a.client.expire(err)
 	}
-	a.client.setClient(client, auth)
+	a.client.adopt(client, auth)
+	a.client.rotate(auth)
 	return auth, nil
 }
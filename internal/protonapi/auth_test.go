@@ -2,6 +2,7 @@ package protonapi
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"testing"
 
@@ -55,4 +56,63 @@ func TestAuthenticatorErrors(t *testing.T) {
 	if err := a.Submit2FA(context.Background(), ""); err == nil {
 		t.Fatal("expected empty code error")
 	}
+	mgr.refreshErr = errors.New("revoked")
+	if _, err := a.Refresh(context.Background(), "uid", "r1"); !errors.Is(err, ErrSessionExpired) || c.Status() != StatusDisconnected {
+		t.Fatalf("expected expired session, got %v (%s)", err, c.Status())
+	}
+}
+
+func TestAuthenticatorSaltKeyPasswordAndLogout(t *testing.T) {
+	fake := &fakeCalendarClient{
+		user:  proton.User{Keys: proton.Keys{{ID: "key-1", Primary: true}}},
+		salts: proton.Salts{{ID: "key-1", KeySalt: base64.StdEncoding.EncodeToString([]byte("0123456789abcdef"))}},
+	}
+	c := &Client{client: fake, auth: Auth{UID: "uid", AccessToken: "acc"}}
+	a := NewAuthenticator(c)
+
+	salted, err := a.SaltKeyPassword(context.Background(), []byte("mailbox"))
+	if err != nil || len(salted) != 31 {
+		t.Fatalf("salt key password = %q, %v", salted, err)
+	}
+	if user, err := c.GetUser(context.Background()); err != nil || user.Keys[0].ID != "key-1" {
+		t.Fatalf("get user = %+v, %v", user, err)
+	}
+
+	fake.salts = nil
+	if _, err := a.SaltKeyPassword(context.Background(), []byte("mailbox")); err == nil {
+		t.Fatal("expected missing salt error")
+	}
+	fake.user = proton.User{}
+	if _, err := a.SaltKeyPassword(context.Background(), []byte("mailbox")); err == nil {
+		t.Fatal("expected missing keys error")
+	}
+	fake.err = errors.New("down")
+	if _, err := a.SaltKeyPassword(context.Background(), []byte("mailbox")); err == nil {
+		t.Fatal("expected user error")
+	}
+	if _, err := c.GetUser(context.Background()); err == nil {
+		t.Fatal("expected get user error")
+	}
+
+	if err := a.Logout(context.Background()); err == nil || !fake.deleted {
+		t.Fatalf("expected revoke error, got %v", err)
+	}
+	if c.Status() != StatusDisconnected {
+		t.Fatalf("expected disconnected, got %s", c.Status())
+	}
+	if err := a.Logout(context.Background()); err == nil {
+		t.Fatal("expected missing session error")
+	}
+	if _, err := a.SaltKeyPassword(context.Background(), nil); err == nil {
+		t.Fatal("expected missing session error")
+	}
+	if _, err := c.GetUser(context.Background()); err == nil {
+		t.Fatal("expected missing session error")
+	}
+
+	fake.err = nil
+	c.client = fake
+	if err := a.Logout(context.Background()); err != nil {
+		t.Fatalf("logout: %v", err)
+	}
 }
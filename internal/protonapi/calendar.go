@@ -2,6 +2,7 @@ package protonapi
 
 import (
 	"context"
+	"net/http"
 	"net/url"
 )
 
@@ -12,8 +13,7 @@ func (c *Client) GetCalendars(ctx context.Context) ([]Calendar, error) {
 	}
 	items, err := client.GetCalendars(ctx)
 	if err != nil {
-		c.setStatus(StatusDisconnected)
-		return nil, err
+		return nil, c.fail(err)
 	}
 	c.setStatus(StatusConnected)
 	return items, nil
@@ -26,8 +26,7 @@ func (c *Client) GetCalendar(ctx context.Context, id string) (Calendar, error) {
 	}
 	item, err := client.GetCalendar(ctx, id)
 	if err != nil {
-		c.setStatus(StatusDisconnected)
-		return Calendar{}, err
+		return Calendar{}, c.fail(err)
 	}
 	c.setStatus(StatusConnected)
 	return item, nil
@@ -40,8 +39,7 @@ func (c *Client) GetCalendarKeys(ctx context.Context, id string) (CalendarKeys,
 	}
 	items, err := client.GetCalendarKeys(ctx, id)
 	if err != nil {
-		c.setStatus(StatusDisconnected)
-		return nil, err
+		return nil, c.fail(err)
 	}
 	c.setStatus(StatusConnected)
 	return items, nil
@@ -54,8 +52,7 @@ func (c *Client) GetCalendarMembers(ctx context.Context, id string) ([]CalendarM
 	}
 	items, err := client.GetCalendarMembers(ctx, id)
 	if err != nil {
-		c.setStatus(StatusDisconnected)
-		return nil, err
+		return nil, c.fail(err)
 	}
 	c.setStatus(StatusConnected)
 	return items, nil
@@ -68,22 +65,20 @@ func (c *Client) GetCalendarPassphrase(ctx context.Context, id string) (Calendar
 	}
 	item, err := client.GetCalendarPassphrase(ctx, id)
 	if err != nil {
-		c.setStatus(StatusDisconnected)
-		return CalendarPassphrase{}, err
+		return CalendarPassphrase{}, c.fail(err)
 	}
 	c.setStatus(StatusConnected)
 	return item, nil
 }
 
-func (c *Client) GetCalendarEvents(ctx context.Context, id string, page, pageSize int) ([]CalendarEvent, error) {
+func (c *Client) GetCalendarEvents(ctx context.Context, id string, page, pageSize int, filter EventFilter) ([]CalendarEvent, error) {
 	client, err := c.requireClient()
 	if err != nil {
 		return nil, err
 	}
-	items, err := client.GetCalendarEvents(ctx, id, page, pageSize, url.Values{})
+	items, err := client.GetCalendarEvents(ctx, id, page, pageSize, filter.values())
 	if err != nil {
-		c.setStatus(StatusDisconnected)
-		return nil, err
+		return nil, c.fail(err)
 	}
 	c.setStatus(StatusConnected)
 	return items, nil
@@ -96,8 +91,7 @@ func (c *Client) GetCalendarEvent(ctx context.Context, calID, eventID string) (C
 	}
 	item, err := client.GetCalendarEvent(ctx, calID, eventID)
 	if err != nil {
-		c.setStatus(StatusDisconnected)
-		return CalendarEvent{}, err
+		return CalendarEvent{}, c.fail(err)
 	}
 	c.setStatus(StatusConnected)
 	return item, nil
@@ -110,9 +104,75 @@ func (c *Client) GetAddresses(ctx context.Context) ([]Address, error) {
 	}
 	items, err := client.GetAddresses(ctx)
 	if err != nil {
-		c.setStatus(StatusDisconnected)
-		return nil, err
+		return nil, c.fail(err)
 	}
 	c.setStatus(StatusConnected)
 	return items, nil
 }
+
+func (c *Client) GetUser(ctx context.Context) (User, error) {
+	client, err := c.requireClient()
+	if err != nil {
+		return User{}, err
+	}
+	user, err := client.GetUser(ctx)
+	if err != nil {
+		return User{}, c.fail(err)
+	}
+	c.setStatus(StatusConnected)
+	return user, nil
+}
+
+// SyncCalendarEvents creates, updates and deletes events in one calendar. The
+// results are returned in request order; per-event failures are reported as
+// an error naming the first failing operation.
+func (c *Client) SyncCalendarEvents(ctx context.Context, calendarID string, req CalendarEventSyncReq) ([]CalendarEventSyncResult, error) {
+	var res struct {
+		Responses []CalendarEventSyncResult
+	}
+	path := "/calendar/v1/" + url.PathEscape(calendarID) + "/events/sync"
+	if err := c.doJSON(ctx, http.MethodPut, path, req, &res); err != nil {
+		return nil, err
+	}
+	for _, r := range res.Responses {
+		if r.Response.Code != codeSuccess {
+			return res.Responses, &APIError{Status: http.StatusUnprocessableEntity, Code: r.Response.Code, Message: r.Response.Error}
+		}
+	}
+	return res.Responses, nil
+}
+
+// GetLatestCalendarModelEventID returns the current event loop cursor of a
+// calendar, from which GetCalendarModelEvents reports later changes.
+func (c *Client) GetLatestCalendarModelEventID(ctx context.Context, calendarID string) (string, error) {
+	var res struct {
+		CalendarModelEventID string
+	}
+	path := "/calendar/v1/" + url.PathEscape(calendarID) + "/modelevents/latest"
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &res); err != nil {
+		return "", err
+	}
+	return res.CalendarModelEventID, nil
+}
+
+// GetCalendarModelEvents returns the event changes after cursor.
+func (c *Client) GetCalendarModelEvents(ctx context.Context, calendarID, cursor string) (CalendarModelEvents, error) {
+	var res CalendarModelEvents
+	path := "/calendar/v1/" + url.PathEscape(calendarID) + "/modelevents/" + url.PathEscape(cursor)
+	if err := c.doJSON(ctx, http.MethodGet, path, nil, &res); err != nil {
+		return CalendarModelEvents{}, err
+	}
+	return res, nil
+}
+
+// GetCalendarUserSettings returns the account's calendar preferences, such as
+// the primary time zone.
+func (c *Client) GetCalendarUserSettings(ctx context.Context) (CalendarUserSettings, error) {
+	var res struct {
+		CalendarUserSettings CalendarUserSettings
+	}
+	if err := c.doJSON(ctx, http.MethodGet, "/settings/calendar", nil, &res); err != nil {
+		return CalendarUserSettings{}, err
+	}
+	return res.CalendarUserSettings, nil
+}
@@ -2,9 +2,12 @@ package protonapi
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"net/url"
 	"sync"
+	"time"
 
 	proton "github.com/ProtonMail/go-proton-api"
 	"github.com/sevenofnine/proton-calendar-bridge/internal/version"
@@ -12,6 +15,17 @@ import (
 
 const DefaultBaseURL = proton.DefaultHostURL
 
+// ErrSessionExpired reports that the access token was rejected and could not
+// be refreshed, so the user has to log in again.
+var ErrSessionExpired = errors.New("proton session expired; log in again")
+
+// ErrUnavailable and ErrRateLimited classify failed requests: the API could
+// not be reached or answered with a server error, or it throttled the client.
+var (
+	ErrUnavailable = errors.New("upstream unavailable")
+	ErrRateLimited = errors.New("upstream rate limit exceeded")
+)
+
 type ConnectionStatus string
 
 const (
@@ -36,16 +50,26 @@ type CalendarAPI interface {
 	GetCalendarEvents(ctx context.Context, calendarID string, page, pageSize int, filter url.Values) ([]proton.CalendarEvent, error)
 	GetCalendarEvent(ctx context.Context, calendarID, eventID string) (proton.CalendarEvent, error)
 	GetAddresses(ctx context.Context) ([]proton.Address, error)
+	GetUser(ctx context.Context) (proton.User, error)
+	GetSalts(ctx context.Context) (proton.Salts, error)
+	AuthDelete(ctx context.Context) error
 }
 
 type Client struct {
-	manager ManagerAPI
-	client  CalendarAPI
-	appVer  string
+	manager    ManagerAPI
+	client     CalendarAPI
+	appVer     string
+	baseURL    string
+	httpClient HTTPDoer
+	onAuth     func(Auth)
 
-	mu     sync.RWMutex
-	auth   Auth
-	status ConnectionStatus
+	// refreshMu serialises token refreshes so concurrent 401s spend the
+	// refresh token once.
+	refreshMu sync.Mutex
+	mu        sync.RWMutex
+	auth      Auth
+	status    ConnectionStatus
+	authErr   error
 }
 
 type ClientOptions struct {
@@ -55,6 +79,12 @@ type ClientOptions struct {
 	AccessToken  string
 	RefreshToken string
 	Manager      ManagerAPI
+	// HTTPClient sends the raw requests for endpoints go-proton-api does not
+	// wrap, such as calendar event sync.
+	HTTPClient HTTPDoer
+	// OnAuth is called with the new tokens whenever the session is refreshed,
+	// so rotated refresh tokens can be persisted.
+	OnAuth func(Auth)
 }
 
 func NewClient(opts ClientOptions) *Client {
@@ -73,25 +103,154 @@ func NewClient(opts ClientOptions) *Client {
 			proton.WithAppVersion(fmt.Sprintf("proton-calendar-bridge@%s", appVersion)),
 		)
 	}
+	httpClient := opts.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
 	c := &Client{
-		manager: manager,
-		appVer:  fmt.Sprintf("proton-calendar-bridge@%s", appVersion),
-		status:  StatusUnknown,
+		manager:    manager,
+		appVer:     fmt.Sprintf("proton-calendar-bridge@%s", appVersion),
+		baseURL:    baseURL,
+		httpClient: httpClient,
+		onAuth:     opts.OnAuth,
+		status:     StatusUnknown,
 	}
 	if opts.UID != "" && opts.AccessToken != "" {
-		c.client = manager.NewClient(opts.UID, opts.AccessToken, opts.RefreshToken)
+		client := manager.NewClient(opts.UID, opts.AccessToken, opts.RefreshToken)
+		c.watch(client)
+		c.client = client
 		c.auth = Auth{UID: opts.UID, AccessToken: opts.AccessToken, RefreshToken: opts.RefreshToken}
 	}
 	return c
 }
 
 func (c *Client) SetSession(auth Auth) {
+	var client *proton.Client
+	if c.manager != nil {
+		client = c.manager.NewClient(auth.UID, auth.AccessToken, auth.RefreshToken)
+		c.watch(client)
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.auth = auth
-	if c.manager != nil {
-		c.client = c.manager.NewClient(auth.UID, auth.AccessToken, auth.RefreshToken)
+	c.authErr = nil
+	if client != nil {
+		c.client = client
+	}
+}
+
+// AuthError returns why the session was lost, or nil while it is usable.
+func (c *Client) AuthError() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.authErr
+}
+
+// watch keeps the session in step with the refreshes go-proton-api performs
+// on its own when a request gets a 401.
+func (c *Client) watch(client *proton.Client) {
+	if client == nil {
+		return
+	}
+	client.AddAuthHandler(func(auth proton.Auth) { c.rotate(auth) })
+	client.AddDeauthHandler(func() { c.expire(errors.New("refresh token revoked")) })
+}
+
+// rotate records refreshed tokens and hands them to OnAuth.
+func (c *Client) rotate(auth Auth) {
+	c.mu.Lock()
+	if auth.UID == "" {
+		auth.UID = c.auth.UID
+	}
+	c.auth = auth
+	c.authErr = nil
+	c.status = StatusConnected
+	onAuth := c.onAuth
+	c.mu.Unlock()
+	if onAuth != nil {
+		onAuth(auth)
+	}
+}
+
+// expire marks the session as lost after a failed refresh.
+func (c *Client) expire(cause error) error {
+	err := fmt.Errorf("%w: %v", ErrSessionExpired, cause)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status = StatusDisconnected
+	c.authErr = err
+	return err
+}
+
+// refresh exchanges the refresh token for new tokens unless another request
+// already did so since staleAccessToken was sent.
+func (c *Client) refresh(ctx context.Context, staleAccessToken string) error {
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	_, auth := c.session()
+	if auth.AccessToken != staleAccessToken {
+		return nil
+	}
+	if auth.UID == "" || auth.RefreshToken == "" {
+		return c.expire(errors.New("no refresh token"))
+	}
+	client, fresh, err := c.manager.NewClientWithRefresh(ctx, auth.UID, auth.RefreshToken)
+	if err != nil {
+		return c.expire(err)
+	}
+	c.adopt(client, fresh)
+	c.rotate(fresh)
+	return nil
+}
+
+// adopt switches to a freshly authenticated client.
+func (c *Client) adopt(client *proton.Client, auth Auth) {
+	c.watch(client)
+	c.setClient(client, auth)
+}
+
+// fail marks the connection as down after a transport, server or auth
+// failure, and reports an expired session when a refresh on the way failed.
+// Other API errors, such as a 404 for a missing event, leave the status
+// alone: the API answered.
+func (c *Client) fail(err error) error {
+	if authErr := c.AuthError(); authErr != nil {
+		c.setStatus(StatusDisconnected)
+		return fmt.Errorf("%w (%v)", authErr, err)
+	}
+	classified := classify(err)
+	if errors.Is(classified, ErrUnavailable) || apiStatus(err) == http.StatusUnauthorized {
+		c.setStatus(StatusDisconnected)
+	}
+	return classified
+}
+
+// classify tags go-proton-api errors with ErrUnavailable or ErrRateLimited.
+func classify(err error) error {
+	var netErr *proton.NetError
+	if errors.As(err, &netErr) {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	switch status := apiStatus(err); {
+	case status == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %w", ErrRateLimited, err)
+	case status >= 500:
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return err
+}
+
+// apiStatus returns the HTTP status of a go-proton-api error, or 0.
+func apiStatus(err error) int {
+	var apiErr *proton.APIError
+	var apiErrValue proton.APIError
+	switch {
+	case errors.As(err, &apiErr):
+		return apiErr.Status
+	case errors.As(err, &apiErrValue):
+		return apiErrValue.Status
 	}
+	return 0
 }
 
 func (c *Client) Status() ConnectionStatus {
@@ -117,6 +276,7 @@ func (c *Client) setClient(client CalendarAPI, auth Auth) {
 	defer c.mu.Unlock()
 	c.client = client
 	c.auth = auth
+	c.authErr = nil
 	c.status = StatusConnected
 }
 
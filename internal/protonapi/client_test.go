@@ -2,8 +2,12 @@ package protonapi
 
 import (
 	"context"
+	"errors"
+	"io"
+	"net/http"
 	"net/url"
 	"testing"
+	"time"
 
 	proton "github.com/ProtonMail/go-proton-api"
 )
@@ -45,6 +49,10 @@ type fakeCalendarClient struct {
 	event      proton.CalendarEvent
 	addresses  []proton.Address
 	err        error
+	filter     url.Values
+	user       proton.User
+	salts      proton.Salts
+	deleted    bool
 }
 
 func (f *fakeCalendarClient) Auth2FA(context.Context, proton.Auth2FAReq) error { return f.auth2FAErr }
@@ -63,7 +71,8 @@ func (f *fakeCalendarClient) GetCalendarMembers(context.Context, string) ([]prot
 func (f *fakeCalendarClient) GetCalendarPassphrase(context.Context, string) (proton.CalendarPassphrase, error) {
 	return f.passphrase, f.err
 }
-func (f *fakeCalendarClient) GetCalendarEvents(context.Context, string, int, int, url.Values) ([]proton.CalendarEvent, error) {
+func (f *fakeCalendarClient) GetCalendarEvents(_ context.Context, _ string, _, _ int, filter url.Values) ([]proton.CalendarEvent, error) {
+	f.filter = filter
 	return f.events, f.err
 }
 func (f *fakeCalendarClient) GetCalendarEvent(context.Context, string, string) (proton.CalendarEvent, error) {
@@ -72,6 +81,14 @@ func (f *fakeCalendarClient) GetCalendarEvent(context.Context, string, string) (
 func (f *fakeCalendarClient) GetAddresses(context.Context) ([]proton.Address, error) {
 	return f.addresses, f.err
 }
+func (f *fakeCalendarClient) GetUser(context.Context) (proton.User, error) { return f.user, f.err }
+func (f *fakeCalendarClient) GetSalts(context.Context) (proton.Salts, error) {
+	return f.salts, f.err
+}
+func (f *fakeCalendarClient) AuthDelete(context.Context) error {
+	f.deleted = true
+	return f.err
+}
 
 func TestClientSetSessionUsesManager(t *testing.T) {
 	mgr := &fakeManager{}
@@ -111,7 +128,7 @@ func TestClientCalendarMethodsAndStatus(t *testing.T) {
 	if _, err := c.GetCalendarPassphrase(context.Background(), "c1"); err != nil {
 		t.Fatalf("GetCalendarPassphrase: %v", err)
 	}
-	if _, err := c.GetCalendarEvents(context.Background(), "c1", 0, 10); err != nil {
+	if _, err := c.GetCalendarEvents(context.Background(), "c1", 0, 10, EventFilter{}); err != nil {
 		t.Fatalf("GetCalendarEvents: %v", err)
 	}
 	if _, err := c.GetCalendarEvent(context.Background(), "c1", "e1"); err != nil {
@@ -131,3 +148,37 @@ func TestClientRequiresSession(t *testing.T) {
 		t.Fatal("expected missing session error")
 	}
 }
+
+func TestClientFailStatus(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want ConnectionStatus
+	}{
+		{&proton.APIError{Status: http.StatusNotFound}, StatusConnected},
+		{proton.APIError{Status: http.StatusUnprocessableEntity}, StatusConnected},
+		{&proton.APIError{Status: http.StatusTooManyRequests}, StatusConnected},
+		{errors.New("decode failed"), StatusConnected},
+		{&proton.APIError{Status: http.StatusUnauthorized}, StatusDisconnected},
+		{&proton.APIError{Status: http.StatusBadGateway}, StatusDisconnected},
+		{&proton.NetError{Cause: io.EOF, Message: "no response"}, StatusDisconnected},
+	} {
+		c := &Client{status: StatusConnected}
+		if err := c.fail(tc.err); err == nil || c.Status() != tc.want {
+			t.Fatalf("fail(%v): status %s, want %s", tc.err, c.Status(), tc.want)
+		}
+	}
+}
+
+func TestEventFilterValues(t *testing.T) {
+	if v := (EventFilter{}).values(); len(v) != 0 {
+		t.Fatalf("expected empty filter, got %v", v)
+	}
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	v := EventFilter{Start: start, End: start.AddDate(0, 0, 7), Type: EventQueryFullDayBeforeWindow}.values()
+	if v.Get("Start") != "1772409600" || v.Get("End") != "1773014400" || v.Get("Timezone") != "UTC" || v.Get("Type") != "3" {
+		t.Fatalf("unexpected filter: %v", v)
+	}
+	if v := (EventFilter{Start: start, End: start, Timezone: "Europe/Berlin"}).values(); v.Get("Timezone") != "Europe/Berlin" || v.Get("Type") != "0" {
+		t.Fatalf("unexpected filter: %v", v)
+	}
+}
@@ -0,0 +1,136 @@
+package protonapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HTTPDoer sends raw API requests that go-proton-api has no wrapper for.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Proton response codes signalling success.
+const (
+	codeSuccess      = 1000
+	codeMultiSuccess = 1001
+)
+
+// APIError is an error envelope returned by the Proton API. RetryAfter is
+// set from the Retry-After header of throttled responses.
+type APIError struct {
+	Status     int
+	Code       int
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("proton api: status %d code %d", e.Status, e.Code)
+	}
+	return fmt.Sprintf("proton api: status %d code %d: %s", e.Status, e.Code, e.Message)
+}
+
+// Unwrap reports throttling and server errors as ErrRateLimited and
+// ErrUnavailable.
+func (e *APIError) Unwrap() error {
+	switch {
+	case e.Status == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.Status >= 500:
+		return ErrUnavailable
+	}
+	return nil
+}
+
+// RetryAfterDelay returns how long the API asked the client to wait.
+func (e *APIError) RetryAfterDelay() time.Duration {
+	return e.RetryAfter
+}
+
+// doJSON sends an authenticated request to path below the API base URL and
+// decodes the response into out. A 401 refreshes the session and retries
+// once.
+func (c *Client) doJSON(ctx context.Context, method, path string, in, out any) error {
+	_, auth := c.session()
+	if auth.UID == "" || auth.AccessToken == "" {
+		return fmt.Errorf("proton session is not initialized")
+	}
+	err := c.doJSONOnce(ctx, method, path, auth, in, out)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusUnauthorized {
+		return err
+	}
+	if err := c.refresh(ctx, auth.AccessToken); err != nil {
+		return err
+	}
+	_, auth = c.session()
+	return c.doJSONOnce(ctx, method, path, auth, in, out)
+}
+
+func (c *Client) doJSONOnce(ctx context.Context, method, path string, auth Auth, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("encode request: %w", err)
+		}
+		body = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.baseURL, "/")+path, body)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.protonmail.v1+json")
+	req.Header.Set("x-pm-appversion", c.appVer)
+	req.Header.Set("x-pm-uid", auth.UID)
+	req.Header.Set("Authorization", "Bearer "+auth.AccessToken)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		c.setStatus(StatusDisconnected)
+		return fmt.Errorf("%s %s: %w: %w", method, path, ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.setStatus(StatusDisconnected)
+		return fmt.Errorf("read response: %w: %w", ErrUnavailable, err)
+	}
+
+	var envelope struct {
+		Code  int
+		Error string
+	}
+	_ = json.Unmarshal(raw, &envelope)
+	if resp.StatusCode >= 400 || envelope.Code != codeSuccess && envelope.Code != codeMultiSuccess {
+		if resp.StatusCode >= 500 {
+			c.setStatus(StatusDisconnected)
+		}
+		apiErr := &APIError{Status: resp.StatusCode, Code: envelope.Code, Message: envelope.Error}
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			apiErr.RetryAfter = time.Duration(secs) * time.Second
+		}
+		return apiErr
+	}
+	c.setStatus(StatusConnected)
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
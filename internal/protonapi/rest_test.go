@@ -0,0 +1,277 @@
+package protonapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	proton "github.com/ProtonMail/go-proton-api"
+)
+
+func TestSyncCalendarEvents(t *testing.T) {
+	var got struct {
+		method, path, uid, auth, appVersion string
+		body                                CalendarEventSyncReq
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.method, got.path = r.Method, r.URL.Path
+		got.uid, got.auth, got.appVersion = r.Header.Get("x-pm-uid"), r.Header.Get("Authorization"), r.Header.Get("x-pm-appversion")
+		_ = json.NewDecoder(r.Body).Decode(&got.body)
+		_, _ = io.WriteString(w, `{"Code":1001,"Responses":[{"Index":0,"Response":{"Code":1000,"Event":{"ID":"new-id","CalendarID":"cal/1"}}}]}`)
+	}))
+	defer srv.Close()
+
+	c := NewClient(ClientOptions{BaseURL: srv.URL + "/api/", AppVersion: "1.2.3", UID: "uid", AccessToken: "acc", Manager: &fakeManager{}})
+	res, err := c.SyncCalendarEvents(context.Background(), "cal/1", CalendarEventSyncReq{
+		MemberID: "member",
+		Events:   []CalendarEventSync{{Event: &CalendarEventData{Permissions: 1, SharedEventContent: []CalendarEventContent{{Type: CalendarEventTypeSigned, Data: "x"}}}}},
+	})
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if len(res) != 1 || res[0].Response.Event.ID != "new-id" {
+		t.Fatalf("unexpected results: %+v", res)
+	}
+	if got.method != http.MethodPut || got.path != "/api/calendar/v1/cal%2F1/events/sync" && got.path != "/api/calendar/v1/cal/1/events/sync" {
+		t.Fatalf("unexpected request %s %s", got.method, got.path)
+	}
+	if got.uid != "uid" || got.auth != "Bearer acc" || got.appVersion != "proton-calendar-bridge@1.2.3" {
+		t.Fatalf("unexpected headers uid=%q auth=%q app=%q", got.uid, got.auth, got.appVersion)
+	}
+	if got.body.MemberID != "member" || len(got.body.Events) != 1 || got.body.Events[0].Event.SharedEventContent[0].Data != "x" {
+		t.Fatalf("unexpected body: %+v", got.body)
+	}
+	if c.Status() != StatusConnected {
+		t.Fatalf("expected connected status, got %s", c.Status())
+	}
+}
+
+func TestSyncCalendarEventsErrors(t *testing.T) {
+	responses := []struct {
+		status int
+		body   string
+	}{
+		{http.StatusOK, `{"Code":1001,"Responses":[{"Index":0,"Response":{"Code":2001,"Error":"Invalid event"}}]}`},
+		{http.StatusUnprocessableEntity, `{"Code":2000,"Error":"Bad request"}`},
+		{http.StatusUnauthorized, `{"Code":401,"Error":"Invalid access token"}`},
+		{http.StatusOK, `not json`},
+	}
+	i := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(responses[i].status)
+		_, _ = io.WriteString(w, responses[i].body)
+		i++
+	}))
+	defer srv.Close()
+
+	c := NewClient(ClientOptions{BaseURL: srv.URL, UID: "uid", AccessToken: "acc", Manager: &fakeManager{}})
+	for _, r := range responses {
+		_, err := c.SyncCalendarEvents(context.Background(), "cal", CalendarEventSyncReq{})
+		var apiErr *APIError
+		if r.status == http.StatusUnauthorized {
+			// Without a refresh token the 401 cannot be recovered from.
+			if !errors.Is(err, ErrSessionExpired) || c.Status() != StatusDisconnected {
+				t.Fatalf("expected expired session, got %v (%s)", err, c.Status())
+			}
+		} else if !errors.As(err, &apiErr) {
+			t.Fatalf("expected api error, got %v", err)
+		}
+	}
+	if c.Status() != StatusDisconnected || c.AuthError() == nil {
+		t.Fatalf("expected disconnected after 401, got %s", c.Status())
+	}
+
+	if _, err := NewClient(ClientOptions{Manager: &fakeManager{}}).SyncCalendarEvents(context.Background(), "cal", CalendarEventSyncReq{}); err == nil {
+		t.Fatal("expected missing session error")
+	}
+	c = NewClient(ClientOptions{BaseURL: "http://127.0.0.1:0", UID: "uid", AccessToken: "acc", Manager: &fakeManager{}})
+	if _, err := c.SyncCalendarEvents(context.Background(), "cal", CalendarEventSyncReq{}); err == nil || !strings.Contains(err.Error(), "PUT") {
+		t.Fatalf("expected transport error, got %v", err)
+	}
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	if got := (&APIError{Status: 500, Code: 0}).Error(); got != "proton api: status 500 code 0" {
+		t.Fatalf("unexpected message %q", got)
+	}
+}
+
+func TestErrorClassification(t *testing.T) {
+	status := http.StatusTooManyRequests
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(status)
+		_, _ = io.WriteString(w, `{"Code":2028,"Error":"slow down"}`)
+	}))
+	defer srv.Close()
+
+	c := NewClient(ClientOptions{BaseURL: srv.URL, UID: "uid", AccessToken: "acc", Manager: &fakeManager{}})
+	_, err := c.GetLatestCalendarModelEventID(context.Background(), "cal")
+	var apiErr *APIError
+	if !errors.Is(err, ErrRateLimited) || !errors.As(err, &apiErr) || apiErr.RetryAfterDelay() != 30*time.Second {
+		t.Fatalf("expected rate limit with retry delay, got %v", err)
+	}
+	status = http.StatusServiceUnavailable
+	if _, err := c.GetLatestCalendarModelEventID(context.Background(), "cal"); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected unavailable, got %v", err)
+	}
+	status = http.StatusUnprocessableEntity
+	if _, err := c.GetLatestCalendarModelEventID(context.Background(), "cal"); errors.Is(err, ErrUnavailable) || errors.Is(err, ErrRateLimited) {
+		t.Fatalf("expected unclassified error, got %v", err)
+	}
+	c = NewClient(ClientOptions{BaseURL: "http://127.0.0.1:0", UID: "uid", AccessToken: "acc", Manager: &fakeManager{}})
+	if _, err := c.GetLatestCalendarModelEventID(context.Background(), "cal"); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("expected transport error to be unavailable, got %v", err)
+	}
+
+	cases := []struct {
+		err  error
+		want error
+	}{
+		{&proton.NetError{Cause: io.EOF, Message: "no response"}, ErrUnavailable},
+		{&proton.APIError{Status: http.StatusTooManyRequests}, ErrRateLimited},
+		{proton.APIError{Status: http.StatusBadGateway}, ErrUnavailable},
+	}
+	for _, tc := range cases {
+		if got := classify(tc.err); !errors.Is(got, tc.want) || !errors.Is(got, tc.err) {
+			t.Fatalf("classify(%v) = %v", tc.err, got)
+		}
+	}
+	plain := errors.New("bad request")
+	if classify(&proton.APIError{Status: 400}) == nil || classify(plain) != plain {
+		t.Fatal("expected other errors to pass through")
+	}
+}
+
+func TestCalendarModelEvents(t *testing.T) {
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		if strings.HasSuffix(r.URL.Path, "/latest") {
+			_, _ = io.WriteString(w, `{"Code":1000,"CalendarModelEventID":"cursor-1"}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"Code":1000,"CalendarModelEventID":"cursor-2","More":1,"Refresh":0,"Events":[
+			{"ID":"ev-1","Action":1,"Event":{"ID":"ev-1","CalendarID":"cal"}},
+			{"ID":"ev-2","Action":0}]}`)
+	}))
+	defer srv.Close()
+
+	c := NewClient(ClientOptions{BaseURL: srv.URL, UID: "uid", AccessToken: "acc", Manager: &fakeManager{}})
+	cursor, err := c.GetLatestCalendarModelEventID(context.Background(), "cal")
+	if err != nil || cursor != "cursor-1" {
+		t.Fatalf("latest cursor = %q, %v", cursor, err)
+	}
+	page, err := c.GetCalendarModelEvents(context.Background(), "cal", cursor)
+	if err != nil {
+		t.Fatalf("model events: %v", err)
+	}
+	if page.CalendarModelEventID != "cursor-2" || !bool(page.More) || len(page.Events) != 2 {
+		t.Fatalf("unexpected page: %+v", page)
+	}
+	if page.Events[0].Action != EventActionCreate || page.Events[0].Event.ID != "ev-1" || page.Events[1].Action != EventActionDelete {
+		t.Fatalf("unexpected changes: %+v", page.Events)
+	}
+	want := []string{"GET /calendar/v1/cal/modelevents/latest", "GET /calendar/v1/cal/modelevents/cursor-1"}
+	if len(paths) != 2 || paths[0] != want[0] || paths[1] != want[1] {
+		t.Fatalf("unexpected requests: %v", paths)
+	}
+
+	c = NewClient(ClientOptions{Manager: &fakeManager{}})
+	if _, err := c.GetLatestCalendarModelEventID(context.Background(), "cal"); err == nil {
+		t.Fatal("expected missing session error")
+	}
+	if _, err := c.GetCalendarModelEvents(context.Background(), "cal", "x"); err == nil {
+		t.Fatal("expected missing session error")
+	}
+}
+
+func TestDoJSONRefreshesOn401(t *testing.T) {
+	var tokens []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		if r.Header.Get("Authorization") != "Bearer a2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = io.WriteString(w, `{"Code":401,"Error":"Invalid access token"}`)
+			return
+		}
+		_, _ = io.WriteString(w, `{"Code":1000,"CalendarModelEventID":"cursor"}`)
+	}))
+	defer srv.Close()
+
+	var rotated []Auth
+	mgr := &fakeManager{refreshClient: &proton.Client{}, refreshAuth: proton.Auth{UID: "uid", AccessToken: "a2", RefreshToken: "r2"}}
+	c := NewClient(ClientOptions{BaseURL: srv.URL, UID: "uid", AccessToken: "a1", RefreshToken: "r1", Manager: mgr, OnAuth: func(a Auth) { rotated = append(rotated, a) }})
+	cursor, err := c.GetLatestCalendarModelEventID(context.Background(), "cal")
+	if err != nil || cursor != "cursor" {
+		t.Fatalf("expected retried request to succeed, got %q %v", cursor, err)
+	}
+	if len(tokens) != 2 || tokens[0] != "Bearer a1" || tokens[1] != "Bearer a2" {
+		t.Fatalf("unexpected request tokens %v", tokens)
+	}
+	if len(rotated) != 1 || rotated[0].RefreshToken != "r2" || c.Status() != StatusConnected || c.AuthError() != nil {
+		t.Fatalf("expected rotated session, got %+v status=%s", rotated, c.Status())
+	}
+	if _, auth := c.session(); auth.AccessToken != "a2" || auth.RefreshToken != "r2" {
+		t.Fatalf("session not updated: %+v", auth)
+	}
+
+	// A rejected refresh token disconnects with an expired session error.
+	mgr.refreshErr = errors.New("invalid refresh token")
+	c.SetSession(Auth{UID: "uid", AccessToken: "a3", RefreshToken: "r3"})
+	_, err = c.GetLatestCalendarModelEventID(context.Background(), "cal")
+	if !errors.Is(err, ErrSessionExpired) || !strings.Contains(err.Error(), "invalid refresh token") {
+		t.Fatalf("expected expired session, got %v", err)
+	}
+	if c.Status() != StatusDisconnected || !errors.Is(c.AuthError(), ErrSessionExpired) {
+		t.Fatalf("expected disconnected, got %s %v", c.Status(), c.AuthError())
+	}
+
+	// Library-level errors after a failed refresh name the expired session.
+	if err := c.fail(errors.New("boom")); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("expected expired session wrapper, got %v", err)
+	}
+	c.SetSession(Auth{UID: "uid", AccessToken: "a4"})
+	if err := c.fail(errors.New("boom")); errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("expected plain error after new session, got %v", err)
+	}
+}
+
+func TestClientFollowsLibraryRefresh(t *testing.T) {
+	var rotated Auth
+	c := NewClient(ClientOptions{Manager: &fakeManager{}, OnAuth: func(a Auth) { rotated = a }})
+	c.SetSession(Auth{UID: "uid", AccessToken: "a1", RefreshToken: "r1"})
+	c.rotate(Auth{AccessToken: "a2", RefreshToken: "r2"})
+	if rotated.UID != "uid" || rotated.RefreshToken != "r2" || c.Status() != StatusConnected {
+		t.Fatalf("unexpected rotation %+v %s", rotated, c.Status())
+	}
+	c.watch(nil)
+	c.watch(&proton.Client{})
+}
+
+func TestGetCalendarUserSettings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/settings/calendar" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, `{"Code":1000,"CalendarUserSettings":{"PrimaryTimezone":"Europe/Zurich","DefaultCalendarID":null}}`)
+	}))
+	defer srv.Close()
+
+	c := NewClient(ClientOptions{BaseURL: srv.URL, UID: "uid", AccessToken: "acc", Manager: &fakeManager{}})
+	settings, err := c.GetCalendarUserSettings(context.Background())
+	if err != nil || settings.PrimaryTimezone != "Europe/Zurich" {
+		t.Fatalf("settings = %+v, %v", settings, err)
+	}
+	c = NewClient(ClientOptions{Manager: &fakeManager{}})
+	if _, err := c.GetCalendarUserSettings(context.Background()); err == nil {
+		t.Fatal("expected missing session error")
+	}
+}
@@ -1,15 +1,32 @@
 package protonapi
 
-import proton "github.com/ProtonMail/go-proton-api"
+import (
+	"net/url"
+	"strconv"
+	"time"
+
+	proton "github.com/ProtonMail/go-proton-api"
+)
 
 type Auth = proton.Auth
 
+const (
+	// TwoFATOTP is set in Auth.TwoFA.Enabled when a TOTP code is required.
+	TwoFATOTP = proton.HasTOTP
+	// TwoPasswordMode means keys are unlocked by a separate mailbox password.
+	TwoPasswordMode = proton.TwoPasswordMode
+)
+
 type Calendar = proton.Calendar
 type CalendarType = proton.CalendarType
 type CalendarFlag = proton.CalendarFlag
 
 const (
-	CalendarTypeNormal = proton.CalendarTypeNormal
+	CalendarTypeNormal     = proton.CalendarTypeNormal
+	CalendarTypeSubscribed = proton.CalendarTypeSubscribed
+	// CalendarTypeHolidays marks the public holiday calendars Proton offers;
+	// go-proton-api does not declare it.
+	CalendarTypeHolidays CalendarType = 2
 )
 
 type CalendarKey = proton.CalendarKey
@@ -18,11 +35,35 @@ type CalendarKeys = proton.CalendarKeys
 type CalendarMember = proton.CalendarMember
 type CalendarPermissions = proton.CalendarPermissions
 
+// Calendar member permission bits.
+const (
+	CalendarPermissionSuperOwner CalendarPermissions = 1 << iota
+	CalendarPermissionOwner
+	CalendarPermissionAdmin
+	CalendarPermissionReadMemberList
+	CalendarPermissionWrite
+	CalendarPermissionRead
+	CalendarPermissionAvailability
+)
+
+// CalendarPermissionsWrite are the bits that allow changing events.
+const CalendarPermissionsWrite = CalendarPermissionSuperOwner | CalendarPermissionOwner | CalendarPermissionAdmin | CalendarPermissionWrite
+
 type CalendarPassphrase = proton.CalendarPassphrase
 type MemberPassphrase = proton.MemberPassphrase
 
 type CalendarEvent = proton.CalendarEvent
 type CalendarEventPart = proton.CalendarEventPart
+type CalendarAttendee = proton.CalendarAttendee
+type CalendarAttendeeStatus = proton.CalendarAttendeeStatus
+
+const (
+	CalendarAttendeeStatusPending = proton.CalendarAttendeeStatusPending
+	CalendarAttendeeStatusMaybe   = proton.CalendarAttendeeStatusMaybe
+	CalendarAttendeeStatusNo      = proton.CalendarAttendeeStatusNo
+	CalendarAttendeeStatusYes     = proton.CalendarAttendeeStatusYes
+)
+
 type CalendarEventType = proton.CalendarEventType
 
 const (
@@ -31,8 +72,136 @@ const (
 	CalendarEventTypeSigned    = proton.CalendarEventTypeSigned
 )
 
+type User = proton.User
+
 type Address = proton.Address
 type AddressStatus = proton.AddressStatus
 
 type Key = proton.Key
 type Keys = proton.Keys
+
+// CalendarEventContent is an event part as sent to the sync endpoint.
+type CalendarEventContent struct {
+	Type      CalendarEventType
+	Data      string
+	Signature string `json:",omitempty"`
+}
+
+// CalendarEventData is the body of a created or updated event.
+type CalendarEventData struct {
+	Permissions           int
+	IsOrganizer           int
+	SharedKeyPacket       string `json:",omitempty"`
+	CalendarKeyPacket     string `json:",omitempty"`
+	SharedEventContent    []CalendarEventContent
+	CalendarEventContent  []CalendarEventContent  `json:",omitempty"`
+	AttendeesEventContent []CalendarEventContent  `json:",omitempty"`
+	Attendees             []CalendarEventAttendee `json:",omitempty"`
+	PersonalEventContent  *CalendarEventContent   `json:",omitempty"`
+}
+
+// CalendarEventAttendee is the token and reply status of an attendee listed
+// in the attendee parts.
+type CalendarEventAttendee struct {
+	Token  string
+	Status CalendarAttendeeStatus
+}
+
+// CalendarEventSync is one operation in a sync request: a create when ID is
+// empty, an update when Event is set, and a delete otherwise.
+type CalendarEventSync struct {
+	ID        string             `json:",omitempty"`
+	Overwrite int                `json:",omitempty"`
+	Event     *CalendarEventData `json:",omitempty"`
+}
+
+type CalendarEventSyncReq struct {
+	MemberID string
+	Events   []CalendarEventSync
+}
+
+type CalendarEventSyncResult struct {
+	Index    int
+	Response struct {
+		Code  int
+		Error string
+		Event CalendarEvent
+	}
+}
+
+// EventQueryType selects which events a windowed listing returns. The
+// "before window" types return events that started before Start, which is how
+// recurring series reaching into the window are found.
+type EventQueryType int
+
+const (
+	EventQueryPartDayInsideWindow EventQueryType = iota
+	EventQueryPartDayBeforeWindow
+	EventQueryFullDayInsideWindow
+	EventQueryFullDayBeforeWindow
+)
+
+// EventQueryTypes lists every query type, in the order they are fetched.
+var EventQueryTypes = []EventQueryType{
+	EventQueryPartDayInsideWindow,
+	EventQueryPartDayBeforeWindow,
+	EventQueryFullDayInsideWindow,
+	EventQueryFullDayBeforeWindow,
+}
+
+// EventFilter restricts GetCalendarEvents to a time window. The zero value
+// lists every event.
+type EventFilter struct {
+	Start    time.Time
+	End      time.Time
+	Timezone string
+	Type     EventQueryType
+}
+
+func (f EventFilter) values() url.Values {
+	v := url.Values{}
+	if f.Start.IsZero() && f.End.IsZero() {
+		return v
+	}
+	v.Set("Start", strconv.FormatInt(f.Start.Unix(), 10))
+	v.Set("End", strconv.FormatInt(f.End.Unix(), 10))
+	tz := f.Timezone
+	if tz == "" {
+		tz = "UTC"
+	}
+	v.Set("Timezone", tz)
+	v.Set("Type", strconv.Itoa(int(f.Type)))
+	return v
+}
+
+// EventAction is the kind of change reported by the calendar event loop.
+type EventAction int
+
+const (
+	EventActionDelete EventAction = iota
+	EventActionCreate
+	EventActionUpdate
+)
+
+// CalendarEventChange is one event delta. Event is empty for deletes.
+type CalendarEventChange struct {
+	ID     string
+	Action EventAction
+	Event  CalendarEvent
+}
+
+// CalendarModelEvents is a page of the calendar event loop. CalendarModelEventID
+// is the cursor to poll next; More is set while further pages are pending and
+// Refresh when the cursor is too old and the calendar must be listed again.
+type CalendarModelEvents struct {
+	CalendarModelEventID string
+	Events               []CalendarEventChange
+	More                 proton.Bool
+	Refresh              int
+}
+
+// CalendarUserSettings are the account-wide calendar preferences.
+type CalendarUserSettings struct {
+	PrimaryTimezone   string
+	DefaultCalendarID string
+}
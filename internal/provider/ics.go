@@ -1,33 +1,168 @@
 package provider
 
 import (
-	"bufio"
 	"context"
-	"errors"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/ical"
 )
 
 type HTTPDoer interface {
 	Do(req *http.Request) (*http.Response, error)
 }
 
+// DefaultICSCacheTTL is how long a fetched feed is served from memory before
+// it is revalidated upstream.
+const DefaultICSCacheTTL = 5 * time.Minute
+
+// DefaultICSCalendarID is the calendar ID of a feed configured as a bare URL
+// when it is the only feed.
+const DefaultICSCalendarID = "ics-default"
+
+// ICSFeed is a single shared calendar link. An empty Name falls back to the
+// feed's X-WR-CALNAME.
+type ICSFeed struct {
+	ID   string
+	Name string
+	URL  string
+}
+
 type ICSProvider struct {
-	url    string
+	feeds  []ICSFeed
 	client HTTPDoer
 	now    func() time.Time
+	ttl    time.Duration
+
+	// fetching holds one lock per feed URL, serialising its upstream fetches
+	// so concurrent pollers share one download without waiting on other
+	// feeds; mu guards fetching, cache, stats, running and report.
+	fetching map[string]*sync.Mutex
+	mu       sync.Mutex
+	cache    map[string]*icsCacheEntry
+	stats    ICSCacheStats
+	running  bool
+	report   func(Refresh)
+}
+
+// icsCacheEntry is a parsed feed together with the validators needed to
+// revalidate it and a digest of its body.
+type icsCacheEntry struct {
+	meta         icsFeedMeta
+	events       []domain.Event
+	digest       string
+	etag         string
+	lastModified string
+	fetchedAt    time.Time
+}
+
+type ICSCacheStats struct {
+	Entries     int       `json:"entries"`
+	Hits        int64     `json:"hits"`
+	Misses      int64     `json:"misses"`
+	NotModified int64     `json:"not_modified"`
+	StaleServed int64     `json:"stale_served"`
+	Errors      int64     `json:"errors"`
+	LastFetch   time.Time `json:"last_fetch,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
 }
 
 func NewICSProvider(url string, client HTTPDoer) *ICSProvider {
+	return NewICSProviderWithCacheTTL(url, client, DefaultICSCacheTTL)
+}
+
+// NewICSProviderWithCacheTTL is NewICSProvider with an explicit cache TTL. A
+// zero TTL revalidates the feed on every request.
+func NewICSProviderWithCacheTTL(url string, client HTTPDoer, ttl time.Duration) *ICSProvider {
+	return NewICSProviderWithFeeds([]ICSFeed{{ID: DefaultICSCalendarID, URL: url}}, client, ttl)
+}
+
+// NewICSProviderWithFeeds serves each feed as its own calendar.
+func NewICSProviderWithFeeds(feeds []ICSFeed, client HTTPDoer, ttl time.Duration) *ICSProvider {
 	if client == nil {
 		client = &http.Client{Timeout: 10 * time.Second}
 	}
-	return &ICSProvider{url: url, client: client, now: time.Now}
+	return &ICSProvider{feeds: feeds, client: client, now: time.Now, ttl: ttl, fetching: make(map[string]*sync.Mutex), cache: make(map[string]*icsCacheEntry)}
+}
+
+// ParseICSFeeds parses a comma or newline separated list of feeds. Each entry
+// is "id|name|url", "id|url" or a bare URL. A single bare URL keeps the
+// ics-default ID; bare URLs in a list are numbered ics-1, ics-2, ...
+func ParseICSFeeds(spec string) ([]ICSFeed, error) {
+	entries := splitFeedEntries(spec)
+	var feeds []ICSFeed
+	seen := make(map[string]bool)
+	for i, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, "|")
+		for j := range parts {
+			parts[j] = strings.TrimSpace(parts[j])
+		}
+		var feed ICSFeed
+		switch len(parts) {
+		case 1:
+			feed = ICSFeed{ID: fmt.Sprintf("ics-%d", i+1), URL: parts[0]}
+		case 2:
+			feed = ICSFeed{ID: parts[0], URL: parts[1]}
+		case 3:
+			feed = ICSFeed{ID: parts[0], Name: parts[1], URL: parts[2]}
+		default:
+			return nil, fmt.Errorf("invalid ics feed %q", entry)
+		}
+		if feed.ID == "" || feed.URL == "" {
+			return nil, fmt.Errorf("invalid ics feed %q: id and url are required", entry)
+		}
+		if seen[feed.ID] {
+			return nil, fmt.Errorf("duplicate ics feed id %q", feed.ID)
+		}
+		seen[feed.ID] = true
+		feeds = append(feeds, feed)
+	}
+	if len(feeds) == 0 {
+		return nil, fmt.Errorf("no ics feeds configured")
+	}
+	if len(feeds) == 1 && len(entries) == 1 && !strings.Contains(entries[0], "|") {
+		feeds[0].ID = DefaultICSCalendarID
+	}
+	return feeds, nil
+}
+
+// feedEntryStart matches what may follow a comma that separates feeds: an
+// "id|" prefix, a URL scheme or nothing.
+var feedEntryStart = regexp.MustCompile(`^\s*([A-Za-z0-9._-]+\s*\||[A-Za-z][A-Za-z0-9+.-]*://|$)`)
+
+// splitFeedEntries splits spec at newlines, and at commas only where a new
+// entry starts, so commas inside a feed URL's query string stay part of it.
+func splitFeedEntries(spec string) []string {
+	var entries []string
+	add := func(entry string) {
+		if entry != "" {
+			entries = append(entries, entry)
+		}
+	}
+	for _, line := range strings.Split(spec, "\n") {
+		start := 0
+		for i := 0; i < len(line); i++ {
+			if line[i] == ',' && feedEntryStart.MatchString(line[i+1:]) {
+				add(line[start:i])
+				start = i + 1
+			}
+		}
+		add(line[start:])
+	}
+	return entries
 }
 
 func (p *ICSProvider) Name() string { return "ics" }
@@ -37,9 +172,9 @@ func (p *ICSProvider) Capabilities(context.Context) (CapabilitySet, error) {
 		ReadOnly:        true,
 		WriteSupported:  false,
 		SharedCalendars: true,
-		Attendees:       false,
-		Reminders:       false,
-		Recurrence:      false,
+		Attendees:       true,
+		Reminders:       true,
+		Recurrence:      true,
 		Notes: []string{
 			"ICS links are read-only from external systems.",
 			"Data freshness depends on Proton sharing sync cadence.",
@@ -47,44 +182,264 @@ func (p *ICSProvider) Capabilities(context.Context) (CapabilitySet, error) {
 	}, nil
 }
 
-func (p *ICSProvider) ListCalendars(context.Context) ([]domain.Calendar, error) {
-	return []domain.Calendar{{
-		ID:          "ics-default",
-		Name:        "Proton ICS (read-only)",
-		ReadOnly:    true,
-		Shared:      true,
-		Permissions: []string{"read"},
-	}}, nil
+func (p *ICSProvider) ListCalendars(ctx context.Context) ([]domain.Calendar, error) {
+	out := make([]domain.Calendar, 0, len(p.feeds))
+	for i, feed := range p.feeds {
+		meta := p.feedMeta(ctx, feed)
+		out = append(out, domain.Calendar{
+			ID:          feed.ID,
+			Name:        meta.Name,
+			Description: meta.Description,
+			Color:       meta.Color,
+			TimeZone:    meta.TimeZone,
+			Type:        domain.CalendarTypeSubscribed,
+			Order:       i,
+			ReadOnly:    true,
+			Shared:      true,
+			Permissions: []string{"read"},
+		})
+	}
+	return out, nil
 }
 
-func (p *ICSProvider) ListEvents(ctx context.Context, calendarID string, from, to time.Time) ([]domain.Event, error) {
-	if calendarID == "" {
-		calendarID = "ics-default"
+// feedMeta returns the feed's calendar properties. The name prefers the
+// configured one, then the feed's X-WR-CALNAME.
+func (p *ICSProvider) feedMeta(ctx context.Context, feed ICSFeed) icsFeedMeta {
+	entry, err := p.cached(ctx, feed.URL)
+	var meta icsFeedMeta
+	if err != nil {
+		slog.Warn("failed to fetch ics feed metadata", "calendar_id", feed.ID, "error", err)
+	} else {
+		meta = entry.meta
+	}
+	switch {
+	case feed.Name != "":
+		meta.Name = feed.Name
+	case meta.Name != "":
+	case feed.ID == DefaultICSCalendarID:
+		meta.Name = "Proton ICS (read-only)"
+	default:
+		meta.Name = feed.ID
 	}
-	body, err := p.fetch(ctx)
+	return meta
+}
+
+func (p *ICSProvider) ListEvents(ctx context.Context, calendarID string, from, to time.Time) ([]domain.Event, error) {
+	events, err := p.load(ctx, calendarID)
 	if err != nil {
 		return nil, err
 	}
-	defer body.Close()
+	return expandEvents(events, from, to, p.now()), nil
+}
 
-	events, err := parseICS(body, calendarID)
+func (p *ICSProvider) ListSeries(ctx context.Context, calendarID string, from, to time.Time) ([]domain.Event, error) {
+	events, err := p.load(ctx, calendarID)
 	if err != nil {
 		return nil, err
 	}
-	if from.IsZero() && to.IsZero() {
-		return events, nil
+	return filterSeries(events, from, to, p.now()), nil
+}
+
+func (p *ICSProvider) GetEvent(ctx context.Context, calendarID, eventID string) (domain.Event, error) {
+	if calendarID == "" {
+		return domain.Event{}, InvalidInputError{Field: "calendar_id", Reason: "required"}
+	}
+	events, err := p.load(ctx, calendarID)
+	if err != nil {
+		return domain.Event{}, err
+	}
+	e, ok := findEvent(events, eventID, p.now())
+	if !ok {
+		return domain.Event{}, fmt.Errorf("%w: %s", ErrEventNotFound, eventID)
+	}
+	return e, nil
+}
+
+// load returns the events of the feed with the given calendar ID, or of every
+// feed when calendarID is empty.
+func (p *ICSProvider) load(ctx context.Context, calendarID string) ([]domain.Event, error) {
+	var feeds []ICSFeed
+	for _, feed := range p.feeds {
+		if calendarID == "" || feed.ID == calendarID {
+			feeds = append(feeds, feed)
+		}
+	}
+	if len(feeds) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrCalendarNotFound, calendarID)
+	}
+	var events []domain.Event
+	for _, feed := range feeds {
+		entry, err := p.cached(ctx, feed.URL)
+		if err != nil {
+			return nil, fmt.Errorf("calendar %s: %w", feed.ID, err)
+		}
+		for _, e := range entry.events {
+			e.CalendarID = feed.ID
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// Health reports the feed cache statistics.
+func (p *ICSProvider) Health() map[string]any {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := p.stats
+	stats.Entries = len(p.cache)
+	return map[string]any{"ics_cache": stats}
+}
+
+// cached returns the parsed feed at url, serving it from memory while it is
+// fresh and revalidating it with If-None-Match/If-Modified-Since once the TTL
+// has passed. When upstream fails, a previously fetched copy is served stale.
+func (p *ICSProvider) cached(ctx context.Context, url string) (*icsCacheEntry, error) {
+	p.mu.Lock()
+	feedMu := p.fetching[url]
+	if feedMu == nil {
+		feedMu = &sync.Mutex{}
+		p.fetching[url] = feedMu
+	}
+	p.mu.Unlock()
+	feedMu.Lock()
+	defer feedMu.Unlock()
+
+	p.mu.Lock()
+	prev := p.cache[url]
+	if prev != nil && p.now().Sub(prev.fetchedAt) < p.ttl {
+		p.stats.Hits++
+		p.mu.Unlock()
+		return prev, nil
 	}
-	filtered := make([]domain.Event, 0, len(events))
-	for _, e := range events {
-		if !from.IsZero() && e.End.Before(from) {
+	p.mu.Unlock()
+
+	next, notModified, err := p.fetch(ctx, url, prev)
+
+	p.mu.Lock()
+	p.stats.LastFetch = p.now()
+	if err != nil {
+		p.stats.Errors++
+		p.stats.LastError = err.Error()
+		if prev == nil {
+			p.mu.Unlock()
+			return nil, err
+		}
+		p.stats.StaleServed++
+		p.mu.Unlock()
+		slog.Warn("serving stale ics feed", "fetched_at", prev.fetchedAt, "error", err)
+		return prev, nil
+	}
+	p.stats.LastError = ""
+	if notModified {
+		p.stats.NotModified++
+	} else {
+		p.stats.Misses++
+	}
+	p.cache[url] = next
+	report := p.report
+	p.mu.Unlock()
+	if report != nil && prev != nil && next.digest != prev.digest {
+		p.reportFeed(report, url, prev, next)
+	}
+	return next, nil
+}
+
+// reportFeed reports a refetched feed that changed to every calendar served
+// from it.
+func (p *ICSProvider) reportFeed(report func(Refresh), url string, prev, next *icsCacheEntry) {
+	for _, feed := range p.feeds {
+		if feed.URL != url {
 			continue
 		}
-		if !to.IsZero() && e.Start.After(to) {
+		report(Refresh{CalendarID: feed.ID, Before: feedEvents(feed, prev), After: feedEvents(feed, next)})
+	}
+	if next.meta != prev.meta {
+		report(Refresh{})
+	}
+}
+
+func feedEvents(feed ICSFeed, entry *icsCacheEntry) []domain.Event {
+	out := make([]domain.Event, 0, len(entry.events))
+	for _, e := range entry.events {
+		e.CalendarID = feed.ID
+		out = append(out, e)
+	}
+	return out
+}
+
+// ReportChanges makes every refetch of a feed whose body changed report the
+// feed's calendars.
+func (p *ICSProvider) ReportChanges(fn func(Refresh)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.report = fn
+}
+
+// RunSync revalidates every feed each cache TTL until ctx is done, so changes
+// are found without waiting for a request. A zero TTL disables it; feeds are
+// then revalidated on every request instead.
+func (p *ICSProvider) RunSync(ctx context.Context) {
+	p.mu.Lock()
+	if p.ttl <= 0 || p.running {
+		p.mu.Unlock()
+		return
+	}
+	p.running = true
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		p.running = false
+		p.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(p.ttl)
+	defer ticker.Stop()
+	for {
+		fetched := make(map[string]bool, len(p.feeds))
+		for _, feed := range p.feeds {
+			if fetched[feed.URL] {
+				continue
+			}
+			fetched[feed.URL] = true
+			if _, err := p.cached(ctx, feed.URL); err != nil && ctx.Err() == nil {
+				slog.Warn("ics refresh failed", "calendar_id", feed.ID, "error", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncStatus reports each feed's last fetch; its cursor is a digest of the
+// feed body, so it moves whenever the served events can change.
+func (p *ICSProvider) SyncStatus() SyncStatus {
+	now := p.now()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := SyncStatus{
+		Enabled:         p.ttl > 0,
+		Running:         p.running,
+		IntervalSeconds: p.ttl.Seconds(),
+		LastError:       p.stats.LastError,
+		Calendars:       make([]CalendarSyncStatus, 0, len(p.feeds)),
+	}
+	for _, feed := range p.feeds {
+		entry := p.cache[feed.URL]
+		if entry == nil {
 			continue
 		}
-		filtered = append(filtered, e)
+		out.Calendars = append(out.Calendars, CalendarSyncStatus{
+			CalendarID: feed.ID,
+			Cursor:     entry.digest,
+			Events:     len(entry.events),
+			LastSync:   entry.fetchedAt,
+			LagSeconds: now.Sub(entry.fetchedAt).Seconds(),
+		})
 	}
-	return filtered, nil
+	return out
 }
 
 func (p *ICSProvider) CreateEvent(context.Context, domain.EventMutation) (domain.Event, error) {
@@ -99,108 +454,105 @@ func (p *ICSProvider) DeleteEvent(context.Context, string) error {
 	return NotSupportedError{Operation: "delete_event"}
 }
 
-func (p *ICSProvider) fetch(ctx context.Context) (io.ReadCloser, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+// fetch downloads and parses the feed at url. With a previous entry the
+// request is conditional, and a 304 returns that entry with a new fetch time.
+func (p *ICSProvider) fetch(ctx context.Context, url string, prev *icsCacheEntry) (*icsCacheEntry, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("build request: %w", err)
+		return nil, false, fmt.Errorf("build request: %w", err)
+	}
+	if prev != nil {
+		if prev.etag != "" {
+			req.Header.Set("If-None-Match", prev.etag)
+		}
+		if prev.lastModified != "" {
+			req.Header.Set("If-Modified-Since", prev.lastModified)
+		}
 	}
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("fetch ics: %w", err)
+		return nil, false, fmt.Errorf("fetch ics: %w: %w", ErrUpstreamUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && prev != nil {
+		next := *prev
+		next.fetchedAt = p.now()
+		return &next, true, nil
 	}
-	if resp.StatusCode != http.StatusOK {
-		defer resp.Body.Close()
-		return nil, fmt.Errorf("fetch ics: unexpected status %d", resp.StatusCode)
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return nil, false, fmt.Errorf("fetch ics: %w", ErrRateLimited)
+	case resp.StatusCode >= 500:
+		return nil, false, fmt.Errorf("fetch ics: %w: status %d", ErrUpstreamUnavailable, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return nil, false, fmt.Errorf("fetch ics: unexpected status %d", resp.StatusCode)
+	}
+	hash := sha256.New()
+	events, meta, err := parseICS(io.TeeReader(resp.Body, hash), "")
+	if err != nil {
+		return nil, false, err
 	}
-	return resp.Body, nil
+	return &icsCacheEntry{
+		meta:         meta,
+		events:       events,
+		digest:       hex.EncodeToString(hash.Sum(nil)[:8]),
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+		fetchedAt:    p.now(),
+	}, false, nil
 }
 
-func parseICS(r io.Reader, calendarID string) ([]domain.Event, error) {
-	scanner := bufio.NewScanner(r)
-	scanner.Buffer(make([]byte, 0, 64*1024), 512*1024)
+// icsFeedMeta holds the calendar properties a feed declares about itself.
+type icsFeedMeta struct {
+	Name        string
+	Description string
+	TimeZone    string
+	Color       string
+}
 
-	type rawEvent struct {
-		id, title, desc, location, dtstart, dtend string
+// parseICS returns the feed's events and calendar properties, read from the
+// X-WR-* extensions or their RFC 7986 equivalents.
+func parseICS(r io.Reader, calendarID string) ([]domain.Event, icsFeedMeta, error) {
+	roots, skipped, err := ical.ParseReport(r)
+	if err != nil {
+		return nil, icsFeedMeta{}, fmt.Errorf("parse ics: %w", err)
 	}
-
-	var raws []rawEvent
-	var current *rawEvent
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		switch {
-		case line == "BEGIN:VEVENT":
-			current = &rawEvent{}
-		case line == "END:VEVENT":
-			if current != nil {
-				raws = append(raws, *current)
-			}
-			current = nil
-		case current != nil:
-			k, v, ok := strings.Cut(line, ":")
-			if !ok {
-				continue
-			}
-			key := strings.ToUpper(strings.Split(k, ";")[0])
-			switch key {
-			case "UID":
-				current.id = v
-			case "SUMMARY":
-				current.title = v
-			case "DESCRIPTION":
-				current.desc = v
-			case "LOCATION":
-				current.location = v
-			case "DTSTART":
-				current.dtstart = v
-			case "DTEND":
-				current.dtend = v
-			}
-		}
+	if len(skipped) > 0 {
+		slog.Warn("skipped malformed ics lines", "calendar_id", calendarID, "count", len(skipped), "first", skipped[0])
 	}
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("scan ics: %w", err)
+	var meta icsFeedMeta
+	for _, cal := range ical.Find(roots, "VCALENDAR") {
+		meta = icsFeedMeta{
+			Name:        firstText(cal, "X-WR-CALNAME", "NAME"),
+			Description: firstText(cal, "X-WR-CALDESC", "DESCRIPTION"),
+			TimeZone:    firstText(cal, "X-WR-TIMEZONE"),
+			Color:       firstText(cal, "X-APPLE-CALENDAR-COLOR", "COLOR"),
+		}
+		if meta != (icsFeedMeta{}) {
+			break
+		}
 	}
 
-	events := make([]domain.Event, 0, len(raws))
-	for _, raw := range raws {
-		if raw.id == "" || raw.dtstart == "" {
-			continue
-		}
-		start, allDay, err := parseICSTime(raw.dtstart)
+	tz := ical.NewTimezones(roots)
+	vevents := ical.Find(roots, "VEVENT")
+	events := make([]domain.Event, 0, len(vevents))
+	for _, vevent := range vevents {
+		e, err := ical.DecodeEvent(vevent, tz)
 		if err != nil {
+			slog.Warn("skipped undecodable ics event", "calendar_id", calendarID, "uid", vevent.Text("UID"), "error", err)
 			continue
 		}
-		end := start
-		if raw.dtend != "" {
-			if parsed, _, err := parseICSTime(raw.dtend); err == nil {
-				end = parsed
-			}
-		}
-		events = append(events, domain.Event{
-			ID:          raw.id,
-			CalendarID:  calendarID,
-			Title:       raw.title,
-			Description: raw.desc,
-			Location:    raw.location,
-			Start:       start,
-			End:         end,
-			AllDay:      allDay,
-		})
+		e.CalendarID = calendarID
+		events = append(events, e)
 	}
-	return events, nil
+	return events, meta, nil
 }
 
-func parseICSTime(v string) (time.Time, bool, error) {
-	v = strings.TrimSpace(v)
-	if len(v) == len("20060102") {
-		t, err := time.Parse("20060102", v)
-		return t, true, err
-	}
-	formats := []string{"20060102T150405Z", "20060102T150405"}
-	for _, f := range formats {
-		if t, err := time.Parse(f, v); err == nil {
-			return t, false, nil
+func firstText(c *ical.Component, names ...string) string {
+	for _, name := range names {
+		if v := c.Text(name); v != "" {
+			return v
 		}
 	}
-	return time.Time{}, false, errors.New("invalid ics datetime")
+	return ""
 }
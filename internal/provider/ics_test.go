@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net/http"
 	"strings"
@@ -41,11 +42,31 @@ func TestICSProviderListEvents(t *testing.T) {
 	}
 }
 
+func TestICSProviderUnfoldsAndUnescapes(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:1\r\nSUMMARY:Plan\\, review\r\nDESCRIPTION:First\\nSecond line that was\r\n  folded\r\nDTSTART:20260212T100000Z\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	p := NewICSProvider("https://x", fakeClient{resp: &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(ics))}})
+	events, err := p.ListEvents(context.Background(), "", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Title != "Plan, review" || events[0].Description != "First\nSecond line that was folded" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+}
+
+func TestICSProviderMalformedFeed(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:1\nEND:VCALENDAR"
+	p := NewICSProvider("https://x", fakeClient{resp: &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(ics))}})
+	if _, err := p.ListEvents(context.Background(), "", time.Time{}, time.Time{}); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
+
 func TestICSProviderFilteringAndNotSupported(t *testing.T) {
 	ics := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:1\nSUMMARY:A\nDTSTART:20260212\nDTEND:20260213\nEND:VEVENT\nEND:VCALENDAR"
 	p := NewICSProvider("https://x", fakeClient{resp: &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(ics))}})
 	from := time.Date(2026, 2, 14, 0, 0, 0, 0, time.UTC)
-	events, err := p.ListEvents(context.Background(), "cal", from, time.Time{})
+	events, err := p.ListEvents(context.Background(), "ics-default", from, time.Time{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -75,12 +96,32 @@ func TestICSProviderFetchErrors(t *testing.T) {
 	}
 }
 
-func TestParseICSTime(t *testing.T) {
-	if _, _, err := parseICSTime("invalid"); err == nil {
-		t.Fatal("expected invalid")
+func TestParseICSTimeZones(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\n" +
+		"BEGIN:VEVENT\nUID:berlin\nDTSTART;TZID=Europe/Berlin:20260216T090000\nDTEND;TZID=Europe/Berlin:20260216T100000\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:day\nDTSTART;VALUE=DATE:20260212\nORGANIZER:mailto:org@example.com\nATTENDEE;PARTSTAT=DECLINED:mailto:a@example.com\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:floating\nDTSTART:20260216T090000\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:invalid\nDTSTART:invalid\nEND:VEVENT\n" +
+		"END:VCALENDAR"
+	events, _, err := parseICS(strings.NewReader(ics), "cal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected invalid event to be skipped, got %+v", events)
+	}
+	berlin := events[0]
+	if !berlin.Start.Equal(time.Date(2026, 2, 16, 8, 0, 0, 0, time.UTC)) || berlin.TimeZone != "Europe/Berlin" || berlin.Floating {
+		t.Fatalf("unexpected zoned event: %+v", berlin)
+	}
+	if !events[1].AllDay {
+		t.Fatalf("expected all-day event: %+v", events[1])
 	}
-	if _, allDay, err := parseICSTime("20260212"); err != nil || !allDay {
-		t.Fatalf("expected all-day parse, got err=%v allDay=%v", err, allDay)
+	if e := events[1]; e.Organizer == nil || e.Organizer.Email != "org@example.com" || len(e.Attendees) != 1 || e.Attendees[0].Status != "DECLINED" {
+		t.Fatalf("unexpected participants: %+v", e)
+	}
+	if !events[2].Floating || events[2].TimeZone != "" {
+		t.Fatalf("expected floating event: %+v", events[2])
 	}
 }
 
@@ -90,7 +131,392 @@ func TestICSProviderCapabilities(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !caps.ReadOnly || caps.WriteSupported {
+	if !caps.ReadOnly || caps.WriteSupported || !caps.Attendees {
 		t.Fatalf("unexpected capabilities: %+v", caps)
 	}
 }
+
+func TestICSProviderGetEvent(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\n" +
+		"BEGIN:VEVENT\nUID:standup\nSUMMARY:Standup\nDTSTART:20260302T090000Z\nDTEND:20260302T091500Z\nRRULE:FREQ=DAILY;COUNT=5\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:standup\nRECURRENCE-ID:20260303T090000Z\nSUMMARY:Late standup\nDTSTART:20260303T110000Z\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:offsite\nSUMMARY:Offsite\nDTSTART;VALUE=DATE:20260310\nRRULE:FREQ=YEARLY\nEND:VEVENT\n" +
+		"END:VCALENDAR"
+	p := NewICSProvider("https://x", fakeClient{resp: &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(ics))}})
+	ctx := context.Background()
+
+	cases := map[string]string{
+		"standup":                  "Standup",
+		"standup_20260305T090000Z": "Standup",
+		"standup_20260303T090000Z": "Late standup",
+		"offsite_20270310":         "Offsite",
+	}
+	for id, want := range cases {
+		e, err := p.GetEvent(ctx, DefaultICSCalendarID, id)
+		if err != nil || e.Title != want || e.ID != id {
+			t.Fatalf("GetEvent(%q) = %+v, %v", id, e, err)
+		}
+	}
+	for _, id := range []string{"nope", "standup_20260310T090000Z", "standup_"} {
+		if _, err := p.GetEvent(ctx, DefaultICSCalendarID, id); !errors.Is(err, ErrEventNotFound) {
+			t.Fatalf("GetEvent(%q): expected not found, got %v", id, err)
+		}
+	}
+	if _, err := p.GetEvent(ctx, "other", "standup"); !errors.Is(err, ErrCalendarNotFound) {
+		t.Fatalf("expected calendar not found, got %v", err)
+	}
+	if _, err := p.GetEvent(ctx, "", "standup"); err == nil {
+		t.Fatal("expected calendar id error")
+	}
+}
+
+func TestICSProviderRecurrence(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:standup\nSUMMARY:Standup\n" +
+		"DTSTART;TZID=Europe/Berlin:20260302T093000\nDTEND;TZID=Europe/Berlin:20260302T094500\n" +
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,WE;UNTIL=20260401T000000Z\n" +
+		"EXDATE;TZID=Europe/Berlin:20260304T093000\nRDATE;TZID=Europe/Berlin:20260307T100000\n" +
+		"END:VEVENT\nEND:VCALENDAR"
+	newProvider := func() *ICSProvider {
+		return NewICSProvider("https://x", fakeClient{resp: &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(ics))}})
+	}
+	from := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	events, err := newProvider().ListEvents(context.Background(), "", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var starts []string
+	for _, e := range events {
+		starts = append(starts, e.Start.UTC().Format("01-02T15:04"))
+	}
+	want := []string{"03-02T08:30", "03-07T09:00", "03-09T08:30"}
+	if strings.Join(starts, " ") != strings.Join(want, " ") {
+		t.Fatalf("unexpected instances: %v", starts)
+	}
+
+	series, err := newProvider().ListSeries(context.Background(), "", from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 1 || series[0].Recurrence == "" || len(series[0].ExceptionDates) != 1 || len(series[0].RecurrenceDates) != 1 {
+		t.Fatalf("unexpected series: %+v", series)
+	}
+
+	if _, err := NewICSProvider("https://x", fakeClient{err: io.EOF}).ListSeries(context.Background(), "", from, to); err == nil {
+		t.Fatal("expected fetch error")
+	}
+	caps, _ := newProvider().Capabilities(context.Background())
+	if !caps.Recurrence {
+		t.Fatal("expected recurrence capability")
+	}
+}
+
+func TestICSProviderRecurrenceOverrides(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\n" +
+		"BEGIN:VEVENT\nUID:weekly\nSUMMARY:Review\nDTSTART:20260302T100000Z\nDTEND:20260302T110000Z\nRRULE:FREQ=WEEKLY;COUNT=3\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:weekly\nRECURRENCE-ID:20260309T100000Z\nSUMMARY:Review (moved)\nDTSTART:20260310T140000Z\nDTEND:20260310T150000Z\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:weekly\nRECURRENCE-ID:20260316T100000Z\nSTATUS:CANCELLED\nDTSTART:20260316T100000Z\nEND:VEVENT\n" +
+		"BEGIN:VEVENT\nUID:weekly\nRECURRENCE-ID:bogus\nDTSTART:20260316T100000Z\nEND:VEVENT\n" +
+		"END:VCALENDAR"
+	p := NewICSProvider("https://x", fakeClient{resp: &http.Response{StatusCode: 200, Body: io.NopCloser(strings.NewReader(ics))}})
+	events, err := p.ListEvents(context.Background(), "", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	if events[0].ID != "weekly_20260302T100000Z" || events[1].ID != "weekly_20260309T100000Z" || events[1].Title != "Review (moved)" {
+		t.Fatalf("unexpected instances: %+v", events)
+	}
+	if events[1].SeriesID != "weekly" || events[1].UID != "weekly" || events[1].Start.Day() != 10 {
+		t.Fatalf("override not merged: %+v", events[1])
+	}
+}
+
+// scriptedClient replays one response per request and records the requests.
+type scriptedClient struct {
+	responses []func() (*http.Response, error)
+	requests  []*http.Request
+}
+
+func (s *scriptedClient) Do(req *http.Request) (*http.Response, error) {
+	s.requests = append(s.requests, req)
+	next := s.responses[0]
+	if len(s.responses) > 1 {
+		s.responses = s.responses[1:]
+	}
+	return next()
+}
+
+func icsResponse(status int, body string, header http.Header) func() (*http.Response, error) {
+	return func() (*http.Response, error) {
+		if header == nil {
+			header = http.Header{}
+		}
+		return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(body))}, nil
+	}
+}
+
+func TestICSProviderCache(t *testing.T) {
+	feed := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:1\nSUMMARY:Cached\nDTSTART:20260212T100000Z\nEND:VEVENT\nEND:VCALENDAR"
+	client := &scriptedClient{responses: []func() (*http.Response, error){
+		icsResponse(http.StatusOK, feed, http.Header{"Etag": {`"v1"`}, "Last-Modified": {"Thu, 12 Feb 2026 10:00:00 GMT"}}),
+		icsResponse(http.StatusNotModified, "", nil),
+		func() (*http.Response, error) { return nil, io.ErrUnexpectedEOF },
+		icsResponse(http.StatusOK, "BEGIN:VCALENDAR\nEND:VCALENDAR", nil),
+	}}
+	now := time.Date(2026, 2, 12, 12, 0, 0, 0, time.UTC)
+	p := NewICSProviderWithCacheTTL("https://x", client, time.Minute)
+	p.now = func() time.Time { return now }
+	var refreshes []Refresh
+	p.ReportChanges(func(r Refresh) { refreshes = append(refreshes, r) })
+
+	list := func(calendarID string) []domain.Event {
+		t.Helper()
+		events, err := p.ListEvents(context.Background(), calendarID, time.Time{}, time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return events
+	}
+
+	if events := list(""); len(events) != 1 || events[0].CalendarID != "ics-default" {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	// Fresh: served from memory.
+	if events := list("ics-default"); len(events) != 1 || len(client.requests) != 1 {
+		t.Fatalf("expected cache hit: %+v requests=%d", events, len(client.requests))
+	}
+
+	// Expired: conditional revalidation answered with 304.
+	now = now.Add(2 * time.Minute)
+	if events := list(""); len(events) != 1 {
+		t.Fatalf("unexpected events after 304: %+v", events)
+	}
+	req := client.requests[1]
+	if req.Header.Get("If-None-Match") != `"v1"` || req.Header.Get("If-Modified-Since") == "" {
+		t.Fatalf("expected conditional request, got %v", req.Header)
+	}
+
+	// Upstream failure: stale copy is served.
+	now = now.Add(2 * time.Minute)
+	if events := list(""); len(events) != 1 {
+		t.Fatalf("expected stale events: %+v", events)
+	}
+
+	if len(refreshes) != 0 {
+		t.Fatalf("expected no refresh before the body changes, got %+v", refreshes)
+	}
+	cursor := p.SyncStatus().Calendars[0].Cursor
+
+	// Recovery: the new body replaces the cached one.
+	if events := list(""); len(events) != 0 {
+		t.Fatalf("expected refreshed feed: %+v", events)
+	}
+	if len(refreshes) != 1 || refreshes[0].CalendarID != "ics-default" || len(refreshes[0].Before) != 1 || refreshes[0].Before[0].CalendarID != "ics-default" || len(refreshes[0].After) != 0 {
+		t.Fatalf("unexpected refreshes %+v", refreshes)
+	}
+	if status := p.SyncStatus(); !status.Enabled || status.Calendars[0].Cursor == cursor || status.Calendars[0].Events != 0 {
+		t.Fatalf("unexpected sync status %+v", status)
+	}
+
+	stats := p.Health()["ics_cache"].(ICSCacheStats)
+	if stats.Entries != 1 || stats.Hits != 1 || stats.Misses != 2 || stats.NotModified != 1 || stats.StaleServed != 1 || stats.Errors != 1 || stats.LastError != "" {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestICSProviderCacheZeroTTLAndColdFailure(t *testing.T) {
+	feed := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:1\nDTSTART:20260212T100000Z\nEND:VEVENT\nEND:VCALENDAR"
+	client := &scriptedClient{responses: []func() (*http.Response, error){
+		icsResponse(http.StatusOK, "BEGIN:VCALENDAR\nBEGIN:VEVENT", nil),
+		icsResponse(http.StatusOK, feed, nil),
+		icsResponse(http.StatusOK, feed, nil),
+	}}
+	p := NewICSProviderWithCacheTTL("https://x", client, 0)
+	if _, err := p.ListEvents(context.Background(), "", time.Time{}, time.Time{}); err == nil {
+		t.Fatal("expected parse error without a cached copy")
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := p.ListEvents(context.Background(), "", time.Time{}, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(client.requests) != 3 || client.requests[2].Header.Get("If-None-Match") != "" {
+		t.Fatalf("expected unconditional refetch on every call, got %d requests", len(client.requests))
+	}
+	stats := p.Health()["ics_cache"].(ICSCacheStats)
+	if stats.Hits != 0 || stats.Misses != 2 || stats.LastError != "" {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	// Without a TTL there is nothing to refresh in the background.
+	p.RunSync(context.Background())
+	if p.SyncStatus().Enabled || len(client.requests) != 3 {
+		t.Fatal("expected background refresh to be disabled")
+	}
+}
+
+func TestICSProviderRunSync(t *testing.T) {
+	feed := "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:1\nDTSTART:20260212T100000Z\nEND:VEVENT\nEND:VCALENDAR"
+	client := feedClient{"https://a": feed}
+	p := NewICSProviderWithFeeds([]ICSFeed{{ID: "a", URL: "https://a"}, {ID: "b", URL: "https://a"}}, client, time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.RunSync(ctx)
+		close(done)
+	}()
+	for i := 0; len(p.SyncStatus().Calendars) < 2; i++ {
+		if i == 1000 {
+			t.Fatal("feeds were not fetched")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	status := p.SyncStatus()
+	if !status.Running || status.IntervalSeconds != 3600 || status.Calendars[0].Events != 1 || status.Calendars[0].Cursor != status.Calendars[1].Cursor {
+		t.Fatalf("unexpected sync status %+v", status)
+	}
+	cancel()
+	<-done
+	if p.SyncStatus().Running {
+		t.Fatal("expected sync to stop")
+	}
+}
+
+func TestParseICSFeeds(t *testing.T) {
+	feeds, err := ParseICSFeeds("https://x/only.ics")
+	if err != nil || len(feeds) != 1 || feeds[0].ID != DefaultICSCalendarID || feeds[0].URL != "https://x/only.ics" {
+		t.Fatalf("unexpected single feed: %+v err=%v", feeds, err)
+	}
+
+	feeds, err = ParseICSFeeds("work|Work|https://x/work.ics,\n home | https://x/home.ics \nhttps://x/third.ics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ICSFeed{
+		{ID: "work", Name: "Work", URL: "https://x/work.ics"},
+		{ID: "home", URL: "https://x/home.ics"},
+		{ID: "ics-3", URL: "https://x/third.ics"},
+	}
+	if len(feeds) != len(want) {
+		t.Fatalf("unexpected feeds: %+v", feeds)
+	}
+	for i := range want {
+		if feeds[i] != want[i] {
+			t.Fatalf("feed %d: got %+v want %+v", i, feeds[i], want[i])
+		}
+	}
+
+	// Commas inside a URL do not split it; commas before an entry do.
+	feeds, err = ParseICSFeeds("https://x/cal.ics?cats=a,b,c")
+	if err != nil || len(feeds) != 1 || feeds[0].URL != "https://x/cal.ics?cats=a,b,c" {
+		t.Fatalf("unexpected feed with commas: %+v err=%v", feeds, err)
+	}
+	feeds, err = ParseICSFeeds("a|https://x/a.ics?f=1,2, b|https://x/b.ics,https://x/c.ics?g=3,4")
+	if err != nil || len(feeds) != 3 || feeds[0].URL != "https://x/a.ics?f=1,2" || feeds[1].ID != "b" || feeds[2].URL != "https://x/c.ics?g=3,4" {
+		t.Fatalf("unexpected feeds: %+v err=%v", feeds, err)
+	}
+
+	for _, bad := range []string{"", " , ", "a|b|c|d", "|https://x", "a|", "a|https://x,a|https://y"} {
+		if _, err := ParseICSFeeds(bad); err == nil {
+			t.Fatalf("expected error for %q", bad)
+		}
+	}
+}
+
+// slowClient blocks requests for slow until release is closed.
+type slowClient struct {
+	feedClient
+	slow    string
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c slowClient) Do(req *http.Request) (*http.Response, error) {
+	if req.URL.String() == c.slow {
+		close(c.started)
+		<-c.release
+	}
+	return c.feedClient.Do(req)
+}
+
+func TestICSProviderSlowFeedDoesNotBlockOthers(t *testing.T) {
+	client := slowClient{
+		feedClient: feedClient{
+			"https://x/slow.ics": "BEGIN:VCALENDAR\nEND:VCALENDAR",
+			"https://x/fast.ics": "BEGIN:VCALENDAR\nEND:VCALENDAR",
+		},
+		slow:    "https://x/slow.ics",
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	p := NewICSProviderWithFeeds([]ICSFeed{{ID: "slow", URL: "https://x/slow.ics"}, {ID: "fast", URL: "https://x/fast.ics"}}, client, time.Minute)
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.ListEvents(context.Background(), "slow", time.Time{}, time.Time{})
+		done <- err
+	}()
+	<-client.started
+	if _, err := p.ListEvents(context.Background(), "fast", time.Time{}, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	close(client.release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+// feedClient serves a fixed body per URL.
+type feedClient map[string]string
+
+func (f feedClient) Do(req *http.Request) (*http.Response, error) {
+	body, ok := f[req.URL.String()]
+	if !ok {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(""))}, nil
+	}
+	return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}, nil
+}
+
+func TestICSProviderMultipleFeeds(t *testing.T) {
+	client := feedClient{
+		"https://x/work.ics": "BEGIN:VCALENDAR\nX-WR-CALNAME:Team\\, Work\nX-WR-CALDESC:Sprint meetings\nX-WR-TIMEZONE:Europe/Berlin\nX-APPLE-CALENDAR-COLOR:#1BADF8\nBEGIN:VEVENT\nUID:same\nSUMMARY:Work\nDTSTART:20260212T100000Z\nEND:VEVENT\nEND:VCALENDAR",
+		"https://x/home.ics": "BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:same\nSUMMARY:Home\nDTSTART:20260212T090000Z\nEND:VEVENT\nEND:VCALENDAR",
+	}
+	p := NewICSProviderWithFeeds([]ICSFeed{
+		{ID: "work", URL: "https://x/work.ics"},
+		{ID: "home", Name: "Family", URL: "https://x/home.ics"},
+		{ID: "gone", URL: "https://x/gone.ics"},
+	}, client, time.Minute)
+
+	cals, err := p.ListCalendars(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cals) != 3 || cals[0].Name != "Team, Work" || cals[1].Name != "Family" || cals[2].Name != "gone" || cals[2].ID != "gone" {
+		t.Fatalf("unexpected calendars: %+v", cals)
+	}
+	if c := cals[0]; c.Description != "Sprint meetings" || c.TimeZone != "Europe/Berlin" || c.Color != "#1BADF8" || c.Type != domain.CalendarTypeSubscribed || cals[1].Order != 1 {
+		t.Fatalf("unexpected feed metadata: %+v", cals)
+	}
+
+	events, err := p.ListEvents(context.Background(), "work", time.Time{}, time.Time{})
+	if err != nil || len(events) != 1 || events[0].Title != "Work" || events[0].CalendarID != "work" {
+		t.Fatalf("unexpected work events: %+v err=%v", events, err)
+	}
+
+	if _, err := p.ListEvents(context.Background(), "nope", time.Time{}, time.Time{}); !errors.Is(err, ErrCalendarNotFound) {
+		t.Fatalf("expected calendar not found, got %v", err)
+	}
+	// Without a calendar ID every feed is listed; one failing feed fails the request.
+	if _, err := p.ListEvents(context.Background(), "", time.Time{}, time.Time{}); err == nil {
+		t.Fatal("expected error from unreachable feed")
+	}
+
+	p.feeds = p.feeds[:2]
+	events, err = p.ListEvents(context.Background(), "", time.Time{}, time.Time{})
+	if err != nil || len(events) != 2 || events[0].CalendarID != "home" || events[1].CalendarID != "work" {
+		t.Fatalf("unexpected merged events: %+v err=%v", events, err)
+	}
+}
@@ -2,8 +2,11 @@ package provider
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"log/slog"
+	"strings"
 	"sync"
 	"time"
 
@@ -11,16 +14,22 @@ import (
 	"github.com/sevenofnine/proton-calendar-bridge/internal/auth"
 	bridgecrypto "github.com/sevenofnine/proton-calendar-bridge/internal/crypto"
 	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/ical"
 	"github.com/sevenofnine/proton-calendar-bridge/internal/protonapi"
 )
 
 type protonCalendarClient interface {
 	GetCalendars(ctx context.Context) ([]protonapi.Calendar, error)
 	GetCalendarMembers(ctx context.Context, id string) ([]protonapi.CalendarMember, error)
-	GetCalendarEvents(ctx context.Context, id string, page, pageSize int) ([]protonapi.CalendarEvent, error)
+	GetCalendarEvents(ctx context.Context, id string, page, pageSize int, filter protonapi.EventFilter) ([]protonapi.CalendarEvent, error)
 	GetCalendarPassphrase(ctx context.Context, id string) (protonapi.CalendarPassphrase, error)
 	GetCalendarKeys(ctx context.Context, id string) (protonapi.CalendarKeys, error)
 	GetAddresses(ctx context.Context) ([]protonapi.Address, error)
+	GetCalendarEvent(ctx context.Context, calendarID, eventID string) (protonapi.CalendarEvent, error)
+	SyncCalendarEvents(ctx context.Context, calendarID string, req protonapi.CalendarEventSyncReq) ([]protonapi.CalendarEventSyncResult, error)
+	GetLatestCalendarModelEventID(ctx context.Context, calendarID string) (string, error)
+	GetCalendarModelEvents(ctx context.Context, calendarID, cursor string) (protonapi.CalendarModelEvents, error)
+	GetCalendarUserSettings(ctx context.Context) (protonapi.CalendarUserSettings, error)
 }
 
 type ProtonProvider struct {
@@ -29,9 +38,16 @@ type ProtonProvider struct {
 	keyPassword []byte
 	keyrings    *auth.KeyringManager
 	decryptor   *bridgecrypto.EventDecryptor
+	encryptor   *bridgecrypto.EventEncryptor
+	writes      bool
 	mu          sync.RWMutex
 	addressKR   *gopenpgp.KeyRing
+	addresses   []protonapi.Address
 	calendarKRs map[string]*gopenpgp.KeyRing
+	// eventCalendars remembers which calendar each listed or written event
+	// belongs to, since update and delete only receive the event ID.
+	eventCalendars map[string]string
+	synced         protonSync
 }
 
 func NewProtonProvider(client *protonapi.Client, store auth.Store) *ProtonProvider {
@@ -45,21 +61,57 @@ func NewProtonProviderWithKeyPassword(client protonCalendarClient, store auth.St
 		keyPassword: keyPassword,
 		keyrings:    auth.NewKeyringManager(client),
 		decryptor:   &bridgecrypto.EventDecryptor{},
+		encryptor:   &bridgecrypto.EventEncryptor{},
 		calendarKRs: make(map[string]*gopenpgp.KeyRing),
 	}
 }
 
+// SetWriteEnabled turns on CreateEvent, UpdateEvent and DeleteEvent.
+func (p *ProtonProvider) SetWriteEnabled(enabled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.writes = enabled
+}
+
+func (p *ProtonProvider) writeEnabled() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.writes
+}
+
 func (p *ProtonProvider) Name() string { return "proton" }
 
+type sessionReporter interface {
+	Status() protonapi.ConnectionStatus
+	AuthError() error
+}
+
+// Health reports the upstream session state, including why it was lost.
+func (p *ProtonProvider) Health() map[string]any {
+	session := map[string]any{"status": protonapi.StatusUnknown}
+	if sr, ok := p.client.(sessionReporter); ok {
+		session["status"] = sr.Status()
+		if err := sr.AuthError(); err != nil {
+			session["error"] = err.Error()
+		}
+	}
+	return map[string]any{"proton_session": session}
+}
+
 func (p *ProtonProvider) Capabilities(context.Context) (CapabilitySet, error) {
+	writes := p.writeEnabled()
+	notes := []string{"Proton provider is read-only; set PCB_PROTON_WRITE=true to enable writes."}
+	if writes {
+		notes = []string{"Writes target whole events or series by their series_id; attendees cannot be written."}
+	}
 	return CapabilitySet{
-		ReadOnly:        true,
-		WriteSupported:  false,
+		ReadOnly:        !writes,
+		WriteSupported:  writes,
 		SharedCalendars: true,
 		Attendees:       true,
 		Reminders:       true,
 		Recurrence:      true,
-		Notes:           []string{"Proton provider is read-only during Phase 2."},
+		Notes:           notes,
 	}, nil
 }
 
@@ -71,21 +123,46 @@ func (p *ProtonProvider) ListCalendars(ctx context.Context) ([]domain.Calendar,
 	if err != nil {
 		return nil, err
 	}
+	// Proton calendars have no time zone of their own; events are shown in
+	// the account's primary one.
+	var timeZone string
+	settings, err := p.client.GetCalendarUserSettings(ctx)
+	if err != nil {
+		slog.Warn("failed to fetch proton calendar settings", "error", err)
+	} else {
+		timeZone = settings.PrimaryTimezone
+	}
+	addresses, err := p.userAddresses(ctx)
+	if err != nil {
+		return nil, err
+	}
 	out := make([]domain.Calendar, 0, len(items))
-	for _, c := range items {
+	for i, c := range items {
 		members, err := p.client.GetCalendarMembers(ctx, c.ID)
 		if err != nil {
 			return nil, fmt.Errorf("get calendar members for %s: %w", c.ID, err)
 		}
-		permissions := []string{"read"}
-		readOnly := true
-		if len(members) > 0 && int(members[0].Permissions) > 0 {
-			permissions = append(permissions, "write")
-			readOnly = false
+		member, err := matchMember(c.ID, members, addresses)
+		if err != nil {
+			// One calendar we cannot act on must not hide the others.
+			slog.Warn("skipping proton calendar", "calendar_id", c.ID, "error", err)
+			continue
+		}
+		permissions, readOnly := memberPermissions(member.Permissions)
+		color := member.Color
+		if color == "" {
+			color = c.Color
 		}
 		out = append(out, domain.Calendar{
 			ID:          c.ID,
 			Name:        c.Name,
+			Description: c.Description,
+			Color:       color,
+			TimeZone:    timeZone,
+			Type:        calendarType(c.Type),
+			Owner:       calendarOwner(members),
+			MemberCount: len(members),
+			Order:       i,
 			ReadOnly:    readOnly,
 			Shared:      c.Type != protonapi.CalendarTypeNormal,
 			Permissions: permissions,
@@ -94,23 +171,141 @@ func (p *ProtonProvider) ListCalendars(ctx context.Context) ([]domain.Calendar,
 	return out, nil
 }
 
+func calendarType(t protonapi.CalendarType) string {
+	switch t {
+	case protonapi.CalendarTypeSubscribed:
+		return domain.CalendarTypeSubscribed
+	case protonapi.CalendarTypeHolidays:
+		return domain.CalendarTypeHolidays
+	default:
+		return domain.CalendarTypePersonal
+	}
+}
+
+// calendarOwner returns the address of the member owning the calendar.
+func calendarOwner(members []protonapi.CalendarMember) string {
+	for _, m := range members {
+		if m.Permissions&(protonapi.CalendarPermissionSuperOwner|protonapi.CalendarPermissionOwner) != 0 {
+			return m.Email
+		}
+	}
+	return ""
+}
+
+// memberPermissions maps Proton permission bits. Members that may only see
+// free/busy information get the "availability" permission alone.
+func memberPermissions(perms protonapi.CalendarPermissions) ([]string, bool) {
+	if perms&protonapi.CalendarPermissionsWrite != 0 {
+		return []string{"read", "write"}, false
+	}
+	if perms != 0 && perms&^protonapi.CalendarPermissionAvailability == 0 {
+		return []string{"availability"}, true
+	}
+	return []string{"read"}, true
+}
+
 func (p *ProtonProvider) ListEvents(ctx context.Context, calendarID string, from, to time.Time) ([]domain.Event, error) {
+	events, err := p.seriesSource(ctx, calendarID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return expandEvents(events, from, to, time.Now()), nil
+}
+
+func (p *ProtonProvider) ListSeries(ctx context.Context, calendarID string, from, to time.Time) ([]domain.Event, error) {
+	events, err := p.seriesSource(ctx, calendarID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return filterSeries(events, from, to, time.Now()), nil
+}
+
+// GetEvent answers from the synced store when possible. Otherwise the event
+// is fetched directly, falling back to a full listing for instance IDs.
+func (p *ProtonProvider) GetEvent(ctx context.Context, calendarID, eventID string) (domain.Event, error) {
+	if p.client == nil {
+		return domain.Event{}, fmt.Errorf("proton client is not configured")
+	}
+	if calendarID == "" {
+		return domain.Event{}, InvalidInputError{Field: "calendar_id", Reason: "required"}
+	}
+	events, synced := p.syncedEvents(calendarID)
+	if !synced {
+		if item, err := p.client.GetCalendarEvent(ctx, calendarID, eventID); err == nil {
+			return p.fetchedEvent(ctx, calendarID, item)
+		}
+		var err error
+		if events, err = p.listSeries(ctx, calendarID, time.Time{}, time.Time{}); err != nil {
+			return domain.Event{}, err
+		}
+	}
+	e, ok := findEvent(events, eventID, time.Now())
+	if !ok {
+		return domain.Event{}, fmt.Errorf("%w: %s", ErrEventNotFound, eventID)
+	}
+	return e, nil
+}
+
+func (p *ProtonProvider) fetchedEvent(ctx context.Context, calendarID string, item protonapi.CalendarEvent) (domain.Event, error) {
+	calKR, err := p.calendarKeyRing(ctx, calendarID)
+	if err != nil {
+		return domain.Event{}, err
+	}
+	addrKR, err := p.addressKeyRing(ctx)
+	if err != nil {
+		return domain.Event{}, err
+	}
+	p.mu.Lock()
+	if p.eventCalendars == nil {
+		p.eventCalendars = make(map[string]string)
+	}
+	p.eventCalendars[item.ID] = calendarID
+	p.mu.Unlock()
+	return p.toEvent(item, calKR, addrKR), nil
+}
+
+// seriesSource answers from the synced store when the calendar has been
+// synced, and from the API otherwise.
+func (p *ProtonProvider) seriesSource(ctx context.Context, calendarID string, from, to time.Time) ([]domain.Event, error) {
+	if events, ok := p.syncedEvents(calendarID); ok {
+		return events, nil
+	}
+	return p.listSeries(ctx, calendarID, from, to)
+}
+
+// listSeries fetches and decrypts the events that can produce instances in
+// [from, to]. With a lower bound only that window is paged from the API,
+// including series that started before it; otherwise every event is listed.
+func (p *ProtonProvider) listSeries(ctx context.Context, calendarID string, from, to time.Time) ([]domain.Event, error) {
 	if p.client == nil {
 		return nil, fmt.Errorf("proton client is not configured")
 	}
 	if calendarID == "" {
-		return nil, fmt.Errorf("calendar id is required")
+		return nil, InvalidInputError{Field: "calendar_id", Reason: "required"}
+	}
+	filters := []protonapi.EventFilter{{}}
+	if !from.IsZero() {
+		_, end := expansionWindow(from, to, time.Now())
+		filters = filters[:0]
+		for _, typ := range protonapi.EventQueryTypes {
+			filters = append(filters, protonapi.EventFilter{Start: from, End: end, Timezone: "UTC", Type: typ})
+		}
 	}
-	const pageSize = 100
 	var items []protonapi.CalendarEvent
-	for page := 0; ; page++ {
-		batch, err := p.client.GetCalendarEvents(ctx, calendarID, page, pageSize)
+	seen := make(map[string]bool)
+	for _, filter := range filters {
+		batch, err := p.pageEvents(ctx, calendarID, filter)
 		if err != nil {
 			return nil, err
 		}
-		items = append(items, batch...)
-		if len(batch) < pageSize {
-			break
+		// Query types can overlap, so drop events an earlier type returned.
+		for _, item := range batch {
+			if !seen[item.ID] {
+				items = append(items, item)
+			}
+		}
+		for _, item := range batch {
+			seen[item.ID] = true
 		}
 	}
 
@@ -125,55 +320,132 @@ func (p *ProtonProvider) ListEvents(ctx context.Context, calendarID string, from
 
 	out := make([]domain.Event, 0, len(items))
 	for _, item := range items {
-		dec, err := p.decryptor.DecryptEvent(item, calKR, addrKR)
+		out = append(out, p.toEvent(item, calKR, addrKR))
+	}
+	p.mu.Lock()
+	if p.eventCalendars == nil {
+		p.eventCalendars = make(map[string]string)
+	}
+	for _, item := range items {
+		p.eventCalendars[item.ID] = calendarID
+	}
+	p.mu.Unlock()
+	return out, nil
+}
+
+func (p *ProtonProvider) pageEvents(ctx context.Context, calendarID string, filter protonapi.EventFilter) ([]protonapi.CalendarEvent, error) {
+	const pageSize = 100
+	var items []protonapi.CalendarEvent
+	for page := 0; ; page++ {
+		batch, err := p.client.GetCalendarEvents(ctx, calendarID, page, pageSize, filter)
 		if err != nil {
-			slog.Warn("failed to decrypt event", "event_id", item.ID, "error", err)
-			out = append(out, domain.Event{
-				ID:         item.ID,
-				CalendarID: item.CalendarID,
-				Title:      "[decrypt error]",
-				Start:      time.Unix(item.StartTime, 0).UTC(),
-				End:        time.Unix(item.EndTime, 0).UTC(),
-				AllDay:     bool(item.FullDay),
-			})
-			continue
+			return nil, err
 		}
-		parsed, err := bridgecrypto.ParseVCalendar(dec.SharedData, dec.PersonalData)
-		if err != nil {
-			slog.Warn("failed to parse event", "event_id", item.ID, "error", err)
-			out = append(out, domain.Event{
-				ID:         item.ID,
-				CalendarID: item.CalendarID,
-				Title:      "[parse error]",
-				Start:      time.Unix(item.StartTime, 0).UTC(),
-				End:        time.Unix(item.EndTime, 0).UTC(),
-				AllDay:     bool(item.FullDay),
-			})
-			continue
+		items = append(items, batch...)
+		if len(batch) < pageSize {
+			return items, nil
 		}
+	}
+}
 
-		e := domain.Event{
-			ID:          item.ID,
-			CalendarID:  item.CalendarID,
-			Title:       parsed.Title,
-			Description: parsed.Description,
-			Location:    parsed.Location,
-			Start:       parsed.Start,
-			End:         parsed.End,
-			AllDay:      parsed.AllDay,
-			Recurrence:  parsed.Recurrence,
-			Attendees:   parsed.Attendees,
-			Reminders:   parsed.Reminders,
+// toEvent decrypts and maps a Proton event. Events that cannot be decrypted
+// or parsed degrade to a placeholder title instead of failing the listing.
+func (p *ProtonProvider) toEvent(item protonapi.CalendarEvent, calKR, addrKR *gopenpgp.KeyRing) domain.Event {
+	dec, err := p.decryptor.DecryptEvent(item, calKR, addrKR)
+	if err != nil {
+		slog.Warn("failed to decrypt event", "event_id", item.ID, "error", err)
+		return domain.Event{
+			ID:         item.ID,
+			UID:        item.UID,
+			CalendarID: item.CalendarID,
+			Title:      "[decrypt error]",
+			Start:      time.Unix(item.StartTime, 0).UTC(),
+			End:        time.Unix(item.EndTime, 0).UTC(),
+			AllDay:     bool(item.FullDay),
 		}
-		if !from.IsZero() && e.End.Before(from) {
-			continue
+	}
+	parsed, err := bridgecrypto.ParseVCalendar(joinParts(dec.SharedData, dec.CalendarData, dec.AttendeesData), dec.PersonalData)
+	if err != nil {
+		slog.Warn("failed to parse event", "event_id", item.ID, "error", err)
+		return domain.Event{
+			ID:         item.ID,
+			UID:        item.UID,
+			CalendarID: item.CalendarID,
+			Title:      "[parse error]",
+			Start:      time.Unix(item.StartTime, 0).UTC(),
+			End:        time.Unix(item.EndTime, 0).UTC(),
+			AllDay:     bool(item.FullDay),
 		}
-		if !to.IsZero() && e.Start.After(to) {
+	}
+
+	uid := parsed.UID
+	if uid == "" {
+		uid = item.UID
+	}
+	var recurrenceID *time.Time
+	if !parsed.RecurrenceID.IsZero() {
+		recurrenceID = &parsed.RecurrenceID
+	}
+	return domain.Event{
+		ID:              item.ID,
+		UID:             uid,
+		RecurrenceID:    recurrenceID,
+		Status:          parsed.Status,
+		CalendarID:      item.CalendarID,
+		Title:           parsed.Title,
+		Description:     parsed.Description,
+		Location:        parsed.Location,
+		Start:           parsed.Start,
+		End:             parsed.End,
+		AllDay:          parsed.AllDay,
+		TimeZone:        parsed.TimeZone,
+		Floating:        parsed.Floating,
+		Transparent:     parsed.Transparent,
+		Recurrence:      parsed.Recurrence,
+		RecurrenceDates: parsed.RDates,
+		ExceptionDates:  parsed.ExDates,
+		Organizer:       parsed.Organizer,
+		Attendees:       attendeeStatuses(parsed, item.Attendees),
+		Reminders:       parsed.Reminders,
+	}
+}
+
+// attendeeStatuses fills in the participation status Proton reports next to
+// the encrypted event, matched by attendee token.
+func attendeeStatuses(parsed bridgecrypto.ParsedEvent, statuses []protonapi.CalendarAttendee) []domain.Attendee {
+	byToken := make(map[string]protonapi.CalendarAttendeeStatus, len(statuses))
+	for _, s := range statuses {
+		byToken[s.Token] = s.Status
+	}
+	for i, token := range parsed.AttendeeTokens {
+		status, ok := byToken[token]
+		if token == "" || !ok {
 			continue
 		}
-		out = append(out, e)
+		switch status {
+		case protonapi.CalendarAttendeeStatusMaybe:
+			parsed.Attendees[i].Status = "TENTATIVE"
+		case protonapi.CalendarAttendeeStatusNo:
+			parsed.Attendees[i].Status = "DECLINED"
+		case protonapi.CalendarAttendeeStatusYes:
+			parsed.Attendees[i].Status = "ACCEPTED"
+		default:
+			parsed.Attendees[i].Status = "NEEDS-ACTION"
+		}
 	}
-	return out, nil
+	return parsed.Attendees
+}
+
+// joinParts concatenates decrypted VCALENDAR parts. STATUS and other
+// calendar-scoped properties live in the calendar parts, not the shared ones.
+func joinParts(parts ...string) string {
+	var out []string
+	for _, part := range parts {
+		if part != "" {
+			out = append(out, part)
+		}
+	}
+	return strings.Join(out, "\n")
 }
 
 func (p *ProtonProvider) addressKeyRing(ctx context.Context) (*gopenpgp.KeyRing, error) {
@@ -196,7 +468,7 @@ func (p *ProtonProvider) addressKeyRing(ctx context.Context) (*gopenpgp.KeyRing,
 	}
 	kr, err := p.keyrings.UnlockAddressKeys(ctx, p.keyPassword)
 	if err != nil {
-		return nil, fmt.Errorf("unlock address keys: %w", err)
+		return nil, fmt.Errorf("unlock address keys: %w: %w", ErrDecryptFailed, err)
 	}
 
 	p.mu.Lock()
@@ -228,14 +500,11 @@ func (p *ProtonProvider) calendarKeyRing(ctx context.Context, calendarID string)
 		return nil, err
 	}
 
-	members, err := p.client.GetCalendarMembers(ctx, calendarID)
+	member, err := p.ownMember(ctx, calendarID)
 	if err != nil {
-		return nil, fmt.Errorf("get calendar members: %w", err)
-	}
-	if len(members) == 0 {
-		return nil, fmt.Errorf("calendar %s has no members", calendarID)
+		return nil, err
 	}
-	memberID := members[0].ID
+	memberID := member.ID
 
 	passphrase, err := p.client.GetCalendarPassphrase(ctx, calendarID)
 	if err != nil {
@@ -243,7 +512,7 @@ func (p *ProtonProvider) calendarKeyRing(ctx context.Context, calendarID string)
 	}
 	calendarPassphrase, err := passphrase.Decrypt(memberID, addrKR)
 	if err != nil {
-		return nil, fmt.Errorf("decrypt calendar passphrase: %w", err)
+		return nil, fmt.Errorf("decrypt calendar passphrase: %w: %w", ErrDecryptFailed, err)
 	}
 
 	keys, err := p.client.GetCalendarKeys(ctx, calendarID)
@@ -252,7 +521,7 @@ func (p *ProtonProvider) calendarKeyRing(ctx context.Context, calendarID string)
 	}
 	calKR, err := keys.Unlock(calendarPassphrase)
 	if err != nil {
-		return nil, fmt.Errorf("unlock calendar keys: %w", err)
+		return nil, fmt.Errorf("unlock calendar keys: %w: %w", ErrDecryptFailed, err)
 	}
 
 	p.mu.Lock()
@@ -264,14 +533,319 @@ func (p *ProtonProvider) calendarKeyRing(ctx context.Context, calendarID string)
 	return calKR, nil
 }
 
-func (p *ProtonProvider) CreateEvent(context.Context, domain.EventMutation) (domain.Event, error) {
-	return domain.Event{}, NotSupportedError{Operation: "create_event"}
+// memberID returns the user's membership in the calendar.
+func (p *ProtonProvider) memberID(ctx context.Context, calendarID string) (string, error) {
+	member, err := p.ownMember(ctx, calendarID)
+	if err != nil {
+		return "", err
+	}
+	return member.ID, nil
+}
+
+// ownMember finds the member of a calendar that belongs to one of the user's
+// addresses. Shared calendars list every member, in no particular order.
+func (p *ProtonProvider) ownMember(ctx context.Context, calendarID string) (protonapi.CalendarMember, error) {
+	members, err := p.client.GetCalendarMembers(ctx, calendarID)
+	if err != nil {
+		return protonapi.CalendarMember{}, fmt.Errorf("get calendar members for %s: %w", calendarID, err)
+	}
+	addresses, err := p.userAddresses(ctx)
+	if err != nil {
+		return protonapi.CalendarMember{}, err
+	}
+	return matchMember(calendarID, members, addresses)
+}
+
+// matchMember picks the member belonging to one of the user's addresses.
+func matchMember(calendarID string, members []protonapi.CalendarMember, addresses []protonapi.Address) (protonapi.CalendarMember, error) {
+	for _, addr := range addresses {
+		for _, m := range members {
+			if strings.EqualFold(m.Email, addr.Email) {
+				return m, nil
+			}
+		}
+	}
+	return protonapi.CalendarMember{}, fmt.Errorf("calendar %s has no member for any of your addresses", calendarID)
+}
+
+func (p *ProtonProvider) userAddresses(ctx context.Context) ([]protonapi.Address, error) {
+	p.mu.RLock()
+	addresses := p.addresses
+	p.mu.RUnlock()
+	if addresses != nil {
+		return addresses, nil
+	}
+	addresses, err := p.client.GetAddresses(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get addresses: %w", err)
+	}
+	p.mu.Lock()
+	p.addresses = addresses
+	p.mu.Unlock()
+	return addresses, nil
+}
+
+func (p *ProtonProvider) CreateEvent(ctx context.Context, in domain.EventMutation) (domain.Event, error) {
+	if !p.writeEnabled() {
+		return domain.Event{}, NotSupportedError{Operation: "create_event"}
+	}
+	if in.CalendarID == "" {
+		return domain.Event{}, InvalidInputError{Field: "calendar_id", Reason: "required"}
+	}
+	if err := validateMutation(in); err != nil {
+		return domain.Event{}, err
+	}
+	if len(in.Attendees) > 0 {
+		return domain.Event{}, NotSupportedError{Operation: "write_attendees"}
+	}
+	calKR, addrKR, member, err := p.writeKeys(ctx, in.CalendarID)
+	if err != nil {
+		return domain.Event{}, err
+	}
+	uid := in.UID
+	if uid == "" {
+		if uid, err = newEventUID(); err != nil {
+			return domain.Event{}, err
+		}
+	}
+	data, err := p.encryptor.EncryptEvent(bridgecrypto.BuildEventParts(uid, member.Email, 0, in, time.Now()), calKR, addrKR, nil)
+	if err != nil {
+		return domain.Event{}, fmt.Errorf("encrypt event: %w", err)
+	}
+	return p.syncEvent(ctx, in.CalendarID, member.ID, protonapi.CalendarEventSync{Event: &data}, calKR, addrKR)
+}
+
+func (p *ProtonProvider) UpdateEvent(ctx context.Context, eventID string, in domain.EventMutation) (domain.Event, error) {
+	if !p.writeEnabled() {
+		return domain.Event{}, NotSupportedError{Operation: "update_event"}
+	}
+	if err := validateMutation(in); err != nil {
+		return domain.Event{}, err
+	}
+	calendarID := in.CalendarID
+	if calendarID == "" {
+		var err error
+		if calendarID, err = p.eventCalendar(ctx, eventID); err != nil {
+			return domain.Event{}, err
+		}
+	}
+	existing, err := p.client.GetCalendarEvent(ctx, calendarID, eventID)
+	if err != nil {
+		return domain.Event{}, fmt.Errorf("get event %s: %w", eventID, err)
+	}
+	calKR, addrKR, member, err := p.writeKeys(ctx, calendarID)
+	if err != nil {
+		return domain.Event{}, err
+	}
+	sessionKey, err := bridgecrypto.SharedSessionKey(existing, calKR)
+	if err != nil {
+		return domain.Event{}, err
+	}
+	existingParts, err := p.decryptor.DecryptEventParts(existing, calKR, addrKR, member.ID)
+	if err != nil {
+		return domain.Event{}, fmt.Errorf("decrypt event %s: %w: %w", eventID, ErrDecryptFailed, err)
+	}
+	current, err := bridgecrypto.ParseVCalendar(joinParts(existingParts.SharedSigned, existingParts.SharedEncrypted), "")
+	if err != nil {
+		return domain.Event{}, fmt.Errorf("parse event %s: %w", eventID, err)
+	}
+	uid := current.UID
+	if uid == "" {
+		uid = existing.UID
+	}
+	if uid == "" {
+		return domain.Event{}, fmt.Errorf("event %s has no UID", eventID)
+	}
+	if in.UID != "" && in.UID != uid {
+		return domain.Event{}, InvalidInputError{Field: "uid", Reason: "cannot be changed"}
+	}
+	// Attendees are kept from the existing event; naming others would need
+	// new invitations, which cannot be sent.
+	if len(in.Attendees) > 0 && !SameAttendees(in.Attendees, p.toEvent(existing, calKR, addrKR).Attendees) {
+		return domain.Event{}, NotSupportedError{Operation: "write_attendees"}
+	}
+	sequence := current.Sequence + 1
+	parts, err := bridgecrypto.UpdateEventParts(existingParts, uid, member.Email, sequence, in, time.Now())
+	if err != nil {
+		return domain.Event{}, fmt.Errorf("update event %s: %w", eventID, err)
+	}
+	data, err := p.encryptor.EncryptEvent(parts, calKR, addrKR, sessionKey)
+	if err != nil {
+		return domain.Event{}, fmt.Errorf("encrypt event: %w", err)
+	}
+	keepUnchangedParts(&data, existing)
+	return p.syncEvent(ctx, calendarID, member.ID, protonapi.CalendarEventSync{ID: eventID, Event: &data}, calKR, addrKR)
+}
+
+// keepUnchangedParts resends the encrypted calendar part and the attendee
+// parts of an existing event, which hold COMMENT and ATTENDEE and which a
+// mutation does not change. They are still signed and encrypted with the
+// event's keys. The signed calendar part is rebuilt by the update.
+func keepUnchangedParts(data *protonapi.CalendarEventData, existing protonapi.CalendarEvent) {
+	data.CalendarKeyPacket = existing.CalendarKeyPacket
+	for _, part := range existing.CalendarEvents {
+		if part.Type&protonapi.CalendarEventTypeEncrypted == 0 {
+			continue
+		}
+		data.CalendarEventContent = append(data.CalendarEventContent, protonapi.CalendarEventContent{Type: part.Type, Data: part.Data, Signature: part.Signature})
+	}
+	for _, part := range existing.AttendeesEvents {
+		data.AttendeesEventContent = append(data.AttendeesEventContent, protonapi.CalendarEventContent{Type: part.Type, Data: part.Data, Signature: part.Signature})
+	}
+	for _, a := range existing.Attendees {
+		data.Attendees = append(data.Attendees, protonapi.CalendarEventAttendee{Token: a.Token, Status: a.Status})
+	}
+}
+
+func (p *ProtonProvider) DeleteEvent(ctx context.Context, eventID string) error {
+	if !p.writeEnabled() {
+		return NotSupportedError{Operation: "delete_event"}
+	}
+	calendarID, err := p.eventCalendar(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	memberID, err := p.memberID(ctx, calendarID)
+	if err != nil {
+		return err
+	}
+	req := protonapi.CalendarEventSyncReq{MemberID: memberID, Events: []protonapi.CalendarEventSync{{ID: eventID}}}
+	if _, err := p.client.SyncCalendarEvents(ctx, calendarID, req); err != nil {
+		return fmt.Errorf("delete event %s: %w", eventID, err)
+	}
+	p.mu.Lock()
+	delete(p.eventCalendars, eventID)
+	p.mu.Unlock()
+	p.removeSynced(calendarID, eventID)
+	return nil
 }
 
-func (p *ProtonProvider) UpdateEvent(context.Context, string, domain.EventMutation) (domain.Event, error) {
-	return domain.Event{}, NotSupportedError{Operation: "update_event"}
+func (p *ProtonProvider) writeKeys(ctx context.Context, calendarID string) (*gopenpgp.KeyRing, *gopenpgp.KeyRing, protonapi.CalendarMember, error) {
+	calKR, err := p.calendarKeyRing(ctx, calendarID)
+	if err != nil {
+		return nil, nil, protonapi.CalendarMember{}, err
+	}
+	addrKR, err := p.addressKeyRing(ctx)
+	if err != nil {
+		return nil, nil, protonapi.CalendarMember{}, err
+	}
+	member, err := p.ownMember(ctx, calendarID)
+	if err != nil {
+		return nil, nil, protonapi.CalendarMember{}, err
+	}
+	return calKR, addrKR, member, nil
 }
 
-func (p *ProtonProvider) DeleteEvent(context.Context, string) error {
-	return NotSupportedError{Operation: "delete_event"}
+// syncEvent sends a single create or update and maps the stored event.
+func (p *ProtonProvider) syncEvent(ctx context.Context, calendarID, memberID string, op protonapi.CalendarEventSync, calKR, addrKR *gopenpgp.KeyRing) (domain.Event, error) {
+	req := protonapi.CalendarEventSyncReq{MemberID: memberID, Events: []protonapi.CalendarEventSync{op}}
+	res, err := p.client.SyncCalendarEvents(ctx, calendarID, req)
+	if err != nil {
+		return domain.Event{}, fmt.Errorf("sync event: %w", err)
+	}
+	if len(res) == 0 || res[0].Response.Event.ID == "" {
+		return domain.Event{}, fmt.Errorf("sync event: no event returned")
+	}
+	stored := res[0].Response.Event
+	if stored.CalendarID == "" {
+		stored.CalendarID = calendarID
+	}
+	p.mu.Lock()
+	if p.eventCalendars == nil {
+		p.eventCalendars = make(map[string]string)
+	}
+	p.eventCalendars[stored.ID] = calendarID
+	p.mu.Unlock()
+	event := p.toEvent(stored, calKR, addrKR)
+	p.storeSynced(calendarID, event)
+	return event, nil
+}
+
+// eventCalendar finds the calendar of an event, asking each calendar in turn
+// when the event has not been listed or written through this provider.
+func (p *ProtonProvider) eventCalendar(ctx context.Context, eventID string) (string, error) {
+	p.mu.RLock()
+	calendarID, ok := p.eventCalendars[eventID]
+	p.mu.RUnlock()
+	if ok {
+		return calendarID, nil
+	}
+	calendars, err := p.client.GetCalendars(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, c := range calendars {
+		if _, err := p.client.GetCalendarEvent(ctx, c.ID, eventID); err == nil {
+			p.mu.Lock()
+			if p.eventCalendars == nil {
+				p.eventCalendars = make(map[string]string)
+			}
+			p.eventCalendars[eventID] = c.ID
+			p.mu.Unlock()
+			return c.ID, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrEventNotFound, eventID)
+}
+
+// maxUIDLength bounds client-chosen UIDs, which Proton indexes.
+const maxUIDLength = 255
+
+func validateMutation(in domain.EventMutation) error {
+	if in.Start.IsZero() {
+		return InvalidInputError{Field: "start", Reason: "required"}
+	}
+	if in.End.IsZero() {
+		return InvalidInputError{Field: "end", Reason: "required"}
+	}
+	if in.End.Before(in.Start) {
+		return InvalidInputError{Field: "end", Reason: "must not be before start"}
+	}
+	if in.TimeZone != "" {
+		if _, err := time.LoadLocation(in.TimeZone); err != nil || in.TimeZone == "Local" {
+			return InvalidInputError{Field: "timezone", Reason: "must be an IANA time zone"}
+		}
+	}
+	if len(in.UID) > maxUIDLength || strings.ContainsAny(in.UID, "\r\n") {
+		return InvalidInputError{Field: "uid", Reason: fmt.Sprintf("must be a single line of at most %d characters", maxUIDLength)}
+	}
+	for i, r := range in.Reminders {
+		if err := validateReminder(r); err != nil {
+			err.Field = fmt.Sprintf("reminders[%d].%s", i, err.Field)
+			return *err
+		}
+	}
+	return nil
+}
+
+// validateReminder accepts the alarm kinds Proton supports: display and email
+// reminders with either a relative offset or an absolute time.
+func validateReminder(r domain.Reminder) *InvalidInputError {
+	switch r.Type {
+	case "", domain.ReminderDisplay, domain.ReminderEmail:
+	default:
+		return &InvalidInputError{Field: "type", Reason: fmt.Sprintf("unsupported reminder type %q", r.Type)}
+	}
+	switch r.Related {
+	case "", domain.RelatedStart, domain.RelatedEnd:
+	default:
+		return &InvalidInputError{Field: "related", Reason: `must be "start" or "end"`}
+	}
+	if (r.Offset == "") == (r.At == nil) {
+		return &InvalidInputError{Field: "offset", Reason: "set either offset or at"}
+	}
+	if r.Offset != "" {
+		if _, err := ical.ParseDuration(r.Offset); err != nil {
+			return &InvalidInputError{Field: "offset", Reason: err.Error()}
+		}
+	}
+	return nil
+}
+
+func newEventUID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("generate uid: %w", err)
+	}
+	return hex.EncodeToString(buf) + "@proton-calendar-bridge", nil
 }
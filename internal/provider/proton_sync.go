@@ -0,0 +1,343 @@
+package provider
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/protonapi"
+)
+
+// DefaultProtonSyncInterval is how often the Proton event loop is polled.
+const DefaultProtonSyncInterval = time.Minute
+
+// protonSync holds the decrypted events of every synced calendar together
+// with the event loop cursor they are current as of.
+type protonSync struct {
+	mu        sync.RWMutex
+	interval  time.Duration
+	running   bool
+	lastError string
+	calendars map[string]*calendarSync
+	loaded    bool
+	report    func(Refresh)
+}
+
+type calendarSync struct {
+	cursor    string
+	events    map[string]domain.Event
+	lastSync  time.Time
+	lastError string
+}
+
+// SetSyncInterval sets how often RunSync polls the event loop. Zero disables
+// background sync, so every listing goes to the API.
+func (p *ProtonProvider) SetSyncInterval(interval time.Duration) {
+	p.synced.mu.Lock()
+	defer p.synced.mu.Unlock()
+	p.synced.interval = interval
+}
+
+// ReportChanges makes the sync report every calendar whose stored events
+// changed, including through the bridge's own writes. Calendar names and
+// colors are not part of the event loop, so each pass also reports that the
+// calendar list may have changed.
+func (p *ProtonProvider) ReportChanges(fn func(Refresh)) {
+	p.synced.mu.Lock()
+	defer p.synced.mu.Unlock()
+	p.synced.report = fn
+}
+
+// RunSync lists every calendar once and then applies event loop deltas to the
+// in-memory store until ctx is done.
+func (p *ProtonProvider) RunSync(ctx context.Context) {
+	p.synced.mu.Lock()
+	interval := p.synced.interval
+	if interval <= 0 || p.synced.running {
+		p.synced.mu.Unlock()
+		return
+	}
+	p.synced.running = true
+	p.synced.mu.Unlock()
+	defer func() {
+		p.synced.mu.Lock()
+		p.synced.running = false
+		p.synced.mu.Unlock()
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		p.syncOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *ProtonProvider) SyncStatus() SyncStatus {
+	now := time.Now()
+	p.synced.mu.RLock()
+	defer p.synced.mu.RUnlock()
+	out := SyncStatus{
+		Enabled:         p.synced.interval > 0,
+		Running:         p.synced.running,
+		IntervalSeconds: p.synced.interval.Seconds(),
+		LastError:       p.synced.lastError,
+		Calendars:       make([]CalendarSyncStatus, 0, len(p.synced.calendars)),
+	}
+	for id, c := range p.synced.calendars {
+		out.Calendars = append(out.Calendars, CalendarSyncStatus{
+			CalendarID: id,
+			Cursor:     c.cursor,
+			Events:     len(c.events),
+			LastSync:   c.lastSync,
+			LagSeconds: now.Sub(c.lastSync).Seconds(),
+			LastError:  c.lastError,
+		})
+	}
+	sort.Slice(out.Calendars, func(i, j int) bool { return out.Calendars[i].CalendarID < out.Calendars[j].CalendarID })
+	return out
+}
+
+// syncOnce brings every calendar up to date. Failures are recorded in the
+// status and the previous state keeps being served.
+func (p *ProtonProvider) syncOnce(ctx context.Context) {
+	if p.client == nil {
+		return
+	}
+	calendars, err := p.client.GetCalendars(ctx)
+	p.synced.mu.Lock()
+	if err != nil {
+		p.synced.lastError = err.Error()
+		p.synced.mu.Unlock()
+		slog.Warn("proton sync: list calendars failed", "error", err)
+		return
+	}
+	p.synced.lastError = ""
+	present := make(map[string]bool, len(calendars))
+	for _, c := range calendars {
+		present[c.ID] = true
+	}
+	var removed []Refresh
+	for id, state := range p.synced.calendars {
+		if !present[id] {
+			removed = append(removed, Refresh{CalendarID: id, Before: state.list()})
+			delete(p.synced.calendars, id)
+		}
+	}
+	report := p.synced.report
+	p.synced.mu.Unlock()
+	if report != nil {
+		defer report(Refresh{})
+		for _, r := range removed {
+			report(r)
+		}
+	}
+
+	for _, c := range calendars {
+		if err := p.syncCalendar(ctx, c.ID); err != nil {
+			slog.Warn("proton sync failed", "calendar_id", c.ID, "error", err)
+			p.synced.mu.Lock()
+			if state := p.synced.calendars[c.ID]; state != nil {
+				state.lastError = err.Error()
+			}
+			p.synced.mu.Unlock()
+		}
+	}
+	p.synced.mu.Lock()
+	p.synced.loaded = true
+	p.synced.mu.Unlock()
+}
+
+// syncCalendar follows the event loop from the stored cursor, falling back to
+// a full listing for new calendars and when the API asks for a refresh.
+func (p *ProtonProvider) syncCalendar(ctx context.Context, calendarID string) error {
+	p.synced.mu.RLock()
+	state := p.synced.calendars[calendarID]
+	var cursor string
+	if state != nil {
+		cursor = state.cursor
+	}
+	p.synced.mu.RUnlock()
+	if state == nil {
+		return p.resyncCalendar(ctx, calendarID)
+	}
+
+	var changes []protonapi.CalendarEventChange
+	for {
+		page, err := p.client.GetCalendarModelEvents(ctx, calendarID, cursor)
+		if err != nil {
+			return err
+		}
+		if page.Refresh != 0 {
+			return p.resyncCalendar(ctx, calendarID)
+		}
+		changes = append(changes, page.Events...)
+		cursor = page.CalendarModelEventID
+		if !page.More {
+			break
+		}
+	}
+
+	updated := make(map[string]domain.Event)
+	if hasUpserts(changes) {
+		calKR, err := p.calendarKeyRing(ctx, calendarID)
+		if err != nil {
+			return err
+		}
+		addrKR, err := p.addressKeyRing(ctx)
+		if err != nil {
+			return err
+		}
+		for _, change := range changes {
+			if change.Action != protonapi.EventActionDelete {
+				updated[change.ID] = p.toEvent(change.Event, calKR, addrKR)
+			}
+		}
+	}
+
+	p.synced.mu.Lock()
+	state = p.synced.calendars[calendarID]
+	if state == nil {
+		p.synced.mu.Unlock()
+		return nil
+	}
+	report := p.synced.report
+	if len(changes) == 0 {
+		report = nil
+	}
+	var before []domain.Event
+	if report != nil {
+		before = state.list()
+	}
+	for _, change := range changes {
+		if change.Action == protonapi.EventActionDelete {
+			delete(state.events, change.ID)
+		} else {
+			state.events[change.ID] = updated[change.ID]
+		}
+	}
+	state.cursor = cursor
+	state.lastSync = time.Now()
+	state.lastError = ""
+	var after []domain.Event
+	if report != nil {
+		after = state.list()
+	}
+	p.synced.mu.Unlock()
+	if report != nil {
+		report(Refresh{CalendarID: calendarID, Before: before, After: after})
+	}
+	return nil
+}
+
+// resyncCalendar replaces the stored events of a calendar with a full
+// listing. The cursor is taken first so no change made meanwhile is missed.
+func (p *ProtonProvider) resyncCalendar(ctx context.Context, calendarID string) error {
+	cursor, err := p.client.GetLatestCalendarModelEventID(ctx, calendarID)
+	if err != nil {
+		return err
+	}
+	events, err := p.listSeries(ctx, calendarID, time.Time{}, time.Time{})
+	if err != nil {
+		return err
+	}
+	state := &calendarSync{cursor: cursor, events: make(map[string]domain.Event, len(events)), lastSync: time.Now()}
+	for _, e := range events {
+		state.events[e.ID] = e
+	}
+	p.synced.mu.Lock()
+	if p.synced.calendars == nil {
+		p.synced.calendars = make(map[string]*calendarSync)
+	}
+	prev, loaded := p.synced.calendars[calendarID], p.synced.loaded
+	p.synced.calendars[calendarID] = state
+	report := p.synced.report
+	p.synced.mu.Unlock()
+	// The first listing of the calendars at startup is the baseline, not a
+	// change; a calendar that shows up later is reported in full.
+	if report != nil && (prev != nil || loaded) {
+		var before []domain.Event
+		if prev != nil {
+			before = prev.list()
+		}
+		report(Refresh{CalendarID: calendarID, Before: before, After: state.list()})
+	}
+	return nil
+}
+
+// list returns the stored events in no particular order.
+func (c *calendarSync) list() []domain.Event {
+	out := make([]domain.Event, 0, len(c.events))
+	for _, e := range c.events {
+		out = append(out, e)
+	}
+	return out
+}
+
+func hasUpserts(changes []protonapi.CalendarEventChange) bool {
+	for _, change := range changes {
+		if change.Action != protonapi.EventActionDelete {
+			return true
+		}
+	}
+	return false
+}
+
+// syncedEvents returns the stored events of a synced calendar, ordered by
+// start time.
+func (p *ProtonProvider) syncedEvents(calendarID string) ([]domain.Event, bool) {
+	p.synced.mu.RLock()
+	defer p.synced.mu.RUnlock()
+	state := p.synced.calendars[calendarID]
+	if state == nil {
+		return nil, false
+	}
+	out := state.list()
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Start.Equal(out[j].Start) {
+			return out[i].Start.Before(out[j].Start)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, true
+}
+
+// storeSynced and removeSynced apply the bridge's own writes right away
+// instead of waiting for the event loop to report them.
+func (p *ProtonProvider) storeSynced(calendarID string, e domain.Event) {
+	p.updateSynced(calendarID, func(events map[string]domain.Event) { events[e.ID] = e })
+}
+
+func (p *ProtonProvider) removeSynced(calendarID, eventID string) {
+	p.updateSynced(calendarID, func(events map[string]domain.Event) { delete(events, eventID) })
+}
+
+func (p *ProtonProvider) updateSynced(calendarID string, apply func(map[string]domain.Event)) {
+	p.synced.mu.Lock()
+	state := p.synced.calendars[calendarID]
+	if state == nil {
+		p.synced.mu.Unlock()
+		return
+	}
+	report := p.synced.report
+	var before []domain.Event
+	if report != nil {
+		before = state.list()
+	}
+	apply(state.events)
+	var after []domain.Event
+	if report != nil {
+		after = state.list()
+	}
+	p.synced.mu.Unlock()
+	if report != nil {
+		report(Refresh{CalendarID: calendarID, Before: before, After: after})
+	}
+}
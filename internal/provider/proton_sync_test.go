@@ -0,0 +1,306 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	proton "github.com/ProtonMail/go-proton-api"
+	gopenpgp "github.com/ProtonMail/gopenpgp/v2/crypto"
+	bridgecrypto "github.com/sevenofnine/proton-calendar-bridge/internal/crypto"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/protonapi"
+)
+
+func clearEvent(id, body string) protonapi.CalendarEvent {
+	return protonapi.CalendarEvent{ID: id, CalendarID: "cal-1", SharedEvents: []proton.CalendarEventPart{{
+		Type: proton.CalendarEventTypeClear,
+		Data: "BEGIN:VCALENDAR\nBEGIN:VEVENT\n" + body + "\nEND:VEVENT\nEND:VCALENDAR",
+	}}}
+}
+
+func syncTestProvider(t *testing.T, fake *fakeProtonClient) *ProtonProvider {
+	t.Helper()
+	kr, err := gopenpgp.NewKeyRing(nil)
+	if err != nil {
+		t.Fatalf("new keyring: %v", err)
+	}
+	return &ProtonProvider{
+		client:      fake,
+		decryptor:   &bridgecrypto.EventDecryptor{},
+		encryptor:   &bridgecrypto.EventEncryptor{},
+		calendarKRs: map[string]*gopenpgp.KeyRing{"cal-1": kr},
+		addressKR:   kr,
+	}
+}
+
+func titles(events []domain.Event) []string {
+	out := make([]string, 0, len(events))
+	for _, e := range events {
+		out = append(out, e.Title)
+	}
+	return out
+}
+
+func TestProtonProviderSyncAppliesDeltas(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeProtonClient{
+		calendars: []protonapi.Calendar{{ID: "cal-1"}},
+		cursor:    "c1",
+		events: []protonapi.CalendarEvent{
+			clearEvent("a", "SUMMARY:Alpha\nDTSTART:20260302T090000Z\nDTEND:20260302T100000Z"),
+			clearEvent("b", "SUMMARY:Beta\nDTSTART:20260303T090000Z\nDTEND:20260303T100000Z"),
+		},
+		modelEvents: map[string]protonapi.CalendarModelEvents{
+			"c1": {CalendarModelEventID: "c2", More: true, Events: []protonapi.CalendarEventChange{
+				{ID: "a", Action: protonapi.EventActionDelete},
+			}},
+			"c2": {CalendarModelEventID: "c3", Events: []protonapi.CalendarEventChange{
+				{ID: "b", Action: protonapi.EventActionUpdate, Event: clearEvent("b", "SUMMARY:Beta v2\nDTSTART:20260303T090000Z\nDTEND:20260303T100000Z")},
+				{ID: "c", Action: protonapi.EventActionCreate, Event: clearEvent("c", "SUMMARY:Gamma\nDTSTART:20260301T090000Z\nDTEND:20260301T100000Z")},
+			}},
+		},
+	}
+	p := syncTestProvider(t, fake)
+	p.SetSyncInterval(time.Minute)
+	var refreshes []Refresh
+	p.ReportChanges(func(r Refresh) { refreshes = append(refreshes, r) })
+	ctx := context.Background()
+
+	p.syncOnce(ctx)
+	if len(refreshes) != 1 || refreshes[0].CalendarID != "" {
+		t.Fatalf("expected the first listing to report only the calendars, got %+v", refreshes)
+	}
+	refreshes = nil
+	calls := fake.eventsCalls
+	events, err := p.ListEvents(ctx, "cal-1", time.Time{}, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if got := titles(events); len(got) != 2 || got[0] != "Alpha" || got[1] != "Beta" {
+		t.Fatalf("unexpected initial events: %v", got)
+	}
+	if fake.eventsCalls != calls {
+		t.Fatal("expected listing to be answered from the synced store")
+	}
+
+	p.syncOnce(ctx)
+	events, err = p.ListEvents(ctx, "cal-1", time.Time{}, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if got := titles(events); len(got) != 2 || got[0] != "Gamma" || got[1] != "Beta v2" {
+		t.Fatalf("unexpected synced events: %v", got)
+	}
+	if len(refreshes) != 2 || refreshes[0].CalendarID != "cal-1" || len(refreshes[0].Before) != 2 || len(refreshes[0].After) != 2 || refreshes[1].CalendarID != "" {
+		t.Fatalf("unexpected refreshes: %+v", refreshes)
+	}
+	series, err := p.ListSeries(ctx, "cal-1", time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC), time.Time{})
+	if err != nil || len(series) != 1 || series[0].ID != "b" {
+		t.Fatalf("unexpected series: %+v err=%v", series, err)
+	}
+	if fake.eventsCalls != calls {
+		t.Fatal("expected deltas to be applied without re-listing")
+	}
+
+	status := p.SyncStatus()
+	if !status.Enabled || status.Running || status.IntervalSeconds != 60 || len(status.Calendars) != 1 {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	if cal := status.Calendars[0]; cal.CalendarID != "cal-1" || cal.Cursor != "c3" || cal.Events != 2 || cal.LastSync.IsZero() || cal.LagSeconds < 0 {
+		t.Fatalf("unexpected calendar status: %+v", cal)
+	}
+
+	// Unsynced calendars still go to the API.
+	_, _ = p.ListEvents(ctx, "other", time.Time{}, time.Time{})
+	if fake.eventsCalls == calls {
+		t.Fatal("expected API listing for unsynced calendar")
+	}
+}
+
+func TestProtonProviderSyncRefreshAndErrors(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeProtonClient{
+		calendars: []protonapi.Calendar{{ID: "cal-1"}},
+		cursor:    "c1",
+		events:    []protonapi.CalendarEvent{clearEvent("a", "SUMMARY:Alpha\nDTSTART:20260302T090000Z\nDTEND:20260302T100000Z")},
+	}
+	p := syncTestProvider(t, fake)
+	var refreshes []Refresh
+	p.ReportChanges(func(r Refresh) {
+		if r.CalendarID != "" {
+			refreshes = append(refreshes, r)
+		}
+	})
+	ctx := context.Background()
+	p.syncOnce(ctx)
+
+	// A refresh request re-lists the calendar from the latest cursor.
+	fake.cursor = "c9"
+	fake.events = []protonapi.CalendarEvent{clearEvent("z", "SUMMARY:Zeta\nDTSTART:20260302T090000Z\nDTEND:20260302T100000Z")}
+	fake.modelEvents = map[string]protonapi.CalendarModelEvents{"c1": {CalendarModelEventID: "c2", Refresh: 1}}
+	p.syncOnce(ctx)
+	events, ok := p.syncedEvents("cal-1")
+	if !ok || len(events) != 1 || events[0].ID != "z" || p.SyncStatus().Calendars[0].Cursor != "c9" {
+		t.Fatalf("expected refreshed store, got %+v", events)
+	}
+	if len(refreshes) != 1 || titles(refreshes[0].Before)[0] != "Alpha" || titles(refreshes[0].After)[0] != "Zeta" {
+		t.Fatalf("unexpected refreshes: %+v", refreshes)
+	}
+
+	// Errors keep the previous state and are reported.
+	fake.modelErr = errors.New("loop down")
+	p.syncOnce(ctx)
+	if cal := p.SyncStatus().Calendars[0]; cal.LastError != "loop down" || cal.Events != 1 {
+		t.Fatalf("unexpected status after loop error: %+v", cal)
+	}
+	fake.err = errors.New("calendars down")
+	p.syncOnce(ctx)
+	if status := p.SyncStatus(); status.LastError != "calendars down" || len(status.Calendars) != 1 {
+		t.Fatalf("unexpected status after list error: %+v", status)
+	}
+
+	// Calendars that disappear are dropped from the store.
+	fake.err, fake.modelErr = nil, nil
+	fake.calendars = nil
+	p.syncOnce(ctx)
+	if _, ok := p.syncedEvents("cal-1"); ok || len(p.SyncStatus().Calendars) != 0 {
+		t.Fatal("expected removed calendar to be dropped")
+	}
+	if len(refreshes) != 2 || len(refreshes[1].Before) != 1 || refreshes[1].After != nil {
+		t.Fatalf("expected the removed calendar's events to be reported, got %+v", refreshes)
+	}
+}
+
+func TestProtonProviderSyncWriteThrough(t *testing.T) {
+	t.Parallel()
+
+	addrKR := generatedKeyRing(t, "Address")
+	calKR := generatedKeyRing(t, "Calendar")
+	fake := &fakeProtonClient{
+		calendars: []protonapi.Calendar{{ID: "cal-1"}},
+		members:   []protonapi.CalendarMember{{ID: "member-1", Email: "me@example.com"}},
+		addresses: []protonapi.Address{{ID: "addr-1", Email: "me@example.com"}},
+		cursor:    "c1",
+	}
+	p := syncTestProvider(t, fake)
+	p.addressKR = addrKR
+	p.calendarKRs["cal-1"] = calKR
+	p.SetWriteEnabled(true)
+	ctx := context.Background()
+	p.syncOnce(ctx)
+	var refreshes []Refresh
+	p.ReportChanges(func(r Refresh) { refreshes = append(refreshes, r) })
+
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	created, err := p.CreateEvent(ctx, domain.EventMutation{CalendarID: "cal-1", Title: "New", Start: start, End: start.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if events, _ := p.syncedEvents("cal-1"); len(events) != 1 || events[0].ID != created.ID {
+		t.Fatalf("expected created event in store, got %+v", events)
+	}
+	if err := p.DeleteEvent(ctx, created.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if events, _ := p.syncedEvents("cal-1"); len(events) != 0 {
+		t.Fatalf("expected deleted event removed, got %+v", events)
+	}
+	if len(refreshes) != 2 || len(refreshes[0].After) != 1 || len(refreshes[1].Before) != 1 || len(refreshes[1].After) != 0 {
+		t.Fatalf("expected both writes to be reported, got %+v", refreshes)
+	}
+}
+
+func TestProtonProviderRunSync(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeProtonClient{calendars: []protonapi.Calendar{{ID: "cal-1"}}, cursor: "c1"}
+	p := syncTestProvider(t, fake)
+
+	// Disabled sync returns immediately.
+	p.RunSync(context.Background())
+	if p.SyncStatus().Enabled {
+		t.Fatal("expected sync disabled by default")
+	}
+
+	p.SetSyncInterval(time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.RunSync(ctx)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := p.syncedEvents("cal-1"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for initial sync")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+	if p.SyncStatus().Running {
+		t.Fatal("expected sync to stop with its context")
+	}
+}
+
+func TestProtonProviderGetEvent(t *testing.T) {
+	t.Parallel()
+
+	series := clearEvent("series", "UID:s\nSUMMARY:Weekly\nDTSTART:20260302T090000Z\nDTEND:20260302T100000Z\nRRULE:FREQ=WEEKLY;COUNT=4")
+	moved := clearEvent("moved", "UID:s\nRECURRENCE-ID:20260309T090000Z\nSUMMARY:Moved\nDTSTART:20260309T130000Z\nDTEND:20260309T140000Z")
+	fake := &fakeProtonClient{events: []protonapi.CalendarEvent{series, moved}, event: series}
+	p := syncTestProvider(t, fake)
+	ctx := context.Background()
+
+	// A stored event is fetched directly.
+	e, err := p.GetEvent(ctx, "cal-1", "series")
+	if err != nil || e.Title != "Weekly" || fake.eventsCalls != 0 {
+		t.Fatalf("get series = %+v, %v (%d listings)", e, err, fake.eventsCalls)
+	}
+	if calendarID, _ := p.eventCalendar(ctx, "series"); calendarID != "cal-1" {
+		t.Fatalf("expected event calendar to be remembered, got %q", calendarID)
+	}
+
+	// Instance IDs fall back to a listing and resolve overrides.
+	e, err = p.GetEvent(ctx, "cal-1", "series_20260316T090000Z")
+	if err != nil || e.SeriesID != "series" || !e.Start.Equal(time.Date(2026, 3, 16, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("get instance = %+v, %v", e, err)
+	}
+	e, err = p.GetEvent(ctx, "cal-1", "series_20260309T090000Z")
+	if err != nil || e.Title != "Moved" {
+		t.Fatalf("get override = %+v, %v", e, err)
+	}
+	if _, err := p.GetEvent(ctx, "cal-1", "series_20260310T090000Z"); !errors.Is(err, ErrEventNotFound) {
+		t.Fatalf("expected not found for a date outside the series, got %v", err)
+	}
+	if _, err := p.GetEvent(ctx, "cal-1", "series_soon"); !errors.Is(err, ErrEventNotFound) {
+		t.Fatalf("expected not found for a malformed instance ID, got %v", err)
+	}
+
+	// Synced calendars answer from memory.
+	if err := p.resyncCalendar(ctx, "cal-1"); err != nil {
+		t.Fatal(err)
+	}
+	calls := fake.eventsCalls
+	if e, err := p.GetEvent(ctx, "cal-1", "moved"); err != nil || e.Title != "Moved" || fake.eventsCalls != calls {
+		t.Fatalf("get synced = %+v, %v", e, err)
+	}
+
+	if _, err := p.GetEvent(ctx, "", "series"); err == nil {
+		t.Fatal("expected calendar id error")
+	}
+	fake.err = errors.New("down")
+	if _, err := p.GetEvent(ctx, "cal-2", "series"); err == nil || errors.Is(err, ErrEventNotFound) {
+		t.Fatalf("expected listing error, got %v", err)
+	}
+	if _, err := (&ProtonProvider{}).GetEvent(ctx, "cal-1", "series"); err == nil {
+		t.Fatal("expected missing client error")
+	}
+}
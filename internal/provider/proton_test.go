@@ -2,6 +2,11 @@ package provider
 
 import (
 	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,28 +23,47 @@ type fakeProtonClient struct {
 	events               []protonapi.CalendarEvent
 	eventPages           map[int][]protonapi.CalendarEvent
 	members              []protonapi.CalendarMember
+	membersByCalendar    map[string][]protonapi.CalendarMember
 	passphrase           protonapi.CalendarPassphrase
 	keys                 protonapi.CalendarKeys
 	addresses            []protonapi.Address
 	err                  error
+	event                protonapi.CalendarEvent
+	eventErr             error
+	syncErr              error
+	syncReqs             []protonapi.CalendarEventSyncReq
 	calendarMembersCalls int
 	passphraseCalls      int
 	keysCalls            int
 	eventsCalls          int
+	filters              []protonapi.EventFilter
+	eventsByType         map[protonapi.EventQueryType][]protonapi.CalendarEvent
+	cursor               string
+	modelEvents          map[string]protonapi.CalendarModelEvents
+	modelErr             error
+	settings             protonapi.CalendarUserSettings
+	settingsErr          error
 }
 
 func (f *fakeProtonClient) GetCalendars(context.Context) ([]protonapi.Calendar, error) {
 	return f.calendars, f.err
 }
-func (f *fakeProtonClient) GetCalendarEvents(_ context.Context, _ string, page, _ int) ([]protonapi.CalendarEvent, error) {
+func (f *fakeProtonClient) GetCalendarEvents(_ context.Context, _ string, page, _ int, filter protonapi.EventFilter) ([]protonapi.CalendarEvent, error) {
 	f.eventsCalls++
+	f.filters = append(f.filters, filter)
+	if f.eventsByType != nil {
+		return f.eventsByType[filter.Type], f.err
+	}
 	if f.eventPages != nil {
 		return f.eventPages[page], f.err
 	}
 	return f.events, f.err
 }
-func (f *fakeProtonClient) GetCalendarMembers(context.Context, string) ([]protonapi.CalendarMember, error) {
+func (f *fakeProtonClient) GetCalendarMembers(_ context.Context, calendarID string) ([]protonapi.CalendarMember, error) {
 	f.calendarMembersCalls++
+	if members, ok := f.membersByCalendar[calendarID]; ok {
+		return members, f.err
+	}
 	return f.members, f.err
 }
 func (f *fakeProtonClient) GetCalendarPassphrase(context.Context, string) (protonapi.CalendarPassphrase, error) {
@@ -53,13 +77,72 @@ func (f *fakeProtonClient) GetCalendarKeys(context.Context, string) (protonapi.C
 func (f *fakeProtonClient) GetAddresses(context.Context) ([]protonapi.Address, error) {
 	return f.addresses, f.err
 }
+func (f *fakeProtonClient) GetCalendarEvent(_ context.Context, calendarID, eventID string) (protonapi.CalendarEvent, error) {
+	if f.eventErr != nil || f.event.ID != eventID || f.event.CalendarID != calendarID {
+		return protonapi.CalendarEvent{}, errors.New("event not found")
+	}
+	return f.event, nil
+}
+
+func (f *fakeProtonClient) GetLatestCalendarModelEventID(context.Context, string) (string, error) {
+	return f.cursor, f.modelErr
+}
+func (f *fakeProtonClient) GetCalendarModelEvents(_ context.Context, _, cursor string) (protonapi.CalendarModelEvents, error) {
+	if f.modelErr != nil {
+		return protonapi.CalendarModelEvents{}, f.modelErr
+	}
+	if page, ok := f.modelEvents[cursor]; ok {
+		return page, nil
+	}
+	return protonapi.CalendarModelEvents{CalendarModelEventID: cursor}, nil
+}
+func (f *fakeProtonClient) GetCalendarUserSettings(context.Context) (protonapi.CalendarUserSettings, error) {
+	return f.settings, f.settingsErr
+}
+
+// SyncCalendarEvents stores created or updated events as the API would and
+// echoes them back.
+func (f *fakeProtonClient) SyncCalendarEvents(_ context.Context, calendarID string, req protonapi.CalendarEventSyncReq) ([]protonapi.CalendarEventSyncResult, error) {
+	f.syncReqs = append(f.syncReqs, req)
+	if f.syncErr != nil {
+		return nil, f.syncErr
+	}
+	var out []protonapi.CalendarEventSyncResult
+	for i, op := range req.Events {
+		var res protonapi.CalendarEventSyncResult
+		res.Index = i
+		res.Response.Code = 1000
+		if op.Event != nil {
+			stored := protonapi.CalendarEvent{ID: op.ID, CalendarID: calendarID, SharedKeyPacket: op.Event.SharedKeyPacket}
+			if stored.ID == "" {
+				stored.ID = "created-id"
+			} else {
+				stored.SharedKeyPacket = f.event.SharedKeyPacket
+			}
+			for _, part := range op.Event.SharedEventContent {
+				stored.SharedEvents = append(stored.SharedEvents, proton.CalendarEventPart{Type: part.Type, Data: part.Data, Signature: part.Signature})
+			}
+			for _, part := range op.Event.CalendarEventContent {
+				stored.CalendarEvents = append(stored.CalendarEvents, proton.CalendarEventPart{Type: part.Type, Data: part.Data, Signature: part.Signature})
+			}
+			if pc := op.Event.PersonalEventContent; pc != nil {
+				stored.PersonalEvents = []proton.CalendarEventPart{{Type: pc.Type, Data: pc.Data, Signature: pc.Signature}}
+			}
+			f.event = stored
+			res.Response.Event = stored
+		}
+		out = append(out, res)
+	}
+	return out, nil
+}
 
 func TestProtonProviderMappingAndEvents(t *testing.T) {
 	t.Parallel()
 
 	fake := &fakeProtonClient{
 		calendars: []protonapi.Calendar{{ID: "cal-1", Name: "Work", Type: proton.CalendarType(1)}},
-		members:   []protonapi.CalendarMember{{ID: "m1", Permissions: proton.CalendarPermissions(1)}},
+		members:   []protonapi.CalendarMember{{ID: "m1", Email: "me@example.com", Permissions: proton.CalendarPermissions(1)}},
+		addresses: []protonapi.Address{{ID: "addr-1", Email: "me@example.com"}},
 		events: []protonapi.CalendarEvent{{
 			ID:         "e1",
 			CalendarID: "cal-1",
@@ -218,3 +301,495 @@ func TestProtonProviderListEventsPaginationAndParseDegrade(t *testing.T) {
 		t.Fatal("expected degraded event in output")
 	}
 }
+
+func TestProtonProviderRecurrence(t *testing.T) {
+	t.Parallel()
+
+	kr, err := gopenpgp.NewKeyRing(nil)
+	if err != nil {
+		t.Fatalf("new keyring: %v", err)
+	}
+	fake := &fakeProtonClient{events: []protonapi.CalendarEvent{{
+		ID:         "e-series",
+		CalendarID: "cal-1",
+		SharedEvents: []proton.CalendarEventPart{{
+			Type: proton.CalendarEventTypeClear,
+			Data: "BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:Gym\nDTSTART:20260302T180000Z\nDTEND:20260302T190000Z\n" +
+				"RRULE:FREQ=DAILY;COUNT=4\nEXDATE:20260303T180000Z\nEND:VEVENT\nEND:VCALENDAR",
+		}},
+	}}}
+	p := &ProtonProvider{
+		client:      fake,
+		store:       auth.Store{},
+		decryptor:   &bridgecrypto.EventDecryptor{},
+		calendarKRs: map[string]*gopenpgp.KeyRing{"cal-1": kr},
+		addressKR:   kr,
+	}
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	events, err := p.ListEvents(context.Background(), "cal-1", from, to)
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if len(events) != 3 || events[1].Start.Day() != 4 {
+		t.Fatalf("unexpected instances: %+v", events)
+	}
+
+	series, err := p.ListSeries(context.Background(), "cal-1", from, to)
+	if err != nil {
+		t.Fatalf("list series: %v", err)
+	}
+	if len(series) != 1 || series[0].Recurrence != "FREQ=DAILY;COUNT=4" || len(series[0].ExceptionDates) != 1 {
+		t.Fatalf("unexpected series: %+v", series)
+	}
+
+	fake.err = errors.New("upstream down")
+	if _, err := p.ListEvents(context.Background(), "cal-1", from, to); err == nil {
+		t.Fatal("expected list events error")
+	}
+	if _, err := p.ListSeries(context.Background(), "cal-1", from, to); err == nil {
+		t.Fatal("expected list series error")
+	}
+}
+
+func TestProtonProviderRecurrenceOverrides(t *testing.T) {
+	t.Parallel()
+
+	kr, err := gopenpgp.NewKeyRing(nil)
+	if err != nil {
+		t.Fatalf("new keyring: %v", err)
+	}
+	clear := func(data string) []proton.CalendarEventPart {
+		return []proton.CalendarEventPart{{Type: proton.CalendarEventTypeClear, Data: data}}
+	}
+	fake := &fakeProtonClient{events: []protonapi.CalendarEvent{
+		{
+			ID: "e-master", UID: "series@proton", CalendarID: "cal-1",
+			SharedEvents: clear("BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:series@proton\nSUMMARY:Sync\nDTSTART:20260302T090000Z\nDTEND:20260302T093000Z\nRRULE:FREQ=DAILY;COUNT=3\nEND:VEVENT\nEND:VCALENDAR"),
+		},
+		{
+			ID: "e-cancel", UID: "series@proton", CalendarID: "cal-1",
+			SharedEvents:   clear("BEGIN:VCALENDAR\nBEGIN:VEVENT\nUID:series@proton\nRECURRENCE-ID:20260303T090000Z\nDTSTART:20260303T090000Z\nEND:VEVENT\nEND:VCALENDAR"),
+			CalendarEvents: clear("BEGIN:VCALENDAR\nBEGIN:VEVENT\nSTATUS:CANCELLED\nEND:VEVENT\nEND:VCALENDAR"),
+		},
+	}}
+	p := &ProtonProvider{
+		client:      fake,
+		store:       auth.Store{},
+		decryptor:   &bridgecrypto.EventDecryptor{},
+		calendarKRs: map[string]*gopenpgp.KeyRing{"cal-1": kr},
+		addressKR:   kr,
+	}
+
+	events, err := p.ListEvents(context.Background(), "cal-1", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if len(events) != 2 || events[0].ID != "e-master_20260302T090000Z" || events[1].ID != "e-master_20260304T090000Z" {
+		t.Fatalf("unexpected instances: %+v", events)
+	}
+	if events[0].UID != "series@proton" || events[0].SeriesID != "e-master" {
+		t.Fatalf("unexpected instance identity: %+v", events[0])
+	}
+}
+
+func generatedKeyRing(t *testing.T, name string) *gopenpgp.KeyRing {
+	t.Helper()
+	key, err := gopenpgp.GenerateKey(name, name+"@example.com", "x25519", 0)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	kr, err := gopenpgp.NewKeyRing(key)
+	if err != nil {
+		t.Fatalf("keyring: %v", err)
+	}
+	return kr
+}
+
+func TestProtonProviderWritesDisabledByDefault(t *testing.T) {
+	t.Parallel()
+
+	p := NewProtonProviderWithKeyPassword(&fakeProtonClient{}, auth.Store{}, nil)
+	caps, _ := p.Capabilities(context.Background())
+	if caps.WriteSupported || !caps.ReadOnly {
+		t.Fatalf("unexpected capabilities: %+v", caps)
+	}
+	if _, err := p.CreateEvent(context.Background(), domain.EventMutation{}); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("expected not supported, got %v", err)
+	}
+	if _, err := p.UpdateEvent(context.Background(), "e", domain.EventMutation{}); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("expected not supported, got %v", err)
+	}
+	if err := p.DeleteEvent(context.Background(), "e"); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("expected not supported, got %v", err)
+	}
+}
+
+func TestProtonProviderWriteLifecycle(t *testing.T) {
+	t.Parallel()
+
+	calKR := generatedKeyRing(t, "calendar")
+	addrKR := generatedKeyRing(t, "address")
+	fake := &fakeProtonClient{
+		calendars: []protonapi.Calendar{{ID: "cal-1"}},
+		members:   []protonapi.CalendarMember{{ID: "member-1", Email: "me@example.com"}},
+		addresses: []protonapi.Address{{ID: "addr-1", Email: "me@example.com"}},
+	}
+	p := NewProtonProviderWithKeyPassword(fake, auth.Store{}, nil)
+	p.calendarKRs["cal-1"] = calKR
+	p.addressKR = addrKR
+	p.SetWriteEnabled(true)
+
+	caps, _ := p.Capabilities(context.Background())
+	if !caps.WriteSupported || caps.ReadOnly {
+		t.Fatalf("unexpected capabilities: %+v", caps)
+	}
+
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	in := domain.EventMutation{
+		UID: "client-uid@example.com", CalendarID: "cal-1", Title: "Dentist", Start: start, End: start.Add(time.Hour), TimeZone: "Europe/Berlin", Recurrence: "FREQ=MONTHLY", Transparent: true,
+		Reminders: []domain.Reminder{{Type: domain.ReminderEmail, Offset: "-PT1H"}},
+	}
+	created, err := p.CreateEvent(context.Background(), in)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.ID != "created-id" || created.Title != "Dentist" || created.UID != in.UID || created.TimeZone != "Europe/Berlin" || !created.Transparent || len(created.Reminders) != 1 ||
+		created.Reminders[0].Type != domain.ReminderEmail || !created.Reminders[0].FireAt.Equal(start.Add(-time.Hour)) {
+		t.Fatalf("unexpected created event: %+v", created)
+	}
+	req := fake.syncReqs[0]
+	if req.MemberID != "member-1" || req.Events[0].Event.SharedKeyPacket == "" ||
+		!strings.Contains(req.Events[0].Event.PersonalEventContent.Data, "ATTENDEE:mailto:me@example.com") ||
+		len(req.Events[0].Event.CalendarEventContent) != 1 || !strings.Contains(req.Events[0].Event.CalendarEventContent[0].Data, "STATUS:CONFIRMED") {
+		t.Fatalf("unexpected create request: %+v", req)
+	}
+
+	// Updates keep the zone and the parts a mutation does not carry.
+	fake.event.CalendarEvents = []proton.CalendarEventPart{{
+		Type: protonapi.CalendarEventTypeClear,
+		Data: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:" + created.UID + "\r\nSTATUS:TENTATIVE\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+	}}
+	fake.event.Attendees = []protonapi.CalendarAttendee{{ID: "a1", Token: "tok-1", Status: protonapi.CalendarAttendeeStatusYes}}
+	in.Title = "Dentist (moved)"
+	in.CalendarID = ""
+	in.TimeZone = ""
+	updated, err := p.UpdateEvent(context.Background(), "created-id", in)
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.Title != "Dentist (moved)" || updated.UID != created.UID || updated.TimeZone != "Europe/Berlin" ||
+		updated.Status != "TENTATIVE" || !updated.Transparent || updated.Recurrence != "FREQ=MONTHLY" {
+		t.Fatalf("unexpected updated event: %+v", updated)
+	}
+	if op := fake.syncReqs[1].Events[0]; op.ID != "created-id" || op.Event.SharedKeyPacket != "" || len(op.Event.CalendarEventContent) != 1 ||
+		len(op.Event.Attendees) != 1 || op.Event.Attendees[0].Token != "tok-1" {
+		t.Fatalf("unexpected update request: %+v", op)
+	}
+	renamed := in
+	renamed.UID = "other-uid"
+	if _, err := p.UpdateEvent(context.Background(), "created-id", renamed); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected the UID change to be refused, got %v", err)
+	}
+	invited := in
+	invited.Attendees = []domain.Attendee{{Email: "guest@example.com"}}
+	if _, err := p.UpdateEvent(context.Background(), "created-id", invited); !errors.Is(err, ErrNotSupported) {
+		t.Fatalf("expected new attendees to be refused, got %v", err)
+	}
+	if len(fake.syncReqs) != 2 {
+		t.Fatalf("expected refused updates not to be sent, got %d requests", len(fake.syncReqs))
+	}
+
+	// A fresh provider without the event index finds the calendar by asking.
+	other := NewProtonProviderWithKeyPassword(fake, auth.Store{}, nil)
+	other.SetWriteEnabled(true)
+	if err := other.DeleteEvent(context.Background(), "created-id"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if op := fake.syncReqs[2].Events[0]; op.ID != "created-id" || op.Event != nil {
+		t.Fatalf("unexpected delete request: %+v", op)
+	}
+	if err := other.DeleteEvent(context.Background(), "unknown"); err == nil {
+		t.Fatal("expected unknown event error")
+	}
+}
+
+func TestProtonProviderWriteErrors(t *testing.T) {
+	t.Parallel()
+
+	calKR := generatedKeyRing(t, "calendar")
+	fake := &fakeProtonClient{
+		members:   []protonapi.CalendarMember{{ID: "member-1", Email: "me@example.com"}},
+		addresses: []protonapi.Address{{ID: "addr-1", Email: "me@example.com"}},
+	}
+	p := NewProtonProviderWithKeyPassword(fake, auth.Store{}, nil)
+	p.calendarKRs["cal-1"] = calKR
+	p.addressKR = calKR
+	p.SetWriteEnabled(true)
+	ctx := context.Background()
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	valid := domain.EventMutation{CalendarID: "cal-1", Title: "x", Start: start, End: start}
+
+	invalid := []domain.EventMutation{
+		{Title: "no calendar", Start: start, End: start},
+		{CalendarID: "cal-1"},
+		{CalendarID: "cal-1", Start: start, End: start.Add(-time.Hour)},
+		{CalendarID: "cal-1", Start: start, End: start, TimeZone: "Mars/Olympus"},
+		{CalendarID: "cal-1", Start: start, End: start, TimeZone: "Local"},
+		{CalendarID: "cal-1", Start: start, End: start, Attendees: []domain.Attendee{{Email: "a@example.com"}}},
+		{CalendarID: "cal-1", Start: start, End: start, UID: "two\nlines"},
+		{CalendarID: "cal-1", Start: start, End: start, Reminders: []domain.Reminder{{Type: "audio", Offset: "-PT5M"}}},
+		{CalendarID: "cal-1", Start: start, End: start, Reminders: []domain.Reminder{{Offset: "-PT5M", Related: "middle"}}},
+		{CalendarID: "cal-1", Start: start, End: start, Reminders: []domain.Reminder{{}}},
+		{CalendarID: "cal-1", Start: start, End: start, Reminders: []domain.Reminder{{Offset: "15 minutes"}}},
+	}
+	for _, in := range invalid {
+		if _, err := p.CreateEvent(ctx, in); err == nil {
+			t.Fatalf("expected validation error for %+v", in)
+		}
+	}
+
+	fake.syncErr = errors.New("sync down")
+	if _, err := p.CreateEvent(ctx, valid); err == nil {
+		t.Fatal("expected sync error")
+	}
+	if _, err := p.UpdateEvent(ctx, "missing", valid); err == nil {
+		t.Fatal("expected missing event error")
+	}
+	if _, err := p.UpdateEvent(ctx, "e", domain.EventMutation{}); err == nil {
+		t.Fatal("expected update validation error")
+	}
+	p.eventCalendars = map[string]string{"e": "cal-1"}
+	if err := p.DeleteEvent(ctx, "e"); err == nil {
+		t.Fatal("expected delete sync error")
+	}
+	fake.err = errors.New("upstream down")
+	if err := p.DeleteEvent(ctx, "e"); err == nil {
+		t.Fatal("expected member lookup error")
+	}
+	if err := p.DeleteEvent(ctx, "other"); err == nil {
+		t.Fatal("expected calendar lookup error")
+	}
+	if _, err := p.CreateEvent(ctx, valid); err == nil {
+		t.Fatal("expected member lookup error on create")
+	}
+}
+
+func TestProtonProviderListEventsWindowedFetch(t *testing.T) {
+	t.Parallel()
+
+	kr, err := gopenpgp.NewKeyRing(nil)
+	if err != nil {
+		t.Fatalf("new keyring: %v", err)
+	}
+	event := func(id, body string) protonapi.CalendarEvent {
+		return protonapi.CalendarEvent{ID: id, CalendarID: "cal-1", SharedEvents: []proton.CalendarEventPart{{
+			Type: proton.CalendarEventTypeClear,
+			Data: "BEGIN:VCALENDAR\nBEGIN:VEVENT\n" + body + "\nEND:VEVENT\nEND:VCALENDAR",
+		}}}
+	}
+	inside := event("inside", "SUMMARY:Inside\nDTSTART:20260303T090000Z\nDTEND:20260303T100000Z")
+	series := event("series", "SUMMARY:Weekly\nDTSTART:20260101T120000Z\nDTEND:20260101T130000Z\nRRULE:FREQ=WEEKLY")
+	fake := &fakeProtonClient{eventsByType: map[protonapi.EventQueryType][]protonapi.CalendarEvent{
+		protonapi.EventQueryPartDayInsideWindow: {inside, series},
+		protonapi.EventQueryPartDayBeforeWindow: {series},
+	}}
+	p := &ProtonProvider{
+		client:      fake,
+		decryptor:   &bridgecrypto.EventDecryptor{},
+		calendarKRs: map[string]*gopenpgp.KeyRing{"cal-1": kr},
+		addressKR:   kr,
+	}
+
+	from := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	events, err := p.ListEvents(context.Background(), "cal-1", from, to)
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if len(events) != 2 || events[0].Title != "Inside" || events[1].Title != "Weekly" || events[1].Start.Day() != 5 {
+		t.Fatalf("unexpected events: %+v", events)
+	}
+	if len(fake.filters) != len(protonapi.EventQueryTypes) {
+		t.Fatalf("expected one query per type, got %+v", fake.filters)
+	}
+	for i, f := range fake.filters {
+		if !f.Start.Equal(from) || !f.End.Equal(to) || f.Timezone != "UTC" || f.Type != protonapi.EventQueryTypes[i] {
+			t.Fatalf("unexpected filter %d: %+v", i, f)
+		}
+	}
+
+	// Without a lower bound every event is listed with an empty filter.
+	fake.filters = nil
+	if _, err := p.ListSeries(context.Background(), "cal-1", time.Time{}, to); err != nil {
+		t.Fatalf("list series: %v", err)
+	}
+	if len(fake.filters) != 1 || !fake.filters[0].Start.IsZero() {
+		t.Fatalf("expected unfiltered listing, got %+v", fake.filters)
+	}
+
+	// An open upper bound is capped at the expansion horizon.
+	fake.filters = nil
+	if _, err := p.ListEvents(context.Background(), "cal-1", from, time.Time{}); err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	if got := fake.filters[0].End.Sub(from); got < defaultExpansionHorizon {
+		t.Fatalf("expected horizon-bounded end, got %v", got)
+	}
+
+	fake.err = errors.New("upstream down")
+	if _, err := p.ListEvents(context.Background(), "cal-1", from, to); err == nil {
+		t.Fatal("expected upstream error")
+	}
+}
+
+func TestProtonProviderHealthReportsSession(t *testing.T) {
+	t.Parallel()
+
+	p := &ProtonProvider{client: &fakeProtonClient{}}
+	if got := p.Health()["proton_session"].(map[string]any); got["status"] != protonapi.StatusUnknown {
+		t.Fatalf("unexpected health without session reporter: %+v", got)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = io.WriteString(w, `{"Code":401,"Error":"Invalid access token"}`)
+	}))
+	defer srv.Close()
+	client := protonapi.NewClient(protonapi.ClientOptions{BaseURL: srv.URL, UID: "uid", AccessToken: "acc"})
+	p = NewProtonProvider(client, auth.Store{})
+	if _, err := client.GetLatestCalendarModelEventID(context.Background(), "cal"); !errors.Is(err, protonapi.ErrSessionExpired) {
+		t.Fatalf("expected expired session, got %v", err)
+	}
+	got := p.Health()["proton_session"].(map[string]any)
+	if got["status"] != protonapi.StatusDisconnected || got["error"] == nil {
+		t.Fatalf("unexpected session health: %+v", got)
+	}
+}
+
+func TestProtonProviderCalendarMetadata(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeProtonClient{
+		calendars: []protonapi.Calendar{
+			{ID: "work", Name: "Work", Description: "Team meetings", Color: "#8080FF"},
+			{ID: "holidays", Name: "Holidays in Switzerland", Color: "#DB0000", Type: protonapi.CalendarTypeHolidays},
+			{ID: "feed", Name: "Football", Type: protonapi.CalendarTypeSubscribed},
+		},
+		members: []protonapi.CalendarMember{
+			{ID: "m-owner", Email: "owner@example.com", Permissions: protonapi.CalendarPermissionSuperOwner | protonapi.CalendarPermissionRead},
+			{ID: "m-me", Email: "me@example.com", Color: "#FFB400", Permissions: protonapi.CalendarPermissionWrite | protonapi.CalendarPermissionRead},
+		},
+		addresses: []protonapi.Address{{ID: "a1", Email: "me@example.com"}},
+		settings:  protonapi.CalendarUserSettings{PrimaryTimezone: "Europe/Zurich"},
+	}
+	p := &ProtonProvider{client: fake}
+
+	calendars, err := p.ListCalendars(context.Background())
+	if err != nil {
+		t.Fatalf("list calendars: %v", err)
+	}
+	work := calendars[0]
+	if work.Description != "Team meetings" || work.Color != "#FFB400" || work.TimeZone != "Europe/Zurich" ||
+		work.Type != domain.CalendarTypePersonal || work.Owner != "owner@example.com" || work.MemberCount != 2 || work.Order != 0 {
+		t.Fatalf("unexpected metadata: %+v", work)
+	}
+	if calendars[1].Type != domain.CalendarTypeHolidays || calendars[1].Order != 1 || calendars[2].Type != domain.CalendarTypeSubscribed {
+		t.Fatalf("unexpected types: %+v", calendars)
+	}
+
+	fake.members[1].Color = ""
+	fake.settingsErr = errors.New("down")
+	calendars, err = p.ListCalendars(context.Background())
+	if err != nil {
+		t.Fatalf("list calendars without settings: %v", err)
+	}
+	if calendars[0].Color != "#8080FF" || calendars[0].TimeZone != "" {
+		t.Fatalf("expected calendar colour and no time zone, got %+v", calendars[0])
+	}
+}
+
+func TestAttendeeStatuses(t *testing.T) {
+	t.Parallel()
+
+	parsed := bridgecrypto.ParsedEvent{
+		Attendees: []domain.Attendee{
+			{Email: "a@example.com", Status: "NEEDS-ACTION"},
+			{Email: "b@example.com", Status: "NEEDS-ACTION"},
+			{Email: "c@example.com", Status: "NEEDS-ACTION"},
+			{Email: "d@example.com", Status: "NEEDS-ACTION"},
+			{Email: "e@example.com", Status: "ACCEPTED"},
+		},
+		AttendeeTokens: []string{"ta", "tb", "tc", "", "te"},
+	}
+	got := attendeeStatuses(parsed, []protonapi.CalendarAttendee{
+		{Token: "ta", Status: protonapi.CalendarAttendeeStatusYes},
+		{Token: "tb", Status: protonapi.CalendarAttendeeStatusNo},
+		{Token: "tc", Status: protonapi.CalendarAttendeeStatusMaybe},
+		{Token: "te", Status: protonapi.CalendarAttendeeStatusPending},
+	})
+	want := []string{"ACCEPTED", "DECLINED", "TENTATIVE", "NEEDS-ACTION", "NEEDS-ACTION"}
+	for i, a := range got {
+		if a.Status != want[i] {
+			t.Fatalf("attendee %d status = %q, want %q", i, a.Status, want[i])
+		}
+	}
+}
+
+func TestProtonProviderMatchesOwnMember(t *testing.T) {
+	t.Parallel()
+
+	fake := &fakeProtonClient{
+		calendars: []protonapi.Calendar{{ID: "shared", Name: "Team", Type: proton.CalendarType(1)}},
+		members: []protonapi.CalendarMember{
+			{ID: "m-owner", Email: "owner@example.com", Permissions: 127},
+			{ID: "m-me", Email: "Me@Example.com", Permissions: protonapi.CalendarPermissionRead | protonapi.CalendarPermissionAvailability},
+		},
+		addresses: []protonapi.Address{{ID: "a1", Email: "alias@example.com"}, {ID: "a2", Email: "me@example.com"}},
+	}
+	p := &ProtonProvider{client: fake}
+
+	calendars, err := p.ListCalendars(context.Background())
+	if err != nil {
+		t.Fatalf("list calendars: %v", err)
+	}
+	if c := calendars[0]; !c.ReadOnly || len(c.Permissions) != 1 || c.Permissions[0] != "read" {
+		t.Fatalf("expected read-only membership from own member, got %+v", c)
+	}
+	if id, err := p.memberID(context.Background(), "shared"); err != nil || id != "m-me" {
+		t.Fatalf("memberID = %q, %v", id, err)
+	}
+
+	cases := []struct {
+		perms    protonapi.CalendarPermissions
+		want     string
+		readOnly bool
+	}{
+		{protonapi.CalendarPermissionWrite | protonapi.CalendarPermissionRead, "read,write", false},
+		{protonapi.CalendarPermissionAvailability, "availability", true},
+		{0, "read", true},
+	}
+	for _, tc := range cases {
+		perms, readOnly := memberPermissions(tc.perms)
+		if strings.Join(perms, ",") != tc.want || readOnly != tc.readOnly {
+			t.Fatalf("memberPermissions(%d) = %v %v", tc.perms, perms, readOnly)
+		}
+	}
+
+	// A calendar without a member for our addresses is skipped, not fatal.
+	fake.calendars = append(fake.calendars, protonapi.Calendar{ID: "own", Name: "Mine"})
+	fake.membersByCalendar = map[string][]protonapi.CalendarMember{"own": {{ID: "m-own", Email: "alias@example.com"}}}
+	fake.members = fake.members[:1]
+	calendars, err = p.ListCalendars(context.Background())
+	if err != nil || len(calendars) != 1 || calendars[0].ID != "own" {
+		t.Fatalf("expected only the calendar we belong to, got %+v %v", calendars, err)
+	}
+	if _, err := p.memberID(context.Background(), "shared"); err == nil || !strings.Contains(err.Error(), "no member for any of your addresses") {
+		t.Fatalf("expected missing membership error, got %v", err)
+	}
+	p = &ProtonProvider{client: &fakeProtonClient{calendars: fake.calendars, members: fake.members, err: errors.New("down")}}
+	if _, err := p.memberID(context.Background(), "shared"); err == nil {
+		t.Fatal("expected members error")
+	}
+}
@@ -4,13 +4,33 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/protonapi"
 )
 
 var ErrNotSupported = errors.New("operation not supported by provider")
 
+var ErrCalendarNotFound = errors.New("calendar not found")
+
+var ErrEventNotFound = errors.New("event not found")
+
+var ErrInvalidInput = errors.New("invalid input")
+
+// ErrDecryptFailed reports keys or event data that could not be decrypted.
+var ErrDecryptFailed = errors.New("decryption failed")
+
+// Upstream failure classes. The Proton client tags its errors with the same
+// sentinels, so they pass through the provider unchanged.
+var (
+	ErrUpstreamUnavailable = protonapi.ErrUnavailable
+	ErrRateLimited         = protonapi.ErrRateLimited
+	ErrSessionExpired      = protonapi.ErrSessionExpired
+)
+
 type CalendarProvider interface {
 	Name() string
 	ListCalendars(ctx context.Context) ([]domain.Calendar, error)
@@ -20,6 +40,68 @@ type CalendarProvider interface {
 	DeleteEvent(ctx context.Context, eventID string) error
 }
 
+// SeriesLister is implemented by providers that can return recurring events
+// as unexpanded series masters. ListEvents on those providers expands series
+// into the instances inside the requested window.
+type SeriesLister interface {
+	ListSeries(ctx context.Context, calendarID string, from, to time.Time) ([]domain.Event, error)
+}
+
+// EventGetter is implemented by providers that can look up a single event,
+// series master or expanded instance by the ID their listings report.
+type EventGetter interface {
+	GetEvent(ctx context.Context, calendarID, eventID string) (domain.Event, error)
+}
+
+// HealthReporter is implemented by providers that expose internal state, such
+// as cache statistics, in the health output.
+type HealthReporter interface {
+	Health() map[string]any
+}
+
+// Syncer is implemented by providers that keep a local copy of the upstream
+// calendars current in the background. RunSync blocks until ctx is done.
+type Syncer interface {
+	RunSync(ctx context.Context)
+	SyncStatus() SyncStatus
+}
+
+// ChangeReporter is implemented by providers that keep a local copy of their
+// calendars current, such as the ICS feed cache and the Proton event loop.
+// They report every refresh that changed the copy, so changes can be found
+// without listing the calendars again. fn must not block.
+type ChangeReporter interface {
+	ReportChanges(fn func(Refresh))
+}
+
+// Refresh is one update of a provider's local copy: the events of CalendarID
+// went from Before to After, both as unexpanded series. A refresh without a
+// CalendarID reports that the calendar list or a calendar's properties may
+// have changed.
+type Refresh struct {
+	CalendarID    string
+	Before, After []domain.Event
+}
+
+type SyncStatus struct {
+	Enabled         bool                 `json:"enabled"`
+	Running         bool                 `json:"running"`
+	IntervalSeconds float64              `json:"interval_seconds"`
+	LastError       string               `json:"last_error,omitempty"`
+	Calendars       []CalendarSyncStatus `json:"calendars"`
+}
+
+// CalendarSyncStatus reports the event loop position of one calendar. Lag is
+// the time since the calendar was last brought up to date.
+type CalendarSyncStatus struct {
+	CalendarID string    `json:"calendar_id"`
+	Cursor     string    `json:"cursor"`
+	Events     int       `json:"events"`
+	LastSync   time.Time `json:"last_sync"`
+	LagSeconds float64   `json:"lag_seconds"`
+	LastError  string    `json:"last_error,omitempty"`
+}
+
 type CapabilitySet struct {
 	ReadOnly        bool     `json:"read_only"`
 	WriteSupported  bool     `json:"write_supported"`
@@ -48,3 +130,65 @@ func (e NotSupportedError) Error() string {
 func (e NotSupportedError) Unwrap() error {
 	return ErrNotSupported
 }
+
+// InvalidInputError rejects a field of a request.
+type InvalidInputError struct {
+	Field  string
+	Reason string
+}
+
+func (e InvalidInputError) Error() string {
+	return fmt.Sprintf("invalid %s: %s", e.Field, e.Reason)
+}
+
+func (e InvalidInputError) Unwrap() error {
+	return ErrInvalidInput
+}
+
+// IsInstance reports whether eventID names a single occurrence of a series,
+// which providers store as part of the series and cannot write on its own.
+// Overrides looked up by their own ID are events of their own.
+func IsInstance(e domain.Event, eventID string) bool {
+	return e.SeriesID != "" && e.ID == eventID && strings.HasPrefix(eventID, e.SeriesID+"_")
+}
+
+// MutationFromEvent carries over the writable fields of an event, including
+// its UID and time zone so a series keeps its identity and wall-clock times,
+// and its attendees so providers can refuse changes they cannot write.
+// Providers keep the series-level properties a mutation does not carry, such
+// as EXDATE and RDATE.
+func MutationFromEvent(e domain.Event) domain.EventMutation {
+	return domain.EventMutation{
+		UID:         e.UID,
+		CalendarID:  e.CalendarID,
+		Title:       e.Title,
+		Description: e.Description,
+		Location:    e.Location,
+		Start:       e.Start,
+		End:         e.End,
+		AllDay:      e.AllDay,
+		Recurrence:  e.Recurrence,
+		Attendees:   e.Attendees,
+		Reminders:   e.Reminders,
+		Transparent: e.Transparent,
+		TimeZone:    e.TimeZone,
+	}
+}
+
+// SameAttendees reports whether two attendee lists hold the same people with
+// the same parameters, in any order. Email addresses compare case-insensitively.
+func SameAttendees(a, b []domain.Attendee) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	key := func(in []domain.Attendee) []domain.Attendee {
+		out := make([]domain.Attendee, len(in))
+		for i, at := range in {
+			at.Email = strings.ToLower(at.Email)
+			out[i] = at
+		}
+		slices.SortFunc(out, func(x, y domain.Attendee) int { return strings.Compare(x.Email, y.Email) })
+		return out
+	}
+	return slices.Equal(key(a), key(b))
+}
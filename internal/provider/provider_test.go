@@ -3,6 +3,8 @@ package provider
 import (
 	"errors"
 	"testing"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
 )
 
 func TestNotSupportedError(t *testing.T) {
@@ -17,3 +19,18 @@ func TestNotSupportedError(t *testing.T) {
 		t.Fatal("expected default message")
 	}
 }
+
+func TestSameAttendees(t *testing.T) {
+	a := domain.Attendee{Email: "a@example.com", Status: "ACCEPTED"}
+	b := domain.Attendee{Email: "b@example.com"}
+	upper := a
+	upper.Email = "A@Example.com"
+	declined := a
+	declined.Status = "DECLINED"
+	if !SameAttendees([]domain.Attendee{a, b}, []domain.Attendee{b, upper}) {
+		t.Fatal("expected order and email case not to matter")
+	}
+	if SameAttendees([]domain.Attendee{a, b}, []domain.Attendee{declined, b}) || SameAttendees([]domain.Attendee{a}, []domain.Attendee{a, b}) {
+		t.Fatal("expected changed or missing attendees to differ")
+	}
+}
@@ -0,0 +1,228 @@
+package provider
+
+import (
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/ical"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/recurrence"
+)
+
+// defaultExpansionHorizon bounds recurrence expansion when the caller gives
+// no upper limit.
+const defaultExpansionHorizon = 365 * 24 * time.Hour
+
+func isRecurring(e domain.Event) bool {
+	return e.Recurrence != "" || len(e.RecurrenceDates) > 0
+}
+
+// occurrences returns the start times of e's instances that overlap
+// [from, to]. The second result is false when the series cannot be expanded.
+func occurrences(e domain.Event, from, to time.Time) ([]time.Time, bool) {
+	set := recurrence.Set{Start: e.Start, RDates: e.RecurrenceDates, ExDates: e.ExceptionDates}
+	if e.Recurrence != "" {
+		rule, err := recurrence.ParseRule(e.Recurrence)
+		if err != nil {
+			slog.Warn("failed to parse recurrence rule", "event_id", e.ID, "rule", e.Recurrence, "error", err)
+			return nil, false
+		}
+		set.Rules = []recurrence.Rule{rule}
+	}
+	lower := from
+	if !lower.IsZero() {
+		lower = lower.Add(-e.End.Sub(e.Start))
+	}
+	return set.Between(lower, to.Add(time.Nanosecond)), true
+}
+
+// expansionWindow fills in the upper bound for open-ended requests.
+func expansionWindow(from, to, now time.Time) (time.Time, time.Time) {
+	if to.IsZero() {
+		base := now
+		if from.After(base) {
+			base = from
+		}
+		to = base.Add(defaultExpansionHorizon)
+	}
+	return from, to
+}
+
+func overlaps(e domain.Event, from, to time.Time) bool {
+	if !from.IsZero() && e.End.Before(from) {
+		return false
+	}
+	if !to.IsZero() && e.Start.After(to) {
+		return false
+	}
+	return true
+}
+
+// instanceID builds the stable ID of the occurrence of series that was
+// originally scheduled at recurrenceID.
+func instanceID(seriesID string, recurrenceID time.Time, allDay bool) string {
+	if allDay {
+		return seriesID + "_" + recurrenceID.Format("20060102")
+	}
+	return seriesID + "_" + recurrenceID.UTC().Format("20060102T150405Z")
+}
+
+func isCancelled(e domain.Event) bool {
+	return e.Status == "CANCELLED"
+}
+
+// seriesKey groups a series master with its RECURRENCE-ID overrides.
+func seriesKey(e domain.Event) string {
+	uid := e.UID
+	if uid == "" {
+		uid = e.ID
+	}
+	return e.CalendarID + "\x00" + uid
+}
+
+// findEvent looks up eventID among events, which are series masters and
+// overrides as a provider stores them, so overrides are found both by their
+// own ID and by the instance ID listings give them. Other instance IDs are
+// resolved by expanding the series at the recurrence ID they encode.
+func findEvent(events []domain.Event, eventID string, now time.Time) (domain.Event, bool) {
+	linked, _ := linkOverrides(events)
+	for _, e := range linked {
+		if e.ID == eventID {
+			return e, true
+		}
+	}
+	for _, e := range events {
+		if e.RecurrenceID != nil && e.ID == eventID {
+			return e, true
+		}
+	}
+	i := strings.LastIndexByte(eventID, '_')
+	if i < 0 {
+		return domain.Event{}, false
+	}
+	var recurrenceID time.Time
+	var err error
+	for _, layout := range []string{"20060102T150405Z", "20060102"} {
+		if recurrenceID, err = time.Parse(layout, eventID[i+1:]); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return domain.Event{}, false
+	}
+	for _, e := range expandEvents(events, recurrenceID, recurrenceID, now) {
+		if e.ID == eventID {
+			return e, true
+		}
+	}
+	return domain.Event{}, false
+}
+
+// linkOverrides gives every RECURRENCE-ID override the ID of the instance it
+// replaces and points it at its series master. It returns the overrides
+// grouped by series key and the original start times they replace.
+func linkOverrides(events []domain.Event) ([]domain.Event, map[string]map[int64]bool) {
+	masters := make(map[string]string)
+	for _, e := range events {
+		if e.RecurrenceID == nil {
+			if _, ok := masters[seriesKey(e)]; !ok {
+				masters[seriesKey(e)] = e.ID
+			}
+		}
+	}
+	replaced := make(map[string]map[int64]bool)
+	out := make([]domain.Event, len(events))
+	for i, e := range events {
+		if e.RecurrenceID != nil {
+			key := seriesKey(e)
+			seriesID, ok := masters[key]
+			if !ok {
+				seriesID = e.ID
+			}
+			e.SeriesID = seriesID
+			e.ID = instanceID(seriesID, *e.RecurrenceID, e.AllDay)
+			if replaced[key] == nil {
+				replaced[key] = make(map[int64]bool)
+			}
+			replaced[key][e.RecurrenceID.UnixNano()] = true
+		}
+		out[i] = e
+	}
+	return out, replaced
+}
+
+// expandEvents replaces recurring series masters with their instances that
+// overlap [from, to] and drops non-recurring events outside the window.
+// RECURRENCE-ID overrides take the place of the instance they modify, and
+// cancelled events and instances are left out.
+func expandEvents(events []domain.Event, from, to, now time.Time) []domain.Event {
+	winFrom, winTo := expansionWindow(from, to, now)
+	events, replaced := linkOverrides(events)
+	out := make([]domain.Event, 0, len(events))
+	for _, e := range events {
+		if isCancelled(e) {
+			continue
+		}
+		if e.RecurrenceID != nil || !isRecurring(e) {
+			if overlaps(e, from, to) {
+				out = append(out, e)
+			}
+			continue
+		}
+		starts, ok := occurrences(e, winFrom, winTo)
+		if !ok {
+			if overlaps(e, from, to) {
+				out = append(out, e)
+			}
+			continue
+		}
+		duration := e.End.Sub(e.Start)
+		overridden := replaced[seriesKey(e)]
+		for _, start := range starts {
+			if overridden[start.UnixNano()] {
+				continue
+			}
+			recurrenceID := start
+			instance := e
+			instance.ID = instanceID(e.ID, start, e.AllDay)
+			instance.SeriesID = e.ID
+			instance.RecurrenceID = &recurrenceID
+			instance.Start = start
+			instance.End = start.Add(duration)
+			instance.RecurrenceDates = nil
+			instance.ExceptionDates = nil
+			instance.Reminders = ical.ScheduleReminders(e.Reminders, instance.Start, instance.End)
+			out = append(out, instance)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out
+}
+
+// filterSeries keeps series masters with at least one instance in [from, to]
+// and non-recurring events and overrides that overlap it, without expanding
+// anything.
+func filterSeries(events []domain.Event, from, to, now time.Time) []domain.Event {
+	events, _ = linkOverrides(events)
+	if from.IsZero() && to.IsZero() {
+		return events
+	}
+	winFrom, winTo := expansionWindow(from, to, now)
+	out := make([]domain.Event, 0, len(events))
+	for _, e := range events {
+		if e.RecurrenceID == nil && isRecurring(e) {
+			if starts, ok := occurrences(e, winFrom, winTo); ok {
+				if len(starts) > 0 {
+					out = append(out, e)
+				}
+				continue
+			}
+		}
+		if overlaps(e, from, to) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
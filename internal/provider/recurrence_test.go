@@ -0,0 +1,157 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/domain"
+)
+
+func TestExpandEvents(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	events := []domain.Event{
+		{ID: "single", Start: start.Add(26 * time.Hour), End: start.Add(27 * time.Hour)},
+		{
+			ID:             "daily",
+			Start:          start,
+			End:            start.Add(time.Hour),
+			Recurrence:     "FREQ=DAILY;COUNT=5",
+			ExceptionDates: []time.Time{start.AddDate(0, 0, 2)},
+		},
+		{ID: "broken", Start: start, End: start.Add(time.Hour), Recurrence: "FREQ=SOMETIMES"},
+		{ID: "old", Start: start.AddDate(0, -1, 0), End: start.AddDate(0, -1, 0).Add(time.Hour)},
+	}
+
+	got := expandEvents(events, start, start.AddDate(0, 0, 7), start)
+	var ids []string
+	for _, e := range got {
+		ids = append(ids, e.ID+"@"+e.Start.Format("02T15"))
+	}
+	want := []string{
+		"daily_20260302T090000Z@02T09", "broken@02T09", "daily_20260303T090000Z@03T09",
+		"single@03T11", "daily_20260305T090000Z@05T09", "daily_20260306T090000Z@06T09",
+	}
+	if len(ids) != len(want) {
+		t.Fatalf("unexpected instances: %v", ids)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("unexpected instances: %v", ids)
+		}
+	}
+	for _, e := range got {
+		if e.End.Sub(e.Start) != time.Hour || e.ExceptionDates != nil || e.ID != "single" && e.ID != "broken" && e.SeriesID != "daily" {
+			t.Fatalf("instance not normalised: %+v", e)
+		}
+	}
+}
+
+func TestExpandEventsOverlapAndHorizon(t *testing.T) {
+	start := time.Date(2026, 1, 1, 23, 0, 0, 0, time.UTC)
+	series := domain.Event{ID: "s", Start: start, End: start.Add(2 * time.Hour), Recurrence: "FREQ=WEEKLY"}
+
+	// An instance that started before from but is still running is included.
+	got := expandEvents([]domain.Event{series}, start.Add(time.Hour), start.Add(90*time.Minute), start)
+	if len(got) != 1 || !got[0].Start.Equal(start) {
+		t.Fatalf("expected running instance: %+v", got)
+	}
+
+	// Without an upper bound expansion stops at the default horizon.
+	got = expandEvents([]domain.Event{series}, time.Time{}, time.Time{}, start)
+	if len(got) != 53 {
+		t.Fatalf("expected a year of weekly instances, got %d", len(got))
+	}
+}
+
+func TestFilterSeries(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	events := []domain.Event{
+		{ID: "yearly", Start: start, End: start.Add(time.Hour), Recurrence: "FREQ=YEARLY"},
+		{ID: "ended", Start: start, End: start.Add(time.Hour), Recurrence: "FREQ=DAILY;COUNT=2"},
+		{ID: "broken", Start: start, End: start.Add(time.Hour), Recurrence: "FREQ=SOMETIMES"},
+		{ID: "rdate", Start: start, End: start.Add(time.Hour), RecurrenceDates: []time.Time{start.AddDate(2, 0, 0)}},
+	}
+
+	if got := filterSeries(events, time.Time{}, time.Time{}, start); len(got) != len(events) {
+		t.Fatalf("expected all events without a window: %+v", got)
+	}
+	from := start.AddDate(2, 0, -1)
+	got := filterSeries(events, from, from.AddDate(0, 0, 7), start)
+	if len(got) != 2 || got[0].ID != "yearly" || got[1].ID != "rdate" {
+		t.Fatalf("unexpected series: %+v", got)
+	}
+	if got[0].Start != start {
+		t.Fatalf("series master must not be shifted: %+v", got[0])
+	}
+}
+
+func TestExpandEventsOverrides(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	moved := start.AddDate(0, 0, 1)
+	cancelled := start.AddDate(0, 0, 2)
+	outside := start.AddDate(0, 0, 10)
+	events := []domain.Event{
+		{ID: "m", UID: "series", CalendarID: "c", Title: "Daily", Start: start, End: start.Add(time.Hour), Recurrence: "FREQ=DAILY;COUNT=4"},
+		{ID: "o1", UID: "series", CalendarID: "c", Title: "Moved", RecurrenceID: &moved, Start: moved.Add(3 * time.Hour), End: moved.Add(4 * time.Hour)},
+		{ID: "o2", UID: "series", CalendarID: "c", Status: "CANCELLED", RecurrenceID: &cancelled, Start: cancelled, End: cancelled.Add(time.Hour)},
+		// An instance moved into the window from outside it.
+		{ID: "o3", UID: "series", CalendarID: "c", Title: "Pulled in", RecurrenceID: &outside, Start: start.Add(-2 * time.Hour), End: start.Add(-time.Hour)},
+		{ID: "gone", UID: "cancelled", CalendarID: "c", Status: "CANCELLED", Start: start, End: start.Add(time.Hour)},
+		{ID: "orphan", UID: "other", CalendarID: "c", RecurrenceID: &moved, Start: moved, End: moved.Add(time.Hour)},
+	}
+
+	got := expandEvents(events, start.Add(-3*time.Hour), start.AddDate(0, 0, 7), start)
+	var ids []string
+	for _, e := range got {
+		ids = append(ids, e.ID+"="+e.Title)
+	}
+	want := []string{
+		"m_20260312T090000Z=Pulled in", "m_20260302T090000Z=Daily", "orphan_20260303T090000Z=",
+		"m_20260303T090000Z=Moved", "m_20260305T090000Z=Daily",
+	}
+	if len(ids) != len(want) {
+		t.Fatalf("unexpected instances: %v", ids)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Fatalf("unexpected instances: %v", ids)
+		}
+	}
+	if got[3].SeriesID != "m" || !got[3].RecurrenceID.Equal(moved) {
+		t.Fatalf("override not linked to series: %+v", got[3])
+	}
+
+	series := filterSeries(events, time.Time{}, time.Time{}, start)
+	if len(series) != len(events) || series[1].ID != "m_20260303T090000Z" || series[0].ID != "m" {
+		t.Fatalf("unexpected series listing: %+v", series)
+	}
+}
+
+func TestInstanceIDAllDay(t *testing.T) {
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	if got := instanceID("uid", day, true); got != "uid_20260302" {
+		t.Fatalf("unexpected id %q", got)
+	}
+}
+
+func TestExpandEventsSchedulesReminders(t *testing.T) {
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	fire := start.Add(-10 * time.Minute)
+	series := domain.Event{
+		ID: "daily", Start: start, End: start.Add(time.Hour), Recurrence: "FREQ=DAILY;COUNT=3",
+		Reminders: []domain.Reminder{{Type: domain.ReminderDisplay, Offset: "-PT10M", Related: domain.RelatedStart, FireAt: &fire}},
+	}
+
+	got := expandEvents([]domain.Event{series}, start, start.AddDate(0, 0, 7), start)
+	if len(got) != 3 {
+		t.Fatalf("unexpected instances: %+v", got)
+	}
+	for _, e := range got {
+		if len(e.Reminders) != 1 || !e.Reminders[0].FireAt.Equal(e.Start.Add(-10*time.Minute)) {
+			t.Fatalf("reminder not scheduled for occurrence %s: %+v", e.ID, e.Reminders)
+		}
+	}
+	if !series.Reminders[0].FireAt.Equal(fire) {
+		t.Fatal("expansion modified the series reminders")
+	}
+}
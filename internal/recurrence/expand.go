@@ -0,0 +1,288 @@
+package recurrence
+
+import (
+	"sort"
+	"time"
+)
+
+// MaxOccurrences caps the number of occurrences Between returns for a single
+// set so that unbounded rules over wide windows stay cheap.
+const MaxOccurrences = 5000
+
+// Set is a recurrence set: DTSTART plus RRULE and RDATE occurrences, minus
+// EXDATE ones.
+type Set struct {
+	Start   time.Time
+	Rules   []Rule
+	RDates  []time.Time
+	ExDates []time.Time
+}
+
+// Between returns the occurrences that start within [from, to) in
+// chronological order. A zero from means "since DTSTART"; to is required.
+func (s Set) Between(from, to time.Time) []time.Time {
+	if to.IsZero() || !to.After(from) {
+		return nil
+	}
+	in := func(t time.Time) bool {
+		return (from.IsZero() || !t.Before(from)) && t.Before(to)
+	}
+
+	var out []time.Time
+	if in(s.Start) {
+		out = append(out, s.Start)
+	}
+	for _, rd := range s.RDates {
+		if in(rd) {
+			out = append(out, rd)
+		}
+	}
+	// Each rule may contribute enough to fill the cap after EXDATE removal.
+	limit := MaxOccurrences + len(s.ExDates) + 1
+	for _, rule := range s.Rules {
+		n := 0
+		rule.iterate(s.Start, from, to, func(t time.Time) bool {
+			if !t.Before(to) || n >= limit {
+				return false
+			}
+			if in(t) {
+				out = append(out, t)
+				n++
+			}
+			return true
+		})
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	filtered := out[:0]
+	for i, t := range out {
+		if i > 0 && t.Equal(out[i-1]) {
+			continue
+		}
+		if s.excluded(t) {
+			continue
+		}
+		filtered = append(filtered, t)
+	}
+	if len(filtered) > MaxOccurrences {
+		filtered = filtered[:MaxOccurrences]
+	}
+	return filtered
+}
+
+func (s Set) excluded(t time.Time) bool {
+	for _, ex := range s.ExDates {
+		if ex.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// iterate yields the occurrences of r anchored at dtstart in chronological
+// order until yield returns false or the periods reach stopAt. Occurrences
+// before skipBefore may be skipped when the rule has no COUNT, which lets wide
+// gaps be jumped. Stopping at the window end rather than after a run of empty
+// periods keeps sparse rules such as FREQ=DAILY;BYMONTH=2;BYMONTHDAY=29
+// working while rules that never match, such as
+// FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30, still terminate.
+func (r Rule) iterate(dtstart, skipBefore, stopAt time.Time, yield func(time.Time) bool) {
+	loc := dtstart.Location()
+	until := r.until(loc)
+	r = r.withDefaults(dtstart)
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	base := r.periodStart(dtstart)
+	k := 0
+	if r.Count == 0 && skipBefore.After(dtstart) {
+		k = r.periodsBetween(base, skipBefore)/interval - 1
+		if k < 0 {
+			k = 0
+		}
+	}
+
+	emitted := 0
+	for ; ; k++ {
+		start, end := r.period(base, k*interval)
+		if start.Year() > 9999 {
+			return
+		}
+		if !time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc).Before(stopAt) {
+			return
+		}
+		candidates := r.candidates(start, end, dtstart, loc)
+		for _, t := range candidates {
+			if t.Before(dtstart) {
+				continue
+			}
+			if !until.IsZero() && t.After(until) {
+				return
+			}
+			emitted++
+			if r.Count > 0 && emitted > r.Count {
+				return
+			}
+			if !yield(t) {
+				return
+			}
+		}
+	}
+}
+
+// withDefaults fills the BYxxx parts implied by DTSTART (RFC 5545 §3.3.10).
+func (r Rule) withDefaults(dtstart time.Time) Rule {
+	if len(r.ByDay) > 0 || len(r.ByMonthDay) > 0 {
+		return r
+	}
+	switch r.Freq {
+	case Weekly:
+		r.ByDay = []WeekdayNum{{Day: dtstart.Weekday()}}
+	case Monthly:
+		r.ByMonthDay = []int{dtstart.Day()}
+	case Yearly:
+		r.ByMonthDay = []int{dtstart.Day()}
+		if len(r.ByMonth) == 0 {
+			r.ByMonth = []int{int(dtstart.Month())}
+		}
+	}
+	return r
+}
+
+// periodStart returns the civil date, as UTC midnight, that opens the period
+// containing dtstart.
+func (r Rule) periodStart(dtstart time.Time) time.Time {
+	day := time.Date(dtstart.Year(), dtstart.Month(), dtstart.Day(), 0, 0, 0, 0, time.UTC)
+	switch r.Freq {
+	case Weekly:
+		offset := (int(day.Weekday()) - int(r.WeekStart) + 7) % 7
+		return day.AddDate(0, 0, -offset)
+	case Monthly:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, time.UTC)
+	case Yearly:
+		return time.Date(day.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// period returns the civil bounds [start, end) of the n-th period after base.
+func (r Rule) period(base time.Time, n int) (time.Time, time.Time) {
+	switch r.Freq {
+	case Weekly:
+		start := base.AddDate(0, 0, 7*n)
+		return start, start.AddDate(0, 0, 7)
+	case Monthly:
+		start := base.AddDate(0, n, 0)
+		return start, start.AddDate(0, 1, 0)
+	case Yearly:
+		start := base.AddDate(n, 0, 0)
+		return start, start.AddDate(1, 0, 0)
+	default:
+		start := base.AddDate(0, 0, n)
+		return start, start.AddDate(0, 0, 1)
+	}
+}
+
+// periodsBetween counts whole periods from base to t.
+func (r Rule) periodsBetween(base, t time.Time) int {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(day.Sub(base).Hours() / 24)
+	switch r.Freq {
+	case Weekly:
+		return days / 7
+	case Monthly:
+		return (day.Year()-base.Year())*12 + int(day.Month()) - int(base.Month())
+	case Yearly:
+		return day.Year() - base.Year()
+	default:
+		return days
+	}
+}
+
+// candidates lists the occurrences inside one period, after BYSETPOS.
+func (r Rule) candidates(start, end, dtstart time.Time, loc *time.Location) []time.Time {
+	var out []time.Time
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		if !r.matches(day) {
+			continue
+		}
+		out = append(out, time.Date(day.Year(), day.Month(), day.Day(), dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, loc))
+	}
+	if len(r.BySetPos) == 0 || len(out) == 0 {
+		return out
+	}
+	var picked []time.Time
+	for _, pos := range r.BySetPos {
+		idx := pos - 1
+		if pos < 0 {
+			idx = len(out) + pos
+		}
+		if idx >= 0 && idx < len(out) {
+			picked = append(picked, out[idx])
+		}
+	}
+	sort.Slice(picked, func(i, j int) bool { return picked[i].Before(picked[j]) })
+	return picked
+}
+
+func (r Rule) matches(day time.Time) bool {
+	if len(r.ByMonth) > 0 && !containsInt(r.ByMonth, int(day.Month())) {
+		return false
+	}
+	lastOfMonth := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if len(r.ByMonthDay) > 0 {
+		ok := false
+		for _, md := range r.ByMonthDay {
+			if md == day.Day() || md < 0 && lastOfMonth+md+1 == day.Day() {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return false
+		}
+	}
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, wd := range r.ByDay {
+		if wd.Day != day.Weekday() {
+			continue
+		}
+		if wd.N == 0 || r.Freq == Daily || r.Freq == Weekly {
+			return true
+		}
+		if r.Freq == Monthly || len(r.ByMonth) > 0 {
+			if nthInSpan(day.Day(), lastOfMonth, wd.N) {
+				return true
+			}
+			continue
+		}
+		lastOfYear := time.Date(day.Year(), 12, 31, 0, 0, 0, 0, time.UTC).YearDay()
+		if nthInSpan(day.YearDay(), lastOfYear, wd.N) {
+			return true
+		}
+	}
+	return false
+}
+
+// nthInSpan reports whether a weekday at 1-based position pos within a span
+// of length last is its n-th (or, for negative n, n-th last) occurrence.
+func nthInSpan(pos, last, n int) bool {
+	if n > 0 {
+		return (pos-1)/7+1 == n
+	}
+	return (last-pos)/7+1 == -n
+}
+
+func containsInt(list []int, v int) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
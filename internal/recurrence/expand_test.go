@@ -0,0 +1,150 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func mustRule(t *testing.T, s string) Rule {
+	t.Helper()
+	r, err := ParseRule(s)
+	if err != nil {
+		t.Fatalf("parse %q: %v", s, err)
+	}
+	return r
+}
+
+func dates(times []time.Time) []string {
+	out := make([]string, 0, len(times))
+	for _, t := range times {
+		out = append(out, t.Format("2006-01-02T15:04"))
+	}
+	return out
+}
+
+func assertDates(t *testing.T, got []time.Time, want ...string) {
+	t.Helper()
+	g := dates(got)
+	if len(g) != len(want) {
+		t.Fatalf("got %v want %v", g, want)
+	}
+	for i := range want {
+		if g[i] != want[i] {
+			t.Fatalf("got %v want %v", g, want)
+		}
+	}
+}
+
+var farFuture = time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func TestSetBetweenRFCExamples(t *testing.T) {
+	t.Parallel()
+
+	start := time.Date(1997, 8, 5, 9, 0, 0, 0, time.UTC)
+	mo := Set{Start: start, Rules: []Rule{mustRule(t, "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO")}}
+	assertDates(t, mo.Between(time.Time{}, farFuture), "1997-08-05T09:00", "1997-08-10T09:00", "1997-08-19T09:00", "1997-08-24T09:00")
+	su := Set{Start: start, Rules: []Rule{mustRule(t, "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU")}}
+	assertDates(t, su.Between(time.Time{}, farFuture), "1997-08-05T09:00", "1997-08-17T09:00", "1997-08-19T09:00", "1997-08-31T09:00")
+
+	lastWorkday := Set{
+		Start: time.Date(2026, 1, 30, 17, 0, 0, 0, time.UTC),
+		Rules: []Rule{mustRule(t, "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1;COUNT=3")},
+	}
+	assertDates(t, lastWorkday.Between(time.Time{}, farFuture), "2026-01-30T17:00", "2026-02-27T17:00", "2026-03-31T17:00")
+
+	lastFriday := Set{Start: time.Date(2026, 1, 30, 10, 0, 0, 0, time.UTC), Rules: []Rule{mustRule(t, "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3")}}
+	assertDates(t, lastFriday.Between(time.Time{}, farFuture), "2026-01-30T10:00", "2026-02-27T10:00", "2026-03-27T10:00")
+
+	monthEnd := Set{Start: time.Date(2026, 1, 31, 8, 0, 0, 0, time.UTC), Rules: []Rule{mustRule(t, "FREQ=MONTHLY;COUNT=3")}}
+	assertDates(t, monthEnd.Between(time.Time{}, farFuture), "2026-01-31T08:00", "2026-03-31T08:00", "2026-05-31T08:00")
+
+	dst := Set{Start: time.Date(2000, 3, 26, 2, 0, 0, 0, time.UTC), Rules: []Rule{mustRule(t, "FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU;COUNT=3")}}
+	assertDates(t, dst.Between(time.Time{}, farFuture), "2000-03-26T02:00", "2001-03-25T02:00", "2002-03-31T02:00")
+
+	twentiethMonday := Set{Start: time.Date(1997, 5, 19, 9, 0, 0, 0, time.UTC), Rules: []Rule{mustRule(t, "FREQ=YEARLY;BYDAY=20MO;COUNT=3")}}
+	assertDates(t, twentiethMonday.Between(time.Time{}, farFuture), "1997-05-19T09:00", "1998-05-18T09:00", "1999-05-17T09:00")
+
+	daily := Set{Start: time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC), Rules: []Rule{mustRule(t, "FREQ=DAILY;INTERVAL=10;BYMONTH=2;UNTIL=20260301")}}
+	assertDates(t, daily.Between(time.Time{}, farFuture), "2026-02-01T09:00", "2026-02-11T09:00", "2026-02-21T09:00")
+}
+
+func TestSetBetweenWindowAndExceptions(t *testing.T) {
+	t.Parallel()
+
+	standup := Set{
+		Start:   time.Date(2025, 1, 6, 9, 30, 0, 0, time.UTC),
+		Rules:   []Rule{mustRule(t, "FREQ=WEEKLY;BYDAY=MO,WE,FR")},
+		RDates:  []time.Time{time.Date(2026, 2, 21, 9, 30, 0, 0, time.UTC)},
+		ExDates: []time.Time{time.Date(2026, 2, 18, 9, 30, 0, 0, time.UTC)},
+	}
+	from := time.Date(2026, 2, 16, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 2, 23, 0, 0, 0, 0, time.UTC)
+	assertDates(t, standup.Between(from, to), "2026-02-16T09:30", "2026-02-20T09:30", "2026-02-21T09:30")
+
+	if got := standup.Between(to, from); got != nil {
+		t.Fatalf("expected empty inverted window, got %v", got)
+	}
+	if got := standup.Between(from, time.Time{}); got != nil {
+		t.Fatalf("expected nil without upper bound, got %v", got)
+	}
+
+	excludedStart := Set{Start: from, ExDates: []time.Time{from}}
+	if got := excludedStart.Between(time.Time{}, to); len(got) != 0 {
+		t.Fatalf("expected DTSTART to be excluded, got %v", got)
+	}
+}
+
+func TestSetBetweenKeepsWallClockAcrossDST(t *testing.T) {
+	t.Parallel()
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("tz database unavailable")
+	}
+	s := Set{Start: time.Date(2026, 3, 23, 9, 0, 0, 0, berlin), Rules: []Rule{mustRule(t, "FREQ=WEEKLY;COUNT=2")}}
+	got := s.Between(time.Time{}, farFuture)
+	if len(got) != 2 {
+		t.Fatalf("unexpected occurrences: %v", got)
+	}
+	if got[0].UTC().Hour() != 8 || got[1].UTC().Hour() != 7 || got[1].Hour() != 9 {
+		t.Fatalf("expected 09:00 local on both sides of the transition, got %v", got)
+	}
+}
+
+func TestSetBetweenTerminatesAndCaps(t *testing.T) {
+	t.Parallel()
+
+	never := Set{Start: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Rules: []Rule{mustRule(t, "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30")}}
+	if got := never.Between(time.Time{}, farFuture); len(got) != 1 {
+		t.Fatalf("expected only DTSTART, got %v", got)
+	}
+
+	everyDay := Set{Start: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), Rules: []Rule{mustRule(t, "FREQ=DAILY")}}
+	if got := everyDay.Between(time.Time{}, farFuture); len(got) != MaxOccurrences {
+		t.Fatalf("expected cap of %d, got %d", MaxOccurrences, len(got))
+	}
+
+	window := everyDay.Between(time.Date(2090, 6, 1, 12, 0, 0, 0, time.UTC), time.Date(2090, 6, 4, 0, 0, 0, 0, time.UTC))
+	assertDates(t, window, "2090-06-02T00:00", "2090-06-03T00:00")
+
+	monthly := Set{Start: time.Date(2000, 1, 15, 0, 0, 0, 0, time.UTC), Rules: []Rule{mustRule(t, "FREQ=MONTHLY;INTERVAL=5")}}
+	assertDates(t, monthly.Between(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)), "2026-04-15T00:00", "2026-09-15T00:00")
+
+	yearly := Set{Start: time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC), Rules: []Rule{mustRule(t, "FREQ=YEARLY")}}
+	assertDates(t, yearly.Between(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)), "2028-02-29T00:00")
+}
+
+func TestSetBetweenSparseDailyRule(t *testing.T) {
+	t.Parallel()
+
+	leapDay := Set{Start: time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC), Rules: []Rule{mustRule(t, "FREQ=DAILY;BYMONTH=2;BYMONTHDAY=29")}}
+	assertDates(t, leapDay.Between(time.Time{}, time.Date(2033, 1, 1, 0, 0, 0, 0, time.UTC)),
+		"2025-03-01T09:00", "2028-02-29T09:00", "2032-02-29T09:00")
+	assertDates(t, leapDay.Between(time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2105, 1, 1, 0, 0, 0, 0, time.UTC)),
+		"2104-02-29T09:00")
+
+	never := Set{Start: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Rules: []Rule{mustRule(t, "FREQ=DAILY;BYMONTH=2;BYMONTHDAY=30")}}
+	if got := never.Between(time.Time{}, time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)); len(got) != 1 {
+		t.Fatalf("expected only DTSTART, got %v", got)
+	}
+}
@@ -0,0 +1,213 @@
+// Package recurrence expands RFC 5545 recurrence rules (RRULE) together with
+// RDATE and EXDATE lists into concrete occurrence start times.
+package recurrence
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency int
+
+const (
+	Daily Frequency = iota
+	Weekly
+	Monthly
+	Yearly
+)
+
+var frequencyNames = map[string]Frequency{
+	"DAILY":   Daily,
+	"WEEKLY":  Weekly,
+	"MONTHLY": Monthly,
+	"YEARLY":  Yearly,
+}
+
+// WeekdayNum is a BYDAY entry such as "MO", "2TU" or "-1SU". N is zero when
+// the entry matches every such weekday in the period.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// Rule is a parsed RRULE. Until, when set, is inclusive.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []int
+	BySetPos   []int
+	WeekStart  time.Weekday
+
+	// untilDate marks an UNTIL given as a DATE, which covers the whole day in
+	// the series' own time zone.
+	untilDate bool
+	// untilFloating marks an UNTIL given without a UTC designator.
+	untilFloating bool
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// ParseRule parses an RRULE value such as "FREQ=WEEKLY;BYDAY=MO,WE".
+func ParseRule(s string) (Rule, error) {
+	r := Rule{Interval: 1, WeekStart: time.Monday}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return Rule{}, fmt.Errorf("empty rule")
+	}
+	seenFreq := false
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return Rule{}, fmt.Errorf("invalid rule part %q", part)
+		}
+		value = strings.ToUpper(strings.TrimSpace(value))
+		switch strings.ToUpper(strings.TrimSpace(key)) {
+		case "FREQ":
+			freq, ok := frequencyNames[value]
+			if !ok {
+				return Rule{}, fmt.Errorf("unsupported FREQ %q", value)
+			}
+			r.Freq = freq
+			seenFreq = true
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return Rule{}, fmt.Errorf("invalid INTERVAL %q", value)
+			}
+			r.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return Rule{}, fmt.Errorf("invalid COUNT %q", value)
+			}
+			r.Count = n
+		case "UNTIL":
+			if err := r.parseUntil(value); err != nil {
+				return Rule{}, err
+			}
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				wd, err := parseWeekdayNum(v)
+				if err != nil {
+					return Rule{}, err
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			days, err := parseIntList(value, -31, 31)
+			if err != nil {
+				return Rule{}, fmt.Errorf("invalid BYMONTHDAY: %w", err)
+			}
+			r.ByMonthDay = days
+		case "BYMONTH":
+			months, err := parseIntList(value, 1, 12)
+			if err != nil {
+				return Rule{}, fmt.Errorf("invalid BYMONTH: %w", err)
+			}
+			r.ByMonth = months
+		case "BYSETPOS":
+			pos, err := parseIntList(value, -366, 366)
+			if err != nil {
+				return Rule{}, fmt.Errorf("invalid BYSETPOS: %w", err)
+			}
+			r.BySetPos = pos
+		case "WKST":
+			day, ok := weekdayCodes[value]
+			if !ok {
+				return Rule{}, fmt.Errorf("invalid WKST %q", value)
+			}
+			r.WeekStart = day
+		default:
+			return Rule{}, fmt.Errorf("unsupported rule part %s", key)
+		}
+	}
+	if !seenFreq {
+		return Rule{}, fmt.Errorf("FREQ is required")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return Rule{}, fmt.Errorf("COUNT and UNTIL are mutually exclusive")
+	}
+	return r, nil
+}
+
+func (r *Rule) parseUntil(value string) error {
+	switch {
+	case len(value) == len("20060102"):
+		t, err := time.Parse("20060102", value)
+		if err != nil {
+			return fmt.Errorf("invalid UNTIL %q", value)
+		}
+		r.Until, r.untilDate = t, true
+	case strings.HasSuffix(value, "Z"):
+		t, err := time.Parse("20060102T150405Z", value)
+		if err != nil {
+			return fmt.Errorf("invalid UNTIL %q", value)
+		}
+		r.Until = t
+	default:
+		t, err := time.Parse("20060102T150405", value)
+		if err != nil {
+			return fmt.Errorf("invalid UNTIL %q", value)
+		}
+		r.Until, r.untilFloating = t, true
+	}
+	return nil
+}
+
+// until returns the inclusive UNTIL bound expressed in loc.
+func (r Rule) until(loc *time.Location) time.Time {
+	switch {
+	case r.Until.IsZero():
+		return time.Time{}
+	case r.untilDate:
+		return time.Date(r.Until.Year(), r.Until.Month(), r.Until.Day(), 23, 59, 59, 0, loc)
+	case r.untilFloating:
+		return time.Date(r.Until.Year(), r.Until.Month(), r.Until.Day(), r.Until.Hour(), r.Until.Minute(), r.Until.Second(), 0, loc)
+	default:
+		return r.Until
+	}
+}
+
+func parseWeekdayNum(v string) (WeekdayNum, error) {
+	v = strings.TrimSpace(v)
+	if len(v) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", v)
+	}
+	day, ok := weekdayCodes[v[len(v)-2:]]
+	if !ok {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", v)
+	}
+	wd := WeekdayNum{Day: day}
+	if prefix := v[:len(v)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -53 || n > 53 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", v)
+		}
+		wd.N = n
+	}
+	return wd, nil
+}
+
+func parseIntList(value string, lo, hi int) ([]int, error) {
+	var out []int
+	for _, v := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil || n == 0 || n < lo || n > hi {
+			return nil, fmt.Errorf("invalid value %q", v)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
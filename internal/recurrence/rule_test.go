@@ -0,0 +1,69 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRule(t *testing.T) {
+	t.Parallel()
+
+	r, err := ParseRule("RRULE:FREQ=MONTHLY;INTERVAL=2;BYDAY=1MO,-1FR,WE;BYMONTHDAY=1,-1;BYMONTH=1,6;BYSETPOS=-1;WKST=SU;UNTIL=20261231T000000Z")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if r.Freq != Monthly || r.Interval != 2 || r.WeekStart != time.Sunday || len(r.BySetPos) != 1 {
+		t.Fatalf("unexpected rule: %+v", r)
+	}
+	if r.ByDay[0] != (WeekdayNum{N: 1, Day: time.Monday}) || r.ByDay[1] != (WeekdayNum{N: -1, Day: time.Friday}) || r.ByDay[2] != (WeekdayNum{Day: time.Wednesday}) {
+		t.Fatalf("unexpected BYDAY: %+v", r.ByDay)
+	}
+	if !r.Until.Equal(time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected UNTIL: %v", r.Until)
+	}
+
+	bad := []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=x",
+		"FREQ=DAILY;COUNT=2;UNTIL=20260101",
+		"FREQ=DAILY;UNTIL=2026",
+		"FREQ=DAILY;UNTIL=2026010xT000000Z",
+		"FREQ=DAILY;UNTIL=2026010xT000000",
+		"FREQ=DAILY;UNTIL=2026010x",
+		"FREQ=WEEKLY;BYDAY=X",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=MONTHLY;BYDAY=0MO",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=YEARLY;BYMONTH=13",
+		"FREQ=YEARLY;BYSETPOS=0",
+		"FREQ=WEEKLY;WKST=XX",
+		"FREQ=WEEKLY;BYHOUR=9",
+		"FREQ",
+	}
+	for _, s := range bad {
+		if _, err := ParseRule(s); err == nil {
+			t.Fatalf("expected error for %q", s)
+		}
+	}
+}
+
+func TestRuleUntilForms(t *testing.T) {
+	t.Parallel()
+
+	loc := time.FixedZone("X", 3600)
+	date, _ := ParseRule("FREQ=DAILY;UNTIL=20260301")
+	if got := date.until(loc); !got.Equal(time.Date(2026, 3, 1, 23, 59, 59, 0, loc)) {
+		t.Fatalf("unexpected DATE until: %v", got)
+	}
+	floating, _ := ParseRule("FREQ=DAILY;UNTIL=20260301T090000")
+	if got := floating.until(loc); !got.Equal(time.Date(2026, 3, 1, 9, 0, 0, 0, loc)) {
+		t.Fatalf("unexpected floating until: %v", got)
+	}
+	none, _ := ParseRule("FREQ=DAILY")
+	if !none.until(loc).IsZero() {
+		t.Fatal("expected zero until")
+	}
+}
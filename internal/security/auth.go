@@ -6,23 +6,59 @@ import (
 	"strings"
 )
 
+// BearerAuth authenticates API requests. Token is the single full-access
+// token of PCB_BEARER_TOKEN; Tokens are the named, scoped ones. Either may be
+// empty.
 type BearerAuth struct {
 	Enabled bool
 	Token   string
+	Tokens  []Token
 }
 
+// Authorize reports whether the request carries a known token.
 func (a BearerAuth) Authorize(r *http.Request) bool {
+	_, ok := a.Authenticate(r)
+	return ok
+}
+
+// Authenticate returns the token the request presents, as a bearer
+// credential or as the password of HTTP Basic credentials with any user name
+// for clients such as CalDAV apps that cannot send bearer tokens. Requests
+// are Unrestricted when auth is disabled.
+func (a BearerAuth) Authenticate(r *http.Request) (Token, bool) {
 	if !a.Enabled {
-		return true
+		return Unrestricted, true
+	}
+	if _, password, ok := r.BasicAuth(); ok {
+		return a.match(password)
 	}
 	head := strings.TrimSpace(r.Header.Get("Authorization"))
 	const prefix = "Bearer "
 	if !strings.HasPrefix(head, prefix) {
-		return false
+		return Token{}, false
+	}
+	return a.match(strings.TrimSpace(strings.TrimPrefix(head, prefix)))
+}
+
+// match compares candidate with every secret, without stopping early, so
+// timing does not reveal which token came close.
+func (a BearerAuth) match(candidate string) (Token, bool) {
+	var found Token
+	ok := false
+	if a.Token != "" && equal(candidate, a.Token) {
+		found, ok = Token{Name: "default", Secret: a.Token, Scopes: []Scope{ScopeAdmin}}, true
+	}
+	for _, t := range a.Tokens {
+		if equal(candidate, t.Secret) && !ok {
+			found, ok = t, true
+		}
 	}
-	candidate := strings.TrimSpace(strings.TrimPrefix(head, prefix))
-	if len(candidate) != len(a.Token) {
+	return found, ok
+}
+
+func equal(candidate, secret string) bool {
+	if len(candidate) != len(secret) {
 		return false
 	}
-	return subtle.ConstantTimeCompare([]byte(candidate), []byte(a.Token)) == 1
+	return subtle.ConstantTimeCompare([]byte(candidate), []byte(secret)) == 1
 }
@@ -29,3 +29,41 @@ func TestAuthorizeDisabled(t *testing.T) {
 		t.Fatal("expected auth bypass")
 	}
 }
+
+func TestAuthorizeBasic(t *testing.T) {
+	a := BearerAuth{Enabled: true, Token: "abc123"}
+	req := httptest.NewRequest("GET", "/", nil)
+	req.SetBasicAuth("anyone", "abc123")
+	if !a.Authorize(req) {
+		t.Fatal("expected basic credentials to be accepted")
+	}
+	req.SetBasicAuth("anyone", "wrong")
+	if a.Authorize(req) {
+		t.Fatal("expected wrong basic password to be rejected")
+	}
+}
+
+func TestAuthenticateNamedTokens(t *testing.T) {
+	reader := Token{Name: "reader", Secret: "reader-secret-00001", Scopes: []Scope{ScopeEventsRead}}
+	a := BearerAuth{Enabled: true, Tokens: []Token{reader}}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer reader-secret-00001")
+	if got, ok := a.Authenticate(req); !ok || got.Name != "reader" {
+		t.Fatalf("expected the reader token, got %+v %v", got, ok)
+	}
+	// Without a full-access token an empty credential must not match.
+	req.Header.Set("Authorization", "Bearer ")
+	if _, ok := a.Authenticate(req); ok {
+		t.Fatal("expected an empty token to be rejected")
+	}
+
+	a.Token = "full"
+	req.SetBasicAuth("anyone", "full")
+	if got, ok := a.Authenticate(req); !ok || !got.Allows(ScopeAdmin) {
+		t.Fatalf("expected the full-access token, got %+v %v", got, ok)
+	}
+	if got, _ := (BearerAuth{}).Authenticate(req); got.Name != Unrestricted.Name {
+		t.Fatalf("expected unrestricted access when disabled, got %+v", got)
+	}
+}
@@ -0,0 +1,171 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Scope names a class of operations an API token may perform.
+type Scope string
+
+const (
+	ScopeCalendarsRead Scope = "calendars:read"
+	ScopeEventsRead    Scope = "events:read"
+	ScopeEventsWrite   Scope = "events:write"
+	// ScopeAdmin grants every other scope and the management routes.
+	ScopeAdmin Scope = "admin"
+)
+
+// ErrForbidden is returned when an authenticated token may not perform an
+// operation.
+var ErrForbidden = errors.New("forbidden")
+
+// minSecretLength keeps named token secrets out of guessing range.
+const minSecretLength = 16
+
+var knownScopes = []Scope{ScopeCalendarsRead, ScopeEventsRead, ScopeEventsWrite, ScopeAdmin}
+
+// Token is a named API credential. An empty Calendars list allows every
+// calendar.
+type Token struct {
+	Name      string
+	Secret    string
+	Scopes    []Scope
+	Calendars []string
+}
+
+// Unrestricted stands for callers that need no credentials: requests when
+// token auth is disabled and the stdio MCP server.
+var Unrestricted = Token{Name: "unrestricted", Scopes: []Scope{ScopeAdmin}}
+
+// Allows reports whether the token holds scope.
+func (t Token) Allows(scope Scope) bool {
+	return slices.Contains(t.Scopes, ScopeAdmin) || slices.Contains(t.Scopes, scope)
+}
+
+// Restricted reports whether the token is limited to some calendars.
+func (t Token) Restricted() bool {
+	return len(t.Calendars) > 0
+}
+
+// AllowsCalendar reports whether the token may access calendarID. A
+// restricted token is never allowed the empty ID, which providers take to
+// mean every calendar.
+func (t Token) AllowsCalendar(calendarID string) bool {
+	return !t.Restricted() || (calendarID != "" && slices.Contains(t.Calendars, calendarID))
+}
+
+// Check returns an ErrForbidden error unless the token holds scope.
+func (t Token) Check(scope Scope) error {
+	if !t.Allows(scope) {
+		return fmt.Errorf("%w: token %q lacks the %s scope", ErrForbidden, t.Name, scope)
+	}
+	return nil
+}
+
+// CheckCalendar returns an ErrForbidden error unless the token may access
+// calendarID.
+func (t Token) CheckCalendar(calendarID string) error {
+	if t.AllowsCalendar(calendarID) {
+		return nil
+	}
+	if calendarID == "" {
+		return fmt.Errorf("%w: token %q is limited to some calendars; name one", ErrForbidden, t.Name)
+	}
+	return fmt.Errorf("%w: token %q may not access calendar %s", ErrForbidden, t.Name, calendarID)
+}
+
+// CheckAllCalendars returns an ErrForbidden error unless the token may
+// access every calendar, for operations whose calendar cannot be verified.
+func (t Token) CheckAllCalendars() error {
+	if t.Restricted() {
+		return fmt.Errorf("%w: token %q is limited to some calendars and the operation's calendar cannot be verified", ErrForbidden, t.Name)
+	}
+	return nil
+}
+
+// CheckCalendars checks every ID in calendarIDs. An empty list, which means
+// every calendar, becomes the token's allowlist.
+func (t Token) CheckCalendars(calendarIDs []string) ([]string, error) {
+	if len(calendarIDs) == 0 {
+		return t.Calendars, nil
+	}
+	for _, id := range calendarIDs {
+		if err := t.CheckCalendar(id); err != nil {
+			return nil, err
+		}
+	}
+	return calendarIDs, nil
+}
+
+// ParseTokens reads comma or newline separated name|secret|scopes|calendars
+// entries. Scopes and calendars are space separated; calendars may be left
+// out to allow every calendar.
+func ParseTokens(spec string) ([]Token, error) {
+	var tokens []Token
+	names := make(map[string]bool)
+	secrets := make(map[string]bool)
+	for _, entry := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, "|")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		if len(parts) < 3 || len(parts) > 4 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid token %q: want name|secret|scopes|calendars", entry)
+		}
+		t := Token{Name: parts[0], Secret: parts[1]}
+		if names[t.Name] {
+			return nil, fmt.Errorf("duplicate token name %q", t.Name)
+		}
+		names[t.Name] = true
+		if len(t.Secret) < minSecretLength {
+			return nil, fmt.Errorf("token %q: secret must be at least %d characters", t.Name, minSecretLength)
+		}
+		if secrets[t.Secret] {
+			return nil, fmt.Errorf("token %q: secret is shared with another token", t.Name)
+		}
+		secrets[t.Secret] = true
+		for _, s := range strings.Fields(parts[2]) {
+			if !slices.Contains(knownScopes, Scope(s)) {
+				return nil, fmt.Errorf("token %q: unknown scope %q", t.Name, s)
+			}
+			t.Scopes = append(t.Scopes, Scope(s))
+		}
+		if len(t.Scopes) == 0 {
+			return nil, fmt.Errorf("token %q: at least one scope is required", t.Name)
+		}
+		if len(parts) == 4 {
+			t.Calendars = strings.Fields(parts[3])
+		}
+		// Admin tokens manage webhooks, which see every calendar.
+		if t.Restricted() && slices.Contains(t.Scopes, ScopeAdmin) {
+			return nil, fmt.Errorf("token %q: admin tokens cannot be limited to calendars", t.Name)
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, nil
+}
+
+type tokenKey struct{}
+
+// NewContext returns a copy of ctx carrying the token that authenticated the
+// request.
+func NewContext(ctx context.Context, t Token) context.Context {
+	return context.WithValue(ctx, tokenKey{}, t)
+}
+
+// FromContext returns the token carried by ctx, or Unrestricted when there
+// is none.
+func FromContext(ctx context.Context) Token {
+	if t, ok := ctx.Value(tokenKey{}).(Token); ok {
+		return t
+	}
+	return Unrestricted
+}
@@ -0,0 +1,89 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseTokens(t *testing.T) {
+	t.Parallel()
+
+	tokens, err := ParseTokens("dashboard|dashboard-secret-0001|calendars:read events:read|work home,\n agent | agent-secret-000001 | events:write ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("got %+v", tokens)
+	}
+	if d := tokens[0]; d.Name != "dashboard" || !slices.Equal(d.Calendars, []string{"work", "home"}) || !d.Allows(ScopeEventsRead) || d.Allows(ScopeEventsWrite) {
+		t.Fatalf("unexpected dashboard token %+v", d)
+	}
+	if a := tokens[1]; a.Secret != "agent-secret-000001" || a.Restricted() || !a.Allows(ScopeEventsWrite) {
+		t.Fatalf("unexpected agent token %+v", a)
+	}
+	if tokens, err := ParseTokens(""); err != nil || len(tokens) != 0 {
+		t.Fatalf("expected no tokens, got %+v %v", tokens, err)
+	}
+
+	for spec, want := range map[string]string{
+		"a|a-secret-0000000001":      "want name|secret",
+		"a|short|admin":              "at least 16",
+		"a|a-secret-0000000001|root": "unknown scope",
+		"a|a-secret-0000000001| ":    "at least one scope",
+		"a|a-secret-0000000001|admin,a|b-secret-0000000001|admin": "duplicate token name",
+		"a|a-secret-0000000001|admin,b|a-secret-0000000001|admin": "shared",
+		"a|a-secret-0000000001|admin|x|y":                         "want name|secret",
+		"|a-secret-0000000001|admin":                              "want name|secret",
+	} {
+		if _, err := ParseTokens(spec); err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%q: expected %q, got %v", spec, want, err)
+		}
+	}
+}
+
+func TestTokenChecks(t *testing.T) {
+	t.Parallel()
+
+	admin := Token{Name: "admin", Scopes: []Scope{ScopeAdmin}}
+	reader := Token{Name: "reader", Scopes: []Scope{ScopeEventsRead}, Calendars: []string{"c1"}}
+	if err := admin.Check(ScopeEventsWrite); err != nil {
+		t.Fatalf("expected admin to hold every scope, got %v", err)
+	}
+	if err := reader.Check(ScopeEventsWrite); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected forbidden, got %v", err)
+	}
+	if err := reader.CheckCalendar("c1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"c2", ""} {
+		if err := reader.CheckCalendar(id); !errors.Is(err, ErrForbidden) {
+			t.Fatalf("calendar %q: expected forbidden, got %v", id, err)
+		}
+	}
+	if err := reader.CheckAllCalendars(); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected forbidden, got %v", err)
+	}
+	if err := admin.CheckAllCalendars(); err != nil {
+		t.Fatal(err)
+	}
+	if ids, err := reader.CheckCalendars(nil); err != nil || !slices.Equal(ids, []string{"c1"}) {
+		t.Fatalf("expected the allowlist, got %v %v", ids, err)
+	}
+	if _, err := reader.CheckCalendars([]string{"c1", "c2"}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("expected forbidden, got %v", err)
+	}
+	if ids, err := admin.CheckCalendars(nil); err != nil || ids != nil {
+		t.Fatalf("expected every calendar, got %v %v", ids, err)
+	}
+
+	ctx := context.Background()
+	if got := FromContext(ctx); got.Name != Unrestricted.Name {
+		t.Fatalf("expected unrestricted, got %+v", got)
+	}
+	if got := FromContext(NewContext(ctx, reader)); got.Name != "reader" {
+		t.Fatalf("expected the reader, got %+v", got)
+	}
+}
@@ -0,0 +1,379 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/changes"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
+)
+
+var (
+	// ErrNotFound reports an unknown subscription or delivery.
+	ErrNotFound = errors.New("webhook not found")
+	// ErrStopped reports a redelivery requested after the dispatcher stopped.
+	ErrStopped = errors.New("webhook dispatcher stopped")
+)
+
+// Delivery states.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+// Retry and retention defaults. Attempt n waits DefaultBackoff * 2^(n-1),
+// capped at MaxBackoff, after the previous one failed.
+const (
+	DefaultMaxAttempts = 6
+	DefaultBackoff     = time.Second
+	MaxBackoff         = 5 * time.Minute
+	historyLimit       = 100
+	deadLetterLimit    = 100
+)
+
+// HTTPDoer sends delivery requests.
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Delivery is one change sent to one subscription, across all its attempts.
+type Delivery struct {
+	ID             string     `json:"id"`
+	SubscriptionID string     `json:"subscription_id"`
+	ChangeID       string     `json:"change_id"`
+	Type           string     `json:"type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	StatusCode     int        `json:"status_code,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	NextAttempt    *time.Time `json:"next_attempt,omitempty"`
+
+	body []byte
+}
+
+type subscriptionState struct {
+	sub        Subscription
+	history    []*Delivery
+	deadLetter []*Delivery
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+// Dispatcher follows a change feed and delivers every change to the
+// subscriptions it matches. Each delivery is retried independently, so
+// deliveries to one subscription may arrive out of order; receivers order
+// them by change ID.
+type Dispatcher struct {
+	feed        *changes.Feed
+	cursor      string
+	client      HTTPDoer
+	log         *slog.Logger
+	now         func() time.Time
+	maxAttempts int
+	backoff     time.Duration
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu   sync.Mutex
+	subs map[string]*subscriptionState
+}
+
+// NewDispatcher returns a dispatcher for feed. A nil client uses an
+// http.Client with a 10 second timeout that does not follow redirects, so
+// deliveries stay on the subscribed local URL; a redirect counts as a failed
+// attempt.
+func NewDispatcher(feed *changes.Feed, client HTTPDoer, logger *slog.Logger) *Dispatcher {
+	if client == nil {
+		client = &http.Client{
+			Timeout: 10 * time.Second,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+	if logger == nil {
+		logger = slog.Default()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		feed:        feed,
+		cursor:      feed.Head(),
+		client:      client,
+		log:         logger,
+		now:         time.Now,
+		maxAttempts: DefaultMaxAttempts,
+		backoff:     DefaultBackoff,
+		ctx:         ctx,
+		cancel:      cancel,
+		subs:        make(map[string]*subscriptionState),
+	}
+}
+
+// Add registers a subscription, assigning an ID and a secret when they are
+// empty, and returns it with its secret.
+func (d *Dispatcher) Add(sub Subscription) (Subscription, error) {
+	if err := sub.validate(); err != nil {
+		return Subscription{}, err
+	}
+	if sub.ID == "" {
+		sub.ID = randomHex(8)
+	}
+	if sub.CreatedAt.IsZero() {
+		sub.CreatedAt = d.now().UTC()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, exists := d.subs[sub.ID]; exists {
+		return Subscription{}, provider.InvalidInputError{Field: "id", Reason: fmt.Sprintf("webhook %q already exists", sub.ID)}
+	}
+	ctx, cancel := context.WithCancel(d.ctx)
+	d.subs[sub.ID] = &subscriptionState{sub: sub, ctx: ctx, cancel: cancel}
+	return sub, nil
+}
+
+// Remove drops a subscription and abandons its pending retries.
+func (d *Dispatcher) Remove(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	state, ok := d.subs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	state.cancel()
+	delete(d.subs, id)
+	return nil
+}
+
+// List returns the subscriptions by ID, without their secrets.
+func (d *Dispatcher) List() []Subscription {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]Subscription, 0, len(d.subs))
+	for _, state := range d.subs {
+		sub := state.sub
+		sub.Secret = ""
+		out = append(out, sub)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Deliveries returns the most recent deliveries of a subscription, newest
+// first.
+func (d *Dispatcher) Deliveries(id string) ([]Delivery, error) {
+	return d.snapshot(id, func(s *subscriptionState) []*Delivery { return s.history })
+}
+
+// DeadLetters returns the deliveries of a subscription that ran out of
+// attempts, newest first.
+func (d *Dispatcher) DeadLetters(id string) ([]Delivery, error) {
+	return d.snapshot(id, func(s *subscriptionState) []*Delivery { return s.deadLetter })
+}
+
+// Redeliver takes a dead letter off the list and starts it over. Once the
+// dispatcher has stopped it fails with ErrStopped and the dead letter stays.
+func (d *Dispatcher) Redeliver(subscriptionID, deliveryID string) (Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	state, ok := d.subs[subscriptionID]
+	if !ok {
+		return Delivery{}, ErrNotFound
+	}
+	for i, dl := range state.deadLetter {
+		if dl.ID != deliveryID {
+			continue
+		}
+		if state.ctx.Err() != nil {
+			return Delivery{}, ErrStopped
+		}
+		state.deadLetter = append(state.deadLetter[:i:i], state.deadLetter[i+1:]...)
+		dl.Status, dl.Attempts, dl.NextAttempt = StatusPending, 0, nil
+		dl.UpdatedAt = d.now().UTC()
+		d.start(state, dl)
+		return *dl, nil
+	}
+	return Delivery{}, ErrNotFound
+}
+
+// Run delivers changes published to the feed since the dispatcher was
+// created until ctx is done, then waits for in-flight attempts to stop.
+func (d *Dispatcher) Run(ctx context.Context) {
+	defer func() {
+		d.mu.Lock()
+		d.cancel()
+		d.mu.Unlock()
+		d.wg.Wait()
+	}()
+	cursor := d.cursor
+	for {
+		wake := d.feed.Wait()
+		items, ok := d.feed.Since(cursor)
+		if !ok {
+			d.log.Warn("webhooks: fell behind the change feed; some changes were not delivered")
+			cursor = d.feed.Head()
+		}
+		for _, c := range items {
+			cursor = c.ID
+			d.dispatch(c)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		}
+	}
+}
+
+// dispatch queues c for every subscription it matches.
+func (d *Dispatcher) dispatch(c changes.Change) {
+	body, err := json.Marshal(c)
+	if err != nil {
+		d.log.Warn("webhooks: encode change failed", "change_id", c.ID, "error", err)
+		return
+	}
+	now := d.now().UTC()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, state := range d.subs {
+		if !state.sub.matches(c) {
+			continue
+		}
+		dl := &Delivery{
+			ID:             randomHex(8),
+			SubscriptionID: state.sub.ID,
+			ChangeID:       c.ID,
+			Type:           c.Type,
+			Status:         StatusPending,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+			body:           body,
+		}
+		state.history = append([]*Delivery{dl}, state.history...)
+		if len(state.history) > historyLimit {
+			state.history = state.history[:historyLimit]
+		}
+		d.start(state, dl)
+	}
+}
+
+// start runs the attempts of dl in the background. d.mu must be held, which
+// keeps it from racing with Run shutting down.
+func (d *Dispatcher) start(state *subscriptionState, dl *Delivery) {
+	sub, ctx := state.sub, state.ctx
+	if ctx.Err() != nil {
+		return
+	}
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.deliver(ctx, sub, dl)
+	}()
+}
+
+// deliver attempts dl until it succeeds, runs out of attempts or ctx ends.
+func (d *Dispatcher) deliver(ctx context.Context, sub Subscription, dl *Delivery) {
+	for attempt := 1; ; attempt++ {
+		code, err := d.post(ctx, sub, dl)
+		if ctx.Err() != nil {
+			return
+		}
+		d.mu.Lock()
+		dl.Attempts, dl.StatusCode, dl.UpdatedAt = attempt, code, d.now().UTC()
+		dl.Error = ""
+		if err != nil {
+			dl.Error = err.Error()
+		}
+		switch {
+		case err == nil:
+			dl.Status, dl.NextAttempt = StatusDelivered, nil
+			d.mu.Unlock()
+			return
+		case attempt >= d.maxAttempts:
+			dl.Status, dl.NextAttempt = StatusDead, nil
+			if state, ok := d.subs[sub.ID]; ok {
+				state.deadLetter = append([]*Delivery{dl}, state.deadLetter...)
+				if len(state.deadLetter) > deadLetterLimit {
+					state.deadLetter = state.deadLetter[:deadLetterLimit]
+				}
+			}
+			d.mu.Unlock()
+			d.log.Warn("webhooks: delivery failed permanently", "subscription_id", sub.ID, "change_id", dl.ChangeID, "error", err)
+			return
+		}
+		wait := d.delay(attempt)
+		next := dl.UpdatedAt.Add(wait)
+		dl.NextAttempt = &next
+		d.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// post sends one attempt; any response other than 2xx is a failure.
+func (d *Dispatcher) post(ctx context.Context, sub Subscription, dl *Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader(dl.body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(d.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(sub.Secret, timestamp, dl.body))
+	req.Header.Set(HeaderDelivery, dl.ID)
+	req.Header.Set(HeaderEvent, dl.Type)
+	res, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64<<10))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("receiver answered %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
+
+// delay is the wait after a failed attempt.
+func (d *Dispatcher) delay(attempt int) time.Duration {
+	wait := d.backoff << (attempt - 1)
+	if wait <= 0 || wait > MaxBackoff {
+		return MaxBackoff
+	}
+	return wait
+}
+
+func (d *Dispatcher) snapshot(id string, pick func(*subscriptionState) []*Delivery) ([]Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	state, ok := d.subs[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	list := pick(state)
+	out := make([]Delivery, 0, len(list))
+	for _, dl := range list {
+		out = append(out, *dl)
+	}
+	return out, nil
+}
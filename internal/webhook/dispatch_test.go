@@ -0,0 +1,229 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/changes"
+)
+
+// receiver records deliveries, counting badly signed ones, and answers 503
+// to the first failures requests.
+type receiver struct {
+	mu       sync.Mutex
+	secret   string
+	failures int
+	got      []changes.Change
+	bad      int
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if r.Header.Get(HeaderSignature) != Sign(rc.secret, r.Header.Get(HeaderTimestamp), body) || r.Header.Get(HeaderDelivery) == "" {
+		rc.bad++
+	}
+	if rc.failures > 0 {
+		rc.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	var c changes.Change
+	_ = json.Unmarshal(body, &c)
+	rc.got = append(rc.got, c)
+}
+
+func (rc *receiver) received() ([]changes.Change, int) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]changes.Change(nil), rc.got...), rc.bad
+}
+
+// waitFor polls cond until it holds or the test times out.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDispatcherDelivers(t *testing.T) {
+	t.Parallel()
+
+	rc := &receiver{secret: "0123456789abcdef", failures: 2}
+	ts := httptest.NewServer(rc)
+	defer ts.Close()
+	feed := changes.NewFeed(0)
+	d := NewDispatcher(feed, nil, nil)
+	d.backoff = time.Millisecond
+	sub, err := d.Add(Subscription{URL: ts.URL, Secret: rc.secret, Types: []string{changes.EventCreated}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := d.List(); len(list) != 1 || list[0].ID != sub.ID || list[0].Secret != "" {
+		t.Fatalf("expected the listing to hide the secret, got %+v", list)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+	feed.Publish([]changes.Change{
+		{Type: changes.EventCreated, CalendarID: "c1", EventID: "e1"},
+		{Type: changes.EventDeleted, CalendarID: "c1", EventID: "e0"},
+	})
+
+	waitFor(t, "delivery", func() bool {
+		items, _ := d.Deliveries(sub.ID)
+		return len(items) == 1 && items[0].Status == StatusDelivered
+	})
+	items, _ := d.Deliveries(sub.ID)
+	if items[0].Attempts != 3 || items[0].Type != changes.EventCreated || items[0].StatusCode != http.StatusOK || items[0].Error != "" {
+		t.Fatalf("unexpected delivery %+v", items[0])
+	}
+	got, bad := rc.received()
+	if len(got) != 1 || got[0].EventID != "e1" || got[0].ID != items[0].ChangeID || bad != 0 {
+		t.Fatalf("unexpected receipts %+v (%d badly signed)", got, bad)
+	}
+	cancel()
+	<-done
+}
+
+func TestDispatcherDeadLetters(t *testing.T) {
+	t.Parallel()
+
+	rc := &receiver{secret: "0123456789abcdef", failures: 3}
+	ts := httptest.NewServer(rc)
+	defer ts.Close()
+	d := NewDispatcher(changes.NewFeed(0), nil, nil)
+	d.backoff = time.Millisecond
+	d.maxAttempts = 2
+	sub, err := d.Add(Subscription{ID: "hook", URL: ts.URL, Secret: rc.secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Add(Subscription{ID: "hook", URL: ts.URL}); err == nil {
+		t.Fatal("expected a duplicate ID to be rejected")
+	}
+	d.dispatch(changes.Change{ID: "x-1", Type: changes.CalendarChanged, CalendarID: "c1"})
+
+	waitFor(t, "dead letter", func() bool {
+		dead, _ := d.DeadLetters(sub.ID)
+		return len(dead) == 1
+	})
+	dead, _ := d.DeadLetters(sub.ID)
+	if dead[0].Status != StatusDead || dead[0].Attempts != 2 || dead[0].StatusCode != http.StatusServiceUnavailable || dead[0].Error == "" {
+		t.Fatalf("unexpected dead letter %+v", dead[0])
+	}
+
+	// The receiver fails once more, then accepts the redelivery.
+	again, err := d.Redeliver(sub.ID, dead[0].ID)
+	if err != nil || again.Status != StatusPending {
+		t.Fatalf("unexpected redelivery %+v %v", again, err)
+	}
+	waitFor(t, "redelivery", func() bool {
+		items, _ := d.Deliveries(sub.ID)
+		return len(items) == 1 && items[0].Status == StatusDelivered
+	})
+	if dead, _ := d.DeadLetters(sub.ID); len(dead) != 0 {
+		t.Fatalf("expected the dead letter to be taken off the list, got %+v", dead)
+	}
+	if _, err := d.Redeliver(sub.ID, dead[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+
+	if err := d.Remove(sub.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Remove(sub.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if _, err := d.Deliveries(sub.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+	if _, err := d.Redeliver(sub.ID, "x"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestRedeliverAfterStop(t *testing.T) {
+	t.Parallel()
+
+	rc := &receiver{secret: "0123456789abcdef", failures: 1}
+	ts := httptest.NewServer(rc)
+	defer ts.Close()
+	d := NewDispatcher(changes.NewFeed(0), nil, nil)
+	d.maxAttempts = 1
+	sub, err := d.Add(Subscription{URL: ts.URL, Secret: rc.secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.dispatch(changes.Change{ID: "x-1", Type: changes.CalendarChanged, CalendarID: "c1"})
+	waitFor(t, "dead letter", func() bool {
+		dead, _ := d.DeadLetters(sub.ID)
+		return len(dead) == 1
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	d.Run(ctx)
+
+	dead, _ := d.DeadLetters(sub.ID)
+	if _, err := d.Redeliver(sub.ID, dead[0].ID); !errors.Is(err, ErrStopped) {
+		t.Fatalf("expected stopped, got %v", err)
+	}
+	if still, _ := d.DeadLetters(sub.ID); len(still) != 1 || still[0].Status != StatusDead {
+		t.Fatalf("expected the dead letter to stay, got %+v", still)
+	}
+}
+
+func TestDispatcherRefusesRedirects(t *testing.T) {
+	t.Parallel()
+
+	rc := &receiver{secret: "0123456789abcdef"}
+	target := httptest.NewServer(rc)
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer redirect.Close()
+	d := NewDispatcher(changes.NewFeed(0), nil, nil)
+	d.maxAttempts = 1
+	sub, err := d.Add(Subscription{URL: redirect.URL, Secret: rc.secret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.dispatch(changes.Change{ID: "x-1", Type: changes.CalendarChanged, CalendarID: "c1"})
+	waitFor(t, "dead letter", func() bool {
+		dead, _ := d.DeadLetters(sub.ID)
+		return len(dead) == 1
+	})
+	if dead, _ := d.DeadLetters(sub.ID); dead[0].StatusCode != http.StatusTemporaryRedirect {
+		t.Fatalf("expected the redirect to fail the attempt, got %+v", dead[0])
+	}
+	if got, _ := rc.received(); len(got) != 0 {
+		t.Fatalf("expected the redirect not to be followed, got %+v", got)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	t.Parallel()
+
+	d := NewDispatcher(changes.NewFeed(0), nil, nil)
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 5: 16 * time.Second, 10: MaxBackoff, 80: MaxBackoff} {
+		if got := d.delay(attempt); got != want {
+			t.Fatalf("delay(%d) = %v want %v", attempt, got, want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Package webhook posts change feed entries to subscribed local URLs, signed
+// with a per-subscription secret and retried until they are delivered or
+// given up on.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/changes"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
+)
+
+// Request headers sent with every delivery. The signature is
+// "sha256=" followed by the hex HMAC-SHA256 of "<timestamp>.<body>".
+const (
+	HeaderSignature = "X-PCB-Signature"
+	HeaderTimestamp = "X-PCB-Timestamp"
+	HeaderDelivery  = "X-PCB-Delivery"
+	HeaderEvent     = "X-PCB-Event"
+)
+
+// minSecretLength keeps secrets long enough to be worth signing with.
+const minSecretLength = 16
+
+// Subscription sends the changes matching Types and CalendarIDs, or every
+// change when they are empty, to URL. Secret is only reported when the
+// subscription is created.
+type Subscription struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret,omitempty"`
+	Types       []string  `json:"types,omitempty"`
+	CalendarIDs []string  `json:"calendar_ids,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (s Subscription) matches(c changes.Change) bool {
+	return (len(s.Types) == 0 || contains(s.Types, c.Type)) &&
+		(len(s.CalendarIDs) == 0 || contains(s.CalendarIDs, c.CalendarID))
+}
+
+// validate checks the subscription and fills in a random secret when none
+// is set.
+func (s *Subscription) validate() error {
+	if err := checkURL(s.URL); err != nil {
+		return provider.InvalidInputError{Field: "url", Reason: err.Error()}
+	}
+	for _, t := range s.Types {
+		switch t {
+		case changes.EventCreated, changes.EventUpdated, changes.EventDeleted, changes.CalendarChanged:
+		default:
+			return provider.InvalidInputError{Field: "types", Reason: fmt.Sprintf("unknown change type %q", t)}
+		}
+	}
+	switch {
+	case s.Secret == "":
+		s.Secret = randomHex(32)
+	case len(s.Secret) < minSecretLength:
+		return provider.InvalidInputError{Field: "secret", Reason: "must be at least " + strconv.Itoa(minSecretLength) + " characters"}
+	}
+	return nil
+}
+
+// checkURL only admits http(s) URLs on the loopback interface; the bridge
+// does not send calendar data off the machine.
+func checkURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("must be an absolute http or https URL")
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return fmt.Errorf("must point at localhost or a loopback address")
+	}
+	return nil
+}
+
+// ParseSubscriptions parses a comma or newline separated list of
+// "id|url|secret" entries, the secret being optional.
+func ParseSubscriptions(spec string) ([]Subscription, error) {
+	var subs []Subscription
+	seen := make(map[string]bool)
+	for _, entry := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == '\n' }) {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, "|")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid webhook %q: want id|url|secret", entry)
+		}
+		if seen[parts[0]] {
+			return nil, fmt.Errorf("duplicate webhook id %q", parts[0])
+		}
+		seen[parts[0]] = true
+		sub := Subscription{ID: parts[0], URL: parts[1]}
+		if len(parts) == 3 {
+			sub.Secret = parts[2]
+		}
+		if err := sub.validate(); err != nil {
+			return nil, fmt.Errorf("webhook %q: %w", sub.ID, err)
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+// Sign returns the signature header value for body sent at timestamp.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	_, _ = rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
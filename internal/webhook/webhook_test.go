@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sevenofnine/proton-calendar-bridge/internal/changes"
+	"github.com/sevenofnine/proton-calendar-bridge/internal/provider"
+)
+
+func TestParseSubscriptions(t *testing.T) {
+	t.Parallel()
+
+	subs, err := ParseSubscriptions("a|http://127.0.0.1:8080/hook|0123456789abcdef,\n b | http://localhost/x ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subs) != 2 || subs[0].Secret != "0123456789abcdef" || subs[1].ID != "b" || len(subs[1].Secret) != 64 {
+		t.Fatalf("unexpected subscriptions %+v", subs)
+	}
+	if subs, err := ParseSubscriptions(""); err != nil || subs != nil {
+		t.Fatalf("expected no subscriptions, got %+v %v", subs, err)
+	}
+	for _, spec := range []string{
+		"http://127.0.0.1/hook",
+		"|http://127.0.0.1/hook",
+		"a|http://127.0.0.1/|s|extra",
+		"a|http://127.0.0.1/,a|http://127.0.0.1/",
+		"a|http://192.0.2.1/hook",
+		"a|http://127.0.0.1/|short",
+	} {
+		if _, err := ParseSubscriptions(spec); err == nil {
+			t.Fatalf("expected %q to be rejected", spec)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		sub   Subscription
+		field string
+	}{
+		{Subscription{URL: "ftp://127.0.0.1/"}, "url"},
+		{Subscription{URL: "http://example.com/hook"}, "url"},
+		{Subscription{URL: "/relative"}, "url"},
+		{Subscription{URL: "http://[::1]:9/", Types: []string{"event.moved"}}, "types"},
+		{Subscription{URL: "https://localhost/", Secret: "tiny"}, "secret"},
+	} {
+		var invalid provider.InvalidInputError
+		if err := tc.sub.validate(); !errors.As(err, &invalid) || invalid.Field != tc.field {
+			t.Fatalf("expected a %s error for %+v, got %v", tc.field, tc.sub, err)
+		}
+	}
+	sub := Subscription{URL: "http://[::1]:9/", Types: []string{changes.EventDeleted}, CalendarIDs: []string{"c1"}}
+	if err := sub.validate(); err != nil {
+		t.Fatal(err)
+	}
+	if !sub.matches(changes.Change{Type: changes.EventDeleted, CalendarID: "c1"}) ||
+		sub.matches(changes.Change{Type: changes.EventCreated, CalendarID: "c1"}) ||
+		sub.matches(changes.Change{Type: changes.EventDeleted, CalendarID: "c2"}) {
+		t.Fatal("unexpected filter result")
+	}
+}
+
+func TestSign(t *testing.T) {
+	t.Parallel()
+
+	got := Sign("secret", "1700000000", []byte("{}"))
+	if want := "sha256=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163"; got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+	if got == Sign("secret", "1700000001", []byte("{}")) || got == Sign("other", "1700000000", []byte("{}")) {
+		t.Fatal("expected the timestamp and secret to be signed")
+	}
+}